@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"flag"
 	"foodie/db"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
@@ -18,14 +20,24 @@ func main() {
 	decimal.MarshalJSONWithoutQuotes = true
 
 	var (
-		dsn    string
-		port   string
-		secret string
+		dsn       string
+		port      string
+		grpcPort  string
+		secret    string
+		retention time.Duration
+		idemTTL   time.Duration
+		aliases   string
+		validate  bool
 	)
 
 	flag.StringVar(&port, "port", "13307", "Server port")
+	flag.StringVar(&grpcPort, "grpc-port", "13308", "gRPC server port, empty disables the gRPC server")
 	flag.StringVar(&dsn, "db", "root:db_password@tcp(127.0.0.1:13306)/db?multiStatements=true", "Database DSN")
 	flag.StringVar(&secret, "secret", "sadghi21849adgjhlh904h3u4", "JWT secret")
+	flag.DurationVar(&retention, "trash-retention", 30*24*time.Hour, "How long deleted objects are kept in the trash")
+	flag.DurationVar(&idemTTL, "idempotency-ttl", 24*time.Hour, "How long responses of requests with idempotency keys are replayed")
+	flag.StringVar(&aliases, "ingredient-aliases", "", "JSON file mapping ingredient names of imported recipes to product names")
+	flag.BoolVar(&validate, "validate-requests", false, "Validate requests against the OpenAPI document")
 	flag.Parse()
 
 	dbh, err := db.Connect(dsn)
@@ -39,7 +51,17 @@ func main() {
 			Fatal("cannot apply migrations to the database")
 	}
 
-	srv := server.NewServer(dbh, port, []byte(secret))
+	am, err := loadAliases(aliases)
+	if err != nil {
+		logrus.WithError(err).
+			Fatal("cannot load ingredient aliases")
+	}
+
+	srv, err := server.NewServer(dbh, port, grpcPort, []byte(secret), retention, idemTTL, am, validate)
+	if err != nil {
+		logrus.WithError(err).
+			Fatal("cannot create the web server")
+	}
 
 	serverStop := make(chan struct{})
 
@@ -71,3 +93,24 @@ func main() {
 
 	logrus.Info("stopped web server")
 }
+
+// loadAliases loads the ingredient aliases from the JSON file, which
+// contains a single object mapping ingredient names to product names. No
+// aliases are loaded if the path is empty.
+func loadAliases(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var aliases map[string]string
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return nil, err
+	}
+
+	return aliases, nil
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"foodie/server/apierr"
+
+	"github.com/rs/xid"
+)
+
+// Visibility specifies who is able to see an object.
+type Visibility string
+
+const (
+	// VisibilityPrivate specifies that the object is visible only to its
+	// owner.
+	VisibilityPrivate Visibility = "private"
+
+	// VisibilityUnlisted specifies that the object is not listed, but is
+	// visible to everyone who knows its id.
+	VisibilityUnlisted Visibility = "unlisted"
+
+	// VisibilityPublic specifies that the object is visible to everyone.
+	VisibilityPublic Visibility = "public"
+)
+
+// Validate checks whether the visibility is of a valid type.
+func (v Visibility) Validate() *apierr.Error {
+	switch v {
+	case VisibilityPrivate, VisibilityUnlisted, VisibilityPublic:
+		return nil
+	default:
+		return apierr.InvalidAttribute("visibility", "must be of a valid type")
+	}
+}
+
+// State specifies the publication state of an object.
+type State string
+
+const (
+	// StateDraft specifies that the object is still being worked on and
+	// is visible only to its owner.
+	StateDraft State = "draft"
+
+	// StatePublished specifies that the object is published and is
+	// visible according to its visibility.
+	StatePublished State = "published"
+)
+
+// Validate checks whether the state is of a valid type.
+func (s State) Validate() *apierr.Error {
+	switch s {
+	case StateDraft, StatePublished:
+		return nil
+	default:
+		return apierr.InvalidAttribute("state", "must be of a valid type")
+	}
+}
+
+// Viewer specifies the user on whose behalf objects are being read. The
+// zero value represents an anonymous guest.
+type Viewer struct {
+	// UserID specifies the id of the authenticated user.
+	UserID xid.ID
+
+	// Admin specifies whether the viewer has administrator permissions.
+	Admin bool
+}
+
+// CanSee checks whether the viewer is allowed to see an object owned by
+// the provided user with the provided visibility and state.
+func (v Viewer) CanSee(uid xid.ID, vis Visibility, st State) bool {
+	if v.Admin || (!v.UserID.IsNil() && v.UserID == uid) {
+		return true
+	}
+
+	return st == StatePublished && vis != VisibilityPrivate
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Viewer_CanSee(t *testing.T) {
+	uid := xid.New()
+
+	tests := map[string]struct {
+		Viewer     Viewer
+		UserID     xid.ID
+		Visibility Visibility
+		State      State
+		Result     bool
+	}{
+		"Guest cannot see a private object": {
+			UserID:     uid,
+			Visibility: VisibilityPrivate,
+			State:      StatePublished,
+		},
+		"Guest cannot see a draft": {
+			UserID:     uid,
+			Visibility: VisibilityPublic,
+			State:      StateDraft,
+		},
+		"Guest cannot see an orphaned private object": {
+			Visibility: VisibilityPrivate,
+			State:      StatePublished,
+		},
+		"Guest can see an unlisted object": {
+			UserID:     uid,
+			Visibility: VisibilityUnlisted,
+			State:      StatePublished,
+			Result:     true,
+		},
+		"Guest can see a public object": {
+			UserID:     uid,
+			Visibility: VisibilityPublic,
+			State:      StatePublished,
+			Result:     true,
+		},
+		"Other user cannot see a private object": {
+			Viewer:     Viewer{UserID: xid.New()},
+			UserID:     uid,
+			Visibility: VisibilityPrivate,
+			State:      StatePublished,
+		},
+		"Owner can see a private draft": {
+			Viewer:     Viewer{UserID: uid},
+			UserID:     uid,
+			Visibility: VisibilityPrivate,
+			State:      StateDraft,
+			Result:     true,
+		},
+		"Admin can see a private draft": {
+			Viewer:     Viewer{UserID: xid.New(), Admin: true},
+			UserID:     uid,
+			Visibility: VisibilityPrivate,
+			State:      StateDraft,
+			Result:     true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.Result, test.Viewer.CanSee(test.UserID, test.Visibility, test.State))
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"github.com/rs/xid"
+)
+
+// Dependents contains objects that reference another object and prevent
+// it from being deleted.
+type Dependents struct {
+	// Recipes contains the dependent recipes.
+	Recipes []Dependent `json:"recipes"`
+
+	// Plans contains the dependent plans.
+	Plans []Dependent `json:"plans"`
+}
+
+// Empty checks whether there are no dependent objects.
+func (d *Dependents) Empty() bool {
+	return len(d.Recipes) == 0 && len(d.Plans) == 0
+}
+
+// Redact hides names of the dependent objects that the viewer is not
+// allowed to see.
+func (d *Dependents) Redact(vw Viewer) {
+	for _, dd := range [][]Dependent{d.Recipes, d.Plans} {
+		for i := range dd {
+			if !vw.CanSee(dd[i].UserID, dd[i].Visibility, dd[i].State) {
+				dd[i].Name = ""
+			}
+		}
+	}
+}
+
+// Dependent contains information about an object that references another
+// object.
+type Dependent struct {
+	// ID specifies the id of the dependent object.
+	ID xid.ID `json:"id"`
+
+	// UserID specifies the user which created the dependent object.
+	UserID xid.ID `json:"user_id"`
+
+	// Name specifies the name of the dependent object. It is empty if the
+	// object is not visible to the viewer.
+	Name string `json:"name,omitempty"`
+
+	// Visibility specifies who is able to see the dependent object.
+	Visibility Visibility `json:"-"`
+
+	// State specifies the publication state of the dependent object.
+	State State `json:"-"`
+
+	// Deleted specifies whether the dependent object is in the trash.
+	Deleted bool `json:"deleted"`
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Dependents_Empty(t *testing.T) {
+	assert.True(t, (&Dependents{}).Empty())
+	assert.False(t, (&Dependents{Plans: []Dependent{{}}}).Empty())
+}
+
+func Test_Dependents_Redact(t *testing.T) {
+	uid := xid.New()
+
+	dd := Dependents{
+		Recipes: []Dependent{
+			{
+				UserID:     xid.New(),
+				Name:       "1",
+				Visibility: VisibilityPrivate,
+				State:      StatePublished,
+			},
+			{
+				UserID:     uid,
+				Name:       "2",
+				Visibility: VisibilityPrivate,
+				State:      StateDraft,
+			},
+		},
+		Plans: []Dependent{
+			{
+				UserID:     xid.New(),
+				Name:       "3",
+				Visibility: VisibilityUnlisted,
+				State:      StatePublished,
+			},
+		},
+	}
+
+	dd.Redact(Viewer{UserID: uid})
+
+	assert.Empty(t, dd.Recipes[0].Name)
+	assert.Equal(t, "2", dd.Recipes[1].Name)
+	assert.Equal(t, "3", dd.Plans[0].Name)
+}
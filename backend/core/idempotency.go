@@ -0,0 +1,50 @@
+package core
+
+import (
+	"foodie/server/apierr"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// IdempotentRequest is a request that was made with an idempotency key,
+// together with its response once it is handled.
+type IdempotentRequest struct {
+	// UserID specifies the user that made the request. It is nil for
+	// guests.
+	UserID xid.ID
+
+	// Key specifies the idempotency key of the request.
+	Key string
+
+	// Hash specifies the hash of the request method, path and body, which
+	// must match for the response to be replayed.
+	Hash string
+
+	// StatusCode specifies the status code of the response. It is zero
+	// while the request is being handled.
+	StatusCode int
+
+	// ContentType specifies the media type of the response body.
+	ContentType string
+
+	// Body contains the response body.
+	Body []byte
+
+	// CreatedAt specifies a time at which the request was made.
+	CreatedAt time.Time
+}
+
+// Completed checks whether the response of the request is stored.
+func (ir IdempotentRequest) Completed() bool {
+	return ir.StatusCode != 0
+}
+
+// ValidateIdempotencyKey validates the idempotency key of a request.
+func ValidateIdempotencyKey(key string) *apierr.Error {
+	var vv apierr.Violations
+
+	checkLength(&vv, "Idempotency-Key", key, _maxIdempotencyKeyLength)
+
+	return vv.Err()
+}
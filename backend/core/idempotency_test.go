@@ -0,0 +1,39 @@
+package core
+
+import (
+	"foodie/server/apierr"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IdempotentRequest_Completed(t *testing.T) {
+	assert.False(t, IdempotentRequest{}.Completed())
+	assert.True(t, IdempotentRequest{StatusCode: 200}.Completed())
+}
+
+func Test_ValidateIdempotencyKey(t *testing.T) {
+	tests := map[string]struct {
+		Key   string
+		Error *apierr.Error
+	}{
+		"Too long key": {
+			Key:   strings.Repeat("a", 256),
+			Error: apierr.InvalidAttribute("Idempotency-Key", "cannot be longer than 255 characters"),
+		},
+		"Valid key": {
+			Key: "a8098c1a-f86e-11da-bd1a-00112444be1e",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.Error, ValidateIdempotencyKey(test.Key))
+		})
+	}
+}
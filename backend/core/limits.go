@@ -0,0 +1,66 @@
+package core
+
+import (
+	"fmt"
+	"foodie/server/apierr"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// _maxNameLength specifies the maximum length of object names. It
+	// matches the size of the name columns.
+	_maxNameLength = 255
+
+	// _maxDescriptionLength specifies the maximum length of object
+	// descriptions. It matches the size of the description columns.
+	_maxDescriptionLength = 1023
+
+	// _maxImageURLLength specifies the maximum length of image urls. It
+	// matches the size of the image_url columns.
+	_maxImageURLLength = 1023
+
+	// _maxAliasesLength specifies the maximum length of all product
+	// aliases joined by line breaks. It matches the size of the aliases
+	// column.
+	_maxAliasesLength = 1023
+
+	// _maxWebhookURLLength specifies the maximum length of webhook urls.
+	// It matches the size of the webhooks url column.
+	_maxWebhookURLLength = 1023
+
+	// _maxIdempotencyKeyLength specifies the maximum length of
+	// idempotency keys. It matches the size of the idempotency_keys key
+	// column.
+	_maxIdempotencyKeyLength = 255
+
+	// _maxCalories specifies the maximum number of calories of a single
+	// serving. It matches the range of the serving_calories column.
+	_maxCalories = 1<<31 - 1
+
+	// _maxPlanRecipeQuantity specifies the maximum quantity of a recipe
+	// in a plan. It matches the range of the plan_recipes quantity
+	// column.
+	_maxPlanRecipeQuantity = 1<<32 - 1
+)
+
+// _maxDecimal specifies the exclusive upper bound of decimal values. It
+// matches the range of the DECIMAL(18, 4) columns.
+var _maxDecimal = decimal.New(1, 14)
+
+// checkLength adds a violation if the value is longer than the maximum
+// number of characters.
+func checkLength(vv *apierr.Violations, attribute, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		vv.Add(attribute, fmt.Sprintf("cannot be longer than %d characters", max))
+	}
+}
+
+// checkDecimal adds a violation if the value is out of the range of the
+// decimal columns.
+func checkDecimal(vv *apierr.Violations, attribute string, value decimal.Decimal) {
+	if value.Cmp(_maxDecimal) >= 0 {
+		vv.Add(attribute, "must be less than "+_maxDecimal.String())
+	}
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"encoding/base64"
+	"foodie/server/apierr"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// Page specifies a window of a list of objects ordered by their creation
+// time and id.
+type Page struct {
+	// Limit specifies the maximum number of objects in the page. It must
+	// be positive.
+	Limit uint64
+
+	// Cursor specifies the position after which the page starts. Nil
+	// cursor specifies the first page.
+	Cursor *Cursor
+}
+
+// Cursor specifies a position in a list of objects ordered by their
+// creation time and id. Lists with a custom sort order are positioned by
+// the offset instead. It is exposed to the clients as an opaque string.
+type Cursor struct {
+	// CreatedAt specifies the creation time of the last object of the
+	// previous page.
+	CreatedAt time.Time
+
+	// ID specifies the id of the last object of the previous page.
+	ID xid.ID
+
+	// Offset specifies the number of objects on the previous pages of a
+	// list with a custom sort order.
+	Offset uint64
+}
+
+// ParseCursor parses a cursor from its opaque string representation.
+func ParseCursor(s string) (*Cursor, *apierr.Error) {
+	invalid := apierr.InvalidAttribute("cursor", "must be a valid cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalid
+	}
+
+	if strings.HasPrefix(string(raw), "+") {
+		off, err := strconv.ParseUint(string(raw[1:]), 10, 64)
+		if err != nil || off == 0 {
+			return nil, invalid
+		}
+
+		return &Cursor{Offset: off}, nil
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return nil, invalid
+	}
+
+	usec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+
+	id, err := xid.FromString(parts[1])
+	if err != nil {
+		return nil, invalid
+	}
+
+	return &Cursor{
+		CreatedAt: time.UnixMicro(usec).UTC(),
+		ID:        id,
+	}, nil
+}
+
+// String returns the opaque string representation of the cursor.
+func (c Cursor) String() string {
+	if c.Offset > 0 {
+		return base64.RawURLEncoding.EncodeToString(
+			[]byte("+" + strconv.FormatUint(c.Offset, 10)),
+		)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(
+		[]byte(strconv.FormatInt(c.CreatedAt.UnixMicro(), 10) + ":" + c.ID.String()),
+	)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (c Cursor) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// Before checks whether the cursor precedes the other cursor in the list
+// order.
+func (c Cursor) Before(other Cursor) bool {
+	if c.CreatedAt.Equal(other.CreatedAt) {
+		return c.ID.Compare(other.ID) < 0
+	}
+
+	return c.CreatedAt.Before(other.CreatedAt)
+}
@@ -0,0 +1,36 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseCursor(t *testing.T) {
+	cur := Cursor{
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		ID:        xid.New(),
+	}
+
+	res, aerr := ParseCursor(cur.String())
+	require.Nil(t, aerr)
+	assert.Equal(t, &cur, res)
+
+	for _, s := range []string{"", "!", "MTIz", "YWJjOmRlZg"} {
+		_, aerr = ParseCursor(s)
+		assert.NotNil(t, aerr, s)
+	}
+}
+
+func Test_Cursor_Before(t *testing.T) {
+	tstamp := time.Now()
+	id1 := xid.New()
+	id2 := xid.New()
+
+	assert.True(t, Cursor{CreatedAt: tstamp, ID: id1}.Before(Cursor{CreatedAt: tstamp, ID: id2}))
+	assert.False(t, Cursor{CreatedAt: tstamp, ID: id2}.Before(Cursor{CreatedAt: tstamp, ID: id1}))
+	assert.True(t, Cursor{CreatedAt: tstamp, ID: id2}.Before(Cursor{CreatedAt: tstamp.Add(time.Second), ID: id1}))
+}
@@ -11,6 +11,7 @@ import (
 // Plan contains plan data.
 type Plan struct {
 	PlanCore
+	Revision
 
 	// ID is a unique plan identifier.
 	ID xid.ID `json:"id"`
@@ -20,6 +21,18 @@ type Plan struct {
 
 	// CreatedAt specifies a time at which the object was created.
 	CreatedAt time.Time `json:"created_at"`
+
+	// DeletedAt specifies a time at which the object was moved to the
+	// trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Cursor returns the position of the plan in a paginated list.
+func (p Plan) Cursor() Cursor {
+	return Cursor{
+		CreatedAt: p.CreatedAt,
+		ID:        p.ID,
+	}
 }
 
 // PlanCore contains core plan information.
@@ -30,31 +43,56 @@ type PlanCore struct {
 	// Description provides a brief description of the plan.
 	Description string `json:"description"`
 
+	// Visibility specifies who is able to see the plan.
+	Visibility Visibility `json:"visibility"`
+
+	// State specifies the publication state of the plan.
+	State State `json:"state"`
+
 	// Recipes contains plan recipes.
 	Recipes []PlanRecipe `json:"recipes"`
 }
 
-// Validate checks whether plan core contains valid attributes.
+// Validate checks whether plan core contains valid attributes. All
+// invalid attributes are reported at once.
 func (pc *PlanCore) Validate() *apierr.Error {
+	var vv apierr.Violations
+
 	if pc.Name == "" {
-		return apierr.InvalidAttribute("name", "cannot be empty")
+		vv.Add("name", "cannot be empty")
 	}
 
+	checkLength(&vv, "name", pc.Name, _maxNameLength)
+
 	if pc.Description == "" {
-		return apierr.InvalidAttribute("description", "cannot be empty")
+		vv.Add("description", "cannot be empty")
 	}
 
+	checkLength(&vv, "description", pc.Description, _maxDescriptionLength)
+	vv.Merge(pc.Visibility.Validate())
+	vv.Merge(pc.State.Validate())
+
 	if len(pc.Recipes) < 1 {
-		return apierr.InvalidAttribute("recipes", "must contain at least one element")
+		vv.Add("recipes", "must contain at least one element")
 	}
 
 	for i, rec := range pc.Recipes {
+		attr := fmt.Sprintf("recipes[%d].quantity", i)
+
 		if rec.Quantity == 0 {
-			return apierr.InvalidAttribute(fmt.Sprintf("recipes[%d].quantity", i), "must be positive")
+			vv.Add(attr, "must be positive")
+		}
+
+		if rec.Quantity > _maxPlanRecipeQuantity {
+			vv.Add(attr, fmt.Sprintf("cannot be greater than %d", _maxPlanRecipeQuantity))
+		}
+
+		if _, ok := rec.Scheduled(); rec.Day != "" && !ok {
+			vv.Add(fmt.Sprintf("recipes[%d].day", i), "must be a date in the YYYY-MM-DD format")
 		}
 	}
 
-	return nil
+	return vv.Err()
 }
 
 // PlanRecipe maps plan recipes with the actual recipes stored in the
@@ -68,6 +106,28 @@ type PlanRecipe struct {
 
 	// Quantity specifies recipe count.
 	Quantity uint64 `json:"quantity"`
+
+	// Day specifies the day at which the recipe is scheduled in the
+	// YYYY-MM-DD format. It is empty if the recipe is not scheduled.
+	Day string `json:"day,omitempty"`
+}
+
+// DayLayout specifies the layout of the days of scheduled plan recipes.
+const DayLayout = "2006-01-02"
+
+// Scheduled returns the day at which the recipe is scheduled. False is
+// returned if the recipe is not scheduled.
+func (pr *PlanRecipe) Scheduled() (time.Time, bool) {
+	if pr.Day == "" {
+		return time.Time{}, false
+	}
+
+	day, err := time.Parse(DayLayout, pr.Day)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return day, true
 }
 
 // FindMatching finds the matching recipe based on the id.
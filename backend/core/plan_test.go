@@ -2,6 +2,7 @@ package core
 
 import (
 	"foodie/server/apierr"
+	"strings"
 	"testing"
 
 	"github.com/rs/xid"
@@ -16,6 +17,8 @@ func Test_PlanCore_Validate(t *testing.T) {
 		"Invalid name": {
 			PlanCore: PlanCore{
 				Description: "123",
+				Visibility:  VisibilityPublic,
+				State:       StatePublished,
 				Recipes: []PlanRecipe{
 					{
 						Quantity: 3,
@@ -29,7 +32,9 @@ func Test_PlanCore_Validate(t *testing.T) {
 		},
 		"Invalid description": {
 			PlanCore: PlanCore{
-				Name: "123",
+				Name:       "123",
+				Visibility: VisibilityPublic,
+				State:      StatePublished,
 				Recipes: []PlanRecipe{
 					{
 						Quantity: 3,
@@ -45,6 +50,8 @@ func Test_PlanCore_Validate(t *testing.T) {
 			PlanCore: PlanCore{
 				Name:        "123",
 				Description: "123",
+				Visibility:  VisibilityPublic,
+				State:       StatePublished,
 			},
 			Error: apierr.InvalidAttribute("recipes", "must contain at least one element"),
 		},
@@ -52,6 +59,8 @@ func Test_PlanCore_Validate(t *testing.T) {
 			PlanCore: PlanCore{
 				Name:        "123",
 				Description: "123",
+				Visibility:  VisibilityPublic,
+				State:       StatePublished,
 				Recipes: []PlanRecipe{
 					{},
 					{
@@ -61,13 +70,85 @@ func Test_PlanCore_Validate(t *testing.T) {
 			},
 			Error: apierr.InvalidAttribute("recipes[0].quantity", "must be positive"),
 		},
+		"Invalid plan recipe day": {
+			PlanCore: PlanCore{
+				Name:        "123",
+				Description: "123",
+				Visibility:  VisibilityPublic,
+				State:       StatePublished,
+				Recipes: []PlanRecipe{
+					{
+						Quantity: 3,
+						Day:      "2022-13-01",
+					},
+				},
+			},
+			Error: apierr.InvalidAttribute("recipes[0].day", "must be a date in the YYYY-MM-DD format"),
+		},
+		"Invalid visibility": {
+			PlanCore: PlanCore{
+				Name:        "123",
+				Description: "123",
+				Visibility:  "friends",
+				State:       StatePublished,
+				Recipes: []PlanRecipe{
+					{
+						Quantity: 3,
+					},
+				},
+			},
+			Error: apierr.InvalidAttribute("visibility", "must be of a valid type"),
+		},
+		"Invalid state": {
+			PlanCore: PlanCore{
+				Name:        "123",
+				Description: "123",
+				Visibility:  VisibilityPrivate,
+				Recipes: []PlanRecipe{
+					{
+						Quantity: 3,
+					},
+				},
+			},
+			Error: apierr.InvalidAttribute("state", "must be of a valid type"),
+		},
+		"Too long name": {
+			PlanCore: PlanCore{
+				Name:        strings.Repeat("ą", 256),
+				Description: "123",
+				Visibility:  VisibilityPublic,
+				State:       StatePublished,
+				Recipes: []PlanRecipe{
+					{
+						Quantity: 1 << 32,
+					},
+				},
+			},
+			Error: apierr.Violations{
+				{Attribute: "name", Message: "cannot be longer than 255 characters"},
+				{Attribute: "recipes[0].quantity", Message: "cannot be greater than 4294967295"},
+			}.Err(),
+		},
+		"Multiple invalid attributes": {
+			PlanCore: PlanCore{},
+			Error: apierr.Violations{
+				{Attribute: "name", Message: "cannot be empty"},
+				{Attribute: "description", Message: "cannot be empty"},
+				{Attribute: "visibility", Message: "must be of a valid type"},
+				{Attribute: "state", Message: "must be of a valid type"},
+				{Attribute: "recipes", Message: "must contain at least one element"},
+			}.Err(),
+		},
 		"Valid plan core": {
 			PlanCore: PlanCore{
 				Name:        "123",
 				Description: "123",
+				Visibility:  VisibilityPublic,
+				State:       StatePublished,
 				Recipes: []PlanRecipe{
 					{
 						Quantity: 3,
+						Day:      "2022-10-12",
 					},
 					{
 						Quantity: 3,
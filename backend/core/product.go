@@ -1,7 +1,9 @@
 package core
 
 import (
+	"fmt"
 	"foodie/server/apierr"
+	"strings"
 	"time"
 
 	"github.com/rs/xid"
@@ -22,15 +24,209 @@ const (
 	ServingTypeUnits ServingType = "units"
 )
 
+// ProductStatus specifies the moderation status of the product.
+type ProductStatus string
+
+const (
+	// ProductStatusPending specifies that the product was submitted by a
+	// regular user and is waiting for a review.
+	ProductStatusPending ProductStatus = "pending"
+
+	// ProductStatusApproved specifies that the product is available to
+	// everyone.
+	ProductStatusApproved ProductStatus = "approved"
+
+	// ProductStatusRejected specifies that the product was rejected by an
+	// admin.
+	ProductStatusRejected ProductStatus = "rejected"
+)
+
+// Validate checks whether the product status is of a valid type.
+func (ps ProductStatus) Validate() *apierr.Error {
+	switch ps {
+	case ProductStatusPending, ProductStatusApproved, ProductStatusRejected:
+		return nil
+	default:
+		return apierr.InvalidAttribute("status", "must be of a valid type")
+	}
+}
+
 // Product contains product data.
 type Product struct {
 	ProductCore
+	Revision
 
 	// ID is a unique product identifier.
 	ID xid.ID `json:"id"`
 
+	// UserID specifies the user which submitted the product.
+	UserID xid.ID `json:"user_id"`
+
+	// Status specifies the moderation status of the product.
+	Status ProductStatus `json:"status"`
+
+	// RejectionReason specifies why the product was rejected by an admin.
+	RejectionReason string `json:"rejection_reason,omitempty"`
+
 	// CreatedAt specifies a time at which the object was created.
 	CreatedAt time.Time `json:"created_at"`
+
+	// DeletedAt specifies a time at which the object was moved to the
+	// trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Cursor returns the position of the product in a paginated list.
+func (p Product) Cursor() Cursor {
+	return Cursor{
+		CreatedAt: p.CreatedAt,
+		ID:        p.ID,
+	}
+}
+
+// UsableBy checks whether the product can be used in recipes of the
+// provided user. Pending products can be used only by their submitters.
+func (p *Product) UsableBy(uid xid.ID) bool {
+	switch p.Status {
+	case ProductStatusApproved:
+		return true
+	case ProductStatusPending:
+		return !uid.IsNil() && p.UserID == uid
+	default:
+		return false
+	}
+}
+
+// VisibleTo checks whether the product is visible to the viewer. Products
+// that are not approved are visible only to their submitters and admins.
+func (p *Product) VisibleTo(vw Viewer) bool {
+	if vw.Admin || p.Status == ProductStatusApproved {
+		return true
+	}
+
+	return !vw.UserID.IsNil() && p.UserID == vw.UserID
+}
+
+// ProductMerge contains the report of products being merged into a single
+// target product.
+type ProductMerge struct {
+	// TargetID specifies the product that the sources were merged into.
+	TargetID xid.ID `json:"target_id"`
+
+	// SourceIDs specifies the products that were merged and deleted.
+	SourceIDs []xid.ID `json:"source_ids"`
+
+	// Recipes contains the recipes that were affected by the merge.
+	Recipes []MergedRecipe `json:"recipes"`
+}
+
+// MergedRecipe contains information about a recipe affected by a product
+// merge.
+type MergedRecipe struct {
+	// RecipeID specifies the id of the affected recipe.
+	RecipeID xid.ID `json:"recipe_id"`
+
+	// Quantity specifies the resulting quantity of the target product in
+	// the recipe.
+	Quantity decimal.Decimal `json:"quantity"`
+
+	// Summed specifies whether the recipe already used the target product
+	// and the quantities were summed.
+	Summed bool `json:"summed"`
+}
+
+// ProductImportAction specifies what was done with a single product of a
+// bulk import.
+type ProductImportAction string
+
+const (
+	// ProductImportCreated specifies that a new product was created.
+	ProductImportCreated ProductImportAction = "created"
+
+	// ProductImportUpdated specifies that an existing product with the
+	// same barcode or name was updated.
+	ProductImportUpdated ProductImportAction = "updated"
+
+	// ProductImportUnchanged specifies that an existing product with the
+	// same barcode or name already had the imported attributes.
+	ProductImportUnchanged ProductImportAction = "unchanged"
+
+	// ProductImportTrashed specifies that the product was not imported,
+	// because its barcode belongs to a product in the trash.
+	ProductImportTrashed ProductImportAction = "trashed"
+)
+
+// ImportedProduct contains the result of a single product of a bulk
+// import.
+type ImportedProduct struct {
+	// Product specifies the created or updated product. It is nil if the
+	// product was not imported.
+	Product *Product
+
+	// Action specifies what was done with the product.
+	Action ProductImportAction
+}
+
+// ProductImport contains the report of a bulk product import.
+type ProductImport struct {
+	// DryRun specifies whether the changes were discarded after being
+	// checked.
+	DryRun bool `json:"dry_run"`
+
+	// Created specifies how many products were created.
+	Created int `json:"created"`
+
+	// Updated specifies how many existing products were updated.
+	Updated int `json:"updated"`
+
+	// Unchanged specifies how many existing products already had the
+	// imported attributes.
+	Unchanged int `json:"unchanged"`
+
+	// Failed specifies how many rows were not imported.
+	Failed int `json:"failed"`
+
+	// Errors contains the errors of the rows that were not imported.
+	Errors []ProductImportError `json:"errors"`
+}
+
+// ProductImportError contains the errors of a single row that was not
+// imported.
+type ProductImportError struct {
+	// Row specifies the line of the imported document at which the row
+	// starts.
+	Row int `json:"row"`
+
+	// Message describes why the row could not be read.
+	Message string `json:"message,omitempty"`
+
+	// Errors contains the invalid attributes of the row.
+	Errors []apierr.Violation `json:"errors,omitempty"`
+}
+
+// Add counts the imported product in the report.
+func (pi *ProductImport) Add(row int, ip ImportedProduct) {
+	switch ip.Action {
+	case ProductImportCreated:
+		pi.Created++
+	case ProductImportUpdated:
+		pi.Updated++
+	case ProductImportUnchanged:
+		pi.Unchanged++
+	case ProductImportTrashed:
+		pi.Fail(ProductImportError{
+			Row: row,
+			Errors: []apierr.Violation{
+				{Attribute: "barcode", Message: "is used by a product in the trash"},
+			},
+		})
+	}
+}
+
+// Fail adds the error of a row that was not imported to the report.
+func (pi *ProductImport) Fail(pe ProductImportError) {
+	pi.Failed++
+	pi.Errors = append(pi.Errors, pe)
 }
 
 // ProductCore contains core product information.
@@ -38,6 +234,14 @@ type ProductCore struct {
 	// Name specifies the name of the product.
 	Name string `json:"name"`
 
+	// Aliases specifies alternative names of the product, e.g. regional
+	// names or common misspellings, by which it can be suggested.
+	Aliases []string `json:"aliases,omitempty"`
+
+	// Barcode specifies the EAN/UPC barcode printed on the product
+	// package. It is unique among all products.
+	Barcode string `json:"barcode,omitempty"`
+
 	// ImageURL specifies the image url for the recipe.
 	ImageURL string `json:"image_url"`
 
@@ -60,29 +264,78 @@ type Serving struct {
 	Calories int `json:"calories"`
 }
 
-// Validate checks whether product core contains valid attributes.
+// Validate checks whether product core contains valid attributes. All
+// invalid attributes are reported at once.
 func (pc *ProductCore) Validate() *apierr.Error {
+	var vv apierr.Violations
+
 	if pc.Name == "" {
-		return apierr.InvalidAttribute("name", "cannot be empty")
+		vv.Add("name", "cannot be empty")
+	}
+
+	checkLength(&vv, "name", pc.Name, _maxNameLength)
+
+	for i, alias := range pc.Aliases {
+		attr := fmt.Sprintf("aliases[%d]", i)
+
+		if strings.TrimSpace(alias) == "" {
+			vv.Add(attr, "cannot be empty")
+		}
+
+		if strings.ContainsAny(alias, "\r\n") {
+			vv.Add(attr, "cannot contain line breaks")
+		}
 	}
 
+	checkLength(&vv, "aliases", strings.Join(pc.Aliases, "\n"), _maxAliasesLength)
+
+	if pc.Barcode != "" && !validBarcode(pc.Barcode) {
+		vv.Add("barcode", "must contain from 8 to 14 digits")
+	}
+
+	checkLength(&vv, "image_url", pc.ImageURL, _maxImageURLLength)
+
 	if pc.Description == "" {
-		return apierr.InvalidAttribute("description", "cannot be empty")
+		vv.Add("description", "cannot be empty")
 	}
 
+	checkLength(&vv, "description", pc.Description, _maxDescriptionLength)
+
 	switch pc.Serving.Type {
 	case ServingTypeGrams, ServingTypeMilliliters, ServingTypeUnits:
 	default:
-		return apierr.InvalidAttribute("type", "must be of a valid type")
+		vv.Add("type", "must be of a valid type")
 	}
 
 	if pc.Serving.Size.Cmp(decimal.Zero) <= 0 {
-		return apierr.InvalidAttribute("size", "cannot be less or equal to 0")
+		vv.Add("size", "cannot be less or equal to 0")
 	}
 
+	checkDecimal(&vv, "size", pc.Serving.Size)
+
 	if pc.Serving.Calories < 0 {
-		return apierr.InvalidAttribute("calories", "cannot be less than 0")
+		vv.Add("calories", "cannot be less than 0")
+	}
+
+	if pc.Serving.Calories > _maxCalories {
+		vv.Add("calories", fmt.Sprintf("cannot be greater than %d", _maxCalories))
+	}
+
+	return vv.Err()
+}
+
+// validBarcode checks whether the barcode consists of 8 to 14 digits, which
+// covers EAN-8, UPC-A, EAN-13 and GTIN-14 barcodes.
+func validBarcode(v string) bool {
+	if len(v) < 8 || len(v) > 14 {
+		return false
+	}
+
+	for _, c := range v {
+		if c < '0' || c > '9' {
+			return false
+		}
 	}
 
-	return nil
+	return true
 }
@@ -2,8 +2,10 @@ package core
 
 import (
 	"foodie/server/apierr"
+	"strings"
 	"testing"
 
+	"github.com/rs/xid"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 )
@@ -24,10 +26,50 @@ func Test_ProductCore_Validate(t *testing.T) {
 			},
 			Error: apierr.InvalidAttribute("name", "cannot be empty"),
 		},
+		"Empty alias": {
+			ProductCore: ProductCore{
+				Name:        "123",
+				Aliases:     []string{"456", " "},
+				Description: "123",
+				Serving: Serving{
+					Type:     ServingTypeMilliliters,
+					Size:     decimal.NewFromInt(10),
+					Calories: 50,
+				},
+			},
+			Error: apierr.InvalidAttribute("aliases[1]", "cannot be empty"),
+		},
+		"Alias with a line break": {
+			ProductCore: ProductCore{
+				Name:        "123",
+				Aliases:     []string{"4\n56"},
+				Description: "123",
+				Serving: Serving{
+					Type:     ServingTypeMilliliters,
+					Size:     decimal.NewFromInt(10),
+					Calories: 50,
+				},
+			},
+			Error: apierr.InvalidAttribute("aliases[0]", "cannot contain line breaks"),
+		},
+		"Invalid barcode": {
+			ProductCore: ProductCore{
+				Name:        "123",
+				Barcode:     "40112a",
+				Description: "123",
+				Serving: Serving{
+					Type:     ServingTypeMilliliters,
+					Size:     decimal.NewFromInt(10),
+					Calories: 50,
+				},
+			},
+			Error: apierr.InvalidAttribute("barcode", "must contain from 8 to 14 digits"),
+		},
 		"Invalid description": {
 			ProductCore: ProductCore{
 				Name: "123",
 				Serving: Serving{
+					Type:     ServingTypeMilliliters,
 					Size:     decimal.NewFromInt(10),
 					Calories: 50,
 				},
@@ -68,9 +110,40 @@ func Test_ProductCore_Validate(t *testing.T) {
 			},
 			Error: apierr.InvalidAttribute("calories", "cannot be less than 0"),
 		},
+		"Too long name and aliases": {
+			ProductCore: ProductCore{
+				Name:        strings.Repeat("a", 256),
+				Aliases:     []string{strings.Repeat("b", 600), strings.Repeat("c", 600)},
+				Description: "123",
+				Serving: Serving{
+					Type:     ServingTypeMilliliters,
+					Size:     decimal.NewFromInt(10),
+					Calories: 50,
+				},
+			},
+			Error: apierr.Violations{
+				{Attribute: "name", Message: "cannot be longer than 255 characters"},
+				{Attribute: "aliases", Message: "cannot be longer than 1023 characters"},
+			}.Err(),
+		},
+		"Multiple invalid attributes": {
+			ProductCore: ProductCore{
+				Serving: Serving{
+					Calories: -1,
+				},
+			},
+			Error: apierr.Violations{
+				{Attribute: "name", Message: "cannot be empty"},
+				{Attribute: "description", Message: "cannot be empty"},
+				{Attribute: "type", Message: "must be of a valid type"},
+				{Attribute: "size", Message: "cannot be less or equal to 0"},
+				{Attribute: "calories", Message: "cannot be less than 0"},
+			}.Err(),
+		},
 		"Valid product core": {
 			ProductCore: ProductCore{
 				Name:        "123",
+				Barcode:     "4006381333931",
 				Description: "123",
 				Serving: Serving{
 					Type:     ServingTypeMilliliters,
@@ -91,3 +164,113 @@ func Test_ProductCore_Validate(t *testing.T) {
 		})
 	}
 }
+
+func Test_Product_UsableBy(t *testing.T) {
+	uid := xid.New()
+
+	tests := map[string]struct {
+		Product Product
+		UserID  xid.ID
+		Result  bool
+	}{
+		"Approved product": {
+			Product: Product{
+				Status: ProductStatusApproved,
+			},
+			UserID: xid.New(),
+			Result: true,
+		},
+		"Pending product of another user": {
+			Product: Product{
+				UserID: uid,
+				Status: ProductStatusPending,
+			},
+			UserID: xid.New(),
+		},
+		"Pending product without an owner": {
+			Product: Product{
+				Status: ProductStatusPending,
+			},
+		},
+		"Pending product of the same user": {
+			Product: Product{
+				UserID: uid,
+				Status: ProductStatusPending,
+			},
+			UserID: uid,
+			Result: true,
+		},
+		"Rejected product": {
+			Product: Product{
+				UserID: uid,
+				Status: ProductStatusRejected,
+			},
+			UserID: uid,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.Result, test.Product.UsableBy(test.UserID))
+		})
+	}
+}
+
+func Test_Product_VisibleTo(t *testing.T) {
+	uid := xid.New()
+
+	tests := map[string]struct {
+		Product Product
+		Viewer  Viewer
+		Result  bool
+	}{
+		"Approved product": {
+			Product: Product{
+				Status: ProductStatusApproved,
+			},
+			Result: true,
+		},
+		"Rejected product of the same user": {
+			Product: Product{
+				UserID: uid,
+				Status: ProductStatusRejected,
+			},
+			Viewer: Viewer{UserID: uid},
+			Result: true,
+		},
+		"Pending product of another user": {
+			Product: Product{
+				UserID: uid,
+				Status: ProductStatusPending,
+			},
+			Viewer: Viewer{UserID: xid.New()},
+		},
+		"Pending product without an owner": {
+			Product: Product{
+				Status: ProductStatusPending,
+			},
+		},
+		"Pending product viewed by an admin": {
+			Product: Product{
+				UserID: uid,
+				Status: ProductStatusPending,
+			},
+			Viewer: Viewer{Admin: true},
+			Result: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.Result, test.Product.VisibleTo(test.Viewer))
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package core
+
+import (
+	"foodie/server/apierr"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
+)
+
+// FieldType specifies the type of the values of a list field.
+type FieldType int
+
+const (
+	// FieldString specifies a field with string values.
+	FieldString FieldType = iota
+
+	// FieldInteger specifies a field with integer values.
+	FieldInteger
+
+	// FieldDecimal specifies a field with decimal values.
+	FieldDecimal
+
+	// FieldBool specifies a field with boolean values.
+	FieldBool
+
+	// FieldTime specifies a field with RFC 3339 time values.
+	FieldTime
+
+	// FieldID specifies a field with object id values. An empty value
+	// matches objects without a reference.
+	FieldID
+)
+
+// ordered checks whether the values of the field type can be compared by
+// their order.
+func (ft FieldType) ordered() bool {
+	switch ft {
+	case FieldInteger, FieldDecimal, FieldTime:
+		return true
+	default:
+		return false
+	}
+}
+
+// parse parses a raw value of the field type.
+func (ft FieldType) parse(v string) (interface{}, bool) {
+	switch ft {
+	case FieldString:
+		return v, true
+	case FieldInteger:
+		i, err := strconv.ParseInt(v, 10, 64)
+		return i, err == nil
+	case FieldDecimal:
+		d, err := decimal.NewFromString(v)
+		return d, err == nil
+	case FieldBool:
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+	case FieldTime:
+		t, err := time.Parse(time.RFC3339, v)
+		return t.UTC(), err == nil
+	case FieldID:
+		if v == "" {
+			return xid.NilID(), true
+		}
+
+		id, err := xid.FromString(v)
+
+		return id, err == nil
+	default:
+		return nil, false
+	}
+}
+
+// Fields specifies the fields by which a list of objects can be filtered
+// and sorted, along with the types of their values.
+type Fields map[string]FieldType
+
+var (
+	// ProductFields specifies the fields of the products list.
+	ProductFields = Fields{
+		"name":         FieldString,
+		"barcode":      FieldString,
+		"serving_type": FieldString,
+		"serving_size": FieldDecimal,
+		"calories":     FieldInteger,
+		"status":       FieldString,
+		"user_id":      FieldID,
+		"created_at":   FieldTime,
+	}
+
+	// RecipeFields specifies the fields of the recipes list.
+	RecipeFields = Fields{
+		"name":       FieldString,
+		"visibility": FieldString,
+		"state":      FieldString,
+		"user_id":    FieldID,
+		"created_at": FieldTime,
+	}
+
+	// PlanFields specifies the fields of the plans list.
+	PlanFields = Fields{
+		"name":       FieldString,
+		"visibility": FieldString,
+		"state":      FieldString,
+		"user_id":    FieldID,
+		"created_at": FieldTime,
+	}
+
+	// UserFields specifies the fields of the users list.
+	UserFields = Fields{
+		"name":       FieldString,
+		"admin":      FieldBool,
+		"created_at": FieldTime,
+	}
+)
+
+// FilterOp specifies the comparison operator of a filter. It is provided
+// as a suffix of the field name, e.g. calories_lte=200.
+type FilterOp string
+
+const (
+	// FilterEq matches values equal to the filter value. It is used when
+	// the field name has no suffix. Repeated fields match values equal to
+	// any of the filter values.
+	FilterEq FilterOp = "eq"
+
+	// FilterNe matches values not equal to the filter value.
+	FilterNe FilterOp = "ne"
+
+	// FilterLt matches values less than the filter value.
+	FilterLt FilterOp = "lt"
+
+	// FilterLte matches values less than or equal to the filter value.
+	FilterLte FilterOp = "lte"
+
+	// FilterGt matches values greater than the filter value.
+	FilterGt FilterOp = "gt"
+
+	// FilterGte matches values greater than or equal to the filter value.
+	FilterGte FilterOp = "gte"
+)
+
+// Filter compares a field of the listed objects against a value.
+type Filter struct {
+	// Field specifies the name of the compared field.
+	Field string
+
+	// Op specifies the comparison operator.
+	Op FilterOp
+
+	// Value contains the parsed value of the field type. Equality filters
+	// of repeated fields contain a slice of the parsed values instead.
+	Value interface{}
+}
+
+// Sort specifies a field by which a list of objects is sorted.
+type Sort struct {
+	// Field specifies the name of the field.
+	Field string
+
+	// Desc specifies whether the objects are sorted in descending order.
+	Desc bool
+}
+
+// Query contains the filters and the sort order of a list of objects.
+type Query struct {
+	// Filters contains the filters that all listed objects must match.
+	Filters []Filter
+
+	// Sort contains the sort order. The default order by the creation
+	// time is used when it is empty.
+	Sort []Sort
+}
+
+// _reservedParams contains the URL query parameters that are not fields.
+var _reservedParams = map[string]bool{
+	"limit":  true,
+	"cursor": true,
+	"sort":   true,
+}
+
+// ParseQuery parses the filters and the sort order from the URL query.
+// Only the provided fields are allowed.
+func ParseQuery(vals url.Values, ff Fields) (Query, *apierr.Error) {
+	var q Query
+
+	for _, v := range strings.Split(vals.Get("sort"), ",") {
+		if v == "" {
+			continue
+		}
+
+		s := Sort{Field: strings.TrimPrefix(v, "-"), Desc: strings.HasPrefix(v, "-")}
+		if _, ok := ff[s.Field]; !ok {
+			return Query{}, apierr.InvalidAttribute("sort", "unknown field "+strconv.Quote(s.Field))
+		}
+
+		q.Sort = append(q.Sort, s)
+	}
+
+	keys := make([]string, 0, len(vals))
+	for key := range vals {
+		if !_reservedParams[key] {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		vv := vals[key]
+
+		name, op := key, FilterEq
+
+		if _, ok := ff[key]; !ok {
+			i := strings.LastIndex(key, "_")
+			if i < 0 {
+				return Query{}, apierr.InvalidAttribute(key, "unknown field")
+			}
+
+			name, op = key[:i], FilterOp(key[i+1:])
+		}
+
+		ft, ok := ff[name]
+		if !ok {
+			return Query{}, apierr.InvalidAttribute(key, "unknown field")
+		}
+
+		switch op {
+		case FilterEq, FilterNe:
+			// OK.
+		case FilterLt, FilterLte, FilterGt, FilterGte:
+			if !ft.ordered() {
+				return Query{}, apierr.InvalidAttribute(key, "unsupported operator")
+			}
+		default:
+			return Query{}, apierr.InvalidAttribute(key, "unknown field")
+		}
+
+		vals := make([]interface{}, 0, len(vv))
+
+		for _, v := range vv {
+			val, ok := ft.parse(v)
+			if !ok {
+				return Query{}, apierr.InvalidAttribute(key, "invalid value")
+			}
+
+			vals = append(vals, val)
+		}
+
+		if op == FilterEq && len(vals) > 1 {
+			// Repeated equality filters could never match all at once, so
+			// any of the values is matched instead.
+			q.Filters = append(q.Filters, Filter{
+				Field: name,
+				Op:    op,
+				Value: vals,
+			})
+
+			continue
+		}
+
+		for _, val := range vals {
+			q.Filters = append(q.Filters, Filter{
+				Field: name,
+				Op:    op,
+				Value: val,
+			})
+		}
+	}
+
+	return q, nil
+}
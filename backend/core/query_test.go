@@ -0,0 +1,115 @@
+package core
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseQuery(t *testing.T) {
+	uid := xid.New()
+	tstamp := time.Date(2022, 9, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		Values url.Values
+		Query  Query
+		Error  bool
+	}{
+		"Empty query": {
+			Values: url.Values{},
+		},
+		"Reserved parameters are ignored": {
+			Values: url.Values{
+				"limit":  {"10"},
+				"cursor": {"abc"},
+			},
+		},
+		"Sort by multiple fields": {
+			Values: url.Values{
+				"sort": {"name,-created_at"},
+			},
+			Query: Query{
+				Sort: []Sort{
+					{Field: "name"},
+					{Field: "created_at", Desc: true},
+				},
+			},
+		},
+		"Sort by unknown field": {
+			Values: url.Values{
+				"sort": {"password"},
+			},
+			Error: true,
+		},
+		"Filters of all types": {
+			Values: url.Values{
+				"serving_type":    {"grams"},
+				"calories_lte":    {"200"},
+				"serving_size_gt": {"1.5"},
+				"user_id":         {uid.String()},
+				"created_at_gte":  {tstamp.Format(time.RFC3339)},
+				"serving_type_ne": {"units"},
+				"calories":        {"100", "150"},
+				"user_id_ne":      {""},
+			},
+			Query: Query{
+				Filters: []Filter{
+					{Field: "calories", Op: FilterEq, Value: []interface{}{int64(100), int64(150)}},
+					{Field: "calories", Op: FilterLte, Value: int64(200)},
+					{Field: "created_at", Op: FilterGte, Value: tstamp},
+					{Field: "serving_size", Op: FilterGt, Value: decimal.RequireFromString("1.5")},
+					{Field: "serving_type", Op: FilterEq, Value: "grams"},
+					{Field: "serving_type", Op: FilterNe, Value: "units"},
+					{Field: "user_id", Op: FilterEq, Value: uid},
+					{Field: "user_id", Op: FilterNe, Value: xid.NilID()},
+				},
+			},
+		},
+		"Unknown field": {
+			Values: url.Values{
+				"password": {"123"},
+			},
+			Error: true,
+		},
+		"Unknown operator": {
+			Values: url.Values{
+				"calories_like": {"1"},
+			},
+			Error: true,
+		},
+		"Unordered field with a range operator": {
+			Values: url.Values{
+				"name_lt": {"a"},
+			},
+			Error: true,
+		},
+		"Invalid value": {
+			Values: url.Values{
+				"calories": {"many"},
+			},
+			Error: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			q, aerr := ParseQuery(test.Values, ProductFields)
+			if test.Error {
+				assert.NotNil(t, aerr)
+				return
+			}
+
+			require.Nil(t, aerr)
+			assert.Equal(t, test.Query, q)
+		})
+	}
+}
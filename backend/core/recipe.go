@@ -12,6 +12,7 @@ import (
 // Recipe contains recipe data.
 type Recipe struct {
 	RecipeCore
+	Revision
 
 	// ID is a unique recipe identifier.
 	ID xid.ID `json:"id"`
@@ -21,6 +22,18 @@ type Recipe struct {
 
 	// CreatedAt specifies a time at which the object was created.
 	CreatedAt time.Time `json:"created_at"`
+
+	// DeletedAt specifies a time at which the object was moved to the
+	// trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Cursor returns the position of the recipe in a paginated list.
+func (r Recipe) Cursor() Cursor {
+	return Cursor{
+		CreatedAt: r.CreatedAt,
+		ID:        r.ID,
+	}
 }
 
 // RecipeCore contains core recipe information.
@@ -34,31 +47,51 @@ type RecipeCore struct {
 	// Description provides a brief description of the recipe.
 	Description string `json:"description"`
 
+	// Visibility specifies who is able to see the recipe.
+	Visibility Visibility `json:"visibility"`
+
+	// State specifies the publication state of the recipe.
+	State State `json:"state"`
+
 	// Products contains recipe products.
 	Products []RecipeProduct `json:"products"`
 }
 
-// Validate checks whether recipe core contains valid attributes.
+// Validate checks whether recipe core contains valid attributes. All
+// invalid attributes are reported at once.
 func (rc *RecipeCore) Validate() *apierr.Error {
+	var vv apierr.Violations
+
 	if rc.Name == "" {
-		return apierr.InvalidAttribute("name", "cannot be empty")
+		vv.Add("name", "cannot be empty")
 	}
 
+	checkLength(&vv, "name", rc.Name, _maxNameLength)
+	checkLength(&vv, "image_url", rc.ImageURL, _maxImageURLLength)
+
 	if rc.Description == "" {
-		return apierr.InvalidAttribute("description", "cannot be empty")
+		vv.Add("description", "cannot be empty")
 	}
 
+	checkLength(&vv, "description", rc.Description, _maxDescriptionLength)
+	vv.Merge(rc.Visibility.Validate())
+	vv.Merge(rc.State.Validate())
+
 	if len(rc.Products) < 2 {
-		return apierr.InvalidAttribute("products", "must contains at least two elements")
+		vv.Add("products", "must contains at least two elements")
 	}
 
 	for i, prod := range rc.Products {
+		attr := fmt.Sprintf("products[%d].quantity", i)
+
 		if !prod.Quantity.IsPositive() {
-			return apierr.InvalidAttribute(fmt.Sprintf("products[%d].quantity", i), "must be positive")
+			vv.Add(attr, "must be positive")
 		}
+
+		checkDecimal(&vv, attr, prod.Quantity)
 	}
 
-	return nil
+	return vv.Err()
 }
 
 // RecipeProduct maps recipe product with the actual product stored in the
@@ -85,3 +118,41 @@ func (rp *RecipeProduct) FindMatching(products []Product) (Product, bool) {
 
 	return Product{}, false
 }
+
+// Ingredient describes an ingredient of an imported recipe before it is
+// matched to a product.
+type Ingredient struct {
+	// Text specifies the ingredient as it was written in the imported
+	// recipe.
+	Text string `json:"ingredient"`
+
+	// Name specifies the name of the ingredient.
+	Name string `json:"-"`
+
+	// Amount specifies how much of the ingredient is used. It is zero if
+	// the amount was not provided.
+	Amount decimal.Decimal `json:"-"`
+
+	// Unit specifies the unit of measurement of the amount.
+	Unit string `json:"-"`
+}
+
+// UnmatchedIngredient describes an ingredient of an imported recipe that
+// could not be matched to a product.
+type UnmatchedIngredient struct {
+	Ingredient
+
+	// Reason describes why the ingredient could not be matched.
+	Reason string `json:"reason"`
+}
+
+// RecipeImport contains the result of a recipe import.
+type RecipeImport struct {
+	// Recipe specifies the imported recipe. It has no id if the recipe
+	// was not saved.
+	Recipe Recipe `json:"recipe"`
+
+	// Unmatched contains the ingredients that were left out of the
+	// recipe.
+	Unmatched []UnmatchedIngredient `json:"unmatched"`
+}
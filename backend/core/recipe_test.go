@@ -2,6 +2,7 @@ package core
 
 import (
 	"foodie/server/apierr"
+	"strings"
 	"testing"
 
 	"github.com/rs/xid"
@@ -17,6 +18,8 @@ func Test_RecipeCore_Validate(t *testing.T) {
 		"Invalid name": {
 			RecipeCore: RecipeCore{
 				Description: "123",
+				Visibility:  VisibilityPublic,
+				State:       StatePublished,
 				Products: []RecipeProduct{
 					{
 						Quantity: decimal.NewFromInt(3),
@@ -30,7 +33,9 @@ func Test_RecipeCore_Validate(t *testing.T) {
 		},
 		"Invalid description": {
 			RecipeCore: RecipeCore{
-				Name: "333",
+				Name:       "333",
+				Visibility: VisibilityPublic,
+				State:      StatePublished,
 				Products: []RecipeProduct{
 					{
 						Quantity: decimal.NewFromInt(3),
@@ -46,6 +51,8 @@ func Test_RecipeCore_Validate(t *testing.T) {
 			RecipeCore: RecipeCore{
 				Name:        "333",
 				Description: "123",
+				Visibility:  VisibilityPublic,
+				State:       StatePublished,
 			},
 			Error: apierr.InvalidAttribute("products", "must contains at least two elements"),
 		},
@@ -53,6 +60,8 @@ func Test_RecipeCore_Validate(t *testing.T) {
 			RecipeCore: RecipeCore{
 				Name:        "333",
 				Description: "123",
+				Visibility:  VisibilityPublic,
+				State:       StatePublished,
 				Products: []RecipeProduct{
 					{
 						Quantity: decimal.NewFromInt(3),
@@ -64,10 +73,65 @@ func Test_RecipeCore_Validate(t *testing.T) {
 			},
 			Error: apierr.InvalidAttribute("products[1].quantity", "must be positive"),
 		},
+		"Invalid visibility": {
+			RecipeCore: RecipeCore{
+				Name:        "123",
+				Description: "123",
+				Visibility:  "friends",
+				State:       StatePublished,
+				Products: []RecipeProduct{
+					{
+						Quantity: decimal.NewFromInt(3),
+					},
+					{
+						Quantity: decimal.NewFromInt(3),
+					},
+				},
+			},
+			Error: apierr.InvalidAttribute("visibility", "must be of a valid type"),
+		},
+		"Invalid state": {
+			RecipeCore: RecipeCore{
+				Name:        "123",
+				Description: "123",
+				Visibility:  VisibilityPrivate,
+				Products: []RecipeProduct{
+					{
+						Quantity: decimal.NewFromInt(3),
+					},
+					{
+						Quantity: decimal.NewFromInt(3),
+					},
+				},
+			},
+			Error: apierr.InvalidAttribute("state", "must be of a valid type"),
+		},
+		"Too long description": {
+			RecipeCore: RecipeCore{
+				Name:        "123",
+				Description: strings.Repeat("a", 1024),
+				Visibility:  VisibilityPublic,
+				State:       StatePublished,
+				Products: []RecipeProduct{
+					{
+						Quantity: decimal.NewFromInt(3),
+					},
+					{
+						Quantity: decimal.New(1, 14),
+					},
+				},
+			},
+			Error: apierr.Violations{
+				{Attribute: "description", Message: "cannot be longer than 1023 characters"},
+				{Attribute: "products[1].quantity", Message: "must be less than 100000000000000"},
+			}.Err(),
+		},
 		"Valid recipe core": {
 			RecipeCore: RecipeCore{
 				Name:        "123",
 				Description: "123",
+				Visibility:  VisibilityPublic,
+				State:       StatePublished,
 				Products: []RecipeProduct{
 					{
 						Quantity: decimal.NewFromInt(3),
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Revision identifies a state of an object that can be modified. It is
+// used to detect conflicting modifications.
+type Revision struct {
+	// Version specifies the number of the revision. It starts at 1 and is
+	// incremented every time the object is modified.
+	Version uint64 `json:"version"`
+
+	// UpdatedAt specifies a time at which the object was last modified.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ETag returns the strong entity tag of the revision.
+func (rv Revision) ETag() string {
+	return `"` + strconv.FormatUint(rv.Version, 10) + `"`
+}
+
+// MatchesETag checks whether the revision matches any entity tag of the
+// comma separated list, as used by the If-Match and If-None-Match headers.
+// The wildcard matches every revision. Weak entity tags are matched only
+// when the weak comparison is requested.
+func (rv Revision) MatchesETag(list string, weak bool) bool {
+	etag := rv.ETag()
+
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if tag == "*" {
+			return true
+		}
+
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+
+		if tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ModifiedSince checks whether the revision was created after the
+// provided time. HTTP dates have a precision of one second, so the
+// modification time is truncated before the comparison.
+func (rv Revision) ModifiedSince(t time.Time) bool {
+	return rv.UpdatedAt.Truncate(time.Second).After(t)
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Revision_ETag(t *testing.T) {
+	assert.Equal(t, `"12"`, Revision{Version: 12}.ETag())
+}
+
+func Test_Revision_MatchesETag(t *testing.T) {
+	tests := map[string]struct {
+		List   string
+		Weak   bool
+		Result bool
+	}{
+		"Empty list": {
+			List: "",
+		},
+		"Wildcard": {
+			List:   "*",
+			Result: true,
+		},
+		"Different tag": {
+			List: `"2"`,
+		},
+		"Matching tag": {
+			List:   `"3"`,
+			Result: true,
+		},
+		"Matching tag in a list": {
+			List:   `"1", "3" ,"5"`,
+			Result: true,
+		},
+		"Weak tag with strong comparison": {
+			List: `W/"3"`,
+		},
+		"Weak tag with weak comparison": {
+			List:   `W/"3"`,
+			Weak:   true,
+			Result: true,
+		},
+		"Unquoted tag": {
+			List: "3",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.Result, Revision{Version: 3}.MatchesETag(test.List, test.Weak))
+		})
+	}
+}
+
+func Test_Revision_ModifiedSince(t *testing.T) {
+	tstamp := time.Date(2022, 9, 14, 12, 0, 0, 0, time.UTC)
+	rv := Revision{UpdatedAt: tstamp.Add(500 * time.Millisecond)}
+
+	assert.False(t, rv.ModifiedSince(tstamp))
+	assert.False(t, rv.ModifiedSince(tstamp.Add(time.Second)))
+	assert.True(t, rv.ModifiedSince(tstamp.Add(-time.Second)))
+}
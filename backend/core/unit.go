@@ -0,0 +1,120 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// _units maps units of measurement to the serving types they can be
+// converted to along with their size in grams, milliliters or units.
+var _units = map[string]struct {
+	Type ServingType
+	Size decimal.Decimal
+}{
+	"mg":          {ServingTypeGrams, decimal.New(1, -3)},
+	"g":           {ServingTypeGrams, decimal.New(1, 0)},
+	"gr":          {ServingTypeGrams, decimal.New(1, 0)},
+	"gram":        {ServingTypeGrams, decimal.New(1, 0)},
+	"grams":       {ServingTypeGrams, decimal.New(1, 0)},
+	"kg":          {ServingTypeGrams, decimal.New(1000, 0)},
+	"kilogram":    {ServingTypeGrams, decimal.New(1000, 0)},
+	"kilograms":   {ServingTypeGrams, decimal.New(1000, 0)},
+	"oz":          {ServingTypeGrams, decimal.New(283495, -4)},
+	"ounce":       {ServingTypeGrams, decimal.New(283495, -4)},
+	"ounces":      {ServingTypeGrams, decimal.New(283495, -4)},
+	"lb":          {ServingTypeGrams, decimal.New(453592, -3)},
+	"lbs":         {ServingTypeGrams, decimal.New(453592, -3)},
+	"pound":       {ServingTypeGrams, decimal.New(453592, -3)},
+	"pounds":      {ServingTypeGrams, decimal.New(453592, -3)},
+	"ml":          {ServingTypeMilliliters, decimal.New(1, 0)},
+	"milliliter":  {ServingTypeMilliliters, decimal.New(1, 0)},
+	"milliliters": {ServingTypeMilliliters, decimal.New(1, 0)},
+	"millilitre":  {ServingTypeMilliliters, decimal.New(1, 0)},
+	"millilitres": {ServingTypeMilliliters, decimal.New(1, 0)},
+	"cl":          {ServingTypeMilliliters, decimal.New(10, 0)},
+	"dl":          {ServingTypeMilliliters, decimal.New(100, 0)},
+	"l":           {ServingTypeMilliliters, decimal.New(1000, 0)},
+	"liter":       {ServingTypeMilliliters, decimal.New(1000, 0)},
+	"liters":      {ServingTypeMilliliters, decimal.New(1000, 0)},
+	"litre":       {ServingTypeMilliliters, decimal.New(1000, 0)},
+	"litres":      {ServingTypeMilliliters, decimal.New(1000, 0)},
+	"tsp":         {ServingTypeMilliliters, decimal.New(5, 0)},
+	"teaspoon":    {ServingTypeMilliliters, decimal.New(5, 0)},
+	"teaspoons":   {ServingTypeMilliliters, decimal.New(5, 0)},
+	"tbsp":        {ServingTypeMilliliters, decimal.New(15, 0)},
+	"tablespoon":  {ServingTypeMilliliters, decimal.New(15, 0)},
+	"tablespoons": {ServingTypeMilliliters, decimal.New(15, 0)},
+	"cup":         {ServingTypeMilliliters, decimal.New(240, 0)},
+	"cups":        {ServingTypeMilliliters, decimal.New(240, 0)},
+	"":            {ServingTypeUnits, decimal.New(1, 0)},
+	"pc":          {ServingTypeUnits, decimal.New(1, 0)},
+	"pcs":         {ServingTypeUnits, decimal.New(1, 0)},
+	"piece":       {ServingTypeUnits, decimal.New(1, 0)},
+	"pieces":      {ServingTypeUnits, decimal.New(1, 0)},
+	"unit":        {ServingTypeUnits, decimal.New(1, 0)},
+	"units":       {ServingTypeUnits, decimal.New(1, 0)},
+}
+
+// IsUnit checks whether the text is a known unit of measurement.
+func IsUnit(text string) bool {
+	_, ok := _units[normalizeUnit(text)]
+	return ok && text != ""
+}
+
+// Unit returns the abbreviated unit of the serving type. Units have no
+// abbreviation, so an empty string is returned for them.
+func (st ServingType) Unit() string {
+	switch st {
+	case ServingTypeGrams:
+		return "g"
+	case ServingTypeMilliliters:
+		return "ml"
+	default:
+		return ""
+	}
+}
+
+// Convert converts the amount measured in the unit to grams, milliliters
+// or units, depending on the serving type. False is returned if the unit
+// is unknown or measures a different serving type.
+func (st ServingType) Convert(amount decimal.Decimal, unit string) (decimal.Decimal, bool) {
+	u, ok := _units[normalizeUnit(unit)]
+	if !ok || u.Type != st {
+		return decimal.Zero, false
+	}
+
+	return amount.Mul(u.Size), true
+}
+
+// normalizeUnit lowercases the unit and removes the abbreviation dot.
+func normalizeUnit(unit string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(unit)), ".")
+}
+
+// Amount returns how much of the product is used by the recipe, measured
+// in the units of its serving type.
+func (rp *RecipeProduct) Amount(prd Product) decimal.Decimal {
+	return rp.Quantity.Mul(prd.Serving.Size)
+}
+
+// Calories returns how many calories of the product are in the recipe.
+func (rp *RecipeProduct) Calories(prd Product) decimal.Decimal {
+	return rp.Quantity.Mul(decimal.NewFromInt(int64(prd.Serving.Calories)))
+}
+
+// NewRecipeProduct creates a recipe product with the quantity of servings
+// that contain the amount of the product measured in the unit. False is
+// returned if the unit cannot be converted to the serving type of the
+// product.
+func NewRecipeProduct(prd Product, amount decimal.Decimal, unit string) (RecipeProduct, bool) {
+	base, ok := prd.Serving.Type.Convert(amount, unit)
+	if !ok || !prd.Serving.Size.IsPositive() {
+		return RecipeProduct{}, false
+	}
+
+	return RecipeProduct{
+		ProductID: prd.ID,
+		Quantity:  base.DivRound(prd.Serving.Size, 4),
+	}, true
+}
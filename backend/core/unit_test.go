@@ -0,0 +1,85 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ServingType_Convert(t *testing.T) {
+	tests := map[string]struct {
+		ServingType ServingType
+		Amount      decimal.Decimal
+		Unit        string
+		Result      decimal.Decimal
+		OK          bool
+	}{
+		"Kilograms to grams": {
+			ServingType: ServingTypeGrams,
+			Amount:      decimal.New(15, -1),
+			Unit:        "Kg.",
+			Result:      decimal.NewFromInt(1500),
+			OK:          true,
+		},
+		"Cups to milliliters": {
+			ServingType: ServingTypeMilliliters,
+			Amount:      decimal.NewFromInt(2),
+			Unit:        "cups",
+			Result:      decimal.NewFromInt(480),
+			OK:          true,
+		},
+		"Without a unit to units": {
+			ServingType: ServingTypeUnits,
+			Amount:      decimal.NewFromInt(3),
+			Result:      decimal.NewFromInt(3),
+			OK:          true,
+		},
+		"Volume to grams": {
+			ServingType: ServingTypeGrams,
+			Amount:      decimal.NewFromInt(1),
+			Unit:        "l",
+		},
+		"Unknown unit": {
+			ServingType: ServingTypeGrams,
+			Amount:      decimal.NewFromInt(1),
+			Unit:        "pinch",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			res, ok := test.ServingType.Convert(test.Amount, test.Unit)
+			assert.Equal(t, test.OK, ok)
+			assert.True(t, test.Result.Equal(res), "expected %s, got %s", test.Result, res)
+		})
+	}
+}
+
+func Test_NewRecipeProduct(t *testing.T) {
+	prd := Product{
+		ID: xid.New(),
+		ProductCore: ProductCore{
+			Serving: Serving{
+				Type:     ServingTypeGrams,
+				Size:     decimal.NewFromInt(100),
+				Calories: 165,
+			},
+		},
+	}
+
+	rp, ok := NewRecipeProduct(prd, decimal.New(25, -2), "kg")
+	assert.True(t, ok)
+	assert.Equal(t, prd.ID, rp.ProductID)
+	assert.True(t, decimal.New(25, -1).Equal(rp.Quantity))
+	assert.True(t, decimal.NewFromInt(250).Equal(rp.Amount(prd)))
+	assert.True(t, decimal.New(4125, -1).Equal(rp.Calories(prd)))
+
+	_, ok = NewRecipeProduct(prd, decimal.NewFromInt(1), "cup")
+	assert.False(t, ok)
+}
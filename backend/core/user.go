@@ -1,6 +1,10 @@
 package core
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"foodie/server/apierr"
 	"time"
 
@@ -32,6 +36,14 @@ type User struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Cursor returns the position of the user in a paginated list.
+func (u User) Cursor() Cursor {
+	return Cursor{
+		CreatedAt: u.CreatedAt,
+		ID:        u.ID,
+	}
+}
+
 // UserInput contains core user information that is used only when creating
 // or updating users.
 type UserInput struct {
@@ -42,13 +54,19 @@ type UserInput struct {
 	Password string `json:"password"`
 }
 
-// Validate checks whether user input contains valid attributes.
+// Validate checks whether user input contains valid attributes. All
+// invalid attributes are reported at once.
 func (ui *UserInput) Validate() *apierr.Error {
+	var vv apierr.Violations
+
 	if ui.Name == "" {
-		return apierr.InvalidAttribute("name", "cannot be empty")
+		vv.Add("name", "cannot be empty")
 	}
 
-	return ValidatePassword(ui.Password)
+	checkLength(&vv, "name", ui.Name, _maxNameLength)
+	vv.Merge(ValidatePassword(ui.Password))
+
+	return vv.Err()
 }
 
 // ValidatePassword validates the password.
@@ -59,3 +77,23 @@ func ValidatePassword(pass string) *apierr.Error {
 
 	return nil
 }
+
+// NewCalendarToken generates a new secret calendar token along with its
+// hash, which is the only part of it that should be stored.
+func NewCalendarToken() (string, string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(data)
+
+	return token, HashCalendarToken(token), nil
+}
+
+// HashCalendarToken returns the hex encoded SHA-256 hash of the calendar
+// token. Tokens are random, so a plain hash is enough to protect them.
+func HashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_UserInput_Validate(t *testing.T) {
@@ -66,3 +67,13 @@ func Test_ValidatePassword(t *testing.T) {
 		})
 	}
 }
+
+func Test_NewCalendarToken(t *testing.T) {
+	token, hash, err := NewCalendarToken()
+	require.NoError(t, err)
+
+	assert.Len(t, token, 43)
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, HashCalendarToken(token))
+	assert.NotEqual(t, hash, HashCalendarToken(token+"a"))
+}
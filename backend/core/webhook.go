@@ -0,0 +1,263 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"foodie/server/apierr"
+	"net/url"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// EventType specifies the kind of a change that webhooks are notified
+// about.
+type EventType string
+
+const (
+	// EventProductCreated is emitted when a product is created.
+	EventProductCreated EventType = "product.created"
+
+	// EventProductUpdated is emitted when a product is updated, approved
+	// or restored from the trash.
+	EventProductUpdated EventType = "product.updated"
+
+	// EventProductDeleted is emitted when a product is rejected, merged
+	// into another product or moved to the trash.
+	EventProductDeleted EventType = "product.deleted"
+
+	// EventRecipeCreated is emitted when a recipe is created or imported.
+	EventRecipeCreated EventType = "recipe.created"
+
+	// EventRecipeUpdated is emitted when a recipe or its products are
+	// updated, or when it is restored from the trash.
+	EventRecipeUpdated EventType = "recipe.updated"
+
+	// EventRecipeDeleted is emitted when a recipe is moved to the trash.
+	EventRecipeDeleted EventType = "recipe.deleted"
+
+	// EventPlanCreated is emitted when a plan is created.
+	EventPlanCreated EventType = "plan.created"
+
+	// EventPlanUpdated is emitted when a plan or its recipes are updated,
+	// or when it is restored from the trash.
+	EventPlanUpdated EventType = "plan.updated"
+
+	// EventPlanDeleted is emitted when a plan is moved to the trash.
+	EventPlanDeleted EventType = "plan.deleted"
+
+	// EventUserCreated is emitted when a user registers or an admin is
+	// created.
+	EventUserCreated EventType = "user.created"
+
+	// EventUserUpdated is emitted when a user changes its password.
+	EventUserUpdated EventType = "user.updated"
+
+	// EventUserDeleted is emitted when a user is deleted.
+	EventUserDeleted EventType = "user.deleted"
+)
+
+// EventTypes contains all event types that webhooks can subscribe to.
+var EventTypes = []EventType{
+	EventProductCreated,
+	EventProductUpdated,
+	EventProductDeleted,
+	EventRecipeCreated,
+	EventRecipeUpdated,
+	EventRecipeDeleted,
+	EventPlanCreated,
+	EventPlanUpdated,
+	EventPlanDeleted,
+	EventUserCreated,
+	EventUserUpdated,
+	EventUserDeleted,
+}
+
+// Valid checks whether the event type is known.
+func (et EventType) Valid() bool {
+	for _, v := range EventTypes {
+		if v == et {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Webhook contains webhook subscription information.
+type Webhook struct {
+	WebhookCore
+
+	// ID specifies the id of the webhook.
+	ID xid.ID `json:"id"`
+
+	// UserID specifies the id of the user that owns the webhook.
+	UserID xid.ID `json:"user_id"`
+
+	// Admin specifies whether the owner of the webhook has administrator
+	// permissions. It decides which events are delivered to the webhook.
+	Admin bool `json:"-"`
+
+	// Secret contains the key that deliveries are signed with. It is
+	// exposed to the clients only once, when the webhook is created.
+	Secret string `json:"-"`
+
+	// CreatedAt specifies a time at which the object was created.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Viewer returns the viewer on whose behalf the events of the webhook are
+// delivered.
+func (wh Webhook) Viewer() Viewer {
+	return Viewer{
+		UserID: wh.UserID,
+		Admin:  wh.Admin,
+	}
+}
+
+// Subscribed checks whether the webhook is active and subscribed to the
+// event type.
+func (wh Webhook) Subscribed(et EventType) bool {
+	if !wh.Active {
+		return false
+	}
+
+	for _, v := range wh.Events {
+		if v == et {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Cursor returns the position of the webhook in a paginated list.
+func (wh Webhook) Cursor() Cursor {
+	return Cursor{
+		CreatedAt: wh.CreatedAt,
+		ID:        wh.ID,
+	}
+}
+
+// WebhookCore contains core webhook information.
+type WebhookCore struct {
+	// URL specifies the address that events are posted to.
+	URL string `json:"url"`
+
+	// Events specifies the event types that the webhook is subscribed to.
+	Events []EventType `json:"events"`
+
+	// Active specifies whether events are delivered to the webhook.
+	Active bool `json:"active"`
+}
+
+// Validate checks whether webhook core contains valid attributes. All
+// invalid attributes are reported at once.
+func (wc *WebhookCore) Validate() *apierr.Error {
+	var vv apierr.Violations
+
+	u, err := url.Parse(wc.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		vv.Add("url", "must be an absolute http or https url")
+	}
+
+	checkLength(&vv, "url", wc.URL, _maxWebhookURLLength)
+
+	if len(wc.Events) < 1 {
+		vv.Add("events", "must contain at least one element")
+	}
+
+	seen := make(map[EventType]struct{}, len(wc.Events))
+
+	for i, et := range wc.Events {
+		attr := fmt.Sprintf("events[%d]", i)
+
+		if !et.Valid() {
+			vv.Add(attr, "must be a known event type")
+		}
+
+		if _, ok := seen[et]; ok {
+			vv.Add(attr, "cannot be repeated")
+		}
+
+		seen[et] = struct{}{}
+	}
+
+	return vv.Err()
+}
+
+// NewWebhookSecret generates a new random secret that webhook deliveries
+// are signed with.
+func NewWebhookSecret() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(data), nil
+}
+
+// DeliveryStatus specifies the state of a webhook delivery.
+type DeliveryStatus string
+
+const (
+	// DeliveryStatusPending specifies that the delivery is waiting for
+	// its next attempt.
+	DeliveryStatusPending DeliveryStatus = "pending"
+
+	// DeliveryStatusSucceeded specifies that the receiver accepted the
+	// delivery.
+	DeliveryStatusSucceeded DeliveryStatus = "succeeded"
+
+	// DeliveryStatusFailed specifies that all attempts of the delivery
+	// failed and it will not be retried.
+	DeliveryStatusFailed DeliveryStatus = "failed"
+)
+
+// WebhookDelivery contains information about a single event delivered to a
+// webhook and its attempts.
+type WebhookDelivery struct {
+	// ID specifies the id of the delivery. It is sent to the receiver, so
+	// that retried deliveries could be recognized.
+	ID xid.ID `json:"id"`
+
+	// WebhookID specifies the id of the webhook that the event is
+	// delivered to.
+	WebhookID xid.ID `json:"webhook_id"`
+
+	// Event specifies the type of the delivered event.
+	Event EventType `json:"event"`
+
+	// Payload contains the JSON body that is posted to the webhook.
+	Payload json.RawMessage `json:"payload"`
+
+	// Status specifies the state of the delivery.
+	Status DeliveryStatus `json:"status"`
+
+	// Attempts specifies how many times the delivery was attempted.
+	Attempts uint `json:"attempts"`
+
+	// NextAttemptAt specifies a time at which the delivery is attempted
+	// next. It is nil once the delivery is finished.
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+
+	// ResponseStatus specifies the HTTP status code of the last response
+	// of the receiver, if there was one.
+	ResponseStatus int `json:"response_status,omitempty"`
+
+	// Error describes why the last attempt failed.
+	Error string `json:"error,omitempty"`
+
+	// CreatedAt specifies a time at which the object was created.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Cursor returns the position of the delivery in a paginated list.
+func (wd WebhookDelivery) Cursor() Cursor {
+	return Cursor{
+		CreatedAt: wd.CreatedAt,
+		ID:        wd.ID,
+	}
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"foodie/server/apierr"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WebhookCore_Validate(t *testing.T) {
+	tests := map[string]struct {
+		WebhookCore WebhookCore
+		Error       *apierr.Error
+	}{
+		"Invalid url": {
+			WebhookCore: WebhookCore{
+				URL:    "ftp://example.com/hook",
+				Events: []EventType{EventRecipeCreated},
+			},
+			Error: apierr.InvalidAttribute("url", "must be an absolute http or https url"),
+		},
+		"Relative url": {
+			WebhookCore: WebhookCore{
+				URL:    "/hook",
+				Events: []EventType{EventRecipeCreated},
+			},
+			Error: apierr.InvalidAttribute("url", "must be an absolute http or https url"),
+		},
+		"Invalid events length": {
+			WebhookCore: WebhookCore{
+				URL: "https://example.com/hook",
+			},
+			Error: apierr.InvalidAttribute("events", "must contain at least one element"),
+		},
+		"Unknown event": {
+			WebhookCore: WebhookCore{
+				URL:    "https://example.com/hook",
+				Events: []EventType{EventRecipeCreated, "recipe.eaten"},
+			},
+			Error: apierr.InvalidAttribute("events[1]", "must be a known event type"),
+		},
+		"Repeated event": {
+			WebhookCore: WebhookCore{
+				URL:    "https://example.com/hook",
+				Events: []EventType{EventPlanDeleted, EventPlanDeleted},
+			},
+			Error: apierr.InvalidAttribute("events[1]", "cannot be repeated"),
+		},
+		"Successful validation": {
+			WebhookCore: WebhookCore{
+				URL:    "http://example.com/hook",
+				Events: []EventType{EventProductCreated, EventUserDeleted},
+				Active: true,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.Error, test.WebhookCore.Validate())
+		})
+	}
+}
+
+func Test_Webhook_Subscribed(t *testing.T) {
+	wh := Webhook{
+		WebhookCore: WebhookCore{
+			Events: []EventType{EventRecipeCreated, EventRecipeDeleted},
+			Active: true,
+		},
+	}
+
+	assert.True(t, wh.Subscribed(EventRecipeCreated))
+	assert.False(t, wh.Subscribed(EventRecipeUpdated))
+
+	wh.Active = false
+
+	assert.False(t, wh.Subscribed(EventRecipeCreated))
+}
+
+func Test_NewWebhookSecret(t *testing.T) {
+	s1, err := NewWebhookSecret()
+	assert.NoError(t, err)
+	assert.Len(t, s1, 64)
+
+	s2, err := NewWebhookSecret()
+	assert.NoError(t, err)
+	assert.NotEqual(t, s1, s2)
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"foodie/core"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/rs/xid"
+)
+
+// GetProductDependents retrieves recipes that use the product and plans
+// that include those recipes. Objects in the trash are included as well,
+// since they still reference the product.
+func GetProductDependents(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	id xid.ID,
+) (*core.Dependents, error) {
+	rr, err := selectDependents(
+		ctx,
+		qc,
+		"recipes",
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Join(
+				"recipe_products ON recipe_products.recipe_id = recipes.id",
+			).Where(
+				squirrel.Eq{"recipe_products.product_id": id},
+			)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := core.Dependents{
+		Recipes: rr,
+		Plans:   make([]core.Dependent, 0),
+	}
+
+	if len(rr) == 0 {
+		return &deps, nil
+	}
+
+	rids := make([]xid.ID, 0, len(rr))
+	for _, rec := range rr {
+		rids = append(rids, rec.ID)
+	}
+
+	deps.Plans, err = selectDependents(
+		ctx,
+		qc,
+		"plans",
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Distinct().Join(
+				"plan_recipes ON plan_recipes.plan_id = plans.id",
+			).Where(
+				squirrel.Eq{"plan_recipes.recipe_id": rids},
+			)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deps, nil
+}
+
+// GetRecipeDependents retrieves plans that include the recipe. Plans in
+// the trash are included as well, since they still reference the recipe.
+func GetRecipeDependents(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	id xid.ID,
+) (*core.Dependents, error) {
+	pp, err := selectDependents(
+		ctx,
+		qc,
+		"plans",
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Join(
+				"plan_recipes ON plan_recipes.plan_id = plans.id",
+			).Where(
+				squirrel.Eq{"plan_recipes.recipe_id": id},
+			)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &core.Dependents{
+		Recipes: make([]core.Dependent, 0),
+		Plans:   pp,
+	}, nil
+}
+
+// selectDependents selects dependent objects of the provided table by the
+// provided decorator function.
+func selectDependents(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	table string,
+	dec func(squirrel.SelectBuilder) squirrel.SelectBuilder,
+) ([]core.Dependent, error) {
+	rows, err := squirrel.QueryContextWith(ctx, qc, dec(squirrel.
+		Select(
+			table+".id",
+			table+".user_id",
+			table+".name",
+			table+".visibility",
+			table+".state",
+			table+".deleted_at IS NOT NULL",
+		).From(table).OrderBy(table+".created_at"),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	dd := make([]core.Dependent, 0)
+
+	for rows.Next() {
+		var dep core.Dependent
+
+		if err := rows.Scan(
+			&dep.ID,
+			&dep.UserID,
+			&dep.Name,
+			&dep.Visibility,
+			&dep.State,
+			&dep.Deleted,
+		); err != nil {
+			return nil, err
+		}
+
+		dd = append(dd, dep)
+	}
+
+	return dd, nil
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"foodie/core"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetProductDependents(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	pid := xid.New()
+
+	mockProducts(t, dbh, core.Product{
+		ID: pid,
+		ProductCore: core.ProductCore{
+			Name: "1",
+			Serving: core.Serving{
+				Type:     "units",
+				Size:     decimal.NewFromInt(1),
+				Calories: 2,
+			},
+		},
+	})
+
+	uid := xid.New()
+	rid1 := xid.New()
+	rid2 := xid.New()
+
+	mockRecipes(t, dbh, []core.Recipe{
+		{
+			ID:        rid1,
+			UserID:    uid,
+			CreatedAt: time.Now().UTC().Truncate(time.Second).Add(-time.Minute),
+			RecipeCore: core.RecipeCore{
+				Name:       "1",
+				Visibility: core.VisibilityPublic,
+				State:      core.StatePublished,
+				Products: []core.RecipeProduct{
+					{RecipeID: rid1, ProductID: pid, Quantity: decimal.NewFromInt(1)},
+				},
+			},
+		},
+		{
+			ID:        rid2,
+			UserID:    uid,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			RecipeCore: core.RecipeCore{
+				Name:       "2",
+				Visibility: core.VisibilityPrivate,
+				State:      core.StatePublished,
+				Products: []core.RecipeProduct{
+					{RecipeID: rid2, ProductID: pid, Quantity: decimal.NewFromInt(1)},
+				},
+			},
+		},
+	}...)
+
+	plid := xid.New()
+
+	mockPlans(t, dbh, core.Plan{
+		ID:     plid,
+		UserID: uid,
+		PlanCore: core.PlanCore{
+			Name:       "1",
+			Visibility: core.VisibilityPublic,
+			State:      core.StatePublished,
+			Recipes: []core.PlanRecipe{
+				{PlanID: plid, RecipeID: rid1, Quantity: 1},
+				{PlanID: plid, RecipeID: rid2, Quantity: 1},
+			},
+		},
+	})
+
+	require.NoError(t, DeleteRecipeByID(context.Background(), dbh, rid2))
+
+	res, err := GetProductDependents(context.Background(), dbh, pid)
+	require.NoError(t, err)
+
+	assert.Equal(t, []core.Dependent{
+		{
+			ID:         rid1,
+			UserID:     uid,
+			Name:       "1",
+			Visibility: core.VisibilityPublic,
+			State:      core.StatePublished,
+		},
+		{
+			ID:         rid2,
+			UserID:     uid,
+			Name:       "2",
+			Visibility: core.VisibilityPrivate,
+			State:      core.StatePublished,
+			Deleted:    true,
+		},
+	}, res.Recipes)
+
+	assert.Equal(t, []core.Dependent{
+		{
+			ID:         plid,
+			UserID:     uid,
+			Name:       "1",
+			Visibility: core.VisibilityPublic,
+			State:      core.StatePublished,
+		},
+	}, res.Plans)
+
+	res, err = GetRecipeDependents(context.Background(), dbh, rid1)
+	require.NoError(t, err)
+	assert.Empty(t, res.Recipes)
+	assert.Len(t, res.Plans, 1)
+
+	res, err = GetProductDependents(context.Background(), dbh, xid.New())
+	require.NoError(t, err)
+	assert.True(t, res.Empty())
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"foodie/core"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/rs/xid"
+)
+
+// ErrDuplicateIdempotencyKey is returned whenever a request is being saved
+// with an idempotency key that was already used by the same user.
+var ErrDuplicateIdempotencyKey = errors.New("duplicate idempotency key")
+
+// InsertIdempotentRequest inserts a request that is about to be handled.
+// A request of the user with the same key that was made before the expiry
+// time, or that was not completed and was made before the abandonment
+// time, is replaced.
+func InsertIdempotentRequest(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	ir core.IdempotentRequest,
+	expiry time.Time,
+	abandoned time.Time,
+) error {
+	_, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		squirrel.Delete("idempotency_keys").Where(squirrel.And{
+			squirrel.Eq{"idempotency_keys.user_id": ir.UserID.String()},
+			squirrel.Eq{"idempotency_keys.key": ir.Key},
+			squirrel.Or{
+				squirrel.Lt{"idempotency_keys.created_at": expiry},
+				squirrel.And{
+					squirrel.Eq{"idempotency_keys.status_code": 0},
+					squirrel.Lt{"idempotency_keys.created_at": abandoned},
+				},
+			},
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = squirrel.ExecContextWith(
+		ctx,
+		ec,
+		squirrel.Insert("idempotency_keys").SetMap(map[string]interface{}{
+			"idempotency_keys.user_id":    ir.UserID.String(),
+			"idempotency_keys.key":        ir.Key,
+			"idempotency_keys.hash":       ir.Hash,
+			"idempotency_keys.created_at": ir.CreatedAt,
+		}),
+	)
+	switch {
+	case err == nil:
+		return nil
+	case isDuplicate(err):
+		return ErrDuplicateIdempotencyKey
+	default:
+		return err
+	}
+}
+
+// GetIdempotentRequest retrieves a request of the user by its idempotency
+// key. Requests made before the expiry time are not retrieved, nor are the
+// requests that were made before the abandonment time and were never
+// completed, e.g. because the server crashed while handling them.
+func GetIdempotentRequest(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	uid xid.ID,
+	key string,
+	expiry time.Time,
+	abandoned time.Time,
+) (*core.IdempotentRequest, error) {
+	sb := squirrel.Select(
+		"idempotency_keys.key",
+		"idempotency_keys.hash",
+		"idempotency_keys.status_code",
+		"idempotency_keys.content_type",
+		"idempotency_keys.body",
+		"idempotency_keys.created_at",
+	).From("idempotency_keys").Where(squirrel.And{
+		squirrel.Eq{"idempotency_keys.user_id": uid.String()},
+		squirrel.Eq{"idempotency_keys.key": key},
+		squirrel.GtOrEq{"idempotency_keys.created_at": expiry},
+		squirrel.Or{
+			squirrel.NotEq{"idempotency_keys.status_code": 0},
+			squirrel.GtOrEq{"idempotency_keys.created_at": abandoned},
+		},
+	})
+
+	rows, err := squirrel.QueryContextWith(ctx, qc, sb)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrNotFound
+	}
+
+	ir := core.IdempotentRequest{
+		UserID: uid,
+	}
+
+	if err := rows.Scan(
+		&ir.Key,
+		&ir.Hash,
+		&ir.StatusCode,
+		&ir.ContentType,
+		&ir.Body,
+		&ir.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &ir, nil
+}
+
+// CompleteIdempotentRequest stores the response of the request.
+func CompleteIdempotentRequest(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	ir core.IdempotentRequest,
+) error {
+	_, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		squirrel.Update("idempotency_keys").SetMap(map[string]interface{}{
+			"idempotency_keys.status_code":  ir.StatusCode,
+			"idempotency_keys.content_type": ir.ContentType,
+			"idempotency_keys.body":         ir.Body,
+		}).Where(squirrel.And{
+			squirrel.Eq{"idempotency_keys.user_id": ir.UserID.String()},
+			squirrel.Eq{"idempotency_keys.key": ir.Key},
+		}),
+	)
+
+	return err
+}
+
+// DeleteIdempotentRequest deletes a request of the user by its
+// idempotency key, so that the key could be used again.
+func DeleteIdempotentRequest(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	uid xid.ID,
+	key string,
+) error {
+	_, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		squirrel.Delete("idempotency_keys").Where(squirrel.And{
+			squirrel.Eq{"idempotency_keys.user_id": uid.String()},
+			squirrel.Eq{"idempotency_keys.key": key},
+		}),
+	)
+
+	return err
+}
+
+// PurgeIdempotentRequests permanently deletes the requests that were made
+// before the expiry time.
+func PurgeIdempotentRequests(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	expiry time.Time,
+) error {
+	_, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		squirrel.Delete("idempotency_keys").Where(
+			squirrel.Lt{"idempotency_keys.created_at": expiry},
+		),
+	)
+
+	return err
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"foodie/core"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IdempotentRequests(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	ir := core.IdempotentRequest{
+		UserID:    xid.New(),
+		Key:       "key",
+		Hash:      "hash",
+		CreatedAt: now,
+	}
+
+	require.NoError(t, InsertIdempotentRequest(context.Background(), dbh, ir, now.Add(-time.Hour), now.Add(-time.Minute)))
+
+	// The same key of another user is independent.
+	require.NoError(t, InsertIdempotentRequest(context.Background(), dbh, core.IdempotentRequest{
+		UserID:    xid.NilID(),
+		Key:       ir.Key,
+		Hash:      "other",
+		CreatedAt: now,
+	}, now.Add(-time.Hour), now.Add(-time.Minute)))
+
+	err := InsertIdempotentRequest(context.Background(), dbh, ir, now.Add(-time.Hour), now.Add(-time.Minute))
+	assert.Equal(t, ErrDuplicateIdempotencyKey, err)
+
+	res, err := GetIdempotentRequest(context.Background(), dbh, ir.UserID, ir.Key, now.Add(-time.Hour), now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "hash", res.Hash)
+	assert.False(t, res.Completed())
+
+	ir.StatusCode = 200
+	ir.ContentType = "application/json"
+	ir.Body = []byte(`{"id":"1"}`)
+
+	require.NoError(t, CompleteIdempotentRequest(context.Background(), dbh, ir))
+
+	res, err = GetIdempotentRequest(context.Background(), dbh, ir.UserID, ir.Key, now.Add(-time.Hour), now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, ir, *res)
+
+	// Expired requests are neither retrieved nor blocking new ones.
+	_, err = GetIdempotentRequest(context.Background(), dbh, ir.UserID, ir.Key, now.Add(time.Second), now.Add(-time.Minute))
+	assert.Equal(t, ErrNotFound, err)
+
+	require.NoError(t, InsertIdempotentRequest(context.Background(), dbh, ir, now.Add(time.Second), now.Add(-time.Minute)))
+
+	require.NoError(t, DeleteIdempotentRequest(context.Background(), dbh, ir.UserID, ir.Key))
+
+	_, err = GetIdempotentRequest(context.Background(), dbh, ir.UserID, ir.Key, now.Add(-time.Hour), now.Add(-time.Minute))
+	assert.Equal(t, ErrNotFound, err)
+
+	// Requests that were never completed are abandoned after the lease.
+	abandoned := core.IdempotentRequest{
+		UserID:    ir.UserID,
+		Key:       "abandoned",
+		Hash:      "hash",
+		CreatedAt: now.Add(-2 * time.Minute),
+	}
+
+	require.NoError(t, InsertIdempotentRequest(context.Background(), dbh, abandoned, now.Add(-time.Hour), now.Add(-time.Minute)))
+
+	_, err = GetIdempotentRequest(context.Background(), dbh, ir.UserID, abandoned.Key, now.Add(-time.Hour), now.Add(-3*time.Minute))
+	require.NoError(t, err)
+
+	_, err = GetIdempotentRequest(context.Background(), dbh, ir.UserID, abandoned.Key, now.Add(-time.Hour), now.Add(-time.Minute))
+	assert.Equal(t, ErrNotFound, err)
+
+	abandoned.CreatedAt = now
+	require.NoError(t, InsertIdempotentRequest(context.Background(), dbh, abandoned, now.Add(-time.Hour), now.Add(-time.Minute)))
+
+	require.NoError(t, PurgeIdempotentRequests(context.Background(), dbh, now.Add(time.Second)))
+
+	_, err = GetIdempotentRequest(context.Background(), dbh, xid.NilID(), ir.Key, now.Add(-time.Hour), now.Add(-time.Minute))
+	assert.Equal(t, ErrNotFound, err)
+}
@@ -31,6 +31,11 @@ func InsertPlan(
 		PlanCore:  pc,
 	}
 
+	pl.Revision = core.Revision{
+		Version:   1,
+		UpdatedAt: pl.CreatedAt,
+	}
+
 	_, err = squirrel.ExecContextWith(
 		ctx,
 		tx,
@@ -39,7 +44,10 @@ func InsertPlan(
 			"plans.user_id":     pl.UserID,
 			"plans.name":        pl.Name,
 			"plans.description": pl.Description,
+			"plans.visibility":  pl.Visibility,
+			"plans.state":       pl.State,
 			"plans.created_at":  pl.CreatedAt,
+			"plans.updated_at":  pl.UpdatedAt,
 		}),
 	)
 	if err != nil {
@@ -65,46 +73,81 @@ func InsertPlan(
 	return &pl, nil
 }
 
-// GetPlans retrieves all plans.
+// GetPlans retrieves a page of plans that are listed to the viewer and
+// match the query. The cursor of the next page is returned if there is one.
 func GetPlans(
 	ctx context.Context,
 	qc squirrel.QueryerContext,
-) ([]core.Plan, error) {
-	return selectPlans(
+	vw core.Viewer,
+	q core.Query,
+	pg core.Page,
+) ([]core.Plan, *core.Cursor, error) {
+	pp, err := selectPlans(
 		ctx,
 		qc,
+		vw,
 		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
-			return sb
+			if !vw.Admin {
+				sb = sb.Where(listedTo("plans", vw))
+			}
+
+			return applyQuery(sb, "plans", _planColumns, q, pg)
 		},
 	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pp, next := trimQueryPage(pp, q, pg)
+
+	return pp, next, nil
 }
 
-// GetPlansByUserID retrieves plans by the user id.
+// GetPlansByUserID retrieves a page of plans by the user id that are
+// listed to the viewer and match the query.
 func GetPlansByUserID(
 	ctx context.Context,
 	qc squirrel.QueryerContext,
+	vw core.Viewer,
 	uid xid.ID,
-) ([]core.Plan, error) {
-	return selectPlans(
+	q core.Query,
+	pg core.Page,
+) ([]core.Plan, *core.Cursor, error) {
+	pp, err := selectPlans(
 		ctx,
 		qc,
+		vw,
 		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
-			return sb.Where(
+			if !vw.Admin {
+				sb = sb.Where(listedTo("plans", vw))
+			}
+
+			return applyQuery(sb.Where(
 				squirrel.Eq{"plans.user_id": uid},
-			)
+			), "plans", _planColumns, q, pg)
 		},
 	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pp, next := trimQueryPage(pp, q, pg)
+
+	return pp, next, nil
 }
 
-// GetPlanByID retrieves a plan by its id.
+// GetPlanByID retrieves a plan by its id. ErrNotFound is returned
+// when the plan is not visible to the viewer.
 func GetPlanByID(
 	ctx context.Context,
 	qc squirrel.QueryerContext,
+	vw core.Viewer,
 	id xid.ID,
 ) (*core.Plan, error) {
 	pp, err := selectPlans(
 		ctx,
 		qc,
+		vw,
 		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
 			return sb.Where(
 				squirrel.Eq{"plans.id": id},
@@ -122,12 +165,16 @@ func GetPlanByID(
 	return &pp[0], nil
 }
 
-// UpdatePlanByID updates an existing plan by its id. An updated plan
-// is returned.
+// UpdatePlanByID updates an existing plan by its id if its current
+// version matches the provided one. ErrVersionMismatch is returned
+// otherwise. Only the attributes that differ from the current plan core are
+// written. An updated plan is returned.
 func UpdatePlanByID(
 	ctx context.Context,
 	db *sql.DB,
 	id xid.ID,
+	version uint64,
+	cur core.PlanCore,
 	pc core.PlanCore,
 ) (*core.Plan, error) {
 	tx, err := db.BeginTx(ctx, nil)
@@ -137,34 +184,100 @@ func UpdatePlanByID(
 
 	defer tx.Rollback()
 
-	if err := deletePlanRecipes(
+	err = execVersioned(
 		ctx,
 		tx,
+		squirrel.Update("plans").SetMap(
+			changedValues(planValues(cur), planValues(pc)),
+		),
+		"plans",
 		id,
-	); err != nil {
+		version,
+	)
+	if err != nil {
 		return nil, err
 	}
 
-	for _, pr := range pc.Recipes {
-		pr.PlanID = id
-
-		if err := upsertPlanRecipe(
-			ctx,
-			tx,
-			pr,
-		); err != nil {
+	if !planRecipesEqual(cur.Recipes, pc.Recipes) {
+		if err := replacePlanRecipes(ctx, tx, id, pc.Recipes); err != nil {
 			return nil, err
 		}
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	pl, err := GetPlanByID(ctx, db, _unrestricted, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return pl, nil
+}
+
+// UpsertPlanRecipe adds the recipe to the plan or updates its quantity if
+// the current version of the plan matches the provided one.
+// ErrVersionMismatch is returned otherwise. An updated plan is returned.
+func UpsertPlanRecipe(
+	ctx context.Context,
+	db *sql.DB,
+	version uint64,
+	pr core.PlanRecipe,
+) (*core.Plan, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	err = execVersioned(ctx, tx, squirrel.Update("plans"), "plans", pr.PlanID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := upsertPlanRecipe(ctx, tx, pr); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return GetPlanByID(ctx, db, _unrestricted, pr.PlanID)
+}
+
+// DeletePlanRecipe removes the recipe from the plan if the current version
+// of the plan matches the provided one. ErrVersionMismatch is returned
+// otherwise. An updated plan is returned.
+func DeletePlanRecipe(
+	ctx context.Context,
+	db *sql.DB,
+	version uint64,
+	pid xid.ID,
+	rid xid.ID,
+) (*core.Plan, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	err = execVersioned(ctx, tx, squirrel.Update("plans"), "plans", pid, version)
+	if err != nil {
+		return nil, err
+	}
+
 	_, err = squirrel.ExecContextWith(
 		ctx,
 		tx,
-		squirrel.Update("plans").SetMap(map[string]interface{}{
-			"plans.name":        pc.Name,
-			"plans.description": pc.Description,
-		}).Where(
-			squirrel.Eq{"plans.id": id},
+		squirrel.Delete("plan_recipes").Where(
+			squirrel.Eq{
+				"plan_recipes.plan_id":   pid,
+				"plan_recipes.recipe_id": rid,
+			},
 		),
 	)
 	if err != nil {
@@ -175,16 +288,90 @@ func UpdatePlanByID(
 		return nil, err
 	}
 
-	pl, err := GetPlanByID(ctx, db, id)
+	return GetPlanByID(ctx, db, _unrestricted, pid)
+}
+
+// DeletePlanByID moves a plan to the trash by its id.
+func DeletePlanByID(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	id xid.ID,
+) error {
+	_, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		touch(squirrel.Update("plans").SetMap(map[string]interface{}{
+			"plans.deleted_at": time.Now(),
+		}), "plans").Where(
+			squirrel.Eq{
+				"plans.id":         id,
+				"plans.deleted_at": nil,
+			},
+		),
+	)
+
+	return err
+}
+
+// GetDeletedPlans retrieves a page of plans that were moved to the trash.
+// Admins retrieve all of them, while other users retrieve only their own.
+func GetDeletedPlans(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	vw core.Viewer,
+	pg core.Page,
+) ([]core.Plan, *core.Cursor, error) {
+	pp, err := selectDeletedPlans(
+		ctx,
+		qc,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			if !vw.Admin {
+				sb = sb.Where(
+					squirrel.Eq{"plans.user_id": vw.UserID},
+				)
+			}
+
+			return paginate(sb, "plans", pg)
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pp, next := trimPage(pp, pg)
+
+	return pp, next, nil
+}
+
+// GetDeletedPlanByID retrieves a plan that was moved to the trash by
+// its id.
+func GetDeletedPlanByID(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	id xid.ID,
+) (*core.Plan, error) {
+	res, err := selectDeletedPlans(
+		ctx,
+		qc,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Where(
+				squirrel.Eq{"plans.id": id},
+			)
+		},
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	return pl, nil
+	if len(res) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &res[0], nil
 }
 
-// DeletePlanByID deletes a plan by its id.
-func DeletePlanByID(
+// RestorePlanByID restores a plan from the trash by its id.
+func RestorePlanByID(
 	ctx context.Context,
 	ec squirrel.ExecerContext,
 	id xid.ID,
@@ -192,7 +379,9 @@ func DeletePlanByID(
 	_, err := squirrel.ExecContextWith(
 		ctx,
 		ec,
-		squirrel.Delete("plans").Where(
+		touch(squirrel.Update("plans").SetMap(map[string]interface{}{
+			"plans.deleted_at": nil,
+		}), "plans").Where(
 			squirrel.Eq{"plans.id": id},
 		),
 	)
@@ -200,21 +389,81 @@ func DeletePlanByID(
 	return err
 }
 
-// selectPlans selects all plans by the provided decorator function.
+// purgePlans permanently deletes plans that were moved to the trash
+// before the provided time.
+func purgePlans(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	before time.Time,
+) error {
+	_, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		squirrel.Delete("plans").Where(
+			squirrel.Lt{"plans.deleted_at": before},
+		),
+	)
+
+	return err
+}
+
+// selectPlans selects all plans that are visible to the viewer by the
+// provided decorator function. Plans that were moved to the trash are
+// excluded.
 func selectPlans(
 	ctx context.Context,
 	qc squirrel.QueryerContext,
+	vw core.Viewer,
 	dec func(squirrel.SelectBuilder) squirrel.SelectBuilder,
 ) ([]core.Plan, error) {
-	rows, err := squirrel.QueryContextWith(ctx, qc, dec(squirrel.
+	sb := plansSelectBuilder().Where(
+		squirrel.Eq{"plans.deleted_at": nil},
+	)
+
+	if !vw.Admin {
+		sb = sb.Where(visibleTo("plans", vw))
+	}
+
+	return queryPlans(ctx, qc, dec(sb))
+}
+
+// selectDeletedPlans selects all plans that were moved to the trash by
+// the provided decorator function.
+func selectDeletedPlans(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	dec func(squirrel.SelectBuilder) squirrel.SelectBuilder,
+) ([]core.Plan, error) {
+	return queryPlans(ctx, qc, dec(plansSelectBuilder().Where(
+		squirrel.NotEq{"plans.deleted_at": nil},
+	)))
+}
+
+// plansSelectBuilder creates a select builder of all plan columns.
+func plansSelectBuilder() squirrel.SelectBuilder {
+	return squirrel.
 		Select(
 			"plans.id",
 			"plans.user_id",
 			"plans.name",
 			"plans.description",
+			"plans.visibility",
+			"plans.state",
 			"plans.created_at",
-		).From("plans"),
-	))
+			"plans.version",
+			"plans.updated_at",
+			"plans.deleted_at",
+		).From("plans")
+}
+
+// queryPlans executes the select builder and scans the plans together
+// with their recipes, which are selected for all plans at once.
+func queryPlans(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	sb squirrel.SelectBuilder,
+) ([]core.Plan, error) {
+	rows, err := squirrel.QueryContextWith(ctx, qc, sb)
 	if err != nil {
 		return nil, err
 	}
@@ -229,20 +478,52 @@ func selectPlans(
 			&pl.UserID,
 			&pl.Name,
 			&pl.Description,
+			&pl.Visibility,
+			&pl.State,
 			&pl.CreatedAt,
+			&pl.Version,
+			&pl.UpdatedAt,
+			&pl.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
 
-		prs, err := getPlanRecipesByPlanID(ctx, qc, pl.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		pl.Recipes = prs
+		pl.Recipes = make([]core.PlanRecipe, 0)
 		pp = append(pp, pl)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The rows are closed before the children are selected, so that the
+	// connection of a transaction is not busy.
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	if len(pp) == 0 {
+		return pp, nil
+	}
+
+	index := make(map[xid.ID]int, len(pp))
+	ids := make([]xid.ID, 0, len(pp))
+
+	for i, pl := range pp {
+		index[pl.ID] = i
+		ids = append(ids, pl.ID)
+	}
+
+	prs, err := getPlanRecipesByPlanIDs(ctx, qc, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pr := range prs {
+		i := index[pr.PlanID]
+		pp[i].Recipes = append(pp[i].Recipes, pr)
+	}
+
 	return pp, nil
 }
 
@@ -263,23 +544,80 @@ func GetPlanRecipesByRecipeID(
 	)
 }
 
-// getPlanRecipesByPlanID selects plan recipes by the plan id.
-func getPlanRecipesByPlanID(
+// getPlanRecipesByPlanIDs selects plan recipes by the plan ids.
+func getPlanRecipesByPlanIDs(
 	ctx context.Context,
 	qc squirrel.QueryerContext,
-	id xid.ID,
+	ids []xid.ID,
 ) ([]core.PlanRecipe, error) {
 	return selectPlanRecipes(
 		ctx,
 		qc,
 		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
 			return sb.Where(
-				squirrel.Eq{"plan_recipes.plan_id": id},
+				squirrel.Eq{"plan_recipes.plan_id": ids},
 			)
 		},
 	)
 }
 
+// planValues returns the column values of the plan core. Plan recipes are
+// stored separately.
+func planValues(pc core.PlanCore) map[string]interface{} {
+	return map[string]interface{}{
+		"plans.name":        pc.Name,
+		"plans.description": pc.Description,
+		"plans.visibility":  pc.Visibility,
+		"plans.state":       pc.State,
+	}
+}
+
+// planRecipesEqual checks whether both lists contain the same recipes with
+// equal quantities and days, regardless of their order.
+func planRecipesEqual(a, b []core.PlanRecipe) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	qq := make(map[xid.ID]core.PlanRecipe, len(a))
+	for _, pr := range a {
+		qq[pr.RecipeID] = pr
+	}
+
+	for _, pr := range b {
+		q, ok := qq[pr.RecipeID]
+		if !ok || q.Quantity != pr.Quantity || q.Day != pr.Day {
+			return false
+		}
+
+		delete(qq, pr.RecipeID)
+	}
+
+	return true
+}
+
+// replacePlanRecipes replaces all recipes of the plan.
+func replacePlanRecipes(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	pid xid.ID,
+	prs []core.PlanRecipe,
+) error {
+	if err := deletePlanRecipes(ctx, ec, pid); err != nil {
+		return err
+	}
+
+	for _, pr := range prs {
+		pr.PlanID = pid
+
+		if err := upsertPlanRecipe(ctx, ec, pr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // deletePlanRecipes deletes all plan recipes.
 func deletePlanRecipes(
 	ctx context.Context,
@@ -310,7 +648,9 @@ func upsertPlanRecipe(
 			"plan_recipes.plan_id":   pr.PlanID,
 			"plan_recipes.recipe_id": pr.RecipeID,
 			"plan_recipes.quantity":  pr.Quantity,
-		}).Suffix("ON DUPLICATE KEY UPDATE plan_recipes.quantity = VALUES(plan_recipes.quantity)"),
+			"plan_recipes.day":       nullString(pr.Day),
+		}).Suffix("ON DUPLICATE KEY UPDATE plan_recipes.quantity = VALUES(plan_recipes.quantity), "+
+			"plan_recipes.day = VALUES(plan_recipes.day)"),
 	)
 
 	return err
@@ -328,6 +668,7 @@ func selectPlanRecipes(
 			"plan_recipes.plan_id",
 			"plan_recipes.recipe_id",
 			"plan_recipes.quantity",
+			"COALESCE(DATE_FORMAT(plan_recipes.day, '%Y-%m-%d'), '')",
 		).From("plan_recipes"),
 	))
 	if err != nil {
@@ -344,6 +685,7 @@ func selectPlanRecipes(
 			&pr.PlanID,
 			&pr.RecipeID,
 			&pr.Quantity,
+			&pr.Day,
 		); err != nil {
 			return nil, err
 		}
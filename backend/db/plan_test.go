@@ -315,7 +315,7 @@ func Test_GetPlans(t *testing.T) {
 
 	mockPlans(t, dbh, pp...)
 
-	res, err := GetPlans(context.Background(), dbh)
+	res, _, err := GetPlans(context.Background(), dbh, _unrestricted, core.Query{}, core.Page{Limit: 100})
 	require.NoError(t, err)
 	assert.Equal(t, pp, res)
 }
@@ -534,7 +534,7 @@ func Test_GetPlansByUserID(t *testing.T) {
 
 	mockPlans(t, dbh, pp...)
 
-	res, err := GetPlansByUserID(context.Background(), dbh, uid2)
+	res, _, err := GetPlansByUserID(context.Background(), dbh, _unrestricted, uid2, core.Query{}, core.Page{Limit: 100})
 	require.NoError(t, err)
 	assert.Equal(t, pp[1:], res)
 }
@@ -626,13 +626,13 @@ func Test_GetPlanByID(t *testing.T) {
 	mockPlans(t, dbh, pln)
 
 	t.Run("not found", func(t *testing.T) {
-		res, err := GetPlanByID(context.Background(), dbh, xid.New())
+		res, err := GetPlanByID(context.Background(), dbh, _unrestricted, xid.New())
 		assert.Empty(t, res)
 		require.Equal(t, ErrNotFound, err)
 	})
 
 	t.Run("successfully retrieved a plan by id", func(t *testing.T) {
-		res, err := GetPlanByID(context.Background(), dbh, pln.ID)
+		res, err := GetPlanByID(context.Background(), dbh, _unrestricted, pln.ID)
 		require.NoError(t, err)
 		assert.Equal(t, &pln, res)
 	})
@@ -745,6 +745,8 @@ func Test_UpdatePlanByID(t *testing.T) {
 
 	mockPlans(t, dbh, pln)
 
+	cur := pln.PlanCore
+
 	pln.Name = "another"
 	pln.Description = "test"
 	pln.Recipes = []core.PlanRecipe{
@@ -760,9 +762,156 @@ func Test_UpdatePlanByID(t *testing.T) {
 		},
 	}
 
-	res, err := UpdatePlanByID(context.Background(), dbh, pln.ID, pln.PlanCore)
+	res, err := UpdatePlanByID(context.Background(), dbh, pln.ID, pln.Version, cur, pln.PlanCore)
 	require.NoError(t, err)
+	assert.Equal(t, pln.Version+1, res.Version)
+	assert.WithinDuration(t, time.Now(), res.UpdatedAt, time.Minute)
+
+	pln.Revision = res.Revision
 	assert.Equal(t, &pln, res)
+
+	_, err = UpdatePlanByID(context.Background(), dbh, pln.ID, pln.Version-1, cur, pln.PlanCore)
+	assert.Equal(t, ErrVersionMismatch, err)
+}
+
+func Test_UpsertPlanRecipe_DeletePlanRecipe(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	uid1 := xid.New()
+
+	mockUsers(t, dbh, core.User{
+		ID:           uid1,
+		Name:         "1",
+		PasswordHash: []byte{1},
+		Admin:        true,
+	})
+
+	pid1 := xid.New()
+	pid2 := xid.New()
+
+	mockProducts(t, dbh, []core.Product{
+		{
+			ID: pid1,
+			ProductCore: core.ProductCore{
+				Name: "123",
+				Serving: core.Serving{
+					Type:     "units",
+					Size:     decimal.NewFromInt(1),
+					Calories: 2,
+				},
+			},
+		},
+		{
+			ID: pid2,
+			ProductCore: core.ProductCore{
+				Name: "223",
+				Serving: core.Serving{
+					Type:     "grams",
+					Size:     decimal.NewFromInt(1),
+					Calories: 5,
+				},
+			},
+		},
+	}...)
+
+	rid1 := xid.New()
+	rid2 := xid.New()
+
+	rr := []core.Recipe{
+		{
+			ID:        rid1,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			UserID:    uid1,
+			RecipeCore: core.RecipeCore{
+				Name:        "1",
+				Description: "test1",
+				Products: []core.RecipeProduct{
+					{
+						RecipeID:  rid1,
+						ProductID: pid1,
+						Quantity:  decimal.New(1000, -4),
+					},
+					{
+						RecipeID:  rid1,
+						ProductID: pid2,
+						Quantity:  decimal.New(3000, -4),
+					},
+				},
+			},
+		},
+		{
+			ID:        rid2,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			UserID:    uid1,
+			RecipeCore: core.RecipeCore{
+				Name:        "0",
+				Description: "test9",
+				Products: []core.RecipeProduct{
+					{
+						RecipeID:  rid2,
+						ProductID: pid2,
+						Quantity:  decimal.New(2000, -4),
+					},
+				},
+			},
+		},
+	}
+
+	mockRecipes(t, dbh, rr...)
+
+	plid1 := xid.New()
+
+	pln := core.Plan{
+		ID:        plid1,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		UserID:    uid1,
+		PlanCore: core.PlanCore{
+			Name:        "1",
+			Description: "test1",
+			Recipes: []core.PlanRecipe{
+				{
+					PlanID:   plid1,
+					RecipeID: rid1,
+					Quantity: 3,
+				},
+			},
+		},
+	}
+
+	mockPlans(t, dbh, pln)
+
+	pr := core.PlanRecipe{
+		PlanID:   plid1,
+		RecipeID: rid2,
+		Quantity: 2,
+	}
+
+	res, err := UpsertPlanRecipe(context.Background(), dbh, pln.Version, pr)
+	require.NoError(t, err)
+	assert.Equal(t, pln.Version+1, res.Version)
+	assert.ElementsMatch(t, append(pln.Recipes, pr), res.Recipes)
+
+	pr.Quantity = 5
+	pr.Day = "2022-10-12"
+
+	res, err = UpsertPlanRecipe(context.Background(), dbh, res.Version, pr)
+	require.NoError(t, err)
+	assert.Equal(t, pln.Version+2, res.Version)
+	assert.ElementsMatch(t, append(pln.Recipes, pr), res.Recipes)
+
+	_, err = UpsertPlanRecipe(context.Background(), dbh, pln.Version, pr)
+	assert.Equal(t, ErrVersionMismatch, err)
+
+	res, err = DeletePlanRecipe(context.Background(), dbh, res.Version, plid1, rid2)
+	require.NoError(t, err)
+	assert.Equal(t, pln.Version+3, res.Version)
+
+	pln.Revision = res.Revision
+	assert.Equal(t, &pln, res)
+
+	_, err = DeletePlanRecipe(context.Background(), dbh, pln.Version-1, plid1, rid1)
+	assert.Equal(t, ErrVersionMismatch, err)
 }
 
 func Test_DeletePlanByID(t *testing.T) {
@@ -1099,7 +1248,11 @@ func mockPlans(t *testing.T, dbh *sql.DB, pp ...core.Plan) {
 				"plans.user_id":     pl.UserID,
 				"plans.name":        pl.Name,
 				"plans.description": pl.Description,
+				"plans.visibility":  pl.Visibility,
+				"plans.state":       pl.State,
 				"plans.created_at":  pl.CreatedAt,
+				"plans.version":     pl.Version,
+				"plans.updated_at":  pl.UpdatedAt,
 			}),
 		)
 		require.NoError(t, err)
@@ -1111,6 +1264,7 @@ func mockPlans(t *testing.T, dbh *sql.DB, pp ...core.Plan) {
 					"plan_recipes.plan_id":   pr.PlanID,
 					"plan_recipes.recipe_id": pr.RecipeID,
 					"plan_recipes.quantity":  pr.Quantity,
+					"plan_recipes.day":       nullString(pr.Day),
 				}).Suffix("ON DUPLICATE KEY UPDATE plan_recipes.quantity = VALUES(plan_recipes.quantity)"),
 			)
 			require.NoError(t, err)
@@ -1125,8 +1279,13 @@ func retrievePlans(t *testing.T, dbh *sql.DB) []core.Plan {
 			"plans.user_id",
 			"plans.name",
 			"plans.description",
+			"plans.visibility",
+			"plans.state",
 			"plans.created_at",
-		).From("plans"),
+		).From("plans").
+		Where(squirrel.Eq{
+			"plans.deleted_at": nil,
+		}),
 	)
 	require.NoError(t, err)
 
@@ -1142,6 +1301,8 @@ func retrievePlans(t *testing.T, dbh *sql.DB) []core.Plan {
 			&pl.UserID,
 			&pl.Name,
 			&pl.Description,
+			&pl.Visibility,
+			&pl.State,
 			&pl.CreatedAt,
 		))
 
@@ -2,66 +2,358 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"foodie/core"
+	"strings"
 	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
 )
 
-// InsertProduct inserts a new product into the database.
+// InsertProduct inserts a new product submitted by the provided user into
+// the database.
 func InsertProduct(
 	ctx context.Context,
 	ec squirrel.ExecerContext,
+	uid xid.ID,
+	st core.ProductStatus,
 	pc core.ProductCore,
 ) (*core.Product, error) {
 	product := core.Product{
 		ID:          xid.New(),
+		UserID:      uid,
+		Status:      st,
 		CreatedAt:   time.Now(),
 		ProductCore: pc,
 	}
 
+	product.Revision = core.Revision{
+		Version:   1,
+		UpdatedAt: product.CreatedAt,
+	}
+
 	_, err := squirrel.ExecContextWith(
 		ctx,
 		ec,
 		squirrel.Insert("products").SetMap(map[string]interface{}{
 			"products.id":               product.ID,
+			"products.user_id":          product.UserID,
 			"products.name":             product.Name,
+			"products.aliases":          joinAliases(product.Aliases),
+			"products.barcode":          nullString(product.Barcode),
 			"products.description":      product.Description,
 			"products.image_url":        product.ImageURL,
 			"products.serving_type":     product.Serving.Type,
 			"products.serving_size":     product.Serving.Size,
 			"products.serving_calories": product.Serving.Calories,
+			"products.status":           product.Status,
 			"products.created_at":       product.CreatedAt,
+			"products.updated_at":       product.UpdatedAt,
 		}),
 	)
-	if err != nil {
+	switch {
+	case err == nil:
+		// OK.
+	case isDuplicate(err):
+		return nil, ErrDuplicateBarcode
+	default:
 		return nil, err
 	}
 
 	return &product, nil
 }
 
-// GetProducts retrieves all products.
-func GetProducts(ctx context.Context, qc squirrel.QueryerContext) ([]core.Product, error) {
+// GetProducts retrieves a page of products that are visible to the viewer
+// and match the query. The cursor of the next page is returned if there is
+// one.
+func GetProducts(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	vw core.Viewer,
+	q core.Query,
+	pg core.Page,
+) ([]core.Product, *core.Cursor, error) {
+	pp, err := selectProducts(
+		ctx,
+		qc,
+		vw,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return applyQuery(sb, "products", _productColumns, q, pg)
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pp, next := trimQueryPage(pp, q, pg)
+
+	return pp, next, nil
+}
+
+// GetProductsByIDs retrieves products by their ids that are visible to the
+// viewer.
+func GetProductsByIDs(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	vw core.Viewer,
+	ids []xid.ID,
+) ([]core.Product, error) {
+	if len(ids) == 0 {
+		return make([]core.Product, 0), nil
+	}
+
 	return selectProducts(
 		ctx,
 		qc,
+		vw,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Where(
+				squirrel.Eq{"products.id": ids},
+			)
+		},
+	)
+}
+
+// GetAllProducts retrieves all products that were not moved to the trash,
+// regardless of their status.
+func GetAllProducts(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+) ([]core.Product, error) {
+	return selectProducts(
+		ctx,
+		qc,
+		_unrestricted,
 		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
 			return sb
 		},
 	)
 }
 
-// GetProductByID retrieves a product by the product id.
+// EachProduct calls the function with every product that was not moved to
+// the trash, regardless of its status, from the oldest to the newest. The
+// products are read one at a time, so that all of them do not have to fit
+// in memory. The iteration stops at the first error of the function.
+func EachProduct(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	fn func(core.Product) error,
+) error {
+	rows, err := squirrel.QueryContextWith(
+		ctx,
+		qc,
+		productsSelectBuilder().Where(
+			squirrel.Eq{"products.deleted_at": nil},
+		).OrderBy(
+			"products.created_at ASC",
+			"products.id ASC",
+		),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		prd, err := scanProduct(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(prd); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ImportProducts creates the products or updates the existing ones in a
+// single transaction. Existing products are matched by their barcodes
+// and, if the imported product has no barcode or no product has it, by
+// the names of approved products. New products are approved and attributed to the provided
+// user. If dry is true, the transaction is rolled back, so that the import
+// can be checked without changing the database.
+func ImportProducts(
+	ctx context.Context,
+	db *sql.DB,
+	uid xid.ID,
+	pcs []core.ProductCore,
+	dry bool,
+) ([]core.ImportedProduct, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	res := make([]core.ImportedProduct, 0, len(pcs))
+
+	for _, pc := range pcs {
+		ip, err := importProduct(ctx, tx, uid, pc)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, ip)
+	}
+
+	if dry {
+		return res, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// importProduct creates the product or updates the existing one that
+// matches it. The barcode of the existing product is kept if the imported
+// product has none.
+func importProduct(
+	ctx context.Context,
+	tx *sql.Tx,
+	uid xid.ID,
+	pc core.ProductCore,
+) (core.ImportedProduct, error) {
+	cur, err := matchProduct(ctx, tx, pc)
+	switch err {
+	case nil:
+		// OK.
+	case ErrNotFound:
+		prd, err := InsertProduct(ctx, tx, uid, core.ProductStatusApproved, pc)
+		if err != nil {
+			return core.ImportedProduct{}, err
+		}
+
+		return core.ImportedProduct{
+			Product: prd,
+			Action:  core.ProductImportCreated,
+		}, nil
+	default:
+		return core.ImportedProduct{}, err
+	}
+
+	if cur.DeletedAt != nil {
+		return core.ImportedProduct{Action: core.ProductImportTrashed}, nil
+	}
+
+	if pc.Barcode == "" {
+		pc.Barcode = cur.Barcode
+	}
+
+	if len(changedValues(productValues(cur.ProductCore), productValues(pc))) == 0 {
+		return core.ImportedProduct{
+			Product: cur,
+			Action:  core.ProductImportUnchanged,
+		}, nil
+	}
+
+	prd, err := UpdateProductByID(ctx, tx, cur.ID, cur.Version, cur.ProductCore, pc)
+	if err != nil {
+		return core.ImportedProduct{}, err
+	}
+
+	return core.ImportedProduct{
+		Product: prd,
+		Action:  core.ProductImportUpdated,
+	}, nil
+}
+
+// matchProduct finds the existing product that the imported product core
+// should update. Products are matched by their barcodes first, including
+// the products in the trash, since barcodes are unique among all of them.
+// Otherwise the oldest approved product with the same name, which was not
+// moved to the trash and, if the core has a barcode, has no barcode of its
+// own, is matched, so that pending submissions of users are never
+// overwritten. ErrNotFound is returned if no product matches.
+func matchProduct(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	pc core.ProductCore,
+) (*core.Product, error) {
+	if pc.Barcode != "" {
+		pp, err := queryProducts(ctx, qc, productsSelectBuilder().Where(
+			squirrel.Eq{"products.barcode": pc.Barcode},
+		).Suffix("FOR UPDATE"))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(pp) > 0 {
+			return &pp[0], nil
+		}
+	}
+
+	cond := squirrel.Eq{
+		"products.name":       pc.Name,
+		"products.status":     core.ProductStatusApproved,
+		"products.deleted_at": nil,
+	}
+
+	if pc.Barcode != "" {
+		cond["products.barcode"] = nil
+	}
+
+	pp, err := queryProducts(ctx, qc, productsSelectBuilder().Where(cond).OrderBy(
+		"products.created_at ASC",
+		"products.id ASC",
+	).Limit(1).Suffix("FOR UPDATE"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pp) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &pp[0], nil
+}
+
+// GetProductsByStatus retrieves a page of products by their moderation
+// status. Products are ordered from the oldest to the newest.
+func GetProductsByStatus(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	st core.ProductStatus,
+	pg core.Page,
+) ([]core.Product, *core.Cursor, error) {
+	pp, err := selectProducts(
+		ctx,
+		qc,
+		_unrestricted,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return paginate(sb.Where(
+				squirrel.Eq{"products.status": st},
+			), "products", pg)
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pp, next := trimPage(pp, pg)
+
+	return pp, next, nil
+}
+
+// GetProductByID retrieves a product by the product id. ErrNotFound is
+// returned when the product is not visible to the viewer.
 func GetProductByID(
 	ctx context.Context,
 	qc squirrel.QueryerContext,
+	vw core.Viewer,
 	id xid.ID,
 ) (*core.Product, error) {
 	products, err := selectProducts(
 		ctx,
 		qc,
+		vw,
 		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
 			return sb.Where(
 				squirrel.Eq{"products.id": id},
@@ -79,33 +371,88 @@ func GetProductByID(
 	return &products[0], nil
 }
 
-// UpdateProductByID updates an existing recipe by its id. An updated product
-// is returned.
+// UpdateProductByID updates an existing product by its id if its current
+// version matches the provided one. ErrVersionMismatch is returned
+// otherwise. Only the attributes that differ from the current product core
+// are written. An updated product is returned.
 func UpdateProductByID(
 	ctx context.Context,
 	ssc squirrel.StdSqlCtx,
 	id xid.ID,
+	version uint64,
+	cur core.ProductCore,
 	pc core.ProductCore,
 ) (*core.Product, error) {
-	_, err := squirrel.ExecContextWith(
+	err := execVersioned(
 		ctx,
 		ssc,
-		squirrel.Update("products").SetMap(map[string]interface{}{
-			"products.name":             pc.Name,
-			"products.description":      pc.Description,
-			"products.image_url":        pc.ImageURL,
-			"products.serving_type":     pc.Serving.Type,
-			"products.serving_size":     pc.Serving.Size,
-			"products.serving_calories": pc.Serving.Calories,
-		}).Where(
-			squirrel.Eq{"products.id": id},
+		squirrel.Update("products").SetMap(
+			changedValues(productValues(cur), productValues(pc)),
 		),
+		"products",
+		id,
+		version,
 	)
+	switch {
+	case err == nil:
+		// OK.
+	case isDuplicate(err):
+		return nil, ErrDuplicateBarcode
+	default:
+		return nil, err
+	}
+
+	prd, err := GetProductByID(ctx, ssc, _unrestricted, id)
 	if err != nil {
 		return nil, err
 	}
 
-	prd, err := GetProductByID(ctx, ssc, id)
+	return prd, nil
+}
+
+// ApproveProductByID approves a pending product by its id. If the product
+// core is provided, the product is updated before being approved. An
+// approved product is returned. ErrNotPending is returned if the product
+// is no longer pending.
+func ApproveProductByID(
+	ctx context.Context,
+	ssc squirrel.StdSqlCtx,
+	id xid.ID,
+	pc *core.ProductCore,
+) (*core.Product, error) {
+	values := map[string]interface{}{}
+
+	if pc != nil {
+		values = productValues(*pc)
+	}
+
+	values["products.status"] = core.ProductStatusApproved
+	values["products.rejection_reason"] = nil
+
+	res, err := squirrel.ExecContextWith(
+		ctx,
+		ssc,
+		touch(squirrel.Update("products").SetMap(values), "products").Where(
+			squirrel.Eq{
+				"products.id":     id,
+				"products.status": core.ProductStatusPending,
+			},
+		),
+	)
+	switch {
+	case err == nil:
+		// OK.
+	case isDuplicate(err):
+		return nil, ErrDuplicateBarcode
+	default:
+		return nil, err
+	}
+
+	if err := checkPending(res); err != nil {
+		return nil, err
+	}
+
+	prd, err := GetProductByID(ctx, ssc, _unrestricted, id)
 	if err != nil {
 		return nil, err
 	}
@@ -113,7 +460,51 @@ func UpdateProductByID(
 	return prd, nil
 }
 
-// DeleteProductByID deletes a product by its id.
+// RejectProductByID rejects a pending product by its id with the provided
+// reason. ErrNotPending is returned if the product is no longer pending.
+func RejectProductByID(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	id xid.ID,
+	reason string,
+) error {
+	res, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		touch(squirrel.Update("products").SetMap(map[string]interface{}{
+			"products.status":           core.ProductStatusRejected,
+			"products.rejection_reason": reason,
+		}), "products").Where(
+			squirrel.Eq{
+				"products.id":     id,
+				"products.status": core.ProductStatusPending,
+			},
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkPending(res)
+}
+
+// checkPending checks whether the review of a pending product updated it.
+// ErrNotPending is returned if it did not, i.e. the product was reviewed
+// by a concurrent request.
+func checkPending(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotPending
+	}
+
+	return nil
+}
+
+// DeleteProductByID moves a product to the trash by its id.
 func DeleteProductByID(
 	ctx context.Context,
 	ec squirrel.ExecerContext,
@@ -122,7 +513,89 @@ func DeleteProductByID(
 	_, err := squirrel.ExecContextWith(
 		ctx,
 		ec,
-		squirrel.Delete("products").Where(
+		touch(squirrel.Update("products").SetMap(map[string]interface{}{
+			"products.deleted_at": time.Now(),
+		}), "products").Where(
+			squirrel.Eq{
+				"products.id":         id,
+				"products.deleted_at": nil,
+			},
+		),
+	)
+
+	return err
+}
+
+// GetDeletedProducts retrieves a page of products that were moved to the
+// trash. Admins retrieve all of them, while other users retrieve only their
+// own.
+func GetDeletedProducts(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	vw core.Viewer,
+	pg core.Page,
+) ([]core.Product, *core.Cursor, error) {
+	pp, err := selectDeletedProducts(
+		ctx,
+		qc,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			if !vw.Admin {
+				sb = sb.Where(
+					squirrel.Eq{"products.user_id": vw.UserID},
+				)
+			}
+
+			return paginate(sb, "products", pg)
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pp, next := trimPage(pp, pg)
+
+	return pp, next, nil
+}
+
+// GetDeletedProductByID retrieves a product that was moved to the trash by
+// its id.
+func GetDeletedProductByID(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	id xid.ID,
+) (*core.Product, error) {
+	res, err := selectDeletedProducts(
+		ctx,
+		qc,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Where(
+				squirrel.Eq{"products.id": id},
+			)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &res[0], nil
+}
+
+// RestoreProductByID restores a product from the trash by its id.
+func RestoreProductByID(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	id xid.ID,
+) error {
+	_, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		touch(squirrel.Update("products").SetMap(map[string]interface{}{
+			"products.deleted_at": nil,
+		}), "products").Where(
 			squirrel.Eq{"products.id": id},
 		),
 	)
@@ -130,24 +603,347 @@ func DeleteProductByID(
 	return err
 }
 
-// selectProducts selects all products by the provided decorator function.
+// purgeProducts permanently deletes products that were moved to the trash
+// before the provided time. Products that are still referenced by
+// recipes, including the ones in the trash, are kept.
+func purgeProducts(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	before time.Time,
+) error {
+	_, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		squirrel.Delete("products").Where(squirrel.And{
+			squirrel.Lt{"products.deleted_at": before},
+			squirrel.Expr(
+				"NOT EXISTS (SELECT 1 FROM recipe_products WHERE recipe_products.product_id = products.id)",
+			),
+		}),
+	)
+
+	return err
+}
+
+// MergeProducts merges the source products into the target product within
+// a single transaction. Recipe products that reference the sources are
+// moved to the target with their quantities converted to the target
+// serving size. If a recipe already uses the target product, the
+// quantities are summed. The merge is refused with TooFewProductsError
+// if summing would leave a recipe with fewer than two products. The
+// source products are moved to the trash afterwards.
+func MergeProducts(
+	ctx context.Context,
+	db *sql.DB,
+	tid xid.ID,
+	sids []xid.ID,
+) (*core.ProductMerge, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	ids := append([]xid.ID{tid}, sids...)
+
+	pp, err := selectProducts(
+		ctx,
+		tx,
+		_unrestricted,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Where(
+				squirrel.Eq{"products.id": ids},
+			).Suffix("FOR UPDATE")
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pp) != len(ids) {
+		return nil, ErrNotFound
+	}
+
+	sizes := make(map[xid.ID]decimal.Decimal, len(pp))
+
+	var target core.Product
+
+	for _, prd := range pp {
+		sizes[prd.ID] = prd.Serving.Size
+
+		if prd.ID == tid {
+			target = prd
+		}
+	}
+
+	for _, prd := range pp {
+		if prd.Serving.Type != target.Serving.Type {
+			return nil, ErrServingMismatch
+		}
+	}
+
+	rps, err := selectRecipeProducts(
+		ctx,
+		tx,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Where(
+				squirrel.Eq{"recipe_products.product_id": ids},
+			).OrderBy("recipe_products.recipe_id").Suffix("FOR UPDATE")
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		order    []xid.ID
+		merged   = make(map[xid.ID]*core.MergedRecipe)
+		affected = make(map[xid.ID]bool)
+		counts   = make(map[xid.ID]int)
+	)
+
+	for _, rp := range rps {
+		counts[rp.RecipeID]++
+
+		mr, ok := merged[rp.RecipeID]
+		if !ok {
+			mr = &core.MergedRecipe{
+				RecipeID: rp.RecipeID,
+				Quantity: decimal.Zero,
+			}
+
+			merged[rp.RecipeID] = mr
+			order = append(order, rp.RecipeID)
+		}
+
+		if rp.ProductID == tid {
+			mr.Summed = true
+			mr.Quantity = mr.Quantity.Add(rp.Quantity)
+
+			continue
+		}
+
+		affected[rp.RecipeID] = true
+		mr.Quantity = mr.Quantity.Add(
+			rp.Quantity.Mul(sizes[rp.ProductID]).Div(target.Serving.Size).Round(4),
+		)
+	}
+
+	if err := checkMergedRecipes(ctx, tx, counts); err != nil {
+		return nil, err
+	}
+
+	_, err = squirrel.ExecContextWith(
+		ctx,
+		tx,
+		squirrel.Delete("recipe_products").Where(
+			squirrel.Eq{"recipe_products.product_id": sids},
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	report := core.ProductMerge{
+		TargetID:  tid,
+		SourceIDs: sids,
+		Recipes:   make([]core.MergedRecipe, 0, len(affected)),
+	}
+
+	for _, rid := range order {
+		if !affected[rid] {
+			continue
+		}
+
+		mr := merged[rid]
+
+		if err := upsertRecipeProduct(ctx, tx, core.RecipeProduct{
+			RecipeID:  mr.RecipeID,
+			ProductID: tid,
+			Quantity:  mr.Quantity,
+		}); err != nil {
+			return nil, err
+		}
+
+		report.Recipes = append(report.Recipes, *mr)
+	}
+
+	_, err = squirrel.ExecContextWith(
+		ctx,
+		tx,
+		touch(squirrel.Update("products").SetMap(map[string]interface{}{
+			"products.deleted_at": time.Now(),
+		}), "products").Where(
+			squirrel.Eq{"products.id": sids},
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(report.Recipes) > 0 {
+		rids := make([]xid.ID, 0, len(report.Recipes))
+		for _, mr := range report.Recipes {
+			rids = append(rids, mr.RecipeID)
+		}
+
+		_, err = squirrel.ExecContextWith(
+			ctx,
+			tx,
+			touch(squirrel.Update("recipes"), "recipes").Where(
+				squirrel.Eq{"recipes.id": rids},
+			),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// checkMergedRecipes checks whether the recipes keep at least two products
+// after their merged products, counted by the recipe ids, are combined
+// into a single one.
+func checkMergedRecipes(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	counts map[xid.ID]int,
+) error {
+	rids := make([]xid.ID, 0, len(counts))
+	for rid, n := range counts {
+		if n > 1 {
+			rids = append(rids, rid)
+		}
+	}
+
+	if len(rids) == 0 {
+		return nil
+	}
+
+	rps, err := selectRecipeProducts(
+		ctx,
+		qc,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Where(
+				squirrel.Eq{"recipe_products.recipe_id": rids},
+			)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	totals := make(map[xid.ID]int, len(rids))
+	for _, rp := range rps {
+		totals[rp.RecipeID]++
+	}
+
+	shrunk := make([]xid.ID, 0)
+
+	for _, rid := range rids {
+		if totals[rid]-counts[rid]+1 < 2 {
+			shrunk = append(shrunk, rid)
+		}
+	}
+
+	if len(shrunk) == 0 {
+		return nil
+	}
+
+	rr, err := selectDependents(
+		ctx,
+		qc,
+		"recipes",
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Where(
+				squirrel.Eq{"recipes.id": shrunk},
+			)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return &TooFewProductsError{Recipes: rr}
+}
+
+// selectProducts selects all products that are visible to the viewer by
+// the provided decorator function. Products that are not approved are
+// visible only to their submitters and admins. Products that were moved
+// to the trash are excluded.
 func selectProducts(
 	ctx context.Context,
 	qc squirrel.QueryerContext,
+	vw core.Viewer,
 	dec func(squirrel.SelectBuilder) squirrel.SelectBuilder,
 ) ([]core.Product, error) {
-	rows, err := squirrel.QueryContextWith(ctx, qc, dec(squirrel.
+	sb := productsSelectBuilder().Where(
+		squirrel.Eq{"products.deleted_at": nil},
+	)
+
+	if !vw.Admin {
+		cond := squirrel.Or{
+			squirrel.Eq{"products.status": core.ProductStatusApproved},
+		}
+
+		if !vw.UserID.IsNil() {
+			cond = append(cond, squirrel.Eq{"products.user_id": vw.UserID})
+		}
+
+		sb = sb.Where(cond)
+	}
+
+	return queryProducts(ctx, qc, dec(sb))
+}
+
+// selectDeletedProducts selects all products that were moved to the trash
+// by the provided decorator function.
+func selectDeletedProducts(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	dec func(squirrel.SelectBuilder) squirrel.SelectBuilder,
+) ([]core.Product, error) {
+	return queryProducts(ctx, qc, dec(productsSelectBuilder().Where(
+		squirrel.NotEq{"products.deleted_at": nil},
+	)))
+}
+
+// productsSelectBuilder creates a select builder of all product columns.
+func productsSelectBuilder() squirrel.SelectBuilder {
+	return squirrel.
 		Select(
 			"products.id",
+			"products.user_id",
 			"products.name",
+			"products.aliases",
+			"COALESCE(products.barcode, '')",
 			"COALESCE(products.image_url, '')",
 			"products.description",
 			"products.serving_type",
 			"products.serving_size",
 			"products.serving_calories",
+			"products.status",
+			"COALESCE(products.rejection_reason, '')",
 			"products.created_at",
-		).From("products"),
-	))
+			"products.version",
+			"products.updated_at",
+			"products.deleted_at",
+		).From("products")
+}
+
+// queryProducts executes the select builder and scans the products.
+func queryProducts(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	sb squirrel.SelectBuilder,
+) ([]core.Product, error) {
+	rows, err := squirrel.QueryContextWith(ctx, qc, sb)
 	if err != nil {
 		return nil, err
 	}
@@ -156,17 +952,8 @@ func selectProducts(
 	products := make([]core.Product, 0)
 
 	for rows.Next() {
-		var product core.Product
-		if err := rows.Scan(
-			&product.ID,
-			&product.Name,
-			&product.ImageURL,
-			&product.Description,
-			&product.Serving.Type,
-			&product.Serving.Size,
-			&product.Serving.Calories,
-			&product.CreatedAt,
-		); err != nil {
+		product, err := scanProduct(rows)
+		if err != nil {
 			return nil, err
 		}
 
@@ -175,3 +962,64 @@ func selectProducts(
 
 	return products, nil
 }
+
+// scanProduct scans a single product selected with productsSelectBuilder.
+func scanProduct(rows *sql.Rows) (core.Product, error) {
+	var (
+		product core.Product
+		aliases string
+	)
+
+	if err := rows.Scan(
+		&product.ID,
+		&product.UserID,
+		&product.Name,
+		&aliases,
+		&product.Barcode,
+		&product.ImageURL,
+		&product.Description,
+		&product.Serving.Type,
+		&product.Serving.Size,
+		&product.Serving.Calories,
+		&product.Status,
+		&product.RejectionReason,
+		&product.CreatedAt,
+		&product.Version,
+		&product.UpdatedAt,
+		&product.DeletedAt,
+	); err != nil {
+		return core.Product{}, err
+	}
+
+	product.Aliases = splitAliases(aliases)
+
+	return product, nil
+}
+
+// productValues returns the column values of the product core.
+func productValues(pc core.ProductCore) map[string]interface{} {
+	return map[string]interface{}{
+		"products.name":             pc.Name,
+		"products.aliases":          joinAliases(pc.Aliases),
+		"products.barcode":          nullString(pc.Barcode),
+		"products.description":      pc.Description,
+		"products.image_url":        pc.ImageURL,
+		"products.serving_type":     pc.Serving.Type,
+		"products.serving_size":     pc.Serving.Size,
+		"products.serving_calories": pc.Serving.Calories,
+	}
+}
+
+// joinAliases joins the product aliases into a single column value.
+func joinAliases(aa []string) string {
+	return strings.Join(aa, "\n")
+}
+
+// splitAliases splits the column value into the product aliases.
+func splitAliases(v string) []string {
+	if v == "" {
+		return nil
+	}
+
+	return strings.Split(v, "\n")
+}
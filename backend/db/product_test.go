@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"foodie/core"
 	"testing"
 	"time"
@@ -19,7 +20,8 @@ func Test_InsertProduct(t *testing.T) {
 	cleanUpTables(t, dbh)
 
 	pc := core.ProductCore{
-		Name: "123",
+		Name:    "123",
+		Barcode: "40063813",
 		Serving: core.Serving{
 			Type:     "units",
 			Size:     decimal.NewFromInt(1),
@@ -30,12 +32,24 @@ func Test_InsertProduct(t *testing.T) {
 	prd, err := InsertProduct(
 		context.Background(),
 		dbh,
+		xid.NilID(),
+		core.ProductStatusPending,
 		pc,
 	)
 	require.NoError(t, err)
 	assert.NotEmpty(t, prd.ID)
 	assert.NotEmpty(t, prd.CreatedAt)
+	assert.Equal(t, core.ProductStatusPending, prd.Status)
 	assert.Equal(t, pc, prd.ProductCore)
+
+	_, err = InsertProduct(
+		context.Background(),
+		dbh,
+		xid.NilID(),
+		core.ProductStatusPending,
+		pc,
+	)
+	assert.Equal(t, ErrDuplicateBarcode, err)
 }
 
 func Test_GetProducts(t *testing.T) {
@@ -83,11 +97,148 @@ func Test_GetProducts(t *testing.T) {
 
 	mockProducts(t, dbh, pp...)
 
-	res, err := GetProducts(context.Background(), dbh)
+	res, _, err := GetProducts(context.Background(), dbh, _unrestricted, core.Query{}, core.Page{Limit: 100})
 	require.NoError(t, err)
 	assert.Equal(t, pp, res)
 }
 
+func Test_GetProducts_Pagination(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	tstamp := time.Now().UTC().Truncate(time.Second)
+
+	pp := []core.Product{
+		{
+			ID:        xid.New(),
+			CreatedAt: tstamp.Add(-time.Minute),
+			ProductCore: core.ProductCore{
+				Name: "1",
+				Serving: core.Serving{
+					Type:     "units",
+					Size:     decimal.New(3000, -4),
+					Calories: 2,
+				},
+			},
+		},
+		{
+			ID:        xid.New(),
+			CreatedAt: tstamp,
+			ProductCore: core.ProductCore{
+				Name: "2",
+				Serving: core.Serving{
+					Type:     "units",
+					Size:     decimal.New(3000, -4),
+					Calories: 2,
+				},
+			},
+		},
+		{
+			ID:        xid.New(),
+			CreatedAt: tstamp,
+			ProductCore: core.ProductCore{
+				Name: "3",
+				Serving: core.Serving{
+					Type:     "units",
+					Size:     decimal.New(3000, -4),
+					Calories: 2,
+				},
+			},
+		},
+	}
+
+	mockProducts(t, dbh, pp...)
+
+	res, next, err := GetProducts(context.Background(), dbh, _unrestricted, core.Query{}, core.Page{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, pp[:2], res)
+	require.NotNil(t, next)
+	assert.Equal(t, pp[1].Cursor(), *next)
+
+	res, next, err = GetProducts(context.Background(), dbh, _unrestricted, core.Query{}, core.Page{Limit: 2, Cursor: next})
+	require.NoError(t, err)
+	assert.Equal(t, pp[2:], res)
+	assert.Nil(t, next)
+}
+
+func Test_GetProducts_Query(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	pp := []core.Product{
+		{
+			ID:        xid.New(),
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			ProductCore: core.ProductCore{
+				Name: "b",
+				Serving: core.Serving{
+					Type:     "grams",
+					Size:     decimal.New(3000, -4),
+					Calories: 150,
+				},
+			},
+		},
+		{
+			ID:        xid.New(),
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			ProductCore: core.ProductCore{
+				Name: "a",
+				Serving: core.Serving{
+					Type:     "grams",
+					Size:     decimal.New(3000, -4),
+					Calories: 100,
+				},
+			},
+		},
+		{
+			ID:        xid.New(),
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			ProductCore: core.ProductCore{
+				Name: "c",
+				Serving: core.Serving{
+					Type:     "grams",
+					Size:     decimal.New(3000, -4),
+					Calories: 300,
+				},
+			},
+		},
+		{
+			ID:        xid.New(),
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			ProductCore: core.ProductCore{
+				Name: "d",
+				Serving: core.Serving{
+					Type:     "units",
+					Size:     decimal.New(3000, -4),
+					Calories: 50,
+				},
+			},
+		},
+	}
+
+	mockProducts(t, dbh, pp...)
+
+	q := core.Query{
+		Filters: []core.Filter{
+			{Field: "serving_type", Op: core.FilterEq, Value: "grams"},
+			{Field: "calories", Op: core.FilterLte, Value: int64(200)},
+		},
+		Sort: []core.Sort{
+			{Field: "name", Desc: true},
+		},
+	}
+
+	res, next, err := GetProducts(context.Background(), dbh, _unrestricted, q, core.Page{Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []core.Product{pp[0]}, res)
+	require.NotNil(t, next)
+
+	res, next, err = GetProducts(context.Background(), dbh, _unrestricted, q, core.Page{Limit: 1, Cursor: next})
+	require.NoError(t, err)
+	assert.Equal(t, []core.Product{pp[1]}, res)
+	assert.Nil(t, next)
+}
+
 func Test_GetProductByID(t *testing.T) {
 	dbh := _dbFn(t)
 	cleanUpTables(t, dbh)
@@ -134,13 +285,13 @@ func Test_GetProductByID(t *testing.T) {
 	mockProducts(t, dbh, pp...)
 
 	t.Run("not found", func(t *testing.T) {
-		res, err := GetProductByID(context.Background(), dbh, xid.New())
+		res, err := GetProductByID(context.Background(), dbh, _unrestricted, xid.New())
 		assert.Empty(t, res)
 		require.Equal(t, ErrNotFound, err)
 	})
 
 	t.Run("successfully retrieved a product by id", func(t *testing.T) {
-		res, err := GetProductByID(context.Background(), dbh, pp[1].ID)
+		res, err := GetProductByID(context.Background(), dbh, _unrestricted, pp[1].ID)
 		require.NoError(t, err)
 		assert.Equal(t, &pp[1], res)
 	})
@@ -165,13 +316,480 @@ func Test_UpdateProductByID(t *testing.T) {
 
 	mockProducts(t, dbh, prd)
 
+	cur := prd.ProductCore
+
 	prd.Name = "12"
 	prd.Serving.Calories = 200
 	prd.Serving.Size = decimal.New(5000, -4)
 
-	res, err := UpdateProductByID(context.Background(), dbh, prd.ID, prd.ProductCore)
+	res, err := UpdateProductByID(context.Background(), dbh, prd.ID, prd.Version, cur, prd.ProductCore)
 	require.NoError(t, err)
+	assert.Equal(t, prd.Version+1, res.Version)
+	assert.WithinDuration(t, time.Now(), res.UpdatedAt, time.Minute)
+
+	prd.Revision = res.Revision
 	assert.Equal(t, &prd, res)
+
+	_, err = UpdateProductByID(context.Background(), dbh, prd.ID, prd.Version-1, cur, prd.ProductCore)
+	assert.Equal(t, ErrVersionMismatch, err)
+}
+
+func Test_EachProduct(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	pp := []core.Product{
+		{
+			ID:        xid.New(),
+			CreatedAt: now.Add(-time.Hour),
+			Status:    core.ProductStatusPending,
+			ProductCore: core.ProductCore{
+				Name:    "1",
+				Barcode: "40063813",
+				Serving: core.Serving{
+					Type:     "units",
+					Size:     decimal.New(1, 0),
+					Calories: 1,
+				},
+			},
+		},
+		{
+			ID:        xid.New(),
+			CreatedAt: now,
+			Status:    core.ProductStatusApproved,
+			ProductCore: core.ProductCore{
+				Name: "2",
+				Serving: core.Serving{
+					Type:     "grams",
+					Size:     decimal.New(1, 0),
+					Calories: 2,
+				},
+			},
+		},
+	}
+
+	mockProducts(t, dbh, pp...)
+
+	var res []core.Product
+
+	err := EachProduct(context.Background(), dbh, func(prd core.Product) error {
+		res = append(res, prd)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, pp, res)
+
+	errStop := errors.New("stop")
+
+	err = EachProduct(context.Background(), dbh, func(prd core.Product) error {
+		return errStop
+	})
+	assert.Equal(t, errStop, err)
+}
+
+func Test_ImportProducts(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	uid := xid.New()
+	sid := xid.New()
+
+	mockUsers(t, dbh, core.User{
+		ID:           uid,
+		Name:         "1",
+		PasswordHash: []byte{1},
+		Admin:        true,
+	}, core.User{
+		ID:           sid,
+		Name:         "2",
+		PasswordHash: []byte{1},
+	})
+
+	now := time.Now().UTC().Truncate(time.Second)
+	serving := core.Serving{
+		Type:     "grams",
+		Size:     decimal.New(100, 0),
+		Calories: 10,
+	}
+
+	pp := []core.Product{
+		{
+			ID:          xid.New(),
+			CreatedAt:   now,
+			Status:      core.ProductStatusApproved,
+			ProductCore: core.ProductCore{Name: "Salt", Serving: serving},
+		},
+		{
+			ID:          xid.New(),
+			CreatedAt:   now,
+			Status:      core.ProductStatusApproved,
+			ProductCore: core.ProductCore{Name: "Oats", Barcode: "40063813", Serving: serving},
+		},
+		{
+			ID:          xid.New(),
+			CreatedAt:   now,
+			Status:      core.ProductStatusApproved,
+			ProductCore: core.ProductCore{Name: "Rice", Barcode: "40063814", Serving: serving},
+		},
+		{
+			ID:          xid.New(),
+			UserID:      sid,
+			CreatedAt:   now,
+			Status:      core.ProductStatusPending,
+			ProductCore: core.ProductCore{Name: "Flour", Serving: serving},
+		},
+	}
+
+	mockProducts(t, dbh, pp...)
+
+	require.NoError(t, DeleteProductByID(context.Background(), dbh, pp[2].ID))
+
+	pcs := []core.ProductCore{
+		{Name: "Salt", Serving: serving},
+		{Name: "Porridge", Barcode: "40063813", Serving: serving},
+		{Name: "Brown rice", Barcode: "40063814", Serving: serving},
+		{Name: "Sugar", Barcode: "40063815", Serving: serving},
+		{Name: "Flour", Serving: core.Serving{Type: "grams", Size: decimal.New(50, 0), Calories: 5}},
+	}
+
+	check := func(res []core.ImportedProduct) {
+		require.Len(t, res, 5)
+
+		assert.Equal(t, core.ProductImportUnchanged, res[0].Action)
+		assert.Equal(t, pp[0].ID, res[0].Product.ID)
+
+		assert.Equal(t, core.ProductImportUpdated, res[1].Action)
+		assert.Equal(t, pp[1].ID, res[1].Product.ID)
+		assert.Equal(t, pcs[1], res[1].Product.ProductCore)
+
+		assert.Equal(t, core.ImportedProduct{Action: core.ProductImportTrashed}, res[2])
+
+		assert.Equal(t, core.ProductImportCreated, res[3].Action)
+		assert.Equal(t, uid, res[3].Product.UserID)
+		assert.Equal(t, core.ProductStatusApproved, res[3].Product.Status)
+		assert.Equal(t, pcs[3], res[3].Product.ProductCore)
+
+		// Pending submissions are not matched by their names.
+		assert.Equal(t, core.ProductImportCreated, res[4].Action)
+		assert.NotEqual(t, pp[3].ID, res[4].Product.ID)
+	}
+
+	res, err := ImportProducts(context.Background(), dbh, uid, pcs, true)
+	require.NoError(t, err)
+	check(res)
+
+	all, err := GetAllProducts(context.Background(), dbh)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []core.Product{pp[0], pp[1], pp[3]}, all)
+
+	res, err = ImportProducts(context.Background(), dbh, uid, pcs, false)
+	require.NoError(t, err)
+	check(res)
+
+	all, err = GetAllProducts(context.Background(), dbh)
+	require.NoError(t, err)
+	assert.Len(t, all, 5)
+	assert.Contains(t, all, pp[3])
+}
+
+func Test_GetProductsByStatus(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	pp := []core.Product{
+		{
+			ID:        xid.New(),
+			Status:    core.ProductStatusApproved,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			ProductCore: core.ProductCore{
+				Name: "1",
+				Serving: core.Serving{
+					Type:     "units",
+					Size:     decimal.New(4000, -4),
+					Calories: 9,
+				},
+			},
+		},
+		{
+			ID:        xid.New(),
+			Status:    core.ProductStatusPending,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			ProductCore: core.ProductCore{
+				Name: "2",
+				Serving: core.Serving{
+					Type:     "units",
+					Size:     decimal.New(4000, -4),
+					Calories: 9,
+				},
+			},
+		},
+	}
+
+	mockProducts(t, dbh, pp...)
+
+	res, _, err := GetProductsByStatus(context.Background(), dbh, core.ProductStatusPending, core.Page{Limit: 100})
+	require.NoError(t, err)
+	assert.Equal(t, pp[1:], res)
+}
+
+func Test_ApproveProductByID(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	prd := core.Product{
+		ID:        xid.New(),
+		Status:    core.ProductStatusPending,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		ProductCore: core.ProductCore{
+			Name: "11",
+			Serving: core.Serving{
+				Type:     "units",
+				Size:     decimal.New(4000, -4),
+				Calories: 9,
+			},
+		},
+	}
+
+	upd := prd
+	upd.ID = xid.New()
+
+	mockProducts(t, dbh, prd, upd)
+
+	t.Run("successfully approved a product", func(t *testing.T) {
+		res, err := ApproveProductByID(context.Background(), dbh, prd.ID, nil)
+		require.NoError(t, err)
+		assert.Equal(t, prd.Version+1, res.Version)
+
+		prd.Status = core.ProductStatusApproved
+		prd.Revision = res.Revision
+		assert.Equal(t, &prd, res)
+	})
+
+	t.Run("successfully updated and approved a product", func(t *testing.T) {
+		upd.Name = "12"
+
+		res, err := ApproveProductByID(context.Background(), dbh, upd.ID, &upd.ProductCore)
+		require.NoError(t, err)
+		assert.Equal(t, upd.Version+1, res.Version)
+
+		upd.Status = core.ProductStatusApproved
+		upd.Revision = res.Revision
+		assert.Equal(t, &upd, res)
+	})
+
+	t.Run("product is not pending", func(t *testing.T) {
+		_, err := ApproveProductByID(context.Background(), dbh, prd.ID, nil)
+		assert.Equal(t, ErrNotPending, err)
+	})
+}
+
+func Test_RejectProductByID(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	prd := core.Product{
+		ID:        xid.New(),
+		Status:    core.ProductStatusPending,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		ProductCore: core.ProductCore{
+			Name: "11",
+			Serving: core.Serving{
+				Type:     "units",
+				Size:     decimal.New(4000, -4),
+				Calories: 9,
+			},
+		},
+	}
+
+	mockProducts(t, dbh, prd)
+
+	require.NoError(t, RejectProductByID(context.Background(), dbh, prd.ID, "duplicate"))
+
+	res, err := GetProductByID(context.Background(), dbh, _unrestricted, prd.ID)
+	require.NoError(t, err)
+	assert.Equal(t, core.ProductStatusRejected, res.Status)
+	assert.Equal(t, "duplicate", res.RejectionReason)
+
+	assert.Equal(t, ErrNotPending, RejectProductByID(context.Background(), dbh, prd.ID, "invalid"))
+}
+
+func Test_Products_Visibility(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	uid := xid.New()
+
+	mockUsers(t, dbh, core.User{
+		ID:           uid,
+		Name:         "1",
+		PasswordHash: []byte{1},
+	})
+
+	approved := core.Product{
+		ID:        xid.New(),
+		Status:    core.ProductStatusApproved,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	pending := core.Product{
+		ID:        xid.New(),
+		UserID:    uid,
+		Status:    core.ProductStatusPending,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	mockProducts(t, dbh, approved, pending)
+
+	t.Run("guest sees only approved products", func(t *testing.T) {
+		res, _, err := GetProducts(context.Background(), dbh, core.Viewer{}, core.Query{}, core.Page{Limit: 100})
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		assert.Equal(t, approved.ID, res[0].ID)
+
+		_, err = GetProductByID(context.Background(), dbh, core.Viewer{UserID: xid.New()}, pending.ID)
+		assert.Equal(t, ErrNotFound, err)
+	})
+
+	t.Run("submitter sees own pending products", func(t *testing.T) {
+		res, _, err := GetProducts(context.Background(), dbh, core.Viewer{UserID: uid}, core.Query{}, core.Page{Limit: 100})
+		require.NoError(t, err)
+		assert.Len(t, res, 2)
+	})
+}
+
+func Test_MergeProducts(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	newProduct := func(st core.ServingType, size int64) core.Product {
+		return core.Product{
+			ID:        xid.New(),
+			Status:    core.ProductStatusApproved,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			ProductCore: core.ProductCore{
+				Name: "1",
+				Serving: core.Serving{
+					Type:     st,
+					Size:     decimal.NewFromInt(size),
+					Calories: 1,
+				},
+			},
+		}
+	}
+
+	target := newProduct(core.ServingTypeGrams, 100)
+	source := newProduct(core.ServingTypeGrams, 50)
+	units := newProduct(core.ServingTypeUnits, 1)
+	single := newProduct(core.ServingTypeGrams, 100)
+
+	mockProducts(t, dbh, target, source, units, single)
+
+	rid1 := xid.New()
+	rid2 := xid.New()
+	rid3 := xid.New()
+	rid4 := xid.New()
+
+	mockRecipes(t, dbh, []core.Recipe{
+		{
+			ID: rid1,
+			RecipeCore: core.RecipeCore{
+				Name: "1",
+				Products: []core.RecipeProduct{
+					{RecipeID: rid1, ProductID: target.ID, Quantity: decimal.NewFromInt(1)},
+					{RecipeID: rid1, ProductID: source.ID, Quantity: decimal.NewFromInt(2)},
+					{RecipeID: rid1, ProductID: units.ID, Quantity: decimal.NewFromInt(1)},
+				},
+			},
+		},
+		{
+			ID: rid2,
+			RecipeCore: core.RecipeCore{
+				Name: "2",
+				Products: []core.RecipeProduct{
+					{RecipeID: rid2, ProductID: source.ID, Quantity: decimal.NewFromInt(4)},
+					{RecipeID: rid2, ProductID: units.ID, Quantity: decimal.NewFromInt(1)},
+				},
+			},
+		},
+		{
+			ID: rid3,
+			RecipeCore: core.RecipeCore{
+				Name: "3",
+				Products: []core.RecipeProduct{
+					{RecipeID: rid3, ProductID: target.ID, Quantity: decimal.NewFromInt(3)},
+					{RecipeID: rid3, ProductID: units.ID, Quantity: decimal.NewFromInt(1)},
+				},
+			},
+		},
+		{
+			ID: rid4,
+			RecipeCore: core.RecipeCore{
+				Name: "4",
+				Products: []core.RecipeProduct{
+					{RecipeID: rid4, ProductID: target.ID, Quantity: decimal.NewFromInt(1)},
+					{RecipeID: rid4, ProductID: single.ID, Quantity: decimal.NewFromInt(1)},
+				},
+			},
+		},
+	}...)
+
+	t.Run("product not found", func(t *testing.T) {
+		res, err := MergeProducts(context.Background(), dbh, target.ID, []xid.ID{xid.New()})
+		assert.Nil(t, res)
+		assert.Equal(t, ErrNotFound, err)
+	})
+
+	t.Run("serving type mismatch", func(t *testing.T) {
+		res, err := MergeProducts(context.Background(), dbh, target.ID, []xid.ID{units.ID})
+		assert.Nil(t, res)
+		assert.Equal(t, ErrServingMismatch, err)
+	})
+
+	t.Run("recipe left with too few products", func(t *testing.T) {
+		res, err := MergeProducts(context.Background(), dbh, target.ID, []xid.ID{single.ID})
+		assert.Nil(t, res)
+
+		var ferr *TooFewProductsError
+		require.ErrorAs(t, err, &ferr)
+		require.Len(t, ferr.Recipes, 1)
+		assert.Equal(t, rid4, ferr.Recipes[0].ID)
+
+		assert.Len(t, retrieveRecipeProducts(t, dbh, rid4), 2)
+	})
+
+	t.Run("successfully merged products", func(t *testing.T) {
+		res, err := MergeProducts(context.Background(), dbh, target.ID, []xid.ID{source.ID})
+		require.NoError(t, err)
+		assert.Equal(t, target.ID, res.TargetID)
+		require.Len(t, res.Recipes, 2)
+
+		for _, mr := range res.Recipes {
+			switch mr.RecipeID {
+			case rid1:
+				assert.True(t, mr.Summed)
+			case rid2:
+				assert.False(t, mr.Summed)
+			default:
+				t.Fatalf("unexpected recipe %s", mr.RecipeID)
+			}
+
+			assert.True(t, decimal.NewFromInt(2).Equal(mr.Quantity))
+		}
+
+		rps := retrieveRecipeProducts(t, dbh, rid1)
+		require.Len(t, rps, 2)
+
+		for _, rp := range rps {
+			if rp.ProductID == target.ID {
+				assert.True(t, decimal.NewFromInt(2).Equal(rp.Quantity))
+			}
+		}
+
+		assert.Len(t, retrieveRecipeProducts(t, dbh, rid3), 2)
+		assert.Len(t, retrieveProducts(t, dbh), 3)
+	})
 }
 
 func Test_DeleteProductByID(t *testing.T) {
@@ -201,6 +819,47 @@ func Test_DeleteProductByID(t *testing.T) {
 	require.Len(t, retrieveProducts(t, dbh), 0)
 }
 
+func Test_RestoreProductByID(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	prd := core.Product{
+		ID:        xid.New(),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		ProductCore: core.ProductCore{
+			Name: "11",
+			Serving: core.Serving{
+				Type:     "units",
+				Size:     decimal.New(4000, -4),
+				Calories: 9,
+			},
+		},
+	}
+
+	mockProducts(t, dbh, prd)
+
+	require.NoError(t, DeleteProductByID(context.Background(), dbh, prd.ID))
+
+	res, err := GetDeletedProductByID(context.Background(), dbh, prd.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, res.DeletedAt)
+
+	_, err = GetProductByID(context.Background(), dbh, _unrestricted, prd.ID)
+	require.Equal(t, ErrNotFound, err)
+
+	require.NoError(t, RestoreProductByID(context.Background(), dbh, prd.ID))
+
+	res, err = GetProductByID(context.Background(), dbh, _unrestricted, prd.ID)
+	require.NoError(t, err)
+	assert.Equal(t, prd.Version+2, res.Version)
+
+	prd.Revision = res.Revision
+	assert.Equal(t, &prd, res)
+
+	_, err = GetDeletedProductByID(context.Background(), dbh, prd.ID)
+	require.Equal(t, ErrNotFound, err)
+}
+
 func Test_selectProduct(t *testing.T) {
 	dbh := _dbFn(t)
 	cleanUpTables(t, dbh)
@@ -246,7 +905,7 @@ func Test_selectProduct(t *testing.T) {
 
 	mockProducts(t, dbh, pp...)
 
-	res, err := selectProducts(context.Background(), dbh, func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+	res, err := selectProducts(context.Background(), dbh, _unrestricted, func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
 		return sb
 	})
 	require.NoError(t, err)
@@ -261,11 +920,16 @@ func mockProducts(t *testing.T, dbh *sql.DB, pp ...core.Product) {
 			dbh,
 			squirrel.Insert("products").SetMap(map[string]interface{}{
 				"products.id":               prd.ID,
+				"products.user_id":          prd.UserID,
 				"products.name":             prd.Name,
+				"products.barcode":          nullString(prd.Barcode),
 				"products.serving_type":     prd.Serving.Type,
 				"products.serving_size":     prd.Serving.Size,
 				"products.serving_calories": prd.Serving.Calories,
+				"products.status":           prd.Status,
 				"products.created_at":       prd.CreatedAt,
+				"products.version":          prd.Version,
+				"products.updated_at":       prd.UpdatedAt,
 			}),
 		)
 		require.NoError(t, err)
@@ -276,12 +940,17 @@ func retrieveProducts(t *testing.T, dbh *sql.DB) []core.Product {
 	rows, err := squirrel.QueryWith(dbh, squirrel.
 		Select(
 			"products.id",
+			"products.user_id",
 			"products.name",
 			"products.serving_type",
 			"products.serving_size",
 			"products.serving_calories",
+			"products.status",
 			"products.created_at",
-		).From("products"),
+		).From("products").
+		Where(squirrel.Eq{
+			"products.deleted_at": nil,
+		}),
 	)
 	require.NoError(t, err)
 
@@ -294,10 +963,12 @@ func retrieveProducts(t *testing.T, dbh *sql.DB) []core.Product {
 
 		require.NoError(t, rows.Scan(
 			&product.ID,
+			&product.UserID,
 			&product.Name,
 			&product.Serving.Type,
 			&product.Serving.Size,
 			&product.Serving.Calories,
+			&product.Status,
 			&product.CreatedAt,
 		))
 
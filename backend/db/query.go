@@ -0,0 +1,128 @@
+package db
+
+import (
+	"foodie/core"
+
+	"github.com/Masterminds/squirrel"
+)
+
+var (
+	// _productColumns maps core.ProductFields to their columns.
+	_productColumns = map[string]string{
+		"name":         "products.name",
+		"barcode":      "products.barcode",
+		"serving_type": "products.serving_type",
+		"serving_size": "products.serving_size",
+		"calories":     "products.serving_calories",
+		"status":       "products.status",
+		"user_id":      "products.user_id",
+		"created_at":   "products.created_at",
+	}
+
+	// _recipeColumns maps core.RecipeFields to their columns.
+	_recipeColumns = map[string]string{
+		"name":       "recipes.name",
+		"visibility": "recipes.visibility",
+		"state":      "recipes.state",
+		"user_id":    "recipes.user_id",
+		"created_at": "recipes.created_at",
+	}
+
+	// _planColumns maps core.PlanFields to their columns.
+	_planColumns = map[string]string{
+		"name":       "plans.name",
+		"visibility": "plans.visibility",
+		"state":      "plans.state",
+		"user_id":    "plans.user_id",
+		"created_at": "plans.created_at",
+	}
+
+	// _userColumns maps core.UserFields to their columns.
+	_userColumns = map[string]string{
+		"name":       "users.name",
+		"admin":      "users.admin",
+		"created_at": "users.created_at",
+	}
+)
+
+// applyQuery applies the filters and the sort order of the query along
+// with the page to the select builder of the provided table. Lists
+// without a custom sort order are paginated by the creation time and id,
+// while the others are paginated by the offset.
+func applyQuery(
+	sb squirrel.SelectBuilder,
+	table string,
+	cols map[string]string,
+	q core.Query,
+	pg core.Page,
+) squirrel.SelectBuilder {
+	for _, f := range q.Filters {
+		sb = sb.Where(compare(cols[f.Field], f.Op, f.Value))
+	}
+
+	if len(q.Sort) == 0 {
+		return paginate(sb, table, pg)
+	}
+
+	ob := make([]string, 0, len(q.Sort)+1)
+
+	for _, s := range q.Sort {
+		if s.Desc {
+			ob = append(ob, cols[s.Field]+" DESC")
+		} else {
+			ob = append(ob, cols[s.Field]+" ASC")
+		}
+	}
+
+	sb = sb.OrderBy(append(ob, table+".id ASC")...).Limit(pg.Limit + 1)
+
+	if pg.Cursor != nil {
+		sb = sb.Offset(pg.Cursor.Offset)
+	}
+
+	return sb
+}
+
+// trimQueryPage trims the objects selected with applyQuery to the page
+// limit and returns the cursor of the next page, or nil if it is the last
+// page.
+func trimQueryPage[T interface{ Cursor() core.Cursor }](
+	oo []T,
+	q core.Query,
+	pg core.Page,
+) ([]T, *core.Cursor) {
+	if len(q.Sort) == 0 {
+		return trimPage(oo, pg)
+	}
+
+	if uint64(len(oo)) <= pg.Limit {
+		return oo, nil
+	}
+
+	next := core.Cursor{Offset: pg.Limit}
+	if pg.Cursor != nil {
+		next.Offset += pg.Cursor.Offset
+	}
+
+	return oo[:pg.Limit], &next
+}
+
+// compare creates a condition that compares the column against the value
+// with the provided operator. Equality against a slice of values matches
+// any of them.
+func compare(col string, op core.FilterOp, v interface{}) squirrel.Sqlizer {
+	switch op {
+	case core.FilterNe:
+		return squirrel.NotEq{col: v}
+	case core.FilterLt:
+		return squirrel.Lt{col: v}
+	case core.FilterLte:
+		return squirrel.LtOrEq{col: v}
+	case core.FilterGt:
+		return squirrel.Gt{col: v}
+	case core.FilterGte:
+		return squirrel.GtOrEq{col: v}
+	default:
+		return squirrel.Eq{col: v}
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/Masterminds/squirrel"
 	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
 )
 
 // InsertRecipe inserts a new recipe into the database.
@@ -31,6 +32,11 @@ func InsertRecipe(
 		RecipeCore: rc,
 	}
 
+	rec.Revision = core.Revision{
+		Version:   1,
+		UpdatedAt: rec.CreatedAt,
+	}
+
 	_, err = squirrel.ExecContextWith(
 		ctx,
 		tx,
@@ -40,7 +46,10 @@ func InsertRecipe(
 			"recipes.name":        rec.Name,
 			"recipes.image_url":   rec.ImageURL,
 			"recipes.description": rec.Description,
+			"recipes.visibility":  rec.Visibility,
+			"recipes.state":       rec.State,
 			"recipes.created_at":  rec.CreatedAt,
+			"recipes.updated_at":  rec.UpdatedAt,
 		}),
 	)
 	if err != nil {
@@ -66,46 +75,81 @@ func InsertRecipe(
 	return &rec, nil
 }
 
-// GetRecipes retrieves all recipes.
+// GetRecipes retrieves a page of recipes that are listed to the viewer and
+// match the query. The cursor of the next page is returned if there is one.
 func GetRecipes(
 	ctx context.Context,
 	qc squirrel.QueryerContext,
-) ([]core.Recipe, error) {
-	return selectRecipes(
+	vw core.Viewer,
+	q core.Query,
+	pg core.Page,
+) ([]core.Recipe, *core.Cursor, error) {
+	rr, err := selectRecipes(
 		ctx,
 		qc,
+		vw,
 		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
-			return sb.Limit(100)
+			if !vw.Admin {
+				sb = sb.Where(listedTo("recipes", vw))
+			}
+
+			return applyQuery(sb, "recipes", _recipeColumns, q, pg)
 		},
 	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rr, next := trimQueryPage(rr, q, pg)
+
+	return rr, next, nil
 }
 
-// GetRecipesByUserID retrieves recipes by the user id.
+// GetRecipesByUserID retrieves a page of recipes by the user id that are
+// listed to the viewer and match the query.
 func GetRecipesByUserID(
 	ctx context.Context,
 	qc squirrel.QueryerContext,
+	vw core.Viewer,
 	uid xid.ID,
-) ([]core.Recipe, error) {
-	return selectRecipes(
+	q core.Query,
+	pg core.Page,
+) ([]core.Recipe, *core.Cursor, error) {
+	rr, err := selectRecipes(
 		ctx,
 		qc,
+		vw,
 		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
-			return sb.Where(
+			if !vw.Admin {
+				sb = sb.Where(listedTo("recipes", vw))
+			}
+
+			return applyQuery(sb.Where(
 				squirrel.Eq{"recipes.user_id": uid},
-			)
+			), "recipes", _recipeColumns, q, pg)
 		},
 	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rr, next := trimQueryPage(rr, q, pg)
+
+	return rr, next, nil
 }
 
-// GetRecipeByID retrieves a recipe by its id.
+// GetRecipeByID retrieves a recipe by its id. ErrNotFound is returned
+// when the recipe is not visible to the viewer.
 func GetRecipeByID(
 	ctx context.Context,
 	qc squirrel.QueryerContext,
+	vw core.Viewer,
 	id xid.ID,
 ) (*core.Recipe, error) {
 	rr, err := selectRecipes(
 		ctx,
 		qc,
+		vw,
 		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
 			return sb.Where(
 				squirrel.Eq{"recipes.id": id},
@@ -123,12 +167,40 @@ func GetRecipeByID(
 	return &rr[0], nil
 }
 
-// UpdateRecipeByID updates an existing recipe by its id. An updated recipe
-// is returned.
+// GetRecipesByIDs retrieves recipes by their ids that are visible to the
+// viewer.
+func GetRecipesByIDs(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	vw core.Viewer,
+	ids []xid.ID,
+) ([]core.Recipe, error) {
+	if len(ids) == 0 {
+		return make([]core.Recipe, 0), nil
+	}
+
+	return selectRecipes(
+		ctx,
+		qc,
+		vw,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Where(
+				squirrel.Eq{"recipes.id": ids},
+			)
+		},
+	)
+}
+
+// UpdateRecipeByID updates an existing recipe by its id if its current
+// version matches the provided one. ErrVersionMismatch is returned
+// otherwise. Only the attributes that differ from the current recipe core
+// are written. An updated recipe is returned.
 func UpdateRecipeByID(
 	ctx context.Context,
 	db *sql.DB,
 	id xid.ID,
+	version uint64,
+	cur core.RecipeCore,
 	rc core.RecipeCore,
 ) (*core.Recipe, error) {
 	tx, err := db.BeginTx(ctx, nil)
@@ -138,35 +210,100 @@ func UpdateRecipeByID(
 
 	defer tx.Rollback()
 
-	if err := deleteRecipeProducts(
+	err = execVersioned(
 		ctx,
 		tx,
+		squirrel.Update("recipes").SetMap(
+			changedValues(recipeValues(cur), recipeValues(rc)),
+		),
+		"recipes",
 		id,
-	); err != nil {
+		version,
+	)
+	if err != nil {
 		return nil, err
 	}
 
-	for _, rp := range rc.Products {
-		rp.RecipeID = id
-
-		if err := upsertRecipeProduct(
-			ctx,
-			tx,
-			rp,
-		); err != nil {
+	if !recipeProductsEqual(cur.Products, rc.Products) {
+		if err := replaceRecipeProducts(ctx, tx, id, rc.Products); err != nil {
 			return nil, err
 		}
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	rec, err := GetRecipeByID(ctx, db, _unrestricted, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// UpsertRecipeProduct adds the product to the recipe or updates its
+// quantity if the current version of the recipe matches the provided one.
+// ErrVersionMismatch is returned otherwise. An updated recipe is returned.
+func UpsertRecipeProduct(
+	ctx context.Context,
+	db *sql.DB,
+	version uint64,
+	rp core.RecipeProduct,
+) (*core.Recipe, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	err = execVersioned(ctx, tx, squirrel.Update("recipes"), "recipes", rp.RecipeID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := upsertRecipeProduct(ctx, tx, rp); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return GetRecipeByID(ctx, db, _unrestricted, rp.RecipeID)
+}
+
+// DeleteRecipeProduct removes the product from the recipe if the current
+// version of the recipe matches the provided one. ErrVersionMismatch is
+// returned otherwise. An updated recipe is returned.
+func DeleteRecipeProduct(
+	ctx context.Context,
+	db *sql.DB,
+	version uint64,
+	rid xid.ID,
+	pid xid.ID,
+) (*core.Recipe, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	err = execVersioned(ctx, tx, squirrel.Update("recipes"), "recipes", rid, version)
+	if err != nil {
+		return nil, err
+	}
+
 	_, err = squirrel.ExecContextWith(
 		ctx,
 		tx,
-		squirrel.Update("recipes").SetMap(map[string]interface{}{
-			"recipes.name":        rc.Name,
-			"recipes.image_url":   rc.ImageURL,
-			"recipes.description": rc.Description,
-		}).Where(
-			squirrel.Eq{"recipes.id": id},
+		squirrel.Delete("recipe_products").Where(
+			squirrel.Eq{
+				"recipe_products.recipe_id":  rid,
+				"recipe_products.product_id": pid,
+			},
 		),
 	)
 	if err != nil {
@@ -177,16 +314,193 @@ func UpdateRecipeByID(
 		return nil, err
 	}
 
-	rec, err := GetRecipeByID(ctx, db, id)
+	return GetRecipeByID(ctx, db, _unrestricted, rid)
+}
+
+// DeleteRecipeByID moves a recipe to the trash by its id.
+func DeleteRecipeByID(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	id xid.ID,
+) error {
+	_, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		touch(squirrel.Update("recipes").SetMap(map[string]interface{}{
+			"recipes.deleted_at": time.Now(),
+		}), "recipes").Where(
+			squirrel.Eq{
+				"recipes.id":         id,
+				"recipes.deleted_at": nil,
+			},
+		),
+	)
+
+	return err
+}
+
+// ReplaceRecipeByID moves a recipe to the trash after reassigning plan
+// recipes that reference it to the replacement recipe within a single
+// transaction. If a plan already includes the replacement, the quantities
+// are summed, and the day of the replacement is kept if it is scheduled.
+func ReplaceRecipeByID(
+	ctx context.Context,
+	db *sql.DB,
+	id xid.ID,
+	rid xid.ID,
+) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	prs, err := selectPlanRecipes(
+		ctx,
+		tx,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Where(
+				squirrel.Eq{"plan_recipes.recipe_id": []xid.ID{id, rid}},
+			).OrderBy("plan_recipes.plan_id").Suffix("FOR UPDATE")
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	var (
+		order    []xid.ID
+		sums     = make(map[xid.ID]uint64)
+		days     = make(map[xid.ID]string)
+		affected = make(map[xid.ID]bool)
+	)
+
+	for _, pr := range prs {
+		if _, ok := sums[pr.PlanID]; !ok {
+			order = append(order, pr.PlanID)
+		}
+
+		sums[pr.PlanID] += pr.Quantity
+
+		if pr.Day != "" && (pr.RecipeID == rid || days[pr.PlanID] == "") {
+			days[pr.PlanID] = pr.Day
+		}
+
+		if pr.RecipeID == id {
+			affected[pr.PlanID] = true
+		}
+	}
+
+	_, err = squirrel.ExecContextWith(
+		ctx,
+		tx,
+		squirrel.Delete("plan_recipes").Where(
+			squirrel.Eq{"plan_recipes.recipe_id": id},
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	var pids []xid.ID
+
+	for _, pid := range order {
+		if !affected[pid] {
+			continue
+		}
+
+		if err := upsertPlanRecipe(ctx, tx, core.PlanRecipe{
+			PlanID:   pid,
+			RecipeID: rid,
+			Quantity: sums[pid],
+			Day:      days[pid],
+		}); err != nil {
+			return err
+		}
+
+		pids = append(pids, pid)
+	}
+
+	if len(pids) > 0 {
+		_, err = squirrel.ExecContextWith(
+			ctx,
+			tx,
+			touch(squirrel.Update("plans"), "plans").Where(
+				squirrel.Eq{"plans.id": pids},
+			),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := DeleteRecipeByID(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetDeletedRecipes retrieves a page of recipes that were moved to the trash.
+// Admins retrieve all of them, while other users retrieve only their own.
+func GetDeletedRecipes(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	vw core.Viewer,
+	pg core.Page,
+) ([]core.Recipe, *core.Cursor, error) {
+	rr, err := selectDeletedRecipes(
+		ctx,
+		qc,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			if !vw.Admin {
+				sb = sb.Where(
+					squirrel.Eq{"recipes.user_id": vw.UserID},
+				)
+			}
+
+			return paginate(sb, "recipes", pg)
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rr, next := trimPage(rr, pg)
+
+	return rr, next, nil
+}
+
+// GetDeletedRecipeByID retrieves a recipe that was moved to the trash by
+// its id.
+func GetDeletedRecipeByID(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	id xid.ID,
+) (*core.Recipe, error) {
+	res, err := selectDeletedRecipes(
+		ctx,
+		qc,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Where(
+				squirrel.Eq{"recipes.id": id},
+			)
+		},
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	return rec, nil
+	if len(res) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &res[0], nil
 }
 
-// DeleteRecipeByID deletes a recipe by its id.
-func DeleteRecipeByID(
+// RestoreRecipeByID restores a recipe from the trash by its id.
+func RestoreRecipeByID(
 	ctx context.Context,
 	ec squirrel.ExecerContext,
 	id xid.ID,
@@ -194,7 +508,9 @@ func DeleteRecipeByID(
 	_, err := squirrel.ExecContextWith(
 		ctx,
 		ec,
-		squirrel.Delete("recipes").Where(
+		touch(squirrel.Update("recipes").SetMap(map[string]interface{}{
+			"recipes.deleted_at": nil,
+		}), "recipes").Where(
 			squirrel.Eq{"recipes.id": id},
 		),
 	)
@@ -202,22 +518,86 @@ func DeleteRecipeByID(
 	return err
 }
 
-// selectRecipes selects all recipes by the provided decorator function.
+// purgeRecipes permanently deletes recipes that were moved to the trash
+// before the provided time. Recipes that are still referenced by plans,
+// including the ones in the trash, are kept.
+func purgeRecipes(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	before time.Time,
+) error {
+	_, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		squirrel.Delete("recipes").Where(squirrel.And{
+			squirrel.Lt{"recipes.deleted_at": before},
+			squirrel.Expr(
+				"NOT EXISTS (SELECT 1 FROM plan_recipes WHERE plan_recipes.recipe_id = recipes.id)",
+			),
+		}),
+	)
+
+	return err
+}
+
+// selectRecipes selects all recipes that are visible to the viewer by the
+// provided decorator function. Recipes that were moved to the trash are
+// excluded.
 func selectRecipes(
 	ctx context.Context,
 	qc squirrel.QueryerContext,
+	vw core.Viewer,
 	dec func(squirrel.SelectBuilder) squirrel.SelectBuilder,
 ) ([]core.Recipe, error) {
-	rows, err := squirrel.QueryContextWith(ctx, qc, dec(squirrel.
+	sb := recipesSelectBuilder().Where(
+		squirrel.Eq{"recipes.deleted_at": nil},
+	)
+
+	if !vw.Admin {
+		sb = sb.Where(visibleTo("recipes", vw))
+	}
+
+	return queryRecipes(ctx, qc, dec(sb))
+}
+
+// selectDeletedRecipes selects all recipes that were moved to the trash by
+// the provided decorator function.
+func selectDeletedRecipes(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	dec func(squirrel.SelectBuilder) squirrel.SelectBuilder,
+) ([]core.Recipe, error) {
+	return queryRecipes(ctx, qc, dec(recipesSelectBuilder().Where(
+		squirrel.NotEq{"recipes.deleted_at": nil},
+	)))
+}
+
+// recipesSelectBuilder creates a select builder of all recipe columns.
+func recipesSelectBuilder() squirrel.SelectBuilder {
+	return squirrel.
 		Select(
 			"recipes.id",
 			"recipes.user_id",
 			"recipes.name",
 			"COALESCE(recipes.image_url, '')",
 			"recipes.description",
+			"recipes.visibility",
+			"recipes.state",
 			"recipes.created_at",
-		).From("recipes"),
-	))
+			"recipes.version",
+			"recipes.updated_at",
+			"recipes.deleted_at",
+		).From("recipes")
+}
+
+// queryRecipes executes the select builder and scans the recipes together
+// with their products, which are selected for all recipes at once.
+func queryRecipes(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	sb squirrel.SelectBuilder,
+) ([]core.Recipe, error) {
+	rows, err := squirrel.QueryContextWith(ctx, qc, sb)
 	if err != nil {
 		return nil, err
 	}
@@ -235,23 +615,91 @@ func selectRecipes(
 			&rec.Name,
 			&rec.ImageURL,
 			&rec.Description,
+			&rec.Visibility,
+			&rec.State,
 			&rec.CreatedAt,
+			&rec.Version,
+			&rec.UpdatedAt,
+			&rec.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
 
-		rps, err := getRecipeProductsByRecipeID(ctx, qc, rec.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		rec.Products = rps
+		rec.Products = make([]core.RecipeProduct, 0)
 		rr = append(rr, rec)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The rows are closed before the children are selected, so that the
+	// connection of a transaction is not busy.
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	if len(rr) == 0 {
+		return rr, nil
+	}
+
+	index := make(map[xid.ID]int, len(rr))
+	ids := make([]xid.ID, 0, len(rr))
+
+	for i, rec := range rr {
+		index[rec.ID] = i
+		ids = append(ids, rec.ID)
+	}
+
+	rps, err := getRecipeProductsByRecipeIDs(ctx, qc, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rp := range rps {
+		i := index[rp.RecipeID]
+		rr[i].Products = append(rr[i].Products, rp)
+	}
+
 	return rr, nil
 }
 
+// recipeValues returns the column values of the recipe core. Recipe
+// products are stored separately.
+func recipeValues(rc core.RecipeCore) map[string]interface{} {
+	return map[string]interface{}{
+		"recipes.name":        rc.Name,
+		"recipes.image_url":   rc.ImageURL,
+		"recipes.description": rc.Description,
+		"recipes.visibility":  rc.Visibility,
+		"recipes.state":       rc.State,
+	}
+}
+
+// recipeProductsEqual checks whether both lists contain the same products
+// with equal quantities, regardless of their order.
+func recipeProductsEqual(a, b []core.RecipeProduct) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	qq := make(map[xid.ID]decimal.Decimal, len(a))
+	for _, rp := range a {
+		qq[rp.ProductID] = rp.Quantity
+	}
+
+	for _, rp := range b {
+		q, ok := qq[rp.ProductID]
+		if !ok || !q.Equal(rp.Quantity) {
+			return false
+		}
+
+		delete(qq, rp.ProductID)
+	}
+
+	return true
+}
+
 // GetRecipeProductsByPruductID selects recipe products by the product id.
 func GetRecipeProductsByProductID(
 	ctx context.Context,
@@ -269,23 +717,45 @@ func GetRecipeProductsByProductID(
 	)
 }
 
-// getRecipeProductsByRecipeID selects recipe products by the recipe id.
-func getRecipeProductsByRecipeID(
+// getRecipeProductsByRecipeIDs selects recipe products by the recipe ids.
+func getRecipeProductsByRecipeIDs(
 	ctx context.Context,
 	qc squirrel.QueryerContext,
-	id xid.ID,
+	ids []xid.ID,
 ) ([]core.RecipeProduct, error) {
 	return selectRecipeProducts(
 		ctx,
 		qc,
 		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
 			return sb.Where(
-				squirrel.Eq{"recipe_products.recipe_id": id},
+				squirrel.Eq{"recipe_products.recipe_id": ids},
 			)
 		},
 	)
 }
 
+// replaceRecipeProducts replaces all products of the recipe.
+func replaceRecipeProducts(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	rid xid.ID,
+	rps []core.RecipeProduct,
+) error {
+	if err := deleteRecipeProducts(ctx, ec, rid); err != nil {
+		return err
+	}
+
+	for _, rp := range rps {
+		rp.RecipeID = rid
+
+		if err := upsertRecipeProduct(ctx, ec, rp); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // deleteRecipeProducts deletes all recipe products.
 func deleteRecipeProducts(
 	ctx context.Context,
@@ -223,7 +223,7 @@ func Test_GetRecipes(t *testing.T) {
 
 	mockRecipes(t, dbh, rr...)
 
-	res, err := GetRecipes(context.Background(), dbh)
+	res, _, err := GetRecipes(context.Background(), dbh, _unrestricted, core.Query{}, core.Page{Limit: 100})
 	require.NoError(t, err)
 	assert.Equal(t, rr, res)
 }
@@ -367,7 +367,7 @@ func Test_GetRecipesByUserID(t *testing.T) {
 
 	mockRecipes(t, dbh, rr...)
 
-	res, err := GetRecipesByUserID(context.Background(), dbh, uid2)
+	res, _, err := GetRecipesByUserID(context.Background(), dbh, _unrestricted, uid2, core.Query{}, core.Page{Limit: 100})
 	require.NoError(t, err)
 	assert.Equal(t, rr[1:], res)
 }
@@ -440,13 +440,13 @@ func Test_GetRecipeByID(t *testing.T) {
 	mockRecipes(t, dbh, rcp)
 
 	t.Run("not found", func(t *testing.T) {
-		res, err := GetRecipeByID(context.Background(), dbh, xid.New())
+		res, err := GetRecipeByID(context.Background(), dbh, _unrestricted, xid.New())
 		assert.Empty(t, res)
 		require.Equal(t, ErrNotFound, err)
 	})
 
 	t.Run("successfully retrieved a recipe by id", func(t *testing.T) {
-		res, err := GetRecipeByID(context.Background(), dbh, rcp.ID)
+		res, err := GetRecipeByID(context.Background(), dbh, _unrestricted, rcp.ID)
 		require.NoError(t, err)
 		assert.Equal(t, &rcp, res)
 	})
@@ -514,6 +514,8 @@ func Test_UpdateRecipeByID(t *testing.T) {
 
 	mockRecipes(t, dbh, rcp)
 
+	cur := rcp.RecipeCore
+
 	rcp.Name = "999"
 	rcp.Description = "34124"
 	rcp.Products = []core.RecipeProduct{
@@ -529,9 +531,134 @@ func Test_UpdateRecipeByID(t *testing.T) {
 		},
 	}
 
-	res, err := UpdateRecipeByID(context.Background(), dbh, rcp.ID, rcp.RecipeCore)
+	res, err := UpdateRecipeByID(context.Background(), dbh, rcp.ID, rcp.Version, cur, rcp.RecipeCore)
 	require.NoError(t, err)
+	assert.Equal(t, rcp.Version+1, res.Version)
+	assert.WithinDuration(t, time.Now(), res.UpdatedAt, time.Minute)
+
+	rcp.Revision = res.Revision
 	assert.Equal(t, &rcp, res)
+
+	_, err = UpdateRecipeByID(context.Background(), dbh, rcp.ID, rcp.Version-1, cur, rcp.RecipeCore)
+	assert.Equal(t, ErrVersionMismatch, err)
+}
+
+func Test_recipeProductsEqual(t *testing.T) {
+	pid1 := xid.New()
+	pid2 := xid.New()
+
+	rps := []core.RecipeProduct{
+		{ProductID: pid1, Quantity: decimal.New(1, 0)},
+		{ProductID: pid2, Quantity: decimal.New(2, 0)},
+	}
+
+	assert.True(t, recipeProductsEqual(rps, []core.RecipeProduct{
+		{ProductID: pid2, Quantity: decimal.New(20000, -4)},
+		{ProductID: pid1, Quantity: decimal.New(1, 0)},
+	}))
+	assert.False(t, recipeProductsEqual(rps, rps[:1]))
+	assert.False(t, recipeProductsEqual(rps, []core.RecipeProduct{
+		{ProductID: pid1, Quantity: decimal.New(1, 0)},
+		{ProductID: pid2, Quantity: decimal.New(3, 0)},
+	}))
+	assert.False(t, recipeProductsEqual(rps, []core.RecipeProduct{
+		{ProductID: pid1, Quantity: decimal.New(1, 0)},
+		{ProductID: pid1, Quantity: decimal.New(1, 0)},
+	}))
+}
+
+func Test_UpsertRecipeProduct_DeleteRecipeProduct(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	uid1 := xid.New()
+
+	mockUsers(t, dbh, core.User{
+		ID:           uid1,
+		Name:         "1",
+		PasswordHash: []byte{1},
+		Admin:        true,
+	})
+
+	pid1 := xid.New()
+	pid2 := xid.New()
+
+	mockProducts(t, dbh, []core.Product{
+		{
+			ID: pid1,
+			ProductCore: core.ProductCore{
+				Name: "123",
+				Serving: core.Serving{
+					Type:     "units",
+					Size:     decimal.NewFromInt(1),
+					Calories: 2,
+				},
+			},
+		},
+		{
+			ID: pid2,
+			ProductCore: core.ProductCore{
+				Name: "223",
+				Serving: core.Serving{
+					Type:     "grams",
+					Size:     decimal.NewFromInt(1),
+					Calories: 5,
+				},
+			},
+		},
+	}...)
+
+	rid1 := xid.New()
+
+	rcp := core.Recipe{
+		ID:        rid1,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		UserID:    uid1,
+		RecipeCore: core.RecipeCore{
+			Name:        "1",
+			Description: "test1",
+			Products: []core.RecipeProduct{
+				{
+					RecipeID:  rid1,
+					ProductID: pid1,
+					Quantity:  decimal.New(1000, -4),
+				},
+			},
+		},
+	}
+
+	mockRecipes(t, dbh, rcp)
+
+	rp := core.RecipeProduct{
+		RecipeID:  rid1,
+		ProductID: pid2,
+		Quantity:  decimal.New(9000, -4),
+	}
+
+	res, err := UpsertRecipeProduct(context.Background(), dbh, rcp.Version, rp)
+	require.NoError(t, err)
+	assert.Equal(t, rcp.Version+1, res.Version)
+	assert.ElementsMatch(t, append(rcp.Products, rp), res.Products)
+
+	rp.Quantity = decimal.New(5000, -4)
+
+	res, err = UpsertRecipeProduct(context.Background(), dbh, res.Version, rp)
+	require.NoError(t, err)
+	assert.Equal(t, rcp.Version+2, res.Version)
+	assert.ElementsMatch(t, append(rcp.Products, rp), res.Products)
+
+	_, err = UpsertRecipeProduct(context.Background(), dbh, rcp.Version, rp)
+	assert.Equal(t, ErrVersionMismatch, err)
+
+	res, err = DeleteRecipeProduct(context.Background(), dbh, res.Version, rid1, pid2)
+	require.NoError(t, err)
+	assert.Equal(t, rcp.Version+3, res.Version)
+
+	rcp.Revision = res.Revision
+	assert.Equal(t, &rcp, res)
+
+	_, err = DeleteRecipeProduct(context.Background(), dbh, rcp.Version-1, rid1, pid1)
+	assert.Equal(t, ErrVersionMismatch, err)
 }
 
 func Test_DeleteRecipeByID(t *testing.T) {
@@ -604,6 +731,128 @@ func Test_DeleteRecipeByID(t *testing.T) {
 	require.Len(t, retrieveRecipes(t, dbh), 0)
 }
 
+func Test_ReplaceRecipeByID(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	rid1 := xid.New()
+	rid2 := xid.New()
+
+	mockRecipes(t, dbh, []core.Recipe{
+		{
+			ID:        rid1,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			RecipeCore: core.RecipeCore{
+				Name: "1",
+			},
+		},
+		{
+			ID:        rid2,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			RecipeCore: core.RecipeCore{
+				Name: "2",
+			},
+		},
+	}...)
+
+	plid1 := xid.New()
+	plid2 := xid.New()
+
+	mockPlans(t, dbh, []core.Plan{
+		{
+			ID: plid1,
+			PlanCore: core.PlanCore{
+				Name: "1",
+				Recipes: []core.PlanRecipe{
+					{PlanID: plid1, RecipeID: rid1, Quantity: 2},
+				},
+			},
+		},
+		{
+			ID: plid2,
+			PlanCore: core.PlanCore{
+				Name: "2",
+				Recipes: []core.PlanRecipe{
+					{PlanID: plid2, RecipeID: rid1, Quantity: 1},
+					{PlanID: plid2, RecipeID: rid2, Quantity: 3},
+				},
+			},
+		},
+	}...)
+
+	require.NoError(t, ReplaceRecipeByID(context.Background(), dbh, rid1, rid2))
+
+	assert.Equal(t, []core.PlanRecipe{
+		{PlanID: plid1, RecipeID: rid2, Quantity: 2},
+	}, retrievePlanRecipes(t, dbh, plid1))
+
+	assert.Equal(t, []core.PlanRecipe{
+		{PlanID: plid2, RecipeID: rid2, Quantity: 4},
+	}, retrievePlanRecipes(t, dbh, plid2))
+
+	_, err := GetDeletedRecipeByID(context.Background(), dbh, rid1)
+	assert.NoError(t, err)
+}
+
+func Test_GetDeletedRecipes(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	uid1 := xid.New()
+	uid2 := xid.New()
+
+	mockUsers(t, dbh, []core.User{
+		{
+			ID:           uid1,
+			Name:         "1",
+			PasswordHash: []byte{1},
+		},
+		{
+			ID:           uid2,
+			Name:         "2",
+			PasswordHash: []byte{2},
+		},
+	}...)
+
+	rr := []core.Recipe{
+		{
+			ID:        xid.New(),
+			UserID:    uid1,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+		},
+		{
+			ID:        xid.New(),
+			UserID:    uid2,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+		},
+		{
+			ID:        xid.New(),
+			UserID:    uid2,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+		},
+	}
+
+	mockRecipes(t, dbh, rr...)
+
+	for _, rec := range rr[:2] {
+		require.NoError(t, DeleteRecipeByID(context.Background(), dbh, rec.ID))
+	}
+
+	t.Run("user retrieves own deleted recipes", func(t *testing.T) {
+		res, _, err := GetDeletedRecipes(context.Background(), dbh, core.Viewer{UserID: uid2}, core.Page{Limit: 100})
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		assert.Equal(t, rr[1].ID, res[0].ID)
+		assert.NotNil(t, res[0].DeletedAt)
+	})
+
+	t.Run("admin retrieves all deleted recipes", func(t *testing.T) {
+		res, _, err := GetDeletedRecipes(context.Background(), dbh, _unrestricted, core.Page{Limit: 100})
+		require.NoError(t, err)
+		assert.Len(t, res, 2)
+	})
+}
+
 func Test_GetRecipeProductsByProductID(t *testing.T) {
 	dbh := _dbFn(t)
 	cleanUpTables(t, dbh)
@@ -759,6 +1008,96 @@ func Test_GetRecipeProductsByProductID(t *testing.T) {
 	}, res)
 }
 
+func Test_Recipes_Visibility(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	uid1 := xid.New()
+	uid2 := xid.New()
+
+	mockUsers(t, dbh, []core.User{
+		{
+			ID:           uid1,
+			Name:         "1",
+			PasswordHash: []byte{1},
+		},
+		{
+			ID:           uid2,
+			Name:         "2",
+			PasswordHash: []byte{2},
+		},
+	}...)
+
+	newRecipe := func(vis core.Visibility, st core.State) core.Recipe {
+		return core.Recipe{
+			ID:        xid.New(),
+			UserID:    uid1,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			RecipeCore: core.RecipeCore{
+				Name:        "1",
+				Description: "test1",
+				Visibility:  vis,
+				State:       st,
+				Products:    []core.RecipeProduct{},
+			},
+		}
+	}
+
+	public := newRecipe(core.VisibilityPublic, core.StatePublished)
+	unlisted := newRecipe(core.VisibilityUnlisted, core.StatePublished)
+	private := newRecipe(core.VisibilityPrivate, core.StatePublished)
+	draft := newRecipe(core.VisibilityPublic, core.StateDraft)
+
+	mockRecipes(t, dbh, public, unlisted, private, draft)
+
+	ids := func(rr []core.Recipe) []xid.ID {
+		res := make([]xid.ID, 0, len(rr))
+		for _, rec := range rr {
+			res = append(res, rec.ID)
+		}
+
+		return res
+	}
+
+	t.Run("guest lists only public and published recipes", func(t *testing.T) {
+		res, _, err := GetRecipes(context.Background(), dbh, core.Viewer{}, core.Query{}, core.Page{Limit: 100})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []xid.ID{public.ID}, ids(res))
+	})
+
+	t.Run("other user lists only public and published recipes", func(t *testing.T) {
+		res, _, err := GetRecipesByUserID(context.Background(), dbh, core.Viewer{UserID: uid2}, uid1, core.Query{}, core.Page{Limit: 100})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []xid.ID{public.ID}, ids(res))
+	})
+
+	t.Run("owner lists all of the recipes", func(t *testing.T) {
+		res, _, err := GetRecipes(context.Background(), dbh, core.Viewer{UserID: uid1}, core.Query{}, core.Page{Limit: 100})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []xid.ID{public.ID, unlisted.ID, private.ID, draft.ID}, ids(res))
+	})
+
+	t.Run("admin lists all of the recipes", func(t *testing.T) {
+		res, _, err := GetRecipes(context.Background(), dbh, core.Viewer{UserID: uid2, Admin: true}, core.Query{}, core.Page{Limit: 100})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []xid.ID{public.ID, unlisted.ID, private.ID, draft.ID}, ids(res))
+	})
+
+	t.Run("guest retrieves an unlisted recipe by id", func(t *testing.T) {
+		res, err := GetRecipeByID(context.Background(), dbh, core.Viewer{}, unlisted.ID)
+		require.NoError(t, err)
+		assert.Equal(t, unlisted.ID, res.ID)
+	})
+
+	t.Run("other user cannot retrieve private recipe or draft by id", func(t *testing.T) {
+		for _, id := range []xid.ID{private.ID, draft.ID} {
+			res, err := GetRecipeByID(context.Background(), dbh, core.Viewer{UserID: uid2}, id)
+			assert.Nil(t, res)
+			assert.Equal(t, ErrNotFound, err)
+		}
+	})
+}
+
 func mockRecipes(t *testing.T, dbh *sql.DB, rr ...core.Recipe) {
 	t.Helper()
 
@@ -770,7 +1109,11 @@ func mockRecipes(t *testing.T, dbh *sql.DB, rr ...core.Recipe) {
 				"recipes.user_id":     rcp.UserID,
 				"recipes.name":        rcp.Name,
 				"recipes.description": rcp.Description,
+				"recipes.visibility":  rcp.Visibility,
+				"recipes.state":       rcp.State,
 				"recipes.created_at":  rcp.CreatedAt,
+				"recipes.version":     rcp.Version,
+				"recipes.updated_at":  rcp.UpdatedAt,
 			}),
 		)
 		require.NoError(t, err)
@@ -796,8 +1139,13 @@ func retrieveRecipes(t *testing.T, dbh *sql.DB) []core.Recipe {
 			"recipes.user_id",
 			"recipes.name",
 			"recipes.description",
+			"recipes.visibility",
+			"recipes.state",
 			"recipes.created_at",
-		).From("recipes"),
+		).From("recipes").
+		Where(squirrel.Eq{
+			"recipes.deleted_at": nil,
+		}),
 	)
 	require.NoError(t, err)
 
@@ -813,6 +1161,8 @@ func retrieveRecipes(t *testing.T, dbh *sql.DB) []core.Recipe {
 			&rec.UserID,
 			&rec.Name,
 			&rec.Description,
+			&rec.Visibility,
+			&rec.State,
 			&rec.CreatedAt,
 		))
 
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"foodie/core"
+	"strings"
+	"unicode"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// SearchProducts retrieves products that are visible to the viewer and
+// whose names or descriptions match the search text. Products are ordered
+// by their relevance.
+func SearchProducts(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	vw core.Viewer,
+	text string,
+	limit uint64,
+) ([]core.Product, error) {
+	ft := fulltextQuery(text)
+	if ft == "" {
+		return make([]core.Product, 0), nil
+	}
+
+	return selectProducts(
+		ctx,
+		qc,
+		vw,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return matchFulltext(sb, "products", ft).Limit(limit)
+		},
+	)
+}
+
+// SearchRecipes retrieves recipes that are listed to the viewer and whose
+// names or descriptions match the search text. Recipes are ordered by
+// their relevance.
+func SearchRecipes(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	vw core.Viewer,
+	text string,
+	limit uint64,
+) ([]core.Recipe, error) {
+	ft := fulltextQuery(text)
+	if ft == "" {
+		return make([]core.Recipe, 0), nil
+	}
+
+	return selectRecipes(
+		ctx,
+		qc,
+		vw,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			if !vw.Admin {
+				sb = sb.Where(listedTo("recipes", vw))
+			}
+
+			return matchFulltext(sb, "recipes", ft).Limit(limit)
+		},
+	)
+}
+
+// SearchPlans retrieves plans that are listed to the viewer and whose
+// names or descriptions match the search text. Plans are ordered by their
+// relevance.
+func SearchPlans(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	vw core.Viewer,
+	text string,
+	limit uint64,
+) ([]core.Plan, error) {
+	ft := fulltextQuery(text)
+	if ft == "" {
+		return make([]core.Plan, 0), nil
+	}
+
+	return selectPlans(
+		ctx,
+		qc,
+		vw,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			if !vw.Admin {
+				sb = sb.Where(listedTo("plans", vw))
+			}
+
+			return matchFulltext(sb, "plans", ft).Limit(limit)
+		},
+	)
+}
+
+// matchFulltext limits objects of the provided table to the ones that
+// match the boolean mode fulltext query and orders them by relevance.
+func matchFulltext(
+	sb squirrel.SelectBuilder,
+	table string,
+	ft string,
+) squirrel.SelectBuilder {
+	match := "MATCH(" + table + ".name, " + table + ".description) AGAINST (? IN BOOLEAN MODE)"
+
+	return sb.Where(match, ft).OrderByClause(match+" DESC", ft)
+}
+
+// fulltextQuery converts the search text into a boolean mode fulltext
+// query which matches words starting with any of the search terms. All
+// characters that have a special meaning in the boolean mode are dropped.
+func fulltextQuery(text string) string {
+	terms := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i := range terms {
+		terms[i] += "*"
+	}
+
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"foodie/core"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SearchRecipes(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	uid := xid.New()
+
+	rr := []core.Recipe{
+		{
+			ID:        xid.New(),
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			RecipeCore: core.RecipeCore{
+				Name:        "Overnight oats",
+				Description: "Healthy breakfast with oats and banana.",
+				Visibility:  core.VisibilityPublic,
+				State:       core.StatePublished,
+			},
+		},
+		{
+			ID:        xid.New(),
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			RecipeCore: core.RecipeCore{
+				Name:        "Baked apples",
+				Description: "Sweet dessert with oatmeal crumble.",
+				Visibility:  core.VisibilityPublic,
+				State:       core.StatePublished,
+			},
+		},
+		{
+			ID:        xid.New(),
+			UserID:    uid,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			RecipeCore: core.RecipeCore{
+				Name:        "Secret oats",
+				Description: "Private recipe.",
+				Visibility:  core.VisibilityPrivate,
+				State:       core.StatePublished,
+			},
+		},
+	}
+
+	mockRecipes(t, dbh, rr...)
+
+	res, err := SearchRecipes(context.Background(), dbh, core.Viewer{}, "oat", 10)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.ElementsMatch(t, []xid.ID{rr[0].ID, rr[1].ID}, []xid.ID{res[0].ID, res[1].ID})
+
+	res, err = SearchRecipes(context.Background(), dbh, core.Viewer{UserID: uid}, "oat", 10)
+	require.NoError(t, err)
+	assert.Len(t, res, 3)
+
+	res, err = SearchRecipes(context.Background(), dbh, core.Viewer{}, "+-*", 10)
+	require.NoError(t, err)
+	assert.Empty(t, res)
+}
+
+func Test_fulltextQuery(t *testing.T) {
+	assert.Equal(t, "oat* banana*", fulltextQuery(" oat +banana* "))
+	assert.Equal(t, "", fulltextQuery(`+-"@<>()~`))
+}
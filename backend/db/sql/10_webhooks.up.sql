@@ -0,0 +1,28 @@
+CREATE TABLE `webhooks` (
+	`id` VARCHAR(20) NOT NULL,
+	`user_id` VARCHAR(20) NOT NULL,
+	`url` VARCHAR(1023) NOT NULL,
+	`events` VARCHAR(1023) NOT NULL,
+	`secret` CHAR(64) NOT NULL,
+	`active` TINYINT(1) NOT NULL DEFAULT 1,
+	`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (`id`),
+	CONSTRAINT `webhooks_user_id_fk` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE `webhook_deliveries` (
+	`id` VARCHAR(20) NOT NULL,
+	`webhook_id` VARCHAR(20) NOT NULL,
+	`event` VARCHAR(31) NOT NULL,
+	`payload` MEDIUMTEXT NOT NULL,
+	`status` VARCHAR(15) NOT NULL DEFAULT 'pending',
+	`attempts` INTEGER UNSIGNED NOT NULL DEFAULT 0,
+	`next_attempt_at` TIMESTAMP NULL DEFAULT NULL,
+	`response_status` INTEGER NULL DEFAULT NULL,
+	`error` VARCHAR(1023) NULL DEFAULT NULL,
+	`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (`id`),
+	CONSTRAINT `webhook_deliveries_webhook_id_fk` FOREIGN KEY (`webhook_id`) REFERENCES `webhooks` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE INDEX `webhook_deliveries_status_idx` ON `webhook_deliveries` (`status`, `next_attempt_at`);
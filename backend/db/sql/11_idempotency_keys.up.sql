@@ -0,0 +1,12 @@
+CREATE TABLE `idempotency_keys` (
+	`user_id` VARCHAR(20) NOT NULL,
+	`key` VARCHAR(255) NOT NULL,
+	`hash` CHAR(64) NOT NULL,
+	`status_code` SMALLINT UNSIGNED NOT NULL DEFAULT 0,
+	`content_type` VARCHAR(255) NOT NULL DEFAULT '',
+	`body` MEDIUMBLOB NULL DEFAULT NULL,
+	`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (`user_id`, `key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE INDEX `idempotency_keys_created_at_idx` ON `idempotency_keys` (`created_at`);
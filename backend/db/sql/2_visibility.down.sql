@@ -0,0 +1,7 @@
+ALTER TABLE `recipes`
+	DROP COLUMN `visibility`,
+	DROP COLUMN `state`;
+
+ALTER TABLE `plans`
+	DROP COLUMN `visibility`,
+	DROP COLUMN `state`;
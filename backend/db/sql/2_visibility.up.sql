@@ -0,0 +1,7 @@
+ALTER TABLE `recipes`
+	ADD COLUMN `visibility` VARCHAR(15) NOT NULL DEFAULT 'public' AFTER `description`,
+	ADD COLUMN `state` VARCHAR(15) NOT NULL DEFAULT 'published' AFTER `visibility`;
+
+ALTER TABLE `plans`
+	ADD COLUMN `visibility` VARCHAR(15) NOT NULL DEFAULT 'public' AFTER `description`,
+	ADD COLUMN `state` VARCHAR(15) NOT NULL DEFAULT 'published' AFTER `visibility`;
@@ -0,0 +1,7 @@
+DROP INDEX `products_status_idx` ON `products`;
+
+ALTER TABLE `products`
+	DROP FOREIGN KEY `products_user_id_fk`,
+	DROP COLUMN `user_id`,
+	DROP COLUMN `status`,
+	DROP COLUMN `rejection_reason`;
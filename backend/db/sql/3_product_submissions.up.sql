@@ -0,0 +1,7 @@
+ALTER TABLE `products`
+	ADD COLUMN `user_id` VARCHAR(20) NULL AFTER `id`,
+	ADD COLUMN `status` VARCHAR(15) NOT NULL DEFAULT 'approved' AFTER `serving_calories`,
+	ADD COLUMN `rejection_reason` VARCHAR(1023) NULL AFTER `status`,
+	ADD CONSTRAINT `products_user_id_fk` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE SET NULL;
+
+CREATE INDEX `products_status_idx` ON `products` (`status`);
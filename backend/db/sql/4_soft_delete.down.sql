@@ -0,0 +1,7 @@
+DROP INDEX `products_deleted_at_idx` ON `products`;
+DROP INDEX `recipes_deleted_at_idx` ON `recipes`;
+DROP INDEX `plans_deleted_at_idx` ON `plans`;
+
+ALTER TABLE `products` DROP COLUMN `deleted_at`;
+ALTER TABLE `recipes` DROP COLUMN `deleted_at`;
+ALTER TABLE `plans` DROP COLUMN `deleted_at`;
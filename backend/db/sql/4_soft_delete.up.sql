@@ -0,0 +1,7 @@
+ALTER TABLE `products` ADD COLUMN `deleted_at` TIMESTAMP NULL DEFAULT NULL AFTER `created_at`;
+ALTER TABLE `recipes` ADD COLUMN `deleted_at` TIMESTAMP NULL DEFAULT NULL AFTER `created_at`;
+ALTER TABLE `plans` ADD COLUMN `deleted_at` TIMESTAMP NULL DEFAULT NULL AFTER `created_at`;
+
+CREATE INDEX `products_deleted_at_idx` ON `products` (`deleted_at`);
+CREATE INDEX `recipes_deleted_at_idx` ON `recipes` (`deleted_at`);
+CREATE INDEX `plans_deleted_at_idx` ON `plans` (`deleted_at`);
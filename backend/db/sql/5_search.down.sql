@@ -0,0 +1,3 @@
+DROP INDEX `products_search_idx` ON `products`;
+DROP INDEX `recipes_search_idx` ON `recipes`;
+DROP INDEX `plans_search_idx` ON `plans`;
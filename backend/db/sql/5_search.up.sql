@@ -0,0 +1,3 @@
+CREATE FULLTEXT INDEX `products_search_idx` ON `products` (`name`, `description`);
+CREATE FULLTEXT INDEX `recipes_search_idx` ON `recipes` (`name`, `description`);
+CREATE FULLTEXT INDEX `plans_search_idx` ON `plans` (`name`, `description`);
@@ -0,0 +1 @@
+ALTER TABLE `products` ADD COLUMN `aliases` VARCHAR(1023) NOT NULL DEFAULT '' AFTER `name`;
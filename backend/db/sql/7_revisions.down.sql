@@ -0,0 +1,7 @@
+ALTER TABLE `products` DROP COLUMN `updated_at`;
+ALTER TABLE `recipes` DROP COLUMN `updated_at`;
+ALTER TABLE `plans` DROP COLUMN `updated_at`;
+
+ALTER TABLE `products` DROP COLUMN `version`;
+ALTER TABLE `recipes` DROP COLUMN `version`;
+ALTER TABLE `plans` DROP COLUMN `version`;
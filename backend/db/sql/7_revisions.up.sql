@@ -0,0 +1,10 @@
+ALTER TABLE `products` ADD COLUMN `version` INTEGER UNSIGNED NOT NULL DEFAULT 1 AFTER `created_at`;
+ALTER TABLE `products` ADD COLUMN `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP AFTER `version`;
+ALTER TABLE `recipes` ADD COLUMN `version` INTEGER UNSIGNED NOT NULL DEFAULT 1 AFTER `created_at`;
+ALTER TABLE `recipes` ADD COLUMN `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP AFTER `version`;
+ALTER TABLE `plans` ADD COLUMN `version` INTEGER UNSIGNED NOT NULL DEFAULT 1 AFTER `created_at`;
+ALTER TABLE `plans` ADD COLUMN `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP AFTER `version`;
+
+UPDATE `products` SET `updated_at` = `created_at`;
+UPDATE `recipes` SET `updated_at` = `created_at`;
+UPDATE `plans` SET `updated_at` = `created_at`;
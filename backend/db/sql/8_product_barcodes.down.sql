@@ -0,0 +1,3 @@
+DROP INDEX `products_barcode_idx` ON `products`;
+
+ALTER TABLE `products` DROP COLUMN `barcode`;
@@ -0,0 +1,3 @@
+ALTER TABLE `products` ADD COLUMN `barcode` VARCHAR(14) NULL DEFAULT NULL AFTER `aliases`;
+
+CREATE UNIQUE INDEX `products_barcode_idx` ON `products` (`barcode`);
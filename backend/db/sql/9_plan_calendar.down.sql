@@ -0,0 +1,5 @@
+DROP INDEX `users_calendar_token_hash_idx` ON `users`;
+
+ALTER TABLE `users` DROP COLUMN `calendar_token_hash`;
+
+ALTER TABLE `plan_recipes` DROP COLUMN `day`;
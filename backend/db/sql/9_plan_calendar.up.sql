@@ -0,0 +1,5 @@
+ALTER TABLE `plan_recipes` ADD COLUMN `day` DATE NULL DEFAULT NULL AFTER `quantity`;
+
+ALTER TABLE `users` ADD COLUMN `calendar_token_hash` CHAR(64) NULL DEFAULT NULL AFTER `admin`;
+
+CREATE UNIQUE INDEX `users_calendar_token_hash_idx` ON `users` (`calendar_token_hash`);
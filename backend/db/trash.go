@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// PurgeDeleted permanently deletes plans, recipes and products that were
+// moved to the trash before the provided time. Plans are purged first, so
+// that the recipes they referenced could be purged as well.
+func PurgeDeleted(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	before time.Time,
+) error {
+	if err := purgePlans(ctx, ec, before); err != nil {
+		return err
+	}
+
+	if err := purgeRecipes(ctx, ec, before); err != nil {
+		return err
+	}
+
+	return purgeProducts(ctx, ec, before)
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"foodie/core"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PurgeDeleted(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	pid1 := xid.New()
+	pid2 := xid.New()
+
+	mockProducts(t, dbh, []core.Product{
+		{
+			ID: pid1,
+			ProductCore: core.ProductCore{
+				Name: "1",
+				Serving: core.Serving{
+					Type:     "units",
+					Size:     decimal.NewFromInt(1),
+					Calories: 2,
+				},
+			},
+		},
+		{
+			ID: pid2,
+			ProductCore: core.ProductCore{
+				Name: "2",
+				Serving: core.Serving{
+					Type:     "units",
+					Size:     decimal.NewFromInt(1),
+					Calories: 2,
+				},
+			},
+		},
+	}...)
+
+	rid := xid.New()
+
+	mockRecipes(t, dbh, core.Recipe{
+		ID: rid,
+		RecipeCore: core.RecipeCore{
+			Name: "1",
+			Products: []core.RecipeProduct{
+				{
+					RecipeID:  rid,
+					ProductID: pid1,
+					Quantity:  decimal.NewFromInt(1),
+				},
+			},
+		},
+	})
+
+	plid := xid.New()
+
+	mockPlans(t, dbh, core.Plan{
+		ID: plid,
+		PlanCore: core.PlanCore{
+			Name: "1",
+			Recipes: []core.PlanRecipe{
+				{
+					PlanID:   plid,
+					RecipeID: rid,
+					Quantity: 1,
+				},
+			},
+		},
+	})
+
+	trash := func(table string, id xid.ID, tstamp time.Time) {
+		_, err := squirrel.ExecWith(
+			dbh,
+			squirrel.Update(table).Set("deleted_at", tstamp).Where(squirrel.Eq{"id": id}),
+		)
+		require.NoError(t, err)
+	}
+
+	old := time.Now().Add(-time.Hour)
+
+	trash("products", pid1, old)
+	trash("products", pid2, time.Now())
+	trash("recipes", rid, old)
+	trash("plans", plid, old)
+
+	require.NoError(t, PurgeDeleted(context.Background(), dbh, time.Now().Add(-time.Minute)))
+
+	_, err := GetDeletedPlanByID(context.Background(), dbh, plid)
+	assert.Equal(t, ErrNotFound, err)
+
+	_, err = GetDeletedRecipeByID(context.Background(), dbh, rid)
+	assert.Equal(t, ErrNotFound, err)
+
+	_, err = GetDeletedProductByID(context.Background(), dbh, pid1)
+	assert.Equal(t, ErrNotFound, err)
+
+	_, err = GetDeletedProductByID(context.Background(), dbh, pid2)
+	assert.NoError(t, err)
+}
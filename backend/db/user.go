@@ -43,15 +43,28 @@ func InsertUser(
 	return &usr, nil
 }
 
-// GetUsers retrieves all users.
-func GetUsers(ctx context.Context, qc squirrel.QueryerContext) ([]core.User, error) {
-	return selectUsers(
+// GetUsers retrieves a page of users that match the query. The cursor of
+// the next page is returned if there is one.
+func GetUsers(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	q core.Query,
+	pg core.Page,
+) ([]core.User, *core.Cursor, error) {
+	uu, err := selectUsers(
 		ctx,
 		qc,
 		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
-			return sb
+			return applyQuery(sb, "users", _userColumns, q, pg)
 		},
 	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	uu, next := trimQueryPage(uu, q, pg)
+
+	return uu, next, nil
 }
 
 // GetUserByName retrieves a user by name.
@@ -126,6 +139,54 @@ func UpdateUserPasswordByID(
 	return err
 }
 
+// GetUserByCalendarTokenHash retrieves a user by the hash of its calendar
+// token.
+func GetUserByCalendarTokenHash(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	hash string,
+) (*core.User, error) {
+	users, err := selectUsers(
+		ctx,
+		qc,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Where(
+				squirrel.Eq{"users.calendar_token_hash": hash},
+			)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(users) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &users[0], nil
+}
+
+// UpdateUserCalendarTokenHashByID replaces the hash of the user calendar
+// token. An empty hash revokes the token.
+func UpdateUserCalendarTokenHashByID(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	id xid.ID,
+	hash string,
+) error {
+	_, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		squirrel.Update("users").SetMap(map[string]interface{}{
+			"users.calendar_token_hash": nullString(hash),
+		}).Where(
+			squirrel.Eq{"users.id": id},
+		),
+	)
+
+	return err
+}
+
 // DelteUserByID deletes user password by user id.
 func DeleteUserByID(
 	ctx context.Context,
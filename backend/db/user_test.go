@@ -76,7 +76,7 @@ func Test_GetUsers(t *testing.T) {
 
 	mockUsers(t, dbh, uu...)
 
-	res, err := GetUsers(context.Background(), dbh)
+	res, _, err := GetUsers(context.Background(), dbh, core.Query{}, core.Page{Limit: 100})
 	require.NoError(t, err)
 	assert.Equal(t, uu, res)
 }
@@ -191,6 +191,39 @@ func Test_UpdateUserPasswordByID(t *testing.T) {
 	assert.Equal(t, usr, uu[0])
 }
 
+func Test_UpdateUserCalendarTokenHashByID(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	usr := core.User{
+		ID:           xid.New(),
+		Name:         "4",
+		PasswordHash: []byte{5},
+		CreatedAt:    time.Now().UTC().Truncate(time.Second),
+		Admin:        false,
+	}
+
+	mockUsers(t, dbh, usr)
+
+	hash := core.HashCalendarToken("token")
+
+	_, err := GetUserByCalendarTokenHash(context.Background(), dbh, hash)
+	assert.Equal(t, ErrNotFound, err)
+
+	err = UpdateUserCalendarTokenHashByID(context.Background(), dbh, usr.ID, hash)
+	require.NoError(t, err)
+
+	res, err := GetUserByCalendarTokenHash(context.Background(), dbh, hash)
+	require.NoError(t, err)
+	assert.Equal(t, &usr, res)
+
+	err = UpdateUserCalendarTokenHashByID(context.Background(), dbh, usr.ID, "")
+	require.NoError(t, err)
+
+	_, err = GetUserByCalendarTokenHash(context.Background(), dbh, hash)
+	assert.Equal(t, ErrNotFound, err)
+}
+
 func Test_DeleteUserByID(t *testing.T) {
 	dbh := _dbFn(t)
 	cleanUpTables(t, dbh)
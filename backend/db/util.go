@@ -1,16 +1,24 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"embed"
 	"errors"
+	"fmt"
+	"foodie/core"
 	"net/http"
 	"os"
+	"reflect"
+	"time"
 
+	"github.com/Masterminds/squirrel"
 	"github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4"
 	mmysql "github.com/golang-migrate/migrate/v4/database/mysql"
 	"github.com/golang-migrate/migrate/v4/source/httpfs"
+	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
 )
 
 //go:embed sql
@@ -19,6 +27,154 @@ var _migrations embed.FS
 // ErrNotFound is returned whenever an object in the database is not found.
 var ErrNotFound = errors.New("not found")
 
+// ErrServingMismatch is returned whenever products with different serving
+// types are being combined.
+var ErrServingMismatch = errors.New("serving type mismatch")
+
+// TooFewProductsError is returned whenever products are being merged in
+// recipes that would be left with fewer than two products.
+type TooFewProductsError struct {
+	// Recipes contains the recipes that would be left with too few
+	// products.
+	Recipes []core.Dependent
+}
+
+// Error returns the description of the error.
+func (e *TooFewProductsError) Error() string {
+	return fmt.Sprintf("%d recipes would be left with too few products", len(e.Recipes))
+}
+
+// ErrVersionMismatch is returned whenever an object is being updated with
+// a version that is no longer current.
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// ErrDuplicateBarcode is returned whenever a product is being saved with
+// a barcode that belongs to another product.
+var ErrDuplicateBarcode = errors.New("duplicate barcode")
+
+// ErrNotPending is returned whenever a product is being reviewed after it
+// was already approved or rejected.
+var ErrNotPending = errors.New("product is not pending")
+
+// _errDuplicateEntry is the MySQL error number of a unique index
+// violation.
+const _errDuplicateEntry = 1062
+
+// _unrestricted is a viewer that is used for internal reads which must see
+// every object regardless of its visibility.
+var _unrestricted = core.Viewer{Admin: true}
+
+// paginate restricts the select builder of the provided table to the page.
+// One object more than the limit is selected, so that trimPage is able to
+// detect whether there is a next page.
+func paginate(
+	sb squirrel.SelectBuilder,
+	table string,
+	pg core.Page,
+) squirrel.SelectBuilder {
+	if pg.Cursor != nil {
+		sb = sb.Where(squirrel.Or{
+			squirrel.Gt{table + ".created_at": pg.Cursor.CreatedAt},
+			squirrel.And{
+				squirrel.Eq{table + ".created_at": pg.Cursor.CreatedAt},
+				squirrel.Gt{table + ".id": pg.Cursor.ID},
+			},
+		})
+	}
+
+	return sb.OrderBy(
+		table+".created_at ASC",
+		table+".id ASC",
+	).Limit(pg.Limit + 1)
+}
+
+// trimPage trims the objects selected with paginate to the page limit and
+// returns the cursor of the next page, or nil if it is the last page.
+func trimPage[T interface{ Cursor() core.Cursor }](
+	oo []T,
+	pg core.Page,
+) ([]T, *core.Cursor) {
+	if uint64(len(oo)) <= pg.Limit {
+		return oo, nil
+	}
+
+	oo = oo[:pg.Limit]
+	next := oo[len(oo)-1].Cursor()
+
+	return oo, &next
+}
+
+// touch marks the objects of the table that are updated by the update
+// builder as modified.
+func touch(ub squirrel.UpdateBuilder, table string) squirrel.UpdateBuilder {
+	return ub.
+		Set(table+".version", squirrel.Expr(table+".version + 1")).
+		Set(table+".updated_at", time.Now())
+}
+
+// changedValues returns the column values of the updated object that
+// differ from the current ones.
+func changedValues(cur, upd map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(upd))
+
+	for col, val := range upd {
+		if d, ok := val.(decimal.Decimal); ok {
+			if cd, ok := cur[col].(decimal.Decimal); ok && d.Equal(cd) {
+				continue
+			}
+		}
+
+		if reflect.DeepEqual(cur[col], val) {
+			continue
+		}
+
+		res[col] = val
+	}
+
+	return res
+}
+
+// execVersioned executes the update builder of an object of the table only
+// if its current version matches the provided one. ErrVersionMismatch is
+// returned otherwise.
+func execVersioned(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	ub squirrel.UpdateBuilder,
+	table string,
+	id xid.ID,
+	version uint64,
+) error {
+	res, err := squirrel.ExecContextWith(ctx, ec, touch(ub, table).Where(
+		squirrel.Eq{
+			table + ".id":      id,
+			table + ".version": version,
+		},
+	))
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrVersionMismatch
+	}
+
+	return nil
+}
+
+// isDuplicate checks whether the error was caused by a violation of a
+// unique index.
+func isDuplicate(err error) bool {
+	var merr *mysql.MySQLError
+
+	return errors.As(err, &merr) && merr.Number == _errDuplicateEntry
+}
+
 // Connect tries to establish a connection to the database by the provided
 // dsn string. Once the connection is established, it returns an API to
 // communicate with the database.
@@ -84,3 +240,48 @@ func Migrate(dbh *sql.DB) error {
 
 	return nil
 }
+
+// visibleTo returns a condition that limits objects of the provided table
+// to the ones that the viewer is allowed to access directly. Admins are not
+// restricted, owners see all of their objects and everyone else sees only
+// published objects that are not private.
+func visibleTo(table string, vw core.Viewer) squirrel.Sqlizer {
+	cond := squirrel.Or{
+		squirrel.And{
+			squirrel.Eq{table + ".state": core.StatePublished},
+			squirrel.NotEq{table + ".visibility": core.VisibilityPrivate},
+		},
+	}
+
+	if !vw.UserID.IsNil() {
+		cond = append(cond, squirrel.Eq{table + ".user_id": vw.UserID})
+	}
+
+	return cond
+}
+
+// listedTo returns a condition that limits objects of the provided table
+// to the ones that should appear in listings for the viewer. Unlisted
+// objects are listed only to their owners.
+func listedTo(table string, vw core.Viewer) squirrel.Sqlizer {
+	cond := squirrel.Or{
+		squirrel.Eq{table + ".visibility": core.VisibilityPublic},
+	}
+
+	if !vw.UserID.IsNil() {
+		cond = append(cond, squirrel.Eq{table + ".user_id": vw.UserID})
+	}
+
+	return cond
+}
+
+// nullString returns the column value of an optional text. Empty texts are
+// stored as NULL, e.g. so that they do not conflict with each other in
+// unique indexes.
+func nullString(v string) interface{} {
+	if v == "" {
+		return nil
+	}
+
+	return v
+}
@@ -3,12 +3,15 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"foodie/core"
 	"net"
 	"os"
 	"testing"
 
 	"github.com/ory/dockertest/v3"
 	dc "github.com/ory/dockertest/v3/docker"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
 )
 
 var _dbFn func(t *testing.T) *sql.DB
@@ -33,6 +36,7 @@ func cleanUpTables(t *testing.T, dbh *sql.DB) {
 		dbh.Exec("DELETE FROM recipes")
 		dbh.Exec("DELETE FROM products")
 		dbh.Exec("DELETE FROM users")
+		dbh.Exec("DELETE FROM idempotency_keys")
 	})
 }
 
@@ -113,3 +117,25 @@ func getHostPort(r *dockertest.Resource, portID string) string {
 
 	return net.JoinHostPort(ip, m[0].HostPort)
 }
+
+func Test_changedValues(t *testing.T) {
+	res := changedValues(
+		map[string]interface{}{
+			"products.name":         "1",
+			"products.image_url":    "a",
+			"products.serving_size": decimal.New(10, 0),
+			"products.status":       core.ProductStatusPending,
+		},
+		map[string]interface{}{
+			"products.name":         "2",
+			"products.image_url":    "a",
+			"products.serving_size": decimal.New(100000, -4),
+			"products.status":       core.ProductStatusApproved,
+		},
+	)
+
+	assert.Equal(t, map[string]interface{}{
+		"products.name":   "2",
+		"products.status": core.ProductStatusApproved,
+	}, res)
+}
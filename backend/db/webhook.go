@@ -0,0 +1,469 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"foodie/core"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/rs/xid"
+)
+
+// InsertWebhook inserts a new webhook of the user into the database.
+func InsertWebhook(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	uid xid.ID,
+	secret string,
+	wc core.WebhookCore,
+) (*core.Webhook, error) {
+	wh := core.Webhook{
+		WebhookCore: wc,
+		ID:          xid.New(),
+		UserID:      uid,
+		Secret:      secret,
+		CreatedAt:   time.Now(),
+	}
+
+	values := webhookValues(wc)
+	values["webhooks.id"] = wh.ID
+	values["webhooks.user_id"] = wh.UserID
+	values["webhooks.secret"] = wh.Secret
+	values["webhooks.created_at"] = wh.CreatedAt
+
+	_, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		squirrel.Insert("webhooks").SetMap(values),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &wh, nil
+}
+
+// GetWebhooksByUserID retrieves a page of webhooks of the user. The cursor
+// of the next page is returned if there is one.
+func GetWebhooksByUserID(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	uid xid.ID,
+	pg core.Page,
+) ([]core.Webhook, *core.Cursor, error) {
+	ww, err := selectWebhooks(
+		ctx,
+		qc,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return paginate(sb.Where(
+				squirrel.Eq{"webhooks.user_id": uid},
+			), "webhooks", pg)
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ww, next := trimPage(ww, pg)
+
+	return ww, next, nil
+}
+
+// GetWebhookByID retrieves a webhook by its id.
+func GetWebhookByID(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	id xid.ID,
+) (*core.Webhook, error) {
+	ww, err := selectWebhooks(
+		ctx,
+		qc,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Where(
+				squirrel.Eq{"webhooks.id": id},
+			)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ww) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &ww[0], nil
+}
+
+// GetWebhooksByEvent retrieves all active webhooks that are subscribed to
+// the event type.
+func GetWebhooksByEvent(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	et core.EventType,
+) ([]core.Webhook, error) {
+	return selectWebhooks(
+		ctx,
+		qc,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Where(
+				squirrel.Eq{"webhooks.active": true},
+			).Where("FIND_IN_SET(?, webhooks.events) > 0", et)
+		},
+	)
+}
+
+// UpdateWebhookByID replaces the core of a webhook by its id.
+func UpdateWebhookByID(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	id xid.ID,
+	wc core.WebhookCore,
+) error {
+	_, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		squirrel.Update("webhooks").SetMap(webhookValues(wc)).Where(
+			squirrel.Eq{"webhooks.id": id},
+		),
+	)
+
+	return err
+}
+
+// DeleteWebhookByID deletes a webhook together with its deliveries by its
+// id.
+func DeleteWebhookByID(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	id xid.ID,
+) error {
+	_, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		squirrel.Delete("webhooks").Where(
+			squirrel.Eq{"webhooks.id": id},
+		),
+	)
+
+	return err
+}
+
+// InsertWebhookDeliveries inserts new pending webhook deliveries into the
+// database.
+func InsertWebhookDeliveries(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	dd ...core.WebhookDelivery,
+) error {
+	if len(dd) == 0 {
+		return nil
+	}
+
+	ib := squirrel.Insert("webhook_deliveries").Columns(
+		"webhook_deliveries.id",
+		"webhook_deliveries.webhook_id",
+		"webhook_deliveries.event",
+		"webhook_deliveries.payload",
+		"webhook_deliveries.status",
+		"webhook_deliveries.attempts",
+		"webhook_deliveries.next_attempt_at",
+		"webhook_deliveries.created_at",
+	)
+
+	for _, wd := range dd {
+		ib = ib.Values(
+			wd.ID,
+			wd.WebhookID,
+			wd.Event,
+			[]byte(wd.Payload),
+			wd.Status,
+			wd.Attempts,
+			wd.NextAttemptAt,
+			wd.CreatedAt,
+		)
+	}
+
+	_, err := squirrel.ExecContextWith(ctx, ec, ib)
+
+	return err
+}
+
+// ClaimWebhookDeliveries retrieves pending webhook deliveries whose next
+// attempt is due and postpones their next attempt by the lease duration
+// within a single transaction. If the dispatcher stops before the claimed
+// deliveries are finished, they are attempted again once the lease
+// expires.
+func ClaimWebhookDeliveries(
+	ctx context.Context,
+	db *sql.DB,
+	now time.Time,
+	lease time.Duration,
+	limit uint64,
+) ([]core.WebhookDelivery, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	dd, err := selectWebhookDeliveries(
+		ctx,
+		tx,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return sb.Where(squirrel.And{
+				squirrel.Eq{"webhook_deliveries.status": core.DeliveryStatusPending},
+				squirrel.LtOrEq{"webhook_deliveries.next_attempt_at": now},
+			}).OrderBy(
+				"webhook_deliveries.next_attempt_at ASC",
+				"webhook_deliveries.id ASC",
+			).Limit(limit).Suffix("FOR UPDATE")
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dd) == 0 {
+		return dd, nil
+	}
+
+	next := now.Add(lease)
+	ids := make([]xid.ID, 0, len(dd))
+
+	for i := range dd {
+		dd[i].NextAttemptAt = &next
+		ids = append(ids, dd[i].ID)
+	}
+
+	_, err = squirrel.ExecContextWith(
+		ctx,
+		tx,
+		squirrel.Update("webhook_deliveries").SetMap(map[string]interface{}{
+			"webhook_deliveries.next_attempt_at": next,
+		}).Where(
+			squirrel.Eq{"webhook_deliveries.id": ids},
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return dd, nil
+}
+
+// UpdateWebhookDelivery stores the outcome of the last attempt of the
+// webhook delivery.
+func UpdateWebhookDelivery(
+	ctx context.Context,
+	ec squirrel.ExecerContext,
+	wd core.WebhookDelivery,
+) error {
+	var rs interface{}
+	if wd.ResponseStatus != 0 {
+		rs = wd.ResponseStatus
+	}
+
+	_, err := squirrel.ExecContextWith(
+		ctx,
+		ec,
+		squirrel.Update("webhook_deliveries").SetMap(map[string]interface{}{
+			"webhook_deliveries.status":          wd.Status,
+			"webhook_deliveries.attempts":        wd.Attempts,
+			"webhook_deliveries.next_attempt_at": wd.NextAttemptAt,
+			"webhook_deliveries.response_status": rs,
+			"webhook_deliveries.error":           nullString(wd.Error),
+		}).Where(
+			squirrel.Eq{"webhook_deliveries.id": wd.ID},
+		),
+	)
+
+	return err
+}
+
+// GetWebhookDeliveries retrieves a page of the delivery log of the
+// webhook. The cursor of the next page is returned if there is one.
+func GetWebhookDeliveries(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	wid xid.ID,
+	pg core.Page,
+) ([]core.WebhookDelivery, *core.Cursor, error) {
+	dd, err := selectWebhookDeliveries(
+		ctx,
+		qc,
+		func(sb squirrel.SelectBuilder) squirrel.SelectBuilder {
+			return paginate(sb.Where(
+				squirrel.Eq{"webhook_deliveries.webhook_id": wid},
+			), "webhook_deliveries", pg)
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dd, next := trimPage(dd, pg)
+
+	return dd, next, nil
+}
+
+// selectWebhooks selects all webhooks together with the permissions of
+// their owners by the provided decorator function.
+func selectWebhooks(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	dec func(squirrel.SelectBuilder) squirrel.SelectBuilder,
+) ([]core.Webhook, error) {
+	rows, err := squirrel.QueryContextWith(ctx, qc, dec(squirrel.
+		Select(
+			"webhooks.id",
+			"webhooks.user_id",
+			"users.admin",
+			"webhooks.url",
+			"webhooks.events",
+			"webhooks.secret",
+			"webhooks.active",
+			"webhooks.created_at",
+		).From("webhooks").Join("users ON users.id = webhooks.user_id"),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ww := make([]core.Webhook, 0)
+
+	for rows.Next() {
+		var (
+			wh     core.Webhook
+			events string
+		)
+
+		if err := rows.Scan(
+			&wh.ID,
+			&wh.UserID,
+			&wh.Admin,
+			&wh.URL,
+			&events,
+			&wh.Secret,
+			&wh.Active,
+			&wh.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		wh.Events = splitEvents(events)
+		ww = append(ww, wh)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ww, nil
+}
+
+// selectWebhookDeliveries selects all webhook deliveries by the provided
+// decorator function.
+func selectWebhookDeliveries(
+	ctx context.Context,
+	qc squirrel.QueryerContext,
+	dec func(squirrel.SelectBuilder) squirrel.SelectBuilder,
+) ([]core.WebhookDelivery, error) {
+	rows, err := squirrel.QueryContextWith(ctx, qc, dec(squirrel.
+		Select(
+			"webhook_deliveries.id",
+			"webhook_deliveries.webhook_id",
+			"webhook_deliveries.event",
+			"webhook_deliveries.payload",
+			"webhook_deliveries.status",
+			"webhook_deliveries.attempts",
+			"webhook_deliveries.next_attempt_at",
+			"COALESCE(webhook_deliveries.response_status, 0)",
+			"COALESCE(webhook_deliveries.error, '')",
+			"webhook_deliveries.created_at",
+		).From("webhook_deliveries"),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	dd := make([]core.WebhookDelivery, 0)
+
+	for rows.Next() {
+		var (
+			wd      core.WebhookDelivery
+			payload []byte
+		)
+
+		if err := rows.Scan(
+			&wd.ID,
+			&wd.WebhookID,
+			&wd.Event,
+			&payload,
+			&wd.Status,
+			&wd.Attempts,
+			&wd.NextAttemptAt,
+			&wd.ResponseStatus,
+			&wd.Error,
+			&wd.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		wd.Payload = payload
+		dd = append(dd, wd)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dd, nil
+}
+
+// webhookValues returns the column values of the webhook core.
+func webhookValues(wc core.WebhookCore) map[string]interface{} {
+	return map[string]interface{}{
+		"webhooks.url":    wc.URL,
+		"webhooks.events": joinEvents(wc.Events),
+		"webhooks.active": wc.Active,
+	}
+}
+
+// joinEvents joins the event types into a single column value, which can
+// be searched with FIND_IN_SET.
+func joinEvents(ee []core.EventType) string {
+	ss := make([]string, 0, len(ee))
+
+	for _, et := range ee {
+		ss = append(ss, string(et))
+	}
+
+	return strings.Join(ss, ",")
+}
+
+// splitEvents splits the column value into the event types.
+func splitEvents(v string) []core.EventType {
+	ee := make([]core.EventType, 0)
+
+	if v == "" {
+		return ee
+	}
+
+	for _, s := range strings.Split(v, ",") {
+		ee = append(ee, core.EventType(s))
+	}
+
+	return ee
+}
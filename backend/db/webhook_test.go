@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"foodie/core"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Webhooks(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	usr := core.User{
+		ID:           xid.New(),
+		Name:         "1",
+		PasswordHash: []byte{1},
+		CreatedAt:    time.Now().UTC().Truncate(time.Second),
+		Admin:        true,
+	}
+
+	mockUsers(t, dbh, usr)
+
+	wh, err := InsertWebhook(context.Background(), dbh, usr.ID, "secret", core.WebhookCore{
+		URL:    "https://example.com/hook",
+		Events: []core.EventType{core.EventRecipeCreated, core.EventPlanDeleted},
+		Active: true,
+	})
+	require.NoError(t, err)
+
+	res, err := GetWebhookByID(context.Background(), dbh, wh.ID)
+	require.NoError(t, err)
+	assert.Equal(t, wh.WebhookCore, res.WebhookCore)
+	assert.Equal(t, "secret", res.Secret)
+	assert.True(t, res.Admin)
+
+	ww, err := GetWebhooksByEvent(context.Background(), dbh, core.EventPlanDeleted)
+	require.NoError(t, err)
+	require.Len(t, ww, 1)
+	assert.Equal(t, wh.ID, ww[0].ID)
+
+	ww, err = GetWebhooksByEvent(context.Background(), dbh, core.EventPlanCreated)
+	require.NoError(t, err)
+	assert.Empty(t, ww)
+
+	wc := wh.WebhookCore
+	wc.Active = false
+
+	require.NoError(t, UpdateWebhookByID(context.Background(), dbh, wh.ID, wc))
+
+	ww, err = GetWebhooksByEvent(context.Background(), dbh, core.EventPlanDeleted)
+	require.NoError(t, err)
+	assert.Empty(t, ww)
+
+	ww, next, err := GetWebhooksByUserID(context.Background(), dbh, usr.ID, core.Page{Limit: 10})
+	require.NoError(t, err)
+	assert.Nil(t, next)
+	require.Len(t, ww, 1)
+	assert.False(t, ww[0].Active)
+
+	require.NoError(t, DeleteWebhookByID(context.Background(), dbh, wh.ID))
+
+	_, err = GetWebhookByID(context.Background(), dbh, wh.ID)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func Test_WebhookDeliveries(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	usr := core.User{
+		ID:           xid.New(),
+		Name:         "1",
+		PasswordHash: []byte{1},
+		CreatedAt:    time.Now().UTC().Truncate(time.Second),
+	}
+
+	mockUsers(t, dbh, usr)
+
+	wh, err := InsertWebhook(context.Background(), dbh, usr.ID, "secret", core.WebhookCore{
+		URL:    "https://example.com/hook",
+		Events: []core.EventType{core.EventRecipeCreated},
+		Active: true,
+	})
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	later := now.Add(time.Hour)
+
+	dd := []core.WebhookDelivery{
+		{
+			ID:            xid.New(),
+			WebhookID:     wh.ID,
+			Event:         core.EventRecipeCreated,
+			Payload:       json.RawMessage(`{"id":"1"}`),
+			Status:        core.DeliveryStatusPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+		},
+		{
+			ID:            xid.New(),
+			WebhookID:     wh.ID,
+			Event:         core.EventRecipeCreated,
+			Payload:       json.RawMessage(`{"id":"2"}`),
+			Status:        core.DeliveryStatusPending,
+			NextAttemptAt: &later,
+			CreatedAt:     now,
+		},
+	}
+
+	require.NoError(t, InsertWebhookDeliveries(context.Background(), dbh, dd...))
+
+	claimed, err := ClaimWebhookDeliveries(context.Background(), dbh, now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, dd[0].ID, claimed[0].ID)
+	assert.JSONEq(t, `{"id":"1"}`, string(claimed[0].Payload))
+
+	// The claimed delivery is leased, so it is not claimed again.
+	claimed, err = ClaimWebhookDeliveries(context.Background(), dbh, now, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	wd := dd[0]
+	wd.Status = core.DeliveryStatusSucceeded
+	wd.Attempts = 1
+	wd.NextAttemptAt = nil
+	wd.ResponseStatus = 204
+
+	require.NoError(t, UpdateWebhookDelivery(context.Background(), dbh, wd))
+
+	log, next, err := GetWebhookDeliveries(context.Background(), dbh, wh.ID, core.Page{Limit: 1})
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	require.Len(t, log, 1)
+	assert.Equal(t, core.DeliveryStatusSucceeded, log[0].Status)
+	assert.Equal(t, uint(1), log[0].Attempts)
+	assert.Equal(t, 204, log[0].ResponseStatus)
+	assert.Nil(t, log[0].NextAttemptAt)
+}
+
+func Test_joinEvents_splitEvents(t *testing.T) {
+	ee := []core.EventType{core.EventProductCreated, core.EventUserDeleted}
+
+	assert.Equal(t, "product.created,user.deleted", joinEvents(ee))
+	assert.Equal(t, ee, splitEvents(joinEvents(ee)))
+	assert.Equal(t, []core.EventType{}, splitEvents(""))
+}
@@ -1,6 +1,7 @@
 package apierr
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 )
@@ -16,24 +17,202 @@ const (
 	DataTypeJSON
 )
 
+// Code is a stable machine-readable identifier of an error.
+type Code string
+
+const (
+	// CodeUnauthorized specifies that the request is not authorized.
+	CodeUnauthorized Code = "unauthorized"
+
+	// CodeForbidden specifies that the user is not allowed to perform the
+	// request.
+	CodeForbidden Code = "forbidden"
+
+	// CodeConflict specifies that the object is in a conflicting state.
+	CodeConflict Code = "conflict"
+
+	// CodePreconditionFailed specifies that the object was modified since
+	// the version known to the client.
+	CodePreconditionFailed Code = "precondition_failed"
+
+	// CodeIdempotencyKeyReused specifies that the idempotency key was
+	// already used for a different request.
+	CodeIdempotencyKeyReused Code = "idempotency_key_reused"
+
+	// CodeInUse specifies that the object is referenced by other objects.
+	CodeInUse Code = "in_use"
+
+	// CodeRequestCanceled specifies that the request was canceled before
+	// being handled.
+	CodeRequestCanceled Code = "request_canceled"
+
+	// CodeNotFound specifies that the object does not exist.
+	CodeNotFound Code = "not_found"
+
+	// CodeRouteNotFound specifies that the route does not exist.
+	CodeRouteNotFound Code = "route_not_found"
+
+	// CodeMethodNotAllowed specifies that the route does not support the
+	// request method.
+	CodeMethodNotAllowed Code = "method_not_allowed"
+
+	// CodeDatabase specifies that the database is unavailable.
+	CodeDatabase Code = "database_unavailable"
+
+	// CodeInternal specifies an unexpected server error.
+	CodeInternal Code = "internal"
+
+	// CodeInvalidAttribute specifies that an attribute of the request is
+	// invalid.
+	CodeInvalidAttribute Code = "invalid_attribute"
+
+	// CodeBadRequest specifies that the request is invalid.
+	CodeBadRequest Code = "bad_request"
+
+	// CodeInvalidBody specifies that the request body cannot be read.
+	CodeInvalidBody Code = "invalid_body"
+
+	// CodeMalformedJSON specifies that the request body is not valid JSON.
+	CodeMalformedJSON Code = "malformed_json"
+)
+
+const (
+	// ContentType specifies the media type of error responses.
+	ContentType = "application/problem+json"
+
+	// HeaderRequestID specifies the response header that contains the
+	// request id. Its value is included in error responses.
+	HeaderRequestID = "X-Request-Id"
+)
+
 // Error contains http error information.
 type Error struct {
 	statusCode int
+	code       Code
 	message    string
+	detail     string
+	attribute  string
+	object     string
+	violations []Violation
+	details    interface{}
+}
+
+// problem is an RFC 7807 problem details object.
+type problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail,omitempty"`
+	Code      Code        `json:"code"`
+	Attribute string      `json:"attribute,omitempty"`
+	Object    string      `json:"object,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+	Errors    []Violation `json:"errors,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
 }
 
-// Respond responds to the response writer with the provided error.
+// Respond responds to the response writer with the provided error as an
+// RFC 7807 problem details object. Response writers prepared by the
+// Middleware for the text format receive the plain text message instead.
 func (e *Error) Respond(w http.ResponseWriter) {
+	if isText(w) {
+		e.respondText(w)
+		return
+	}
+
+	detail := e.detail
+	if detail == "" {
+		detail = e.message
+	}
+
+	data, err := json.Marshal(problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(e.statusCode),
+		Status:    e.statusCode,
+		Detail:    detail,
+		Code:      e.code,
+		Attribute: e.attribute,
+		Object:    e.object,
+		RequestID: w.Header().Get(HeaderRequestID),
+		Errors:    e.violations,
+		Details:   e.details,
+	})
+	if err != nil {
+		e.respondText(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(e.statusCode)
+	w.Write(data) //nolint:errcheck // cannot recover from this.
+}
+
+// respondText responds with the plain text message of the error. Errors
+// that contain details are written as JSON objects.
+func (e *Error) respondText(w http.ResponseWriter) {
+	if e.details != nil {
+		data, err := json.Marshal(struct {
+			Message string      `json:"message"`
+			Details interface{} `json:"details"`
+		}{
+			Message: e.message,
+			Details: e.details,
+		})
+		if err == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(e.statusCode)
+			w.Write(data) //nolint:errcheck // cannot recover from this.
+
+			return
+		}
+	}
+
 	w.WriteHeader(e.statusCode)
 	if e.message != "" {
 		w.Write([]byte(e.message)) //nolint:errcheck // cannot recover from this.
 	}
 }
 
+// Error returns the human-readable message of the error, so that it could
+// be reported by protocols other than plain HTTP.
+func (e *Error) Error() string {
+	switch {
+	case e.detail != "":
+		return e.detail
+	case e.message != "":
+		return e.message
+	default:
+		return http.StatusText(e.statusCode)
+	}
+}
+
+// StatusCode returns the HTTP status code of the error.
+func (e *Error) StatusCode() int {
+	return e.statusCode
+}
+
+// Code returns the machine-readable code of the error.
+func (e *Error) Code() Code {
+	return e.code
+}
+
+// Attribute returns the invalid attribute of the error, if there is a
+// single one.
+func (e *Error) Attribute() string {
+	return e.attribute
+}
+
+// Details returns the additional details of the error, e.g. the objects
+// that depend on an object in use.
+func (e *Error) Details() interface{} {
+	return e.details
+}
+
 // Unauthorized creates a new unauthorized error.
 func Unauthorized() *Error {
 	return &Error{
 		statusCode: http.StatusUnauthorized,
+		code:       CodeUnauthorized,
 	}
 }
 
@@ -41,14 +220,51 @@ func Unauthorized() *Error {
 func Forbidden() *Error {
 	return &Error{
 		statusCode: http.StatusForbidden,
+		code:       CodeForbidden,
 	}
 }
 
-// Conflict creates a new conflict error.
-func Conflict(object string) *Error {
+// Conflict creates a new conflict error of the object with a message
+// that describes the conflict.
+func Conflict(object, message string) *Error {
 	return &Error{
 		statusCode: http.StatusConflict,
-		message:    object,
+		code:       CodeConflict,
+		message:    message,
+		object:     object,
+	}
+}
+
+// PreconditionFailed creates a new error of a conditional request whose
+// object was modified since the version known to the client.
+func PreconditionFailed(object string) *Error {
+	return &Error{
+		statusCode: http.StatusPreconditionFailed,
+		code:       CodePreconditionFailed,
+		message:    fmt.Sprintf("%s was modified", object),
+		object:     object,
+	}
+}
+
+// IdempotencyKeyReused creates a new error of a request whose idempotency
+// key was already used for a request with a different body.
+func IdempotencyKeyReused() *Error {
+	return &Error{
+		statusCode: http.StatusUnprocessableEntity,
+		code:       CodeIdempotencyKeyReused,
+		message:    "idempotency key was used for a different request",
+	}
+}
+
+// InUse creates a new conflict error which contains details about the
+// objects that depend on the object being deleted.
+func InUse(object string, dependents interface{}) *Error {
+	return &Error{
+		statusCode: http.StatusConflict,
+		code:       CodeInUse,
+		message:    fmt.Sprintf("%s in use", object),
+		object:     object,
+		details:    dependents,
 	}
 }
 
@@ -56,6 +272,7 @@ func Conflict(object string) *Error {
 func Context() *Error {
 	return &Error{
 		statusCode: http.StatusBadRequest,
+		code:       CodeRequestCanceled,
 	}
 }
 
@@ -63,7 +280,27 @@ func Context() *Error {
 func NotFound(object string) *Error {
 	return &Error{
 		statusCode: http.StatusNotFound,
+		code:       CodeNotFound,
 		message:    object,
+		detail:     fmt.Sprintf("%s not found", object),
+		object:     object,
+	}
+}
+
+// RouteNotFound creates a new error of a route that does not exist.
+func RouteNotFound() *Error {
+	return &Error{
+		statusCode: http.StatusNotFound,
+		code:       CodeRouteNotFound,
+	}
+}
+
+// MethodNotAllowed creates a new error of a request method that the route
+// does not support.
+func MethodNotAllowed() *Error {
+	return &Error{
+		statusCode: http.StatusMethodNotAllowed,
+		code:       CodeMethodNotAllowed,
 	}
 }
 
@@ -71,6 +308,7 @@ func NotFound(object string) *Error {
 func Database() *Error {
 	return &Error{
 		statusCode: http.StatusServiceUnavailable,
+		code:       CodeDatabase,
 	}
 }
 
@@ -78,38 +316,42 @@ func Database() *Error {
 func Internal() *Error {
 	return &Error{
 		statusCode: http.StatusInternalServerError,
+		code:       CodeInternal,
 	}
 }
 
 // InvalidAttribute creates a new attribute error.
 func InvalidAttribute(attribute, message string) *Error {
-	return &Error{
-		statusCode: http.StatusBadRequest,
-		message:    fmt.Sprintf("%s: %s", attribute, message),
-	}
+	return Violations{{Attribute: attribute, Message: message}}.Err()
 }
 
 // BadRequest creates a new bad request error.
 func BadRequest(message string) *Error {
 	return &Error{
 		statusCode: http.StatusBadRequest,
+		code:       CodeBadRequest,
 		message:    message,
 	}
 }
 
 // MalformedDataInput creates a new malformed error.
 func MalformedDataInput(dt DataType) *Error {
-	var message string
+	var (
+		code    = CodeBadRequest
+		message string
+	)
+
 	switch dt {
 	case DataTypeRequestBody:
-		message = "invalid body"
+		code, message = CodeInvalidBody, "invalid body"
 	case DataTypeJSON:
-		message = "malformed json"
+		code, message = CodeMalformedJSON, "malformed json"
 	default:
 	}
 
 	return &Error{
 		statusCode: http.StatusBadRequest,
+		code:       code,
 		message:    message,
 	}
 }
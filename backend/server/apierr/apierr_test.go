@@ -2,6 +2,7 @@ package apierr
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -33,7 +34,7 @@ func (mrw *_mockResposeWriter) WriteHeader(statusCode int) {
 	mrw.writeHeaderFn(statusCode)
 }
 
-func Test_Error_Respond(t *testing.T) {
+func Test_Error_Respond_Text(t *testing.T) {
 	stubResponseWriterMock := func(expData []byte, expStatusCode int) *_mockResposeWriter {
 		return &_mockResposeWriter{
 			writeFn: func(data []byte) (int, error) {
@@ -53,7 +54,7 @@ func Test_Error_Respond(t *testing.T) {
 
 		(&Error{
 			statusCode: http.StatusTeapot,
-		}).Respond(mrw)
+		}).Respond(&textWriter{ResponseWriter: mrw})
 
 		assert.Equal(t, 1, mrw.writeHeaderCalls)
 		assert.Equal(t, 0, mrw.writeCalls)
@@ -68,7 +69,7 @@ func Test_Error_Respond(t *testing.T) {
 		(&Error{
 			statusCode: http.StatusTeapot,
 			message:    "test21",
-		}).Respond(mrw)
+		}).Respond(&textWriter{ResponseWriter: mrw})
 
 		assert.Equal(t, 1, mrw.writeHeaderCalls)
 		assert.Equal(t, 1, mrw.writeCalls)
@@ -76,11 +77,139 @@ func Test_Error_Respond(t *testing.T) {
 	})
 }
 
+func Test_Error_Respond_TextDetails(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	InUse("product", map[string]int{"recipes": 2}).Respond(&textWriter{ResponseWriter: rec})
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"message":"product in use","details":{"recipes":2}}`, rec.Body.String())
+}
+
+func Test_Error_Respond(t *testing.T) {
+	tests := map[string]struct {
+		Error  *Error
+		Result string
+	}{
+		"Invalid attribute": {
+			Error: InvalidAttribute("name", "cannot be empty"),
+			Result: `{
+				"type": "about:blank",
+				"title": "Bad Request",
+				"status": 400,
+				"detail": "name: cannot be empty",
+				"code": "invalid_attribute",
+				"attribute": "name",
+				"request_id": "req-1",
+				"errors": [
+					{"attribute": "name", "message": "cannot be empty"}
+				]
+			}`,
+		},
+		"Not found": {
+			Error: NotFound("product"),
+			Result: `{
+				"type": "about:blank",
+				"title": "Not Found",
+				"status": 404,
+				"detail": "product not found",
+				"code": "not_found",
+				"object": "product",
+				"request_id": "req-1"
+			}`,
+		},
+		"In use": {
+			Error: InUse("recipe", map[string]int{"plans": 1}),
+			Result: `{
+				"type": "about:blank",
+				"title": "Conflict",
+				"status": 409,
+				"detail": "recipe in use",
+				"code": "in_use",
+				"object": "recipe",
+				"request_id": "req-1",
+				"details": {"plans": 1}
+			}`,
+		},
+		"Without message": {
+			Error: Unauthorized(),
+			Result: `{
+				"type": "about:blank",
+				"title": "Unauthorized",
+				"status": 401,
+				"code": "unauthorized",
+				"request_id": "req-1"
+			}`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			rec.Header().Set(HeaderRequestID, "req-1")
+
+			test.Error.Respond(rec)
+
+			assert.Equal(t, test.Error.statusCode, rec.Code)
+			assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+			assert.JSONEq(t, test.Result, rec.Body.String())
+		})
+	}
+}
+
+func Test_Error_Error(t *testing.T) {
+	tests := map[string]struct {
+		Error  *Error
+		Result string
+	}{
+		"Detail": {
+			Error:  NotFound("recipe"),
+			Result: "recipe not found",
+		},
+		"Message": {
+			Error:  BadRequest("invalid"),
+			Result: "invalid",
+		},
+		"Status text": {
+			Error:  Forbidden(),
+			Result: "Forbidden",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.Result, test.Error.Error())
+		})
+	}
+}
+
+func Test_Error_Accessors(t *testing.T) {
+	aerr := InvalidAttribute("name", "cannot be empty")
+	assert.Equal(t, http.StatusBadRequest, aerr.StatusCode())
+	assert.Equal(t, CodeInvalidAttribute, aerr.Code())
+	assert.Equal(t, "name", aerr.Attribute())
+	assert.Nil(t, aerr.Details())
+
+	aerr = InUse("recipe", []int{1})
+	assert.Equal(t, http.StatusConflict, aerr.StatusCode())
+	assert.Equal(t, []int{1}, aerr.Details())
+}
+
 func Test_Unauthorized(t *testing.T) {
 	assert.Equal(
 		t,
 		&Error{
 			statusCode: http.StatusUnauthorized,
+			code:       CodeUnauthorized,
 		},
 		Unauthorized(),
 	)
@@ -91,6 +220,7 @@ func Test_Forbidden(t *testing.T) {
 		t,
 		&Error{
 			statusCode: http.StatusForbidden,
+			code:       CodeForbidden,
 		},
 		Forbidden(),
 	)
@@ -101,9 +231,50 @@ func Test_Conflict(t *testing.T) {
 		t,
 		&Error{
 			statusCode: http.StatusConflict,
+			code:       CodeConflict,
 			message:    "132",
+			object:     "user",
+		},
+		Conflict("user", "132"),
+	)
+}
+
+func Test_PreconditionFailed(t *testing.T) {
+	assert.Equal(
+		t,
+		&Error{
+			statusCode: http.StatusPreconditionFailed,
+			code:       CodePreconditionFailed,
+			message:    "recipe was modified",
+			object:     "recipe",
 		},
-		Conflict("132"),
+		PreconditionFailed("recipe"),
+	)
+}
+
+func Test_IdempotencyKeyReused(t *testing.T) {
+	assert.Equal(
+		t,
+		&Error{
+			statusCode: http.StatusUnprocessableEntity,
+			code:       CodeIdempotencyKeyReused,
+			message:    "idempotency key was used for a different request",
+		},
+		IdempotencyKeyReused(),
+	)
+}
+
+func Test_InUse(t *testing.T) {
+	assert.Equal(
+		t,
+		&Error{
+			statusCode: http.StatusConflict,
+			code:       CodeInUse,
+			message:    "recipe in use",
+			object:     "recipe",
+			details:    []int{1},
+		},
+		InUse("recipe", []int{1}),
 	)
 }
 
@@ -112,6 +283,7 @@ func Test_Context(t *testing.T) {
 		t,
 		&Error{
 			statusCode: http.StatusBadRequest,
+			code:       CodeRequestCanceled,
 		},
 		Context(),
 	)
@@ -122,7 +294,10 @@ func Test_NotFound(t *testing.T) {
 		t,
 		&Error{
 			statusCode: http.StatusNotFound,
+			code:       CodeNotFound,
 			message:    "test111",
+			detail:     "test111 not found",
+			object:     "test111",
 		},
 		NotFound("test111"),
 	)
@@ -133,6 +308,7 @@ func Test_Database(t *testing.T) {
 		t,
 		&Error{
 			statusCode: http.StatusServiceUnavailable,
+			code:       CodeDatabase,
 		},
 		Database(),
 	)
@@ -143,6 +319,7 @@ func Test_Internal(t *testing.T) {
 		t,
 		&Error{
 			statusCode: http.StatusInternalServerError,
+			code:       CodeInternal,
 		},
 		Internal(),
 	)
@@ -153,7 +330,12 @@ func Test_InvalidAttribute(t *testing.T) {
 		t,
 		&Error{
 			statusCode: http.StatusBadRequest,
+			code:       CodeInvalidAttribute,
 			message:    "11: 22",
+			attribute:  "11",
+			violations: []Violation{
+				{Attribute: "11", Message: "22"},
+			},
 		},
 		InvalidAttribute("11", "22"),
 	)
@@ -164,6 +346,7 @@ func Test_BadRequest(t *testing.T) {
 		t,
 		&Error{
 			statusCode: http.StatusBadRequest,
+			code:       CodeBadRequest,
 			message:    "1122",
 		},
 		BadRequest("1122"),
@@ -178,12 +361,14 @@ func Test_MalformedDataInput(t *testing.T) {
 		"Invalid data type": {
 			Result: &Error{
 				statusCode: http.StatusBadRequest,
+				code:       CodeBadRequest,
 			},
 		},
 		"Request body data type": {
 			DataType: DataTypeRequestBody,
 			Result: &Error{
 				statusCode: http.StatusBadRequest,
+				code:       CodeInvalidBody,
 				message:    "invalid body",
 			},
 		},
@@ -191,6 +376,7 @@ func Test_MalformedDataInput(t *testing.T) {
 			DataType: DataTypeJSON,
 			Result: &Error{
 				statusCode: http.StatusBadRequest,
+				code:       CodeMalformedJSON,
 				message:    "malformed json",
 			},
 		},
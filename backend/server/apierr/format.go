@@ -0,0 +1,72 @@
+package apierr
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const (
+	// HeaderFormat specifies the request header that selects the format of
+	// error responses.
+	HeaderFormat = "X-Error-Format"
+
+	// FormatText specifies the plain text error format that was used
+	// before the problem details objects were introduced.
+	FormatText = "text"
+)
+
+// Middleware prepares the response writer for error responses. The
+// request id retrieved from the request context, if there is one, is set
+// as a response header, so that it is included in the problem details
+// objects. Requests with the text error format header receive plain text
+// errors.
+func Middleware(requestID func(context.Context) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if id := requestID(r.Context()); id != "" {
+				w.Header().Set(HeaderRequestID, id)
+			}
+
+			if strings.EqualFold(r.Header.Get(HeaderFormat), FormatText) {
+				w = &textWriter{ResponseWriter: w}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// textWriter marks the response writer of a request that expects plain
+// text errors.
+type textWriter struct {
+	http.ResponseWriter
+}
+
+// Unwrap returns the underlying response writer.
+func (tw *textWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// Flush sends any buffered data to the client, if the underlying response
+// writer supports it.
+func (tw *textWriter) Flush() {
+	if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// isText checks whether the response writer, or any of the writers it
+// wraps, expects plain text errors.
+func isText(w http.ResponseWriter) bool {
+	for {
+		switch ww := w.(type) {
+		case *textWriter:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = ww.Unwrap()
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package apierr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Middleware(t *testing.T) {
+	requestID := func(context.Context) string {
+		return "req-1"
+	}
+
+	hdl := Middleware(requestID)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		NotFound("product").Respond(w)
+	}))
+
+	t.Run("Problem details", func(t *testing.T) {
+		t.Parallel()
+
+		rec := httptest.NewRecorder()
+		hdl.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "req-1", rec.Header().Get(HeaderRequestID))
+		assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Body.String(), `"request_id":"req-1"`)
+	})
+
+	t.Run("Text format", func(t *testing.T) {
+		t.Parallel()
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(HeaderFormat, FormatText)
+
+		rec := httptest.NewRecorder()
+		hdl.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "req-1", rec.Header().Get(HeaderRequestID))
+		assert.Empty(t, rec.Header().Get("Content-Type"))
+		assert.Equal(t, "product", rec.Body.String())
+	})
+}
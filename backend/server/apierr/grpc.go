@@ -0,0 +1,80 @@
+package apierr
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+)
+
+// ErrorDomain specifies the domain of the error details reported through
+// gRPC.
+const ErrorDomain = "foodie"
+
+// GRPCStatus converts the error to a gRPC status, so that it could be
+// returned by gRPC handlers directly. The error code is reported in the
+// ErrorInfo details and the invalid attributes in the BadRequest details.
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(e.GRPCCode(), e.Error())
+
+	info := &errdetails.ErrorInfo{
+		Reason: string(e.code),
+		Domain: ErrorDomain,
+	}
+
+	if e.object != "" {
+		info.Metadata = map[string]string{"object": e.object}
+	}
+
+	details := []protoiface.MessageV1{info}
+
+	if len(e.violations) > 0 {
+		br := &errdetails.BadRequest{}
+
+		for _, v := range e.violations {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Attribute,
+				Description: v.Message,
+			})
+		}
+
+		details = append(details, br)
+	}
+
+	dst, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+
+	return dst
+}
+
+// GRPCCode returns the gRPC status code that corresponds to the error.
+func (e *Error) GRPCCode() codes.Code {
+	switch e.code {
+	case CodeUnauthorized:
+		return codes.Unauthenticated
+	case CodeForbidden:
+		return codes.PermissionDenied
+	case CodeConflict:
+		return codes.AlreadyExists
+	case CodePreconditionFailed:
+		return codes.Aborted
+	case CodeInUse, CodeIdempotencyKeyReused:
+		return codes.FailedPrecondition
+	case CodeRequestCanceled:
+		return codes.Canceled
+	case CodeNotFound, CodeRouteNotFound:
+		return codes.NotFound
+	case CodeMethodNotAllowed:
+		return codes.Unimplemented
+	case CodeDatabase:
+		return codes.Unavailable
+	case CodeInternal:
+		return codes.Internal
+	case CodeInvalidAttribute, CodeBadRequest, CodeInvalidBody, CodeMalformedJSON:
+		return codes.InvalidArgument
+	default:
+		return codes.Unknown
+	}
+}
@@ -0,0 +1,122 @@
+package apierr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_Error_GRPCStatus(t *testing.T) {
+	t.Run("without violations", func(t *testing.T) {
+		t.Parallel()
+
+		st, ok := status.FromError(NotFound("recipe"))
+		require.True(t, ok)
+
+		assert.Equal(t, codes.NotFound, st.Code())
+		assert.Equal(t, "recipe not found", st.Message())
+
+		details := st.Details()
+		require.Len(t, details, 1)
+
+		info, ok := details[0].(*errdetails.ErrorInfo)
+		require.True(t, ok)
+
+		assert.Equal(t, string(CodeNotFound), info.Reason)
+		assert.Equal(t, ErrorDomain, info.Domain)
+		assert.Equal(t, map[string]string{"object": "recipe"}, info.Metadata)
+	})
+
+	t.Run("with violations", func(t *testing.T) {
+		t.Parallel()
+
+		st, ok := status.FromError(InvalidAttribute("name", "cannot be blank"))
+		require.True(t, ok)
+
+		assert.Equal(t, codes.InvalidArgument, st.Code())
+
+		details := st.Details()
+		require.Len(t, details, 2)
+
+		br, ok := details[1].(*errdetails.BadRequest)
+		require.True(t, ok)
+		require.Len(t, br.FieldViolations, 1)
+
+		assert.Equal(t, "name", br.FieldViolations[0].Field)
+		assert.Equal(t, "cannot be blank", br.FieldViolations[0].Description)
+	})
+}
+
+func Test_Error_GRPCCode(t *testing.T) {
+	tests := map[string]struct {
+		Error *Error
+		Code  codes.Code
+	}{
+		"Unauthorized": {
+			Error: Unauthorized(),
+			Code:  codes.Unauthenticated,
+		},
+		"Forbidden": {
+			Error: Forbidden(),
+			Code:  codes.PermissionDenied,
+		},
+		"Conflict": {
+			Error: Conflict("user", "name"),
+			Code:  codes.AlreadyExists,
+		},
+		"Precondition failed": {
+			Error: PreconditionFailed("recipe"),
+			Code:  codes.Aborted,
+		},
+		"In use": {
+			Error: InUse("product", nil),
+			Code:  codes.FailedPrecondition,
+		},
+		"Idempotency key reused": {
+			Error: IdempotencyKeyReused(),
+			Code:  codes.FailedPrecondition,
+		},
+		"Request canceled": {
+			Error: Context(),
+			Code:  codes.Canceled,
+		},
+		"Not found": {
+			Error: NotFound("plan"),
+			Code:  codes.NotFound,
+		},
+		"Database": {
+			Error: Database(),
+			Code:  codes.Unavailable,
+		},
+		"Internal": {
+			Error: Internal(),
+			Code:  codes.Internal,
+		},
+		"Invalid attribute": {
+			Error: InvalidAttribute("id", "must be a valid id"),
+			Code:  codes.InvalidArgument,
+		},
+		"Bad request": {
+			Error: BadRequest("test"),
+			Code:  codes.InvalidArgument,
+		},
+		"Unknown": {
+			Error: &Error{},
+			Code:  codes.Unknown,
+		},
+	}
+
+	for tn, tc := range tests {
+		tc := tc
+
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.Code, tc.Error.GRPCCode())
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package apierr
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Violation describes a single invalid attribute.
+type Violation struct {
+	// Attribute specifies the name of the invalid attribute.
+	Attribute string `json:"attribute"`
+
+	// Message describes why the attribute is invalid.
+	Message string `json:"message"`
+}
+
+// Violations collects invalid attributes, so that all of them can be
+// reported at once.
+type Violations []Violation
+
+// Add adds an invalid attribute.
+func (vv *Violations) Add(attribute, message string) {
+	*vv = append(*vv, Violation{
+		Attribute: attribute,
+		Message:   message,
+	})
+}
+
+// Merge adds the invalid attributes of the attribute error. Nil errors
+// are ignored.
+func (vv *Violations) Merge(e *Error) {
+	if e == nil {
+		return
+	}
+
+	*vv = append(*vv, e.violations...)
+}
+
+// Err creates a new attribute error that contains all invalid
+// attributes. Nil is returned if there are none.
+func (vv Violations) Err() *Error {
+	if len(vv) == 0 {
+		return nil
+	}
+
+	mm := make([]string, 0, len(vv))
+	for _, v := range vv {
+		mm = append(mm, fmt.Sprintf("%s: %s", v.Attribute, v.Message))
+	}
+
+	e := &Error{
+		statusCode: http.StatusBadRequest,
+		code:       CodeInvalidAttribute,
+		message:    strings.Join(mm, "; "),
+		violations: vv,
+	}
+
+	if len(vv) == 1 {
+		e.attribute = vv[0].Attribute
+	}
+
+	return e
+}
+
+// Violations returns the invalid attributes of the error. Nil is returned
+// if the error is not an attribute error.
+func (e *Error) Violations() Violations {
+	return e.violations
+}
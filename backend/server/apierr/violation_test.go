@@ -0,0 +1,32 @@
+package apierr
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Violations_Err(t *testing.T) {
+	var vv Violations
+
+	assert.Nil(t, vv.Err())
+
+	vv.Add("name", "cannot be empty")
+	vv.Merge(nil)
+	vv.Merge(InvalidAttribute("state", "must be of a valid type"))
+
+	assert.Equal(
+		t,
+		&Error{
+			statusCode: http.StatusBadRequest,
+			code:       CodeInvalidAttribute,
+			message:    "name: cannot be empty; state: must be of a valid type",
+			violations: []Violation{
+				{Attribute: "name", Message: "cannot be empty"},
+				{Attribute: "state", Message: "must be of a valid type"},
+			},
+		},
+		vv.Err(),
+	)
+}
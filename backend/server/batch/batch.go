@@ -0,0 +1,121 @@
+// Package batch loads objects by their keys in batches, so that resolving
+// the same field of many objects does not issue a query per object.
+package batch
+
+import (
+	"context"
+	"sync"
+)
+
+// FetchFunc retrieves the values of the keys. Keys without a value are
+// left out of the result, e.g. when the objects do not exist.
+type FetchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Loader loads values by their keys. Keys are first primed, e.g. when the
+// parent objects are resolved, and all of the pending keys are fetched at
+// once by the first load that needs one of them. Fetched values are cached
+// for the lifetime of the loader, which should be a single request. It is
+// safe for concurrent use.
+type Loader[K comparable, V any] struct {
+	fetch FetchFunc[K, V]
+
+	mu      sync.Mutex
+	pending map[K]struct{}
+	values  map[K]V
+	missing map[K]struct{}
+}
+
+// NewLoader creates a new loader that fetches values with the function.
+func NewLoader[K comparable, V any](fetch FetchFunc[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:   fetch,
+		pending: make(map[K]struct{}),
+		values:  make(map[K]V),
+		missing: make(map[K]struct{}),
+	}
+}
+
+// Prime schedules the keys to be fetched by the next batch. Keys that
+// were already fetched are ignored.
+func (l *Loader[K, V]) Prime(keys ...K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if l.known(key) {
+			continue
+		}
+
+		l.pending[key] = struct{}{}
+	}
+}
+
+// Load retrieves the value of the key. All of the pending keys are
+// fetched together with it, unless it was already fetched. False is
+// returned if the key has no value.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.known(key) {
+		l.pending[key] = struct{}{}
+
+		if err := l.flush(ctx); err != nil {
+			var zero V
+			return zero, false, err
+		}
+	}
+
+	v, ok := l.values[key]
+
+	return v, ok, nil
+}
+
+// Store caches the value of the key, e.g. when it was retrieved by other
+// means.
+func (l *Loader[K, V]) Store(key K, v V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.pending, key)
+	delete(l.missing, key)
+	l.values[key] = v
+}
+
+// known checks whether the key was already fetched.
+func (l *Loader[K, V]) known(key K) bool {
+	if _, ok := l.values[key]; ok {
+		return true
+	}
+
+	_, ok := l.missing[key]
+
+	return ok
+}
+
+// flush fetches all of the pending keys. Keys that were not returned are
+// remembered as missing.
+func (l *Loader[K, V]) flush(ctx context.Context) error {
+	keys := make([]K, 0, len(l.pending))
+	for key := range l.pending {
+		keys = append(keys, key)
+	}
+
+	vv, err := l.fetch(ctx, keys)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		delete(l.pending, key)
+
+		if v, ok := vv[key]; ok {
+			l.values[key] = v
+			continue
+		}
+
+		l.missing[key] = struct{}{}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Loader(t *testing.T) {
+	var calls [][]int
+
+	l := NewLoader(func(_ context.Context, keys []int) (map[int]string, error) {
+		kk := append([]int(nil), keys...)
+		sort.Ints(kk)
+		calls = append(calls, kk)
+
+		vv := make(map[int]string)
+
+		for _, key := range keys {
+			if key%2 == 0 {
+				vv[key] = string(rune('a' + key))
+			}
+		}
+
+		return vv, nil
+	})
+
+	l.Prime(1, 2, 4)
+
+	v, ok, err := l.Load(context.Background(), 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "c", v)
+
+	_, ok, err = l.Load(context.Background(), 1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	v, ok, err = l.Load(context.Background(), 4)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "e", v)
+
+	l.Prime(2, 6)
+	l.Store(8, "x")
+
+	v, ok, err = l.Load(context.Background(), 8)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "x", v)
+
+	_, _, err = l.Load(context.Background(), 3)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]int{{1, 2, 4}, {3, 6}}, calls)
+}
+
+func Test_Loader_Concurrent(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+
+	l := NewLoader(func(_ context.Context, keys []int) (map[int]int, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+
+		vv := make(map[int]int, len(keys))
+		for _, key := range keys {
+			vv[key] = key * 10
+		}
+
+		return vv, nil
+	})
+
+	keys := make([]int, 100)
+	for i := range keys {
+		keys[i] = i
+	}
+
+	l.Prime(keys...)
+
+	var wg sync.WaitGroup
+
+	for _, key := range keys {
+		wg.Add(1)
+
+		go func(key int) {
+			defer wg.Done()
+
+			v, ok, err := l.Load(context.Background(), key)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, key*10, v)
+		}(key)
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 1, calls)
+}
+
+func Test_Loader_Error(t *testing.T) {
+	l := NewLoader(func(_ context.Context, keys []int) (map[int]int, error) {
+		return nil, errors.New("test")
+	})
+
+	_, ok, err := l.Load(context.Background(), 1)
+	assert.Error(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"fmt"
+	"foodie/core"
+	"foodie/db"
+	"foodie/server/apierr"
+	"foodie/server/ical"
+	"foodie/server/schemaorg"
+	"net/http"
+	"strings"
+
+	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
+)
+
+// _calendarProdID identifies the server in the iCalendar feeds.
+const _calendarProdID = "-//foodie//plans//EN"
+
+// GetPlanCalendar retrieves the plan as an iCalendar feed with a single
+// all-day event per scheduled recipe. Calendar apps cannot send the
+// authorization header, so the plan can also be retrieved on behalf of
+// its viewer by the secret calendar token in the token query parameter.
+// Recipes that are not visible to the viewer are omitted.
+func (s *Server) GetPlanCalendar(w http.ResponseWriter, r *http.Request) {
+	pid, aerr := s.extractPathID(r, "planID")
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	vw, aerr := s.extractCalendarViewer(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	pl, err := db.GetPlanByID(r.Context(), s.db, vw, pid)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	case db.ErrNotFound:
+		apierr.NotFound("plan").Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("fetching plan by id")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	cal := ical.Calendar{
+		ProdID: _calendarProdID,
+		Name:   pl.Name,
+		Events: make([]ical.Event, 0, len(pl.Recipes)),
+	}
+
+	for _, pr := range pl.Recipes {
+		day, ok := pr.Scheduled()
+		if !ok {
+			continue
+		}
+
+		rec, pp, aerr := s.fetchViewerRecipe(r, vw, pr.RecipeID)
+		if aerr != nil {
+			aerr.Respond(w)
+			return
+		}
+
+		if rec == nil {
+			// The recipe is not visible to the viewer.
+			continue
+		}
+
+		cal.Events = append(cal.Events, ical.Event{
+			UID:         pl.ID.String() + "-" + rec.ID.String() + "@foodie",
+			Stamp:       pl.UpdatedAt,
+			Day:         day,
+			Summary:     planRecipeSummary(*rec, pr),
+			Description: planRecipeDescription(*rec, pp, pr),
+		})
+	}
+
+	w.Header().Set("Content-Type", ical.ContentType)
+	w.Header().Set("Content-Disposition", `inline; filename="calendar.ics"`)
+
+	if _, err := cal.WriteTo(w); err != nil {
+		s.log.WithError(err).Error("writing to client response data")
+	}
+}
+
+// CreateCalendarToken generates a new secret calendar token of the user,
+// which replaces the previous one. The token is returned only once, since
+// only its hash is stored.
+func (s *Server) CreateCalendarToken(w http.ResponseWriter, r *http.Request) {
+	uid, aerr := s.extractContextUserID(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	token, hash, err := core.NewCalendarToken()
+	if err != nil {
+		s.log.WithError(err).Error("generating calendar token")
+		apierr.Internal().Respond(w)
+
+		return
+	}
+
+	err = db.UpdateUserCalendarTokenHashByID(r.Context(), s.db, uid, hash)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("updating user calendar token")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	s.respondJSON(w, struct {
+		Token string `json:"token"`
+	}{
+		Token: token,
+	})
+}
+
+// RevokeCalendarToken revokes the secret calendar token of the user, so
+// that calendar subscriptions stop working.
+func (s *Server) RevokeCalendarToken(w http.ResponseWriter, r *http.Request) {
+	uid, aerr := s.extractContextUserID(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	err := db.UpdateUserCalendarTokenHashByID(r.Context(), s.db, uid, "")
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("revoking user calendar token")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// extractCalendarViewer extracts the viewer of the calendar request. The
+// user that owns the calendar token is the viewer if the token query
+// parameter is provided. Calendar tokens never grant admin permissions.
+func (s *Server) extractCalendarViewer(r *http.Request) (core.Viewer, *apierr.Error) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		return s.extractViewer(r), nil
+	}
+
+	usr, err := db.GetUserByCalendarTokenHash(r.Context(), s.db, core.HashCalendarToken(token))
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		return core.Viewer{}, apierr.Context()
+	case db.ErrNotFound:
+		return core.Viewer{}, apierr.Unauthorized()
+	default:
+		s.log.WithError(err).Error("fetching user by calendar token")
+		return core.Viewer{}, apierr.Database()
+	}
+
+	return core.Viewer{UserID: usr.ID}, nil
+}
+
+// fetchViewerRecipe retrieves the recipe along with its products, both as
+// seen by the viewer. A nil recipe is returned if it is not visible.
+func (s *Server) fetchViewerRecipe(
+	r *http.Request,
+	vw core.Viewer,
+	rid xid.ID,
+) (*core.Recipe, []core.Product, *apierr.Error) {
+	rec, err := db.GetRecipeByID(r.Context(), s.db, vw, rid)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		return nil, nil, apierr.Context()
+	case db.ErrNotFound:
+		return nil, nil, nil
+	default:
+		s.log.WithError(err).Error("fetching recipe by id")
+		return nil, nil, apierr.Database()
+	}
+
+	pids := make([]xid.ID, 0, len(rec.Products))
+	for _, rp := range rec.Products {
+		pids = append(pids, rp.ProductID)
+	}
+
+	pp, err := db.GetProductsByIDs(r.Context(), s.db, vw, pids)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		return nil, nil, apierr.Context()
+	default:
+		s.log.WithError(err).Error("fetching products")
+		return nil, nil, apierr.Database()
+	}
+
+	return rec, pp, nil
+}
+
+// planRecipeSummary returns the title of the plan recipe event.
+func planRecipeSummary(rec core.Recipe, pr core.PlanRecipe) string {
+	if pr.Quantity > 1 {
+		return fmt.Sprintf("%s ×%d", rec.Name, pr.Quantity)
+	}
+
+	return rec.Name
+}
+
+// planRecipeDescription returns the details of the plan recipe event: the
+// recipe description, the ingredients and the calories, both multiplied by
+// the quantity of the recipe in the plan.
+func planRecipeDescription(rec core.Recipe, pp []core.Product, pr core.PlanRecipe) string {
+	qty := decimal.NewFromInt(int64(pr.Quantity))
+	cal := decimal.Zero
+
+	lines := []string{rec.Description, "", "Ingredients:"}
+
+	for _, rp := range rec.Products {
+		prd, ok := rp.FindMatching(pp)
+		if !ok {
+			continue
+		}
+
+		lines = append(lines, "- "+schemaorg.FormatIngredient(
+			rp.Amount(prd).Mul(qty),
+			prd.Serving.Type.Unit(),
+			prd.Name,
+		))
+		cal = cal.Add(rp.Calories(prd).Mul(qty))
+	}
+
+	lines = append(lines, "", "Calories: "+cal.Round(0).String())
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"foodie/core"
+	"foodie/db"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/rs/xid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Server_GetPlanCalendar(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	rec, other := insertSharedRecipe(t, dbh)
+
+	pl, err := db.InsertPlan(context.Background(), dbh, rec.UserID, core.PlanCore{
+		Name:        "Week",
+		Description: "Meals of the week.",
+		Visibility:  core.VisibilityPublic,
+		State:       core.StatePublished,
+		Recipes: []core.PlanRecipe{{
+			RecipeID: rec.ID,
+			Quantity: 1,
+			Day:      "2026-10-19",
+		}},
+	})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		UserID xid.ID
+		Secret bool
+	}{
+		"Guest": {},
+		"Another user": {
+			UserID: other,
+		},
+		"Plan owner": {
+			UserID: rec.UserID,
+			Secret: true,
+		},
+	}
+
+	for tName, tCase := range tests {
+		t.Run(tName, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("planID", pl.ID.String())
+
+			ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+			if !tCase.UserID.IsNil() {
+				ctx = withViewer(ctx, tCase.UserID, false)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "http://test.com/api/plans/"+pl.ID.String()+"/calendar.ics", nil)
+			req = req.WithContext(ctx)
+			resp := httptest.NewRecorder()
+
+			s := &Server{log: logrus.New(), db: dbh}
+			s.GetPlanCalendar(resp, req)
+
+			require.Equal(t, http.StatusOK, resp.Code)
+
+			// Long lines are folded, so they must be unfolded first.
+			body := strings.ReplaceAll(resp.Body.String(), "\r\n ", "")
+			assert.Contains(t, body, "Oats")
+			assert.Equal(t, tCase.Secret, strings.Contains(body, "Secret"))
+		})
+	}
+}
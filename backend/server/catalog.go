@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"foodie/core"
+	"foodie/db"
+	"foodie/server/apierr"
+	"foodie/server/catalog"
+	"io"
+	"net/http"
+
+	"github.com/rs/xid"
+)
+
+// _importBatchSize specifies how many imported products are written to
+// the database in a single transaction. Dry runs are not split into
+// batches.
+const _importBatchSize = 500
+
+// ImportProducts creates or updates products from a CSV or JSON lines
+// document provided in the request body. The document is read row by row
+// and every valid row is upserted by its barcode or name in batches, each
+// of which is committed separately. Invalid rows are skipped and reported.
+// If the dry_run query parameter is true, all rows are upserted in a
+// single transaction, so that later rows see the earlier ones as they
+// would in a real import, and the changes are discarded.
+func (s *Server) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	uid, aerr := s.extractContextUserID(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	f, ok := catalog.FormatOf(r.Header.Get("Content-Type"))
+	if !ok {
+		apierr.BadRequest("content type must be text/csv or application/x-ndjson").Respond(w)
+		return
+	}
+
+	dry, aerr := extractDryRun(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	rd, err := catalog.NewReader(f, r.Body)
+	switch {
+	case err == nil:
+		// OK.
+	case errors.Is(err, catalog.ErrNoNameColumn):
+		apierr.BadRequest("csv " + err.Error()).Respond(w)
+		return
+	default:
+		apierr.MalformedDataInput(apierr.DataTypeRequestBody).Respond(w)
+		return
+	}
+
+	rep := core.ProductImport{
+		DryRun: dry,
+		Errors: make([]core.ProductImportError, 0),
+	}
+
+	var (
+		batch []core.ProductCore
+		rows  []int
+	)
+
+	for {
+		row, pc, err := rd.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var rerr *catalog.RowError
+
+		switch {
+		case err == nil:
+			// OK.
+		case errors.As(err, &rerr):
+			rep.Fail(core.ProductImportError{
+				Row:     rerr.Row,
+				Message: rerr.Message,
+				Errors:  rerr.Violations,
+			})
+
+			continue
+		default:
+			apierr.MalformedDataInput(apierr.DataTypeRequestBody).Respond(w)
+			return
+		}
+
+		if aerr := pc.Validate(); aerr != nil {
+			rep.Fail(core.ProductImportError{
+				Row:    row,
+				Errors: aerr.Violations(),
+			})
+
+			continue
+		}
+
+		batch = append(batch, pc)
+		rows = append(rows, row)
+
+		if dry || len(batch) < _importBatchSize {
+			continue
+		}
+
+		if aerr := s.importProducts(r.Context(), uid, &rep, batch, rows); aerr != nil {
+			aerr.Respond(w)
+			return
+		}
+
+		batch, rows = batch[:0], rows[:0]
+	}
+
+	if len(batch) > 0 {
+		if aerr := s.importProducts(r.Context(), uid, &rep, batch, rows); aerr != nil {
+			aerr.Respond(w)
+			return
+		}
+	}
+
+	s.respondJSON(w, rep)
+}
+
+// importProducts upserts a single batch of imported products and adds
+// the results to the report. The suggestion index is updated and webhook
+// events are emitted unless the import is a dry run.
+func (s *Server) importProducts(
+	ctx context.Context,
+	uid xid.ID,
+	rep *core.ProductImport,
+	pcs []core.ProductCore,
+	rows []int,
+) *apierr.Error {
+	ipp, err := db.ImportProducts(ctx, s.db, uid, pcs, rep.DryRun)
+	switch err {
+	case nil:
+		// OK.
+	case ctx.Err():
+		return apierr.Context()
+	case db.ErrDuplicateBarcode:
+		return apierr.Conflict("product", "barcode is used by another product")
+	default:
+		s.log.WithError(err).Error("importing products")
+		return apierr.Database()
+	}
+
+	for i, ip := range ipp {
+		rep.Add(rows[i], ip)
+
+		if rep.DryRun || ip.Product == nil {
+			continue
+		}
+
+		switch ip.Action {
+		case core.ProductImportCreated:
+			s.indexProduct(*ip.Product)
+			s.emitProduct(core.EventProductCreated, *ip.Product)
+		case core.ProductImportUpdated:
+			s.indexProduct(*ip.Product)
+			s.emitProduct(core.EventProductUpdated, *ip.Product)
+		}
+	}
+
+	return nil
+}
+
+// ExportProducts streams all products that were not moved to the trash,
+// regardless of their status, as a CSV or JSON lines document selected
+// by the format query parameter. JSON lines are exported by default.
+func (s *Server) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	f := catalog.FormatJSONLines
+
+	if v := r.URL.Query().Get("format"); v != "" {
+		var ok bool
+
+		f, ok = catalog.ParseFormat(v)
+		if !ok {
+			apierr.InvalidAttribute("format", "must be csv or jsonl").Respond(w)
+			return
+		}
+	}
+
+	wr, err := catalog.NewWriter(f, w)
+	if err != nil {
+		s.log.WithError(err).Error("creating a catalog writer")
+		apierr.Internal().Respond(w)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", f.ContentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="products.`+string(f)+`"`)
+
+	var written bool
+
+	err = db.EachProduct(r.Context(), s.db, func(prd core.Product) error {
+		written = true
+		return wr.Write(prd)
+	})
+	if err == nil {
+		err = wr.Flush()
+	}
+
+	switch {
+	case err == nil:
+		// OK.
+	case written:
+		// The response might have already been started, so the error
+		// can only be logged.
+		s.log.WithError(err).Error("exporting products")
+	case err == r.Context().Err():
+		w.Header().Del("Content-Disposition")
+		apierr.Context().Respond(w)
+	default:
+		s.log.WithError(err).Error("exporting products")
+		w.Header().Del("Content-Disposition")
+		apierr.Database().Respond(w)
+	}
+}
@@ -0,0 +1,335 @@
+// Package catalog reads and writes product catalogs in the CSV and JSON
+// lines formats, so that products can be imported and exported in bulk.
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"foodie/core"
+	"foodie/server/apierr"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Format specifies the format of a product catalog.
+type Format string
+
+const (
+	// FormatCSV specifies a CSV document with a header row.
+	FormatCSV Format = "csv"
+
+	// FormatJSONLines specifies a document with a single JSON object per
+	// line.
+	FormatJSONLines Format = "jsonl"
+)
+
+// ErrNoNameColumn is returned whenever the header of a CSV document does
+// not contain the name column.
+var ErrNoNameColumn = errors.New("header must contain the name column")
+
+// _columns specifies the columns of exported CSV documents. Columns after
+// serving_calories are ignored during imports.
+var _columns = []string{
+	"name",
+	"aliases",
+	"barcode",
+	"image_url",
+	"description",
+	"serving_type",
+	"serving_size",
+	"serving_calories",
+	"id",
+	"status",
+	"created_at",
+}
+
+// ParseFormat parses the format by its name.
+func ParseFormat(v string) (Format, bool) {
+	switch f := Format(strings.ToLower(v)); f {
+	case FormatCSV, FormatJSONLines:
+		return f, true
+	default:
+		return "", false
+	}
+}
+
+// FormatOf returns the format of the media type, e.g. of the Content-Type
+// header.
+func FormatOf(mediaType string) (Format, bool) {
+	mt, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return "", false
+	}
+
+	switch mt {
+	case "text/csv":
+		return FormatCSV, true
+	case "application/x-ndjson", "application/jsonl", "application/json-lines":
+		return FormatJSONLines, true
+	default:
+		return "", false
+	}
+}
+
+// ContentType returns the media type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// RowError describes a row that could not be read. Reading can continue
+// with the next row.
+type RowError struct {
+	// Row specifies the line of the document at which the row starts.
+	Row int
+
+	// Message describes why the row could not be read.
+	Message string
+
+	// Violations contains the values of the row that could not be parsed.
+	Violations apierr.Violations
+}
+
+// Error returns the description of the row error.
+func (e *RowError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+	}
+
+	mm := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		mm = append(mm, fmt.Sprintf("%s: %s", v.Attribute, v.Message))
+	}
+
+	return fmt.Sprintf("row %d: %s", e.Row, strings.Join(mm, "; "))
+}
+
+// Reader reads product cores from a catalog one row at a time.
+type Reader interface {
+	// Read reads the next product core along with the line at which its
+	// row starts. io.EOF is returned once there are no more rows, and a
+	// *RowError if the row is invalid.
+	Read() (int, core.ProductCore, error)
+}
+
+// NewReader creates a reader of the catalog in the provided format. The
+// header of CSV documents is read immediately.
+func NewReader(f Format, r io.Reader) (Reader, error) {
+	if f == FormatCSV {
+		return newCSVReader(r)
+	}
+
+	return &jsonReader{r: bufio.NewReader(r)}, nil
+}
+
+// csvReader reads product cores from a CSV document. Columns are matched
+// by their names in the header, so their order does not matter and
+// unknown columns are ignored.
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+// newCSVReader creates a reader of the CSV document and reads its header.
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if _, ok := columns["name"]; !ok {
+		return nil, ErrNoNameColumn
+	}
+
+	return &csvReader{r: cr, columns: columns}, nil
+}
+
+// Read reads the next product core. Aliases are separated by line breaks
+// within a single quoted value.
+func (cr *csvReader) Read() (int, core.ProductCore, error) {
+	rec, err := cr.r.Read()
+	if err != nil {
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			return perr.StartLine, core.ProductCore{}, &RowError{
+				Row:     perr.StartLine,
+				Message: perr.Err.Error(),
+			}
+		}
+
+		return 0, core.ProductCore{}, err
+	}
+
+	row, _ := cr.r.FieldPos(0)
+
+	value := func(name string) string {
+		i, ok := cr.columns[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+
+		return strings.TrimSpace(rec[i])
+	}
+
+	pc := core.ProductCore{
+		Name:        value("name"),
+		Barcode:     value("barcode"),
+		ImageURL:    value("image_url"),
+		Description: value("description"),
+		Serving: core.Serving{
+			Type: core.ServingType(value("serving_type")),
+		},
+	}
+
+	if v := value("aliases"); v != "" {
+		pc.Aliases = strings.Split(strings.ReplaceAll(v, "\r\n", "\n"), "\n")
+	}
+
+	var vv apierr.Violations
+
+	if v := value("serving_size"); v != "" {
+		size, err := decimal.NewFromString(v)
+		if err != nil {
+			vv.Add("serving_size", "must be a decimal number")
+		}
+
+		pc.Serving.Size = size
+	}
+
+	if v := value("serving_calories"); v != "" {
+		cal, err := strconv.Atoi(v)
+		if err != nil {
+			vv.Add("serving_calories", "must be an integer")
+		}
+
+		pc.Serving.Calories = cal
+	}
+
+	if len(vv) > 0 {
+		return row, core.ProductCore{}, &RowError{Row: row, Violations: vv}
+	}
+
+	return row, pc, nil
+}
+
+// jsonReader reads product cores from a JSON lines document. Blank lines
+// are skipped.
+type jsonReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+// Read reads the next product core.
+func (jr *jsonReader) Read() (int, core.ProductCore, error) {
+	for {
+		data, err := jr.r.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(data) == 0) {
+			return 0, core.ProductCore{}, err
+		}
+
+		jr.line++
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+
+		var pc core.ProductCore
+		if err := json.Unmarshal(data, &pc); err != nil {
+			return jr.line, core.ProductCore{}, &RowError{
+				Row:     jr.line,
+				Message: "malformed json",
+			}
+		}
+
+		return jr.line, pc, nil
+	}
+}
+
+// Writer writes products to a catalog one at a time.
+type Writer interface {
+	// Write writes a single product.
+	Write(prd core.Product) error
+
+	// Flush writes any buffered data to the underlying writer.
+	Flush() error
+}
+
+// NewWriter creates a writer of the catalog in the provided format. The
+// header of CSV documents is written immediately.
+func NewWriter(f Format, w io.Writer) (Writer, error) {
+	if f == FormatCSV {
+		cw := csv.NewWriter(w)
+		if err := cw.Write(_columns); err != nil {
+			return nil, err
+		}
+
+		return &csvWriter{w: cw}, nil
+	}
+
+	return &jsonWriter{enc: json.NewEncoder(w)}, nil
+}
+
+// csvWriter writes products to a CSV document.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// Write writes a single product as a CSV row.
+func (cw *csvWriter) Write(prd core.Product) error {
+	return cw.w.Write([]string{
+		prd.Name,
+		strings.Join(prd.Aliases, "\n"),
+		prd.Barcode,
+		prd.ImageURL,
+		prd.Description,
+		string(prd.Serving.Type),
+		prd.Serving.Size.String(),
+		strconv.Itoa(prd.Serving.Calories),
+		prd.ID.String(),
+		string(prd.Status),
+		prd.CreatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// Flush writes the buffered rows to the underlying writer.
+func (cw *csvWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// jsonWriter writes products to a JSON lines document.
+type jsonWriter struct {
+	enc *json.Encoder
+}
+
+// Write writes a single product as a JSON line.
+func (jw *jsonWriter) Write(prd core.Product) error {
+	return jw.enc.Encode(prd)
+}
+
+// Flush does nothing, since products are written immediately.
+func (jw *jsonWriter) Flush() error {
+	return nil
+}
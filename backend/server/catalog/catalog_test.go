@@ -0,0 +1,276 @@
+package catalog
+
+import (
+	"bytes"
+	"foodie/core"
+	"foodie/server/apierr"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type readResult struct {
+	Row     int
+	Product core.ProductCore
+	Error   error
+}
+
+func readAll(t *testing.T, rd Reader) []readResult {
+	t.Helper()
+
+	var res []readResult
+
+	for {
+		row, pc, err := rd.Read()
+		if err == io.EOF {
+			return res
+		}
+
+		if _, ok := err.(*RowError); !ok {
+			require.NoError(t, err)
+		}
+
+		res = append(res, readResult{Row: row, Product: pc, Error: err})
+	}
+}
+
+func Test_FormatOf(t *testing.T) {
+	tests := map[string]struct {
+		MediaType string
+		Format    Format
+		OK        bool
+	}{
+		"CSV": {
+			MediaType: "text/csv; charset=utf-8",
+			Format:    FormatCSV,
+			OK:        true,
+		},
+		"JSON lines": {
+			MediaType: "application/x-ndjson",
+			Format:    FormatJSONLines,
+			OK:        true,
+		},
+		"Unsupported media type": {
+			MediaType: "application/json",
+		},
+		"Invalid media type": {
+			MediaType: ";",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f, ok := FormatOf(test.MediaType)
+			assert.Equal(t, test.OK, ok)
+			assert.Equal(t, test.Format, f)
+		})
+	}
+}
+
+func Test_NewReader_CSV(t *testing.T) {
+	tests := map[string]struct {
+		Data   string
+		Error  error
+		Result []readResult
+	}{
+		"Header without the name column": {
+			Data:  "description\nabc\n",
+			Error: ErrNoNameColumn,
+		},
+		"Valid rows in any column order": {
+			Data: "serving_calories,Name,serving_size,serving_type,description,aliases,barcode,unknown\n" +
+				"105,Banana,1,units,Fruit,\"Plantain\nBanan\",4011,x\n" +
+				"0,Salt,100,grams,Salt,,,\n",
+			Result: []readResult{
+				{
+					Row: 2,
+					Product: core.ProductCore{
+						Name:        "Banana",
+						Aliases:     []string{"Plantain", "Banan"},
+						Barcode:     "4011",
+						Description: "Fruit",
+						Serving: core.Serving{
+							Type:     core.ServingTypeUnits,
+							Size:     decimal.NewFromInt(1),
+							Calories: 105,
+						},
+					},
+				},
+				{
+					Row: 4,
+					Product: core.ProductCore{
+						Name:        "Salt",
+						Description: "Salt",
+						Serving: core.Serving{
+							Type:     core.ServingTypeGrams,
+							Size:     decimal.NewFromInt(100),
+							Calories: 0,
+						},
+					},
+				},
+			},
+		},
+		"Invalid values": {
+			Data: "name,serving_size,serving_calories\n" +
+				"Banana,one,many\n" +
+				"Apple,1,95\n",
+			Result: []readResult{
+				{
+					Row: 2,
+					Error: &RowError{
+						Row: 2,
+						Violations: apierr.Violations{
+							{Attribute: "serving_size", Message: "must be a decimal number"},
+							{Attribute: "serving_calories", Message: "must be an integer"},
+						},
+					},
+				},
+				{
+					Row: 3,
+					Product: core.ProductCore{
+						Name: "Apple",
+						Serving: core.Serving{
+							Size:     decimal.NewFromInt(1),
+							Calories: 95,
+						},
+					},
+				},
+			},
+		},
+		"Malformed row": {
+			Data: "name,description\n" +
+				"Ba\"nana,Fruit\n" +
+				"Apple,Fruit\n",
+			Result: []readResult{
+				{
+					Row: 2,
+					Error: &RowError{
+						Row:     2,
+						Message: "bare \" in non-quoted-field",
+					},
+				},
+				{
+					Row: 3,
+					Product: core.ProductCore{
+						Name:        "Apple",
+						Description: "Fruit",
+					},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rd, err := NewReader(FormatCSV, strings.NewReader(test.Data))
+			if test.Error != nil {
+				assert.Equal(t, test.Error, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.Result, readAll(t, rd))
+		})
+	}
+}
+
+func Test_NewReader_JSONLines(t *testing.T) {
+	data := "{\"name\":\"Banana\",\"barcode\":\"4011\",\"serving\":{\"type\":\"units\",\"size\":1,\"calories\":105}}\n" +
+		"\n" +
+		"{\"name\":\n" +
+		"{\"name\":\"Salt\",\"id\":\"cceqj5n6i1e7hgou9lv0\"}"
+
+	rd, err := NewReader(FormatJSONLines, strings.NewReader(data))
+	require.NoError(t, err)
+
+	assert.Equal(t, []readResult{
+		{
+			Row: 1,
+			Product: core.ProductCore{
+				Name:    "Banana",
+				Barcode: "4011",
+				Serving: core.Serving{
+					Type:     core.ServingTypeUnits,
+					Size:     decimal.NewFromInt(1),
+					Calories: 105,
+				},
+			},
+		},
+		{
+			Row: 3,
+			Error: &RowError{
+				Row:     3,
+				Message: "malformed json",
+			},
+		},
+		{
+			Row: 4,
+			Product: core.ProductCore{
+				Name: "Salt",
+			},
+		},
+	}, readAll(t, rd))
+}
+
+func Test_NewWriter(t *testing.T) {
+	prd := core.Product{
+		ID:     xid.New(),
+		Status: core.ProductStatusApproved,
+		ProductCore: core.ProductCore{
+			Name:        "Banana",
+			Aliases:     []string{"Plantain", "Banan"},
+			Barcode:     "4011",
+			Description: "Curved, yellow fruit",
+			Serving: core.Serving{
+				Type:     core.ServingTypeUnits,
+				Size:     decimal.NewFromInt(1),
+				Calories: 105,
+			},
+		},
+		CreatedAt: time.Date(2022, 9, 15, 23, 52, 10, 0, time.UTC),
+	}
+
+	for _, f := range []Format{FormatCSV, FormatJSONLines} {
+		f := f
+
+		t.Run(string(f), func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			wr, err := NewWriter(f, &buf)
+			require.NoError(t, err)
+			require.NoError(t, wr.Write(prd))
+			require.NoError(t, wr.Flush())
+
+			rd, err := NewReader(f, &buf)
+			require.NoError(t, err)
+
+			res := readAll(t, rd)
+			require.Len(t, res, 1)
+			require.NoError(t, res[0].Error)
+
+			assert.Equal(t, prd.Name, res[0].Product.Name)
+			assert.Equal(t, prd.Aliases, res[0].Product.Aliases)
+			assert.Equal(t, prd.Barcode, res[0].Product.Barcode)
+			assert.Equal(t, prd.Description, res[0].Product.Description)
+			assert.Equal(t, prd.Serving.Type, res[0].Product.Serving.Type)
+			assert.True(t, prd.Serving.Size.Equal(res[0].Product.Serving.Size))
+			assert.Equal(t, prd.Serving.Calories, res[0].Product.Serving.Calories)
+		})
+	}
+}
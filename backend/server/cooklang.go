@@ -0,0 +1,77 @@
+package server
+
+import (
+	"bytes"
+	"foodie/server/apierr"
+	"foodie/server/cooklang"
+	"net/http"
+)
+
+// GetRecipeCooklang retrieves a single recipe by its id as a Cooklang
+// document.
+func (s *Server) GetRecipeCooklang(w http.ResponseWriter, r *http.Request) {
+	rec, pp, ok := s.fetchRecipeWithProducts(w, r)
+	if !ok {
+		return
+	}
+
+	cr := cooklang.NewRecipe(rec, pp)
+
+	w.Header().Set("Content-Type", cooklang.ContentType)
+
+	if _, err := w.Write([]byte(cr.String())); err != nil {
+		s.log.WithError(err).Error("writing to client response data")
+	}
+}
+
+// ImportRecipeCooklang creates a recipe from a Cooklang document, which
+// is provided either as the request body or as the file field of a
+// multipart form. The title metadata is used as the name of the recipe,
+// or the name of the uploaded file if there is none. Ingredients are
+// matched to products by their names, and the ones that could not be
+// matched are reported. The recipe is created as a private draft, so that
+// it could be reviewed. If the dry_run query parameter is true, the recipe
+// is not saved.
+func (s *Server) ImportRecipeCooklang(w http.ResponseWriter, r *http.Request) {
+	uid, aerr := s.extractContextUserID(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	dry, aerr := extractDryRun(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	doc, aerr := extractDocument(w, r, map[string]string{
+		".cook": "text/plain",
+	})
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	switch doc.MediaType {
+	case "text/plain", "text/x-cooklang":
+		// OK.
+	default:
+		apierr.BadRequest("content type must be text/plain").Respond(w)
+		return
+	}
+
+	cr, err := cooklang.Parse(bytes.NewReader(doc.Data))
+	if err != nil {
+		apierr.MalformedDataInput(apierr.DataTypeRequestBody).Respond(w)
+		return
+	}
+
+	res, aerr := s.importRecipe(r.Context(), uid, cr.Core(doc.Name), cr.Ingredients(), dry)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	s.respondJSON(w, res)
+}
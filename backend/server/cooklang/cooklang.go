@@ -0,0 +1,470 @@
+// Package cooklang parses and serializes recipes written in the Cooklang
+// markup language (https://cooklang.org) and converts them to and from
+// recipe cores.
+package cooklang
+
+import (
+	"bufio"
+	"foodie/core"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+// ContentType specifies the media type of Cooklang documents.
+const ContentType = "text/plain; charset=utf-8"
+
+// ItemType specifies the type of a step item.
+type ItemType string
+
+const (
+	// ItemText specifies plain text.
+	ItemText ItemType = "text"
+
+	// ItemIngredient specifies an ingredient, e.g. @salt or
+	// @chicken breast{250%g}.
+	ItemIngredient ItemType = "ingredient"
+
+	// ItemCookware specifies a cookware, e.g. #pot or #frying pan{}.
+	ItemCookware ItemType = "cookware"
+
+	// ItemTimer specifies a timer, e.g. ~{25%minutes} or ~rest{5%min}.
+	ItemTimer ItemType = "timer"
+)
+
+// _markers maps the markers of step items to their types.
+var _markers = map[rune]ItemType{
+	'@': ItemIngredient,
+	'#': ItemCookware,
+	'~': ItemTimer,
+}
+
+// Recipe is a Cooklang recipe.
+type Recipe struct {
+	// Metadata contains the metadata of the recipe in the order in which
+	// it was written.
+	Metadata []Metadata
+
+	// Steps contains the steps of the recipe.
+	Steps []Step
+}
+
+// Metadata is a single metadata entry, e.g. ">> servings: 2".
+type Metadata struct {
+	// Key specifies the name of the entry.
+	Key string
+
+	// Value specifies the value of the entry.
+	Value string
+}
+
+// Step is a single paragraph of the recipe method.
+type Step struct {
+	// Items contains the text, ingredients, cookware and timers of the
+	// step in the order in which they were written.
+	Items []Item
+}
+
+// Item is a single part of a step.
+type Item struct {
+	// Type specifies the type of the item.
+	Type ItemType
+
+	// Name specifies the name of the item, or the text itself for text
+	// items.
+	Name string
+
+	// Quantity specifies the quantity of the item as it was written, e.g.
+	// "1/2". It is empty if the quantity was not provided.
+	Quantity string
+
+	// Unit specifies the unit of the quantity.
+	Unit string
+}
+
+// String returns the Cooklang markup of the item.
+func (it Item) String() string {
+	var marker string
+
+	switch it.Type {
+	case ItemIngredient:
+		marker = "@"
+	case ItemCookware:
+		marker = "#"
+	case ItemTimer:
+		marker = "~"
+	default:
+		return it.Name
+	}
+
+	if it.Quantity == "" && it.Unit == "" && it.Name != "" && isWord(it.Name) {
+		return marker + it.Name
+	}
+
+	amount := it.Quantity
+	if it.Unit != "" {
+		amount += "%" + it.Unit
+	}
+
+	return marker + it.Name + "{" + amount + "}"
+}
+
+// Amount returns the quantity of the item as a number. False is returned
+// if the quantity was not provided or is not a number, e.g. "some".
+func (it Item) Amount() (decimal.Decimal, bool) {
+	q := strings.TrimSpace(it.Quantity)
+	if q == "" {
+		return decimal.Zero, false
+	}
+
+	if num, den, ok := strings.Cut(q, "/"); ok {
+		n, err := decimal.NewFromString(strings.TrimSpace(num))
+		if err != nil {
+			return decimal.Zero, false
+		}
+
+		d, err := decimal.NewFromString(strings.TrimSpace(den))
+		if err != nil || d.IsZero() {
+			return decimal.Zero, false
+		}
+
+		return n.DivRound(d, 4), true
+	}
+
+	d, err := decimal.NewFromString(strings.Replace(q, ",", ".", 1))
+	if err != nil {
+		return decimal.Zero, false
+	}
+
+	return d, true
+}
+
+// Text returns the step as plain text, without the markup.
+func (st Step) Text() string {
+	var sb strings.Builder
+
+	for _, it := range st.Items {
+		switch {
+		case it.Type == ItemText, it.Name != "":
+			sb.WriteString(it.Name)
+		default:
+			sb.WriteString(strings.TrimSpace(it.Quantity + " " + it.Unit))
+		}
+	}
+
+	return sb.String()
+}
+
+// Value returns the value of the first metadata entry with the key. The
+// keys are compared case-insensitively.
+func (r *Recipe) Value(key string) string {
+	for _, md := range r.Metadata {
+		if strings.EqualFold(md.Key, key) {
+			return md.Value
+		}
+	}
+
+	return ""
+}
+
+// Items returns all items of the type from all steps.
+func (r *Recipe) Items(t ItemType) []Item {
+	var ii []Item
+
+	for _, st := range r.Steps {
+		for _, it := range st.Items {
+			if it.Type == t {
+				ii = append(ii, it)
+			}
+		}
+	}
+
+	return ii
+}
+
+// String returns the Cooklang markup of the recipe. Metadata is written
+// first, followed by the steps separated by blank lines.
+func (r *Recipe) String() string {
+	var sb strings.Builder
+
+	for _, md := range r.Metadata {
+		sb.WriteString(">> " + md.Key + ": " + md.Value + "\n")
+	}
+
+	for i, st := range r.Steps {
+		if i > 0 || len(r.Metadata) > 0 {
+			sb.WriteString("\n")
+		}
+
+		for _, it := range st.Items {
+			sb.WriteString(it.String())
+		}
+
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+var (
+	// _blockComment matches block comments, which can span multiple
+	// lines.
+	_blockComment = regexp.MustCompile(`(?s)\[-.*?-\]`)
+
+	// _metadata matches metadata lines.
+	_metadata = regexp.MustCompile(`^>>\s*([^:]+?)\s*:\s*(.*?)\s*$`)
+)
+
+// Parse parses the Cooklang document. Comments are left out, metadata
+// lines are collected, and every paragraph of the remaining lines becomes
+// a step.
+func Parse(r io.Reader) (*Recipe, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &Recipe{}
+
+	var para []string
+
+	flush := func() {
+		if len(para) > 0 {
+			rec.Steps = append(rec.Steps, parseStep(strings.Join(para, "\n")))
+			para = nil
+		}
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(_blockComment.ReplaceAllString(string(data), "")))
+
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.Index(line, "--"); i >= 0 {
+			line = line[:i]
+		}
+
+		line = strings.TrimSpace(line)
+
+		if m := _metadata.FindStringSubmatch(line); m != nil {
+			rec.Metadata = append(rec.Metadata, Metadata{Key: m[1], Value: m[2]})
+			continue
+		}
+
+		if line == "" {
+			flush()
+			continue
+		}
+
+		para = append(para, line)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	flush()
+
+	return rec, nil
+}
+
+// parseStep parses the items of a single step.
+func parseStep(text string) Step {
+	var (
+		st   Step
+		prev int
+	)
+
+	rr := []rune(text)
+
+	for i := 0; i < len(rr); i++ {
+		t, ok := _markers[rr[i]]
+		if !ok {
+			continue
+		}
+
+		it, n := parseItem(t, rr[i+1:])
+		if n == 0 {
+			continue
+		}
+
+		if prev < i {
+			st.Items = append(st.Items, Item{Type: ItemText, Name: string(rr[prev:i])})
+		}
+
+		st.Items = append(st.Items, it)
+		i += n
+		prev = i + 1
+	}
+
+	if prev < len(rr) {
+		st.Items = append(st.Items, Item{Type: ItemText, Name: string(rr[prev:])})
+	}
+
+	return st
+}
+
+// parseItem parses the item that follows its marker. Names that contain
+// multiple words must be terminated by braces, while single word names
+// can be written without them. The number of runes consumed is returned
+// along with the item, which is zero if the marker does not start an
+// item.
+func parseItem(t ItemType, rr []rune) (Item, int) {
+	it := Item{Type: t}
+
+	// Look for braces that terminate a name of multiple words. The name
+	// cannot contain other markers or line breaks.
+	for i, r := range rr {
+		if _, ok := _markers[r]; ok || r == '}' || r == '\n' {
+			break
+		}
+
+		if r != '{' {
+			continue
+		}
+
+		end := strings.IndexRune(string(rr[i+1:]), '}')
+		if end < 0 {
+			break
+		}
+
+		name := strings.TrimSpace(string(rr[:i]))
+		if name == "" && t != ItemTimer {
+			break
+		}
+
+		body := []rune(string(rr[i+1:])[:end])
+		it.Name = name
+		it.Quantity, it.Unit, _ = strings.Cut(string(body), "%")
+		it.Quantity = strings.TrimSpace(it.Quantity)
+		it.Unit = strings.TrimSpace(it.Unit)
+
+		return it, i + 1 + len(body) + 1
+	}
+
+	n := 0
+	for n < len(rr) && isWordRune(rr[n]) {
+		n++
+	}
+
+	if n == 0 {
+		return Item{}, 0
+	}
+
+	it.Name = string(rr[:n])
+
+	return it, n
+}
+
+// isWord checks whether the text can be written as a name without
+// braces.
+func isWord(text string) bool {
+	for _, r := range text {
+		if !isWordRune(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isWordRune checks whether the rune can be a part of a single word name.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-'
+}
+
+// NewRecipe creates a Cooklang recipe of the recipe. The name,
+// description and image are written as metadata, and every product is
+// written as an ingredient on a separate line of a single step, with its
+// amount measured in the units of its serving type. Products that are
+// not provided are left out.
+func NewRecipe(rec core.Recipe, pp []core.Product) Recipe {
+	cr := Recipe{
+		Metadata: []Metadata{
+			{Key: "title", Value: singleLine(rec.Name)},
+			{Key: "description", Value: singleLine(rec.Description)},
+		},
+	}
+
+	if rec.ImageURL != "" {
+		cr.Metadata = append(cr.Metadata, Metadata{Key: "image", Value: singleLine(rec.ImageURL)})
+	}
+
+	var st Step
+
+	for _, rp := range rec.Products {
+		prd, ok := rp.FindMatching(pp)
+		if !ok {
+			continue
+		}
+
+		if len(st.Items) > 0 {
+			st.Items = append(st.Items, Item{Type: ItemText, Name: "\n"})
+		}
+
+		st.Items = append(st.Items, Item{
+			Type:     ItemIngredient,
+			Name:     prd.Name,
+			Quantity: rp.Amount(prd).String(),
+			Unit:     prd.Serving.Type.Unit(),
+		})
+	}
+
+	if len(st.Items) > 0 {
+		cr.Steps = append(cr.Steps, st)
+	}
+
+	return cr
+}
+
+// singleLine replaces the line breaks of the text with spaces, since
+// metadata values cannot span multiple lines.
+func singleLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Core returns the recipe core of the Cooklang recipe. The name is taken
+// from the title metadata, or from the provided fallback name if there is
+// none, e.g. from the name of the file. The name is also used as the
+// description if there is none, and the recipe is a private draft.
+func (r *Recipe) Core(fallback string) core.RecipeCore {
+	rc := core.RecipeCore{
+		Name:        strings.TrimSpace(r.Value("title")),
+		ImageURL:    strings.TrimSpace(r.Value("image")),
+		Description: strings.TrimSpace(r.Value("description")),
+		Visibility:  core.VisibilityPrivate,
+		State:       core.StateDraft,
+	}
+
+	if rc.Name == "" {
+		rc.Name = strings.TrimSpace(fallback)
+	}
+
+	if rc.Description == "" {
+		rc.Description = rc.Name
+	}
+
+	return rc
+}
+
+// Ingredients returns the ingredients of all steps. Cookware and timers
+// have no counterparts in recipe cores, so they are left out.
+func (r *Recipe) Ingredients() []core.Ingredient {
+	ii := r.Items(ItemIngredient)
+	res := make([]core.Ingredient, 0, len(ii))
+
+	for _, it := range ii {
+		amount, _ := it.Amount()
+
+		res = append(res, core.Ingredient{
+			Text:   it.String(),
+			Name:   it.Name,
+			Amount: amount,
+			Unit:   it.Unit,
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,225 @@
+package cooklang
+
+import (
+	"foodie/core"
+	"strings"
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Parse(t *testing.T) {
+	doc := `>> title: Pancakes
+>> servings: 2
+
+-- Batter first.
+Crack @eggs{2} into a #large bowl{} and add @whole milk{250%ml}.
+Whisk with a #whisk. [- until smooth -]
+
+Fry on a #pan for ~{3%minutes}, then let it ~rest{1/2%min}. Add @salt.
+`
+
+	rec, err := Parse(strings.NewReader(doc))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Metadata{
+		{Key: "title", Value: "Pancakes"},
+		{Key: "servings", Value: "2"},
+	}, rec.Metadata)
+
+	assert.Equal(t, []Step{
+		{
+			Items: []Item{
+				{Type: ItemText, Name: "Crack "},
+				{Type: ItemIngredient, Name: "eggs", Quantity: "2"},
+				{Type: ItemText, Name: " into a "},
+				{Type: ItemCookware, Name: "large bowl"},
+				{Type: ItemText, Name: " and add "},
+				{Type: ItemIngredient, Name: "whole milk", Quantity: "250", Unit: "ml"},
+				{Type: ItemText, Name: ".\nWhisk with a "},
+				{Type: ItemCookware, Name: "whisk"},
+				{Type: ItemText, Name: "."},
+			},
+		},
+		{
+			Items: []Item{
+				{Type: ItemText, Name: "Fry on a "},
+				{Type: ItemCookware, Name: "pan"},
+				{Type: ItemText, Name: " for "},
+				{Type: ItemTimer, Quantity: "3", Unit: "minutes"},
+				{Type: ItemText, Name: ", then let it "},
+				{Type: ItemTimer, Name: "rest", Quantity: "1/2", Unit: "min"},
+				{Type: ItemText, Name: ". Add "},
+				{Type: ItemIngredient, Name: "salt"},
+				{Type: ItemText, Name: "."},
+			},
+		},
+	}, rec.Steps)
+
+	assert.Equal(t, "Fry on a pan for 3 minutes, then let it rest. Add salt.", rec.Steps[1].Text())
+	assert.Len(t, rec.Items(ItemCookware), 3)
+	assert.Len(t, rec.Items(ItemTimer), 2)
+
+	res, err := Parse(strings.NewReader(rec.String()))
+	require.NoError(t, err)
+	assert.Equal(t, rec, res)
+}
+
+func Test_Item_String(t *testing.T) {
+	tests := map[string]struct {
+		Item   Item
+		Result string
+	}{
+		"Text": {
+			Item:   Item{Type: ItemText, Name: "Boil "},
+			Result: "Boil ",
+		},
+		"Single word ingredient": {
+			Item:   Item{Type: ItemIngredient, Name: "salt"},
+			Result: "@salt",
+		},
+		"Multiple word ingredient": {
+			Item:   Item{Type: ItemIngredient, Name: "sea salt"},
+			Result: "@sea salt{}",
+		},
+		"Ingredient with quantity and unit": {
+			Item:   Item{Type: ItemIngredient, Name: "milk", Quantity: "250", Unit: "ml"},
+			Result: "@milk{250%ml}",
+		},
+		"Cookware with quantity": {
+			Item:   Item{Type: ItemCookware, Name: "pan", Quantity: "2"},
+			Result: "#pan{2}",
+		},
+		"Unnamed timer": {
+			Item:   Item{Type: ItemTimer, Quantity: "10", Unit: "minutes"},
+			Result: "~{10%minutes}",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.Result, test.Item.String())
+		})
+	}
+}
+
+func Test_Item_Amount(t *testing.T) {
+	tests := map[string]struct {
+		Quantity string
+		Amount   decimal.Decimal
+		OK       bool
+	}{
+		"Integer": {
+			Quantity: "2",
+			Amount:   decimal.NewFromInt(2),
+			OK:       true,
+		},
+		"Decimal": {
+			Quantity: "0,5",
+			Amount:   decimal.New(5, -1),
+			OK:       true,
+		},
+		"Fraction": {
+			Quantity: "1/4",
+			Amount:   decimal.New(25, -2),
+			OK:       true,
+		},
+		"Text": {
+			Quantity: "some",
+		},
+		"Empty": {},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			amount, ok := Item{Quantity: test.Quantity}.Amount()
+			assert.Equal(t, test.OK, ok)
+			assert.True(t, test.Amount.Equal(amount), "expected %s, got %s", test.Amount, amount)
+		})
+	}
+}
+
+func Test_NewRecipe(t *testing.T) {
+	chicken := core.Product{
+		ID: xid.New(),
+		ProductCore: core.ProductCore{
+			Name: "Chicken Breast",
+			Serving: core.Serving{
+				Type:     core.ServingTypeGrams,
+				Size:     decimal.NewFromInt(100),
+				Calories: 165,
+			},
+		},
+	}
+
+	banana := core.Product{
+		ID: xid.New(),
+		ProductCore: core.ProductCore{
+			Name: "Banana",
+			Serving: core.Serving{
+				Type:     core.ServingTypeUnits,
+				Size:     decimal.NewFromInt(1),
+				Calories: 105,
+			},
+		},
+	}
+
+	rec := core.Recipe{
+		RecipeCore: core.RecipeCore{
+			Name:        "Chicken with bananas",
+			Description: "Unusual,\nbut tasty",
+			Products: []core.RecipeProduct{
+				{ProductID: chicken.ID, Quantity: decimal.New(25, -1)},
+				{ProductID: banana.ID, Quantity: decimal.NewFromInt(2)},
+			},
+		},
+	}
+
+	cr := NewRecipe(rec, []core.Product{chicken, banana})
+	assert.Equal(t, ">> title: Chicken with bananas\n"+
+		">> description: Unusual, but tasty\n"+
+		"\n"+
+		"@Chicken Breast{250%g}\n"+
+		"@Banana{2}\n", cr.String())
+
+	assert.Equal(t, []core.Ingredient{
+		{
+			Text:   "@Chicken Breast{250%g}",
+			Name:   "Chicken Breast",
+			Amount: decimal.NewFromInt(250),
+			Unit:   "g",
+		},
+		{
+			Text:   "@Banana{2}",
+			Name:   "Banana",
+			Amount: decimal.NewFromInt(2),
+		},
+	}, cr.Ingredients())
+}
+
+func Test_Recipe_Core(t *testing.T) {
+	rec := Recipe{
+		Metadata: []Metadata{
+			{Key: "Image", Value: "soup.png"},
+		},
+	}
+
+	assert.Equal(t, core.RecipeCore{
+		Name:        "Soup",
+		ImageURL:    "soup.png",
+		Description: "Soup",
+		Visibility:  core.VisibilityPrivate,
+		State:       core.StateDraft,
+	}, rec.Core("Soup"))
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"foodie/core"
+	"foodie/server/apierr"
+	"foodie/server/events"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+const (
+	// _eventHistorySize specifies how many latest events are kept for
+	// subscribers that resume the event stream.
+	_eventHistorySize = 1000
+
+	// _eventKeepAlive specifies how often a comment is sent to idle event
+	// streams, so that proxies do not close them.
+	_eventKeepAlive = 15 * time.Second
+)
+
+// changeEvent is the notification of a change that is sent to webhooks and
+// event streams.
+type changeEvent struct {
+	// ID specifies the id of the event. It is shared by the deliveries of
+	// the event to all webhooks.
+	ID xid.ID `json:"id"`
+
+	// Event specifies the type of the event.
+	Event core.EventType `json:"event"`
+
+	// CreatedAt specifies a time at which the event occurred.
+	CreatedAt time.Time `json:"created_at"`
+
+	// Data contains the created, updated or deleted object.
+	Data interface{} `json:"data"`
+}
+
+// GetEvents streams the changes of the objects that the user is allowed to
+// see as Server-Sent Events. If the Last-Event-ID header is provided, the
+// events that were published after it and are still kept in the history
+// are sent first. If some of them are no longer kept, a reset event is
+// sent, after which the client should fetch the objects again. The stream
+// is closed once the server is stopped or the client falls too far
+// behind.
+func (s *Server) GetEvents(w http.ResponseWriter, r *http.Request) {
+	fl, ok := w.(http.Flusher)
+	if !ok {
+		s.log.Error("response writer does not support flushing")
+		apierr.Internal().Respond(w)
+
+		return
+	}
+
+	var (
+		last   uint64
+		resume bool
+	)
+
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		var err error
+
+		last, err = strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			apierr.BadRequest("invalid Last-Event-ID header").Respond(w)
+			return
+		}
+
+		resume = true
+	}
+
+	sub, ee, complete := s.events.Subscribe(s.extractViewer(r), last, resume)
+	defer s.events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+
+	for _, ev := range ee {
+		writeEvent(w, ev)
+	}
+
+	fl.Flush()
+
+	ticker := time.NewTicker(_eventKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.ctx.Done():
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+
+			writeEvent(w, ev)
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+
+		fl.Flush()
+	}
+}
+
+// writeEvent writes the event in the Server-Sent Events format.
+func writeEvent(w http.ResponseWriter, ev events.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, ev.Data)
+}
+
+// emitProduct emits the product event to the subscribers whose users are
+// able to see the product.
+func (s *Server) emitProduct(et core.EventType, prd core.Product) {
+	s.emitEvent(et, prd.VisibleTo, prd)
+}
+
+// emitRecipe emits the recipe event to the subscribers whose users are
+// able to see the recipe.
+func (s *Server) emitRecipe(et core.EventType, rec core.Recipe) {
+	s.emitEvent(et, func(vw core.Viewer) bool {
+		return vw.CanSee(rec.UserID, rec.Visibility, rec.State)
+	}, rec)
+}
+
+// emitPlan emits the plan event to the subscribers whose users are able
+// to see the plan.
+func (s *Server) emitPlan(et core.EventType, pl core.Plan) {
+	s.emitEvent(et, func(vw core.Viewer) bool {
+		return vw.CanSee(pl.UserID, pl.Visibility, pl.State)
+	}, pl)
+}
+
+// emitUser emits the user event to admins and to the user itself.
+func (s *Server) emitUser(et core.EventType, usr core.User) {
+	s.emitEvent(et, func(vw core.Viewer) bool {
+		return vw.Admin || vw.UserID == usr.ID
+	}, usr)
+}
+
+// emitEvent publishes the event to the live event streams and stores its
+// deliveries to the subscribed webhooks. Only the viewers for which
+// visible returns true receive the event.
+func (s *Server) emitEvent(et core.EventType, visible func(core.Viewer) bool, data interface{}) {
+	payload, err := json.Marshal(changeEvent{
+		ID:        xid.New(),
+		Event:     et,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		s.log.WithError(err).Error("marshaling change event")
+		return
+	}
+
+	s.events.Publish(et, payload, visible)
+	s.storeWebhookDeliveries(et, visible, payload)
+}
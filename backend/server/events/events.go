@@ -0,0 +1,177 @@
+// Package events broadcasts change notifications to live subscribers. A
+// bounded history of the latest events is kept, so that subscribers that
+// reconnect could resume from the last event they received.
+package events
+
+import (
+	"foodie/core"
+	"sync"
+	"time"
+)
+
+// _subscriptionBuffer specifies how many events can wait to be sent to a
+// single subscriber before it is dropped.
+const _subscriptionBuffer = 64
+
+// Event is a single change notification.
+type Event struct {
+	// ID specifies the id of the event. Ids increase with every published
+	// event and are not reused after a restart.
+	ID uint64
+
+	// Type specifies the type of the change.
+	Type core.EventType
+
+	// Data contains the JSON representation of the change.
+	Data []byte
+
+	// visible checks whether the viewer is allowed to see the changed
+	// object.
+	visible func(core.Viewer) bool
+}
+
+// Subscription receives the events that its viewer is allowed to see.
+type Subscription struct {
+	// C receives the published events. It is closed once the subscriber
+	// falls too far behind or the subscription is cancelled.
+	C <-chan Event
+
+	// ch is the sending side of C.
+	ch chan Event
+
+	// viewer specifies the user that receives the events.
+	viewer core.Viewer
+}
+
+// Broker publishes events to the subscriptions and keeps the history of
+// the latest events.
+type Broker struct {
+	// mu guards the fields below.
+	mu sync.Mutex
+
+	// next specifies the id of the next published event.
+	next uint64
+
+	// history contains the latest events in a ring buffer.
+	history []Event
+
+	// start specifies the position of the oldest event in the history.
+	start int
+
+	// subs contains the active subscriptions.
+	subs map[*Subscription]struct{}
+}
+
+// NewBroker creates a broker that keeps at most size latest events. Event
+// ids start at the current time in nanoseconds, so that ids published
+// after a restart are greater than the ones published before it.
+func NewBroker(size int) *Broker {
+	return &Broker{
+		next:    uint64(time.Now().UnixNano()),
+		history: make([]Event, 0, size),
+		subs:    make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns an id to the event, adds it to the history and sends it
+// to the subscriptions whose viewers are allowed to see it. Subscribers
+// that cannot keep up are dropped, so that they could resume from the
+// history after reconnecting.
+func (b *Broker) Publish(et core.EventType, data []byte, visible func(core.Viewer) bool) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ev := Event{
+		ID:      b.next,
+		Type:    et,
+		Data:    data,
+		visible: visible,
+	}
+
+	b.next++
+
+	if len(b.history) < cap(b.history) {
+		b.history = append(b.history, ev)
+	} else if len(b.history) > 0 {
+		b.history[b.start] = ev
+		b.start = (b.start + 1) % len(b.history)
+	}
+
+	for sub := range b.subs {
+		if !visible(sub.viewer) {
+			continue
+		}
+
+		select {
+		case sub.ch <- ev:
+		default:
+			b.unsubscribe(sub)
+		}
+	}
+
+	return ev
+}
+
+// Subscribe creates a subscription of the viewer. If resume is true, the
+// events published after the last event id that are visible to the viewer
+// are returned as well. The returned flag reports whether no events could
+// have been missed, i.e. whether no events after the last one were
+// evicted from the history or resume is false.
+func (b *Broker) Subscribe(vw core.Viewer, last uint64, resume bool) (*Subscription, []Event, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, _subscriptionBuffer)
+	sub := &Subscription{
+		C:      ch,
+		ch:     ch,
+		viewer: vw,
+	}
+
+	b.subs[sub] = struct{}{}
+
+	if !resume {
+		return sub, nil, true
+	}
+
+	if last >= b.next {
+		// The id was not issued by this broker.
+		return sub, nil, false
+	}
+
+	complete := last+1 == b.next
+	if len(b.history) > 0 {
+		complete = last+1 >= b.history[b.start].ID
+	}
+
+	ee := make([]Event, 0)
+
+	for i := range b.history {
+		ev := b.history[(b.start+i)%len(b.history)]
+
+		if ev.ID > last && ev.visible(vw) {
+			ee = append(ee, ev)
+		}
+	}
+
+	return sub, ee, complete
+}
+
+// Unsubscribe cancels the subscription and closes its channel. It can be
+// called multiple times.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.unsubscribe(sub)
+}
+
+// unsubscribe cancels the subscription. The lock must be held.
+func (b *Broker) unsubscribe(sub *Subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+
+	delete(b.subs, sub)
+	close(sub.ch)
+}
@@ -0,0 +1,135 @@
+package events
+
+import (
+	"foodie/core"
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Broker_Publish(t *testing.T) {
+	owner := core.Viewer{UserID: xid.New()}
+	other := core.Viewer{UserID: xid.New()}
+
+	b := NewBroker(10)
+
+	osub, _, _ := b.Subscribe(owner, 0, false)
+	defer b.Unsubscribe(osub)
+
+	xsub, _, _ := b.Subscribe(other, 0, false)
+	defer b.Unsubscribe(xsub)
+
+	ev := b.Publish(core.EventPlanUpdated, []byte(`{}`), func(vw core.Viewer) bool {
+		return vw.UserID == owner.UserID
+	})
+
+	select {
+	case res := <-osub.C:
+		assert.Equal(t, ev.ID, res.ID)
+		assert.Equal(t, core.EventPlanUpdated, res.Type)
+	default:
+		t.Fatal("event was not received")
+	}
+
+	assert.Empty(t, xsub.C)
+}
+
+func Test_Broker_Publish_slowSubscriber(t *testing.T) {
+	b := NewBroker(10)
+
+	sub, _, _ := b.Subscribe(core.Viewer{}, 0, false)
+
+	for i := 0; i <= _subscriptionBuffer; i++ {
+		b.Publish(core.EventRecipeCreated, nil, func(core.Viewer) bool { return true })
+	}
+
+	n := 0
+	for range sub.C {
+		n++
+	}
+
+	assert.Equal(t, _subscriptionBuffer, n)
+
+	// The subscription is already cancelled.
+	b.Unsubscribe(sub)
+}
+
+func Test_Broker_Subscribe(t *testing.T) {
+	visible := func(core.Viewer) bool { return true }
+	hidden := func(core.Viewer) bool { return false }
+
+	b := NewBroker(3)
+	ids := make([]uint64, 0, 5)
+
+	for i := 0; i < 5; i++ {
+		fn := visible
+		if i == 3 {
+			fn = hidden
+		}
+
+		ids = append(ids, b.Publish(core.EventProductCreated, nil, fn).ID)
+	}
+
+	tests := map[string]struct {
+		Last     uint64
+		Resume   bool
+		IDs      []uint64
+		Complete bool
+	}{
+		"No resume": {
+			Complete: true,
+		},
+		"Evicted last event": {
+			Last:   ids[0],
+			Resume: true,
+			IDs:    []uint64{ids[2], ids[4]},
+		},
+		"Oldest kept event": {
+			Last:     ids[1],
+			Resume:   true,
+			IDs:      []uint64{ids[2], ids[4]},
+			Complete: true,
+		},
+		"Hidden events are skipped": {
+			Last:     ids[2],
+			Resume:   true,
+			IDs:      []uint64{ids[4]},
+			Complete: true,
+		},
+		"Newest event": {
+			Last:     ids[4],
+			Resume:   true,
+			Complete: true,
+		},
+		"Unknown event": {
+			Last:   ids[4] + 1,
+			Resume: true,
+		},
+	}
+
+	for tName, tCase := range tests {
+		tCase := tCase
+
+		t.Run(tName, func(t *testing.T) {
+			t.Parallel()
+
+			sub, ee, complete := b.Subscribe(core.Viewer{}, tCase.Last, tCase.Resume)
+			defer b.Unsubscribe(sub)
+
+			res := make([]uint64, 0, len(ee))
+			for _, ev := range ee {
+				res = append(res, ev.ID)
+			}
+
+			require.Equal(t, tCase.Complete, complete)
+
+			if tCase.IDs == nil {
+				assert.Empty(t, res)
+			} else {
+				assert.Equal(t, tCase.IDs, res)
+			}
+		})
+	}
+}
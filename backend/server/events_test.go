@@ -0,0 +1,92 @@
+package server
+
+import (
+	"bufio"
+	"foodie/core"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Server_GetEvents(t *testing.T) {
+	s, err := NewServer(nil, "0", "", []byte("secret"), time.Hour, time.Hour, nil, false)
+	require.NoError(t, err)
+
+	uid := xid.New()
+	visible := func(vw core.Viewer) bool { return vw.UserID == uid }
+	hidden := func(core.Viewer) bool { return false }
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.GetEvents(w, r.WithContext(withViewer(r.Context(), uid, false)))
+	}))
+	defer srv.Close()
+
+	first := s.events.Publish(core.EventPlanCreated, []byte(`{"n":1}`), visible)
+	s.events.Publish(core.EventPlanCreated, []byte(`{"n":2}`), hidden)
+	s.events.Publish(core.EventPlanUpdated, []byte(`{"n":3}`), visible)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+
+	req.Header.Set("Last-Event-ID", strconv.FormatUint(first.ID, 10))
+
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	rd := bufio.NewReader(resp.Body)
+
+	// The resumed event is followed by a live one.
+	assert.Contains(t, readEvent(t, rd), "event: plan.updated\ndata: {\"n\":3}")
+
+	s.events.Publish(core.EventPlanDeleted, []byte(`{"n":4}`), visible)
+	assert.Contains(t, readEvent(t, rd), "event: plan.deleted\ndata: {\"n\":4}")
+
+	// Stopping the server closes the stream.
+	s.cancel()
+
+	_, err = rd.ReadString('\n')
+	assert.Error(t, err)
+}
+
+func Test_Server_GetEvents_invalidLastEventID(t *testing.T) {
+	s, err := NewServer(nil, "0", "", []byte("secret"), time.Hour, time.Hour, nil, false)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+
+	rec := httptest.NewRecorder()
+	s.GetEvents(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// readEvent reads a single event of the stream.
+func readEvent(t *testing.T, rd *bufio.Reader) string {
+	t.Helper()
+
+	var sb strings.Builder
+
+	for {
+		line, err := rd.ReadString('\n')
+		require.NoError(t, err)
+
+		if line == "\n" {
+			return sb.String()
+		}
+
+		sb.WriteString(line)
+	}
+}
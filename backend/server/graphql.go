@@ -0,0 +1,720 @@
+package server
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"foodie/core"
+	"foodie/db"
+	"foodie/server/apierr"
+	"foodie/server/batch"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
+)
+
+// _graphqlSchema contains the GraphQL schema of the API.
+//
+//go:embed schema.graphql
+var _graphqlSchema string
+
+// graphqlRequest is a GraphQL request sent over HTTP.
+type graphqlRequest struct {
+	// Query contains the GraphQL document.
+	Query string `json:"query"`
+
+	// OperationName specifies the operation of the document to execute.
+	OperationName string `json:"operationName"`
+
+	// Variables contains the values of the operation variables.
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// newGraphQLSchema parses the GraphQL schema of the API with the resolvers
+// of the server.
+func newGraphQLSchema(s *Server) *graphql.Schema {
+	return graphql.MustParseSchema(
+		_graphqlSchema,
+		&graphqlResolver{s: s},
+		graphql.UseStringDescriptions(),
+		graphql.UseFieldResolvers(),
+		graphql.MaxDepth(_graphqlMaxDepth),
+	)
+}
+
+// _graphqlMaxDepth specifies the maximum field nesting depth of GraphQL
+// queries.
+const _graphqlMaxDepth = 10
+
+// GraphQL executes a GraphQL query or mutation. Queries are resolved on
+// behalf of the viewer, while mutations require the authorization token
+// and follow the same ownership rules as the rest of the API. Errors are
+// reported in the errors list of the response, with the error code and
+// status in their extensions.
+func (s *Server) GraphQL(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		apierr.MalformedDataInput(apierr.DataTypeRequestBody).Respond(w)
+		return
+	}
+
+	var req graphqlRequest
+
+	if err := json.Unmarshal(data, &req); err != nil {
+		apierr.MalformedDataInput(apierr.DataTypeJSON).Respond(w)
+		return
+	}
+
+	if req.Query == "" {
+		apierr.InvalidAttribute("query", "cannot be empty").Respond(w)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), _contextKeyGraphQL, s.newGraphQLLoaders(s.extractViewer(r)))
+
+	s.respondJSON(w, s.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
+
+// graphqlError reports an API error through GraphQL. The code and the
+// status of the error are included in its extensions.
+type graphqlError struct {
+	err *apierr.Error
+}
+
+// Error returns the human-readable message of the error.
+func (e graphqlError) Error() string {
+	return e.err.Error()
+}
+
+// Extensions returns the machine-readable attributes of the error.
+func (e graphqlError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{
+		"code":   e.err.Code(),
+		"status": e.err.StatusCode(),
+	}
+
+	if attr := e.err.Attribute(); attr != "" {
+		ext["attribute"] = attr
+	}
+
+	if vv := e.err.Violations(); len(vv) > 1 {
+		ext["errors"] = vv
+	}
+
+	if det := e.err.Details(); det != nil {
+		ext["details"] = det
+	}
+
+	return ext
+}
+
+// graphqlLoaders contains the state of a single GraphQL request: the
+// viewer and the loaders that fetch the nested objects in batches.
+type graphqlLoaders struct {
+	// viewer specifies the user on whose behalf the request is resolved.
+	viewer core.Viewer
+
+	// recipes loads recipes as seen by the viewer.
+	recipes *batch.Loader[xid.ID, core.Recipe]
+
+	// products loads products as seen by the viewer.
+	products *batch.Loader[xid.ID, core.Product]
+}
+
+// newGraphQLLoaders creates the loaders of a single GraphQL request.
+func (s *Server) newGraphQLLoaders(vw core.Viewer) *graphqlLoaders {
+	ll := &graphqlLoaders{
+		viewer: vw,
+	}
+
+	ll.products = batch.NewLoader(func(ctx context.Context, ids []xid.ID) (map[xid.ID]core.Product, error) {
+		pp, err := db.GetProductsByIDs(ctx, s.db, vw, ids)
+		switch err {
+		case nil:
+			// OK.
+		case ctx.Err():
+			return nil, graphqlError{apierr.Context()}
+		default:
+			s.log.WithError(err).Error("fetching products")
+			return nil, graphqlError{apierr.Database()}
+		}
+
+		res := make(map[xid.ID]core.Product, len(pp))
+		for _, prd := range pp {
+			res[prd.ID] = prd
+		}
+
+		return res, nil
+	})
+
+	ll.recipes = batch.NewLoader(func(ctx context.Context, ids []xid.ID) (map[xid.ID]core.Recipe, error) {
+		rr, err := db.GetRecipesByIDs(ctx, s.db, vw, ids)
+		switch err {
+		case nil:
+			// OK.
+		case ctx.Err():
+			return nil, graphqlError{apierr.Context()}
+		default:
+			s.log.WithError(err).Error("fetching recipes")
+			return nil, graphqlError{apierr.Database()}
+		}
+
+		res := make(map[xid.ID]core.Recipe, len(rr))
+		for _, rec := range rr {
+			ll.primeProducts(rec)
+			res[rec.ID] = rec
+		}
+
+		return res, nil
+	})
+
+	return ll
+}
+
+// graphqlLoadersFromContext retrieves the loaders of the GraphQL request
+// from the context.
+func graphqlLoadersFromContext(ctx context.Context) *graphqlLoaders {
+	ll, _ := ctx.Value(_contextKeyGraphQL).(*graphqlLoaders)
+	return ll
+}
+
+// primeProducts schedules the products of the recipe to be loaded by the
+// next batch.
+func (ll *graphqlLoaders) primeProducts(rec core.Recipe) {
+	ids := make([]xid.ID, 0, len(rec.Products))
+	for _, rp := range rec.Products {
+		ids = append(ids, rp.ProductID)
+	}
+
+	ll.products.Prime(ids...)
+}
+
+// newRecipeResolver creates a resolver of the recipe. Its products are
+// scheduled to be loaded together with the products of other recipes.
+func (ll *graphqlLoaders) newRecipeResolver(rec core.Recipe) *recipeResolver {
+	ll.primeProducts(rec)
+	return &recipeResolver{ll: ll, rec: rec}
+}
+
+// newPlanResolver creates a resolver of the plan. Its recipes are
+// scheduled to be loaded together with the recipes of other plans.
+func (ll *graphqlLoaders) newPlanResolver(pl core.Plan) *planResolver {
+	ids := make([]xid.ID, 0, len(pl.Recipes))
+	for _, pr := range pl.Recipes {
+		ids = append(ids, pr.RecipeID)
+	}
+
+	ll.recipes.Prime(ids...)
+
+	return &planResolver{ll: ll, pl: pl}
+}
+
+// graphqlPageArgs contains the pagination arguments of list fields.
+type graphqlPageArgs struct {
+	// First specifies the maximum number of objects in the page.
+	First *int32
+
+	// After specifies the cursor after which the page starts.
+	After *string
+}
+
+// page converts the arguments to the pagination parameters.
+func (args graphqlPageArgs) page() (core.Page, *apierr.Error) {
+	pg := core.Page{
+		Limit: _defaultPageLimit,
+	}
+
+	if args.First != nil {
+		if *args.First <= 0 || *args.First > _maxPageLimit {
+			return core.Page{}, apierr.InvalidAttribute(
+				"first",
+				"must be a number between 1 and "+strconv.Itoa(_maxPageLimit),
+			)
+		}
+
+		pg.Limit = uint64(*args.First)
+	}
+
+	if args.After != nil && *args.After != "" {
+		cur, aerr := core.ParseCursor(*args.After)
+		if aerr != nil {
+			return core.Page{}, aerr
+		}
+
+		pg.Cursor = cur
+	}
+
+	return pg, nil
+}
+
+// graphqlCursor returns the string representation of the cursor of the
+// next page, if there is one.
+func graphqlCursor(next *core.Cursor) *string {
+	if next == nil {
+		return nil
+	}
+
+	v := next.String()
+
+	return &v
+}
+
+// parseGraphQLID parses the object id of the argument.
+func parseGraphQLID(attr string, id graphql.ID) (xid.ID, *apierr.Error) {
+	res, err := xid.FromString(string(id))
+	if err != nil {
+		return xid.NilID(), apierr.InvalidAttribute(attr, "must be a valid id")
+	}
+
+	return res, nil
+}
+
+// graphqlResolver resolves the root query and mutation fields.
+type graphqlResolver struct {
+	s *Server
+}
+
+// dbError converts the database error to an error of a resolver.
+func (gr *graphqlResolver) dbError(ctx context.Context, err error, msg string) error {
+	return graphqlError{gr.s.dbError(ctx, err, msg)}
+}
+
+// authorized retrieves the loaders of the request, which must have been
+// authorized. If super is true, administrator permissions are required.
+func (gr *graphqlResolver) authorized(ctx context.Context, super bool) (*graphqlLoaders, error) {
+	ll := graphqlLoadersFromContext(ctx)
+
+	switch {
+	case ll.viewer.UserID.IsNil():
+		return nil, graphqlError{apierr.Unauthorized()}
+	case super && !ll.viewer.Admin:
+		return nil, graphqlError{apierr.Forbidden()}
+	default:
+		return ll, nil
+	}
+}
+
+// Self resolves the authorized user.
+func (gr *graphqlResolver) Self(ctx context.Context) (*userResolver, error) {
+	ll, err := gr.authorized(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	usr, err := db.GetUserByID(ctx, gr.s.db, ll.viewer.UserID)
+	switch err {
+	case nil:
+		// OK.
+	case db.ErrNotFound:
+		return nil, graphqlError{apierr.NotFound("user")}
+	default:
+		return nil, gr.dbError(ctx, err, "fetching user by id")
+	}
+
+	return &userResolver{usr: *usr}, nil
+}
+
+// User resolves a single user by its id.
+func (gr *graphqlResolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	if _, err := gr.authorized(ctx, true); err != nil {
+		return nil, err
+	}
+
+	uid, aerr := parseGraphQLID("id", args.ID)
+	if aerr != nil {
+		return nil, graphqlError{aerr}
+	}
+
+	usr, err := db.GetUserByID(ctx, gr.s.db, uid)
+	switch err {
+	case nil:
+		// OK.
+	case db.ErrNotFound:
+		return nil, nil
+	default:
+		return nil, gr.dbError(ctx, err, "fetching user by id")
+	}
+
+	return &userResolver{usr: *usr}, nil
+}
+
+// userPage is a single page of users.
+type userPage struct {
+	Data       []*userResolver
+	NextCursor *string
+}
+
+// Users resolves a page of users.
+func (gr *graphqlResolver) Users(ctx context.Context, args graphqlPageArgs) (*userPage, error) {
+	if _, err := gr.authorized(ctx, true); err != nil {
+		return nil, err
+	}
+
+	pg, aerr := args.page()
+	if aerr != nil {
+		return nil, graphqlError{aerr}
+	}
+
+	uu, next, err := db.GetUsers(ctx, gr.s.db, core.Query{}, pg)
+	if err != nil {
+		return nil, gr.dbError(ctx, err, "fetching users")
+	}
+
+	res := &userPage{
+		Data:       make([]*userResolver, 0, len(uu)),
+		NextCursor: graphqlCursor(next),
+	}
+
+	for _, usr := range uu {
+		res.Data = append(res.Data, &userResolver{usr: usr})
+	}
+
+	return res, nil
+}
+
+// Product resolves a single product by its id.
+func (gr *graphqlResolver) Product(ctx context.Context, args struct{ ID graphql.ID }) (*productResolver, error) {
+	ll := graphqlLoadersFromContext(ctx)
+
+	pid, aerr := parseGraphQLID("id", args.ID)
+	if aerr != nil {
+		return nil, graphqlError{aerr}
+	}
+
+	prd, err := db.GetProductByID(ctx, gr.s.db, ll.viewer, pid)
+	switch err {
+	case nil:
+		// OK.
+	case db.ErrNotFound:
+		return nil, nil
+	default:
+		return nil, gr.dbError(ctx, err, "fetching product by id")
+	}
+
+	ll.products.Store(prd.ID, *prd)
+
+	return &productResolver{prd: *prd}, nil
+}
+
+// productPage is a single page of products.
+type productPage struct {
+	Data       []*productResolver
+	NextCursor *string
+}
+
+// Products resolves a page of products.
+func (gr *graphqlResolver) Products(ctx context.Context, args graphqlPageArgs) (*productPage, error) {
+	ll := graphqlLoadersFromContext(ctx)
+
+	pg, aerr := args.page()
+	if aerr != nil {
+		return nil, graphqlError{aerr}
+	}
+
+	pp, next, err := db.GetProducts(ctx, gr.s.db, ll.viewer, core.Query{}, pg)
+	if err != nil {
+		return nil, gr.dbError(ctx, err, "fetching products")
+	}
+
+	res := &productPage{
+		Data:       make([]*productResolver, 0, len(pp)),
+		NextCursor: graphqlCursor(next),
+	}
+
+	for _, prd := range pp {
+		res.Data = append(res.Data, &productResolver{prd: prd})
+	}
+
+	return res, nil
+}
+
+// Recipe resolves a single recipe by its id.
+func (gr *graphqlResolver) Recipe(ctx context.Context, args struct{ ID graphql.ID }) (*recipeResolver, error) {
+	ll := graphqlLoadersFromContext(ctx)
+
+	rid, aerr := parseGraphQLID("id", args.ID)
+	if aerr != nil {
+		return nil, graphqlError{aerr}
+	}
+
+	rec, ok, err := ll.recipes.Load(ctx, rid)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return ll.newRecipeResolver(rec), nil
+}
+
+// recipePage is a single page of recipes.
+type recipePage struct {
+	Data       []*recipeResolver
+	NextCursor *string
+}
+
+// Recipes resolves a page of recipes.
+func (gr *graphqlResolver) Recipes(ctx context.Context, args graphqlPageArgs) (*recipePage, error) {
+	ll := graphqlLoadersFromContext(ctx)
+
+	pg, aerr := args.page()
+	if aerr != nil {
+		return nil, graphqlError{aerr}
+	}
+
+	rr, next, err := db.GetRecipes(ctx, gr.s.db, ll.viewer, core.Query{}, pg)
+	if err != nil {
+		return nil, gr.dbError(ctx, err, "fetching recipes")
+	}
+
+	res := &recipePage{
+		Data:       make([]*recipeResolver, 0, len(rr)),
+		NextCursor: graphqlCursor(next),
+	}
+
+	for _, rec := range rr {
+		ll.recipes.Store(rec.ID, rec)
+		res.Data = append(res.Data, ll.newRecipeResolver(rec))
+	}
+
+	return res, nil
+}
+
+// Plan resolves a single plan by its id.
+func (gr *graphqlResolver) Plan(ctx context.Context, args struct{ ID graphql.ID }) (*planResolver, error) {
+	ll := graphqlLoadersFromContext(ctx)
+
+	pid, aerr := parseGraphQLID("id", args.ID)
+	if aerr != nil {
+		return nil, graphqlError{aerr}
+	}
+
+	pl, err := db.GetPlanByID(ctx, gr.s.db, ll.viewer, pid)
+	switch err {
+	case nil:
+		// OK.
+	case db.ErrNotFound:
+		return nil, nil
+	default:
+		return nil, gr.dbError(ctx, err, "fetching plan by id")
+	}
+
+	return ll.newPlanResolver(*pl), nil
+}
+
+// planPage is a single page of plans.
+type planPage struct {
+	Data       []*planResolver
+	NextCursor *string
+}
+
+// Plans resolves a page of plans.
+func (gr *graphqlResolver) Plans(ctx context.Context, args graphqlPageArgs) (*planPage, error) {
+	ll := graphqlLoadersFromContext(ctx)
+
+	pg, aerr := args.page()
+	if aerr != nil {
+		return nil, graphqlError{aerr}
+	}
+
+	pp, next, err := db.GetPlans(ctx, gr.s.db, ll.viewer, core.Query{}, pg)
+	if err != nil {
+		return nil, gr.dbError(ctx, err, "fetching plans")
+	}
+
+	res := &planPage{
+		Data:       make([]*planResolver, 0, len(pp)),
+		NextCursor: graphqlCursor(next),
+	}
+
+	for _, pl := range pp {
+		res.Data = append(res.Data, ll.newPlanResolver(pl))
+	}
+
+	return res, nil
+}
+
+// recipeInput contains the attributes of a new recipe.
+type recipeInput struct {
+	Name        string
+	ImageURL    *string
+	Description string
+	Visibility  string
+	State       string
+	Products    []struct {
+		ProductID graphql.ID
+		Quantity  string
+	}
+}
+
+// core converts the input to a recipe core.
+func (in recipeInput) core() (core.RecipeCore, *apierr.Error) {
+	rc := core.RecipeCore{
+		Name:        in.Name,
+		Description: in.Description,
+		Visibility:  core.Visibility(in.Visibility),
+		State:       core.State(in.State),
+		Products:    make([]core.RecipeProduct, 0, len(in.Products)),
+	}
+
+	if in.ImageURL != nil {
+		rc.ImageURL = *in.ImageURL
+	}
+
+	for i, rp := range in.Products {
+		pid, aerr := parseGraphQLID(fmt.Sprintf("products[%d].product_id", i), rp.ProductID)
+		if aerr != nil {
+			return core.RecipeCore{}, aerr
+		}
+
+		qty, err := decimal.NewFromString(rp.Quantity)
+		if err != nil {
+			return core.RecipeCore{}, apierr.InvalidAttribute(
+				fmt.Sprintf("products[%d].quantity", i),
+				"must be a decimal number",
+			)
+		}
+
+		rc.Products = append(rc.Products, core.RecipeProduct{
+			ProductID: pid,
+			Quantity:  qty,
+		})
+	}
+
+	return rc, nil
+}
+
+// CreateRecipe creates a recipe of the authorized user.
+func (gr *graphqlResolver) CreateRecipe(ctx context.Context, args struct{ Input recipeInput }) (*recipeResolver, error) {
+	ll, err := gr.authorized(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, aerr := args.Input.core()
+	if aerr != nil {
+		return nil, graphqlError{aerr}
+	}
+
+	rec, aerr := gr.s.createRecipe(ctx, ll.viewer.UserID, rc)
+	if aerr != nil {
+		return nil, graphqlError{aerr}
+	}
+
+	return ll.newRecipeResolver(*rec), nil
+}
+
+// DeleteRecipe moves a recipe to the trash. The recipe can be deleted only
+// by an admin or the user that created it, and only if it is not included
+// in plans.
+func (gr *graphqlResolver) DeleteRecipe(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	ll, err := gr.authorized(ctx, false)
+	if err != nil {
+		return false, err
+	}
+
+	rid, aerr := parseGraphQLID("id", args.ID)
+	if aerr != nil {
+		return false, graphqlError{aerr}
+	}
+
+	if aerr := gr.s.deleteRecipe(ctx, ll.viewer, rid); aerr != nil {
+		return false, graphqlError{aerr}
+	}
+
+	return true, nil
+}
+
+// planInput contains the attributes of a new plan.
+type planInput struct {
+	Name        string
+	Description string
+	Visibility  string
+	State       string
+	Recipes     []struct {
+		RecipeID graphql.ID
+		Quantity int32
+		Day      *string
+	}
+}
+
+// core converts the input to a plan core.
+func (in planInput) core() (core.PlanCore, *apierr.Error) {
+	pc := core.PlanCore{
+		Name:        in.Name,
+		Description: in.Description,
+		Visibility:  core.Visibility(in.Visibility),
+		State:       core.State(in.State),
+		Recipes:     make([]core.PlanRecipe, 0, len(in.Recipes)),
+	}
+
+	for i, pr := range in.Recipes {
+		rid, aerr := parseGraphQLID(fmt.Sprintf("recipes[%d].recipe_id", i), pr.RecipeID)
+		if aerr != nil {
+			return core.PlanCore{}, aerr
+		}
+
+		if pr.Quantity < 0 {
+			return core.PlanCore{}, apierr.InvalidAttribute(
+				fmt.Sprintf("recipes[%d].quantity", i),
+				"must be positive",
+			)
+		}
+
+		cpr := core.PlanRecipe{
+			RecipeID: rid,
+			Quantity: uint64(pr.Quantity),
+		}
+
+		if pr.Day != nil {
+			cpr.Day = *pr.Day
+		}
+
+		pc.Recipes = append(pc.Recipes, cpr)
+	}
+
+	return pc, nil
+}
+
+// CreatePlan creates a plan of the authorized user.
+func (gr *graphqlResolver) CreatePlan(ctx context.Context, args struct{ Input planInput }) (*planResolver, error) {
+	ll, err := gr.authorized(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	pc, aerr := args.Input.core()
+	if aerr != nil {
+		return nil, graphqlError{aerr}
+	}
+
+	pl, aerr := gr.s.createPlan(ctx, ll.viewer.UserID, pc)
+	if aerr != nil {
+		return nil, graphqlError{aerr}
+	}
+
+	return ll.newPlanResolver(*pl), nil
+}
+
+// DeletePlan moves a plan to the trash. The plan can be deleted only by an
+// admin or the user that created it.
+func (gr *graphqlResolver) DeletePlan(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	ll, err := gr.authorized(ctx, false)
+	if err != nil {
+		return false, err
+	}
+
+	pid, aerr := parseGraphQLID("id", args.ID)
+	if aerr != nil {
+		return false, graphqlError{aerr}
+	}
+
+	if aerr := gr.s.deletePlan(ctx, ll.viewer, pid); aerr != nil {
+		return false, graphqlError{aerr}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Server_GraphQL(t *testing.T) {
+	t.Parallel()
+
+	type gqlError struct {
+		Message    string                 `json:"message"`
+		Path       []interface{}          `json:"path"`
+		Extensions map[string]interface{} `json:"extensions"`
+	}
+
+	tests := map[string]struct {
+		UserID     xid.ID
+		Admin      bool
+		Body       string
+		Response   string
+		StatusCode int
+		Errors     []gqlError
+	}{
+		"Invalid body JSON": {
+			Body:       "{",
+			Response:   "malformed json",
+			StatusCode: http.StatusBadRequest,
+		},
+		"Empty query": {
+			Body:       `{"query":""}`,
+			Response:   "query: cannot be empty",
+			StatusCode: http.StatusBadRequest,
+		},
+		"Anonymous self": {
+			Body:       `{"query":"{ self { id } }"}`,
+			StatusCode: http.StatusOK,
+			Errors: []gqlError{{
+				Message:    "Unauthorized",
+				Path:       []interface{}{"self"},
+				Extensions: map[string]interface{}{"code": "unauthorized", "status": float64(http.StatusUnauthorized)},
+			}},
+		},
+		"Users without administrator permissions": {
+			UserID:     xid.New(),
+			Body:       `{"query":"{ users { data { id } } }"}`,
+			StatusCode: http.StatusOK,
+			Errors: []gqlError{{
+				Message:    "Forbidden",
+				Path:       []interface{}{"users"},
+				Extensions: map[string]interface{}{"code": "forbidden", "status": float64(http.StatusForbidden)},
+			}},
+		},
+		"Invalid product id": {
+			Body:       `{"query":"query($id: ID!) { product(id: $id) { name } }","variables":{"id":"x"}}`,
+			StatusCode: http.StatusOK,
+			Errors: []gqlError{{
+				Message:    "id: must be a valid id",
+				Path:       []interface{}{"product"},
+				Extensions: map[string]interface{}{"code": "invalid_attribute", "status": float64(http.StatusBadRequest), "attribute": "id"},
+			}},
+		},
+		"Anonymous mutation": {
+			Body:       `{"query":"mutation { deletePlan(id: \"cbsbq6ps2so2a8n6mpbg\") }"}`,
+			StatusCode: http.StatusOK,
+			Errors: []gqlError{{
+				Message:    "Unauthorized",
+				Path:       []interface{}{"deletePlan"},
+				Extensions: map[string]interface{}{"code": "unauthorized", "status": float64(http.StatusUnauthorized)},
+			}},
+		},
+	}
+
+	for tName, tCase := range tests {
+		tCase := tCase
+
+		t.Run(tName, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Server{log: logrus.New()}
+			s.schema = newGraphQLSchema(s)
+
+			ctx := context.Background()
+			if !tCase.UserID.IsNil() {
+				ctx = context.WithValue(ctx, _contextKeyUserID, tCase.UserID)
+				ctx = context.WithValue(ctx, _contextKeyAdmin, tCase.Admin)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "http://test.com/api/graphql", strings.NewReader(tCase.Body))
+			req = req.WithContext(ctx)
+			rec := httptest.NewRecorder()
+
+			s.GraphQL(rec, req)
+
+			assert.Equal(t, tCase.StatusCode, rec.Code)
+
+			if tCase.Errors == nil {
+				assert.Contains(t, rec.Body.String(), tCase.Response)
+				return
+			}
+
+			var res struct {
+				Errors []gqlError `json:"errors"`
+			}
+
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			assert.Equal(t, tCase.Errors, res.Errors)
+		})
+	}
+}
+
+func Test_Server_GraphQL_recipeProducts(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	rec, other := insertSharedRecipe(t, dbh)
+
+	tests := map[string]struct {
+		UserID   xid.ID
+		Products []string
+		Calories int
+	}{
+		"Another user": {
+			UserID:   other,
+			Products: []string{"Oats"},
+			Calories: 100,
+		},
+		"Recipe owner": {
+			UserID:   rec.UserID,
+			Products: []string{"Oats", "Secret"},
+			Calories: 200,
+		},
+	}
+
+	for tName, tCase := range tests {
+		t.Run(tName, func(t *testing.T) {
+			s := &Server{log: logrus.New(), db: dbh}
+			s.schema = newGraphQLSchema(s)
+
+			body := `{"query":"query($id: ID!) { recipe(id: $id) { calories products { product { name } } } }","variables":{"id":"` + rec.ID.String() + `"}}`
+
+			req := httptest.NewRequest(http.MethodPost, "http://test.com/api/graphql", strings.NewReader(body))
+			req = req.WithContext(withViewer(context.Background(), tCase.UserID, false))
+			resp := httptest.NewRecorder()
+
+			s.GraphQL(resp, req)
+
+			require.Equal(t, http.StatusOK, resp.Code)
+
+			var res struct {
+				Data struct {
+					Recipe struct {
+						Calories int `json:"calories"`
+						Products []struct {
+							Product *struct {
+								Name string `json:"name"`
+							} `json:"product"`
+						} `json:"products"`
+					} `json:"recipe"`
+				} `json:"data"`
+			}
+
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &res))
+
+			var names []string
+
+			for _, rp := range res.Data.Recipe.Products {
+				if rp.Product != nil {
+					names = append(names, rp.Product.Name)
+				}
+			}
+
+			assert.ElementsMatch(t, tCase.Products, names)
+			assert.Equal(t, tCase.Calories, res.Data.Recipe.Calories)
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package server
+
+import (
+	"context"
+	"foodie/core"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/shopspring/decimal"
+)
+
+// userResolver resolves the fields of a user.
+type userResolver struct {
+	usr core.User
+}
+
+// ID resolves the id of the user.
+func (ur *userResolver) ID() graphql.ID {
+	return graphql.ID(ur.usr.ID.String())
+}
+
+// Name resolves the name of the user.
+func (ur *userResolver) Name() string {
+	return ur.usr.Name
+}
+
+// Admin resolves whether the user has administrator permissions.
+func (ur *userResolver) Admin() bool {
+	return ur.usr.Admin
+}
+
+// CreatedAt resolves the creation time of the user.
+func (ur *userResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: ur.usr.CreatedAt}
+}
+
+// servingResolver resolves the fields of the serving of a product.
+type servingResolver struct {
+	sv core.Serving
+}
+
+// Type resolves the serving measurement type.
+func (sr *servingResolver) Type() string {
+	return string(sr.sv.Type)
+}
+
+// Size resolves the amount in a single serving.
+func (sr *servingResolver) Size() string {
+	return sr.sv.Size.String()
+}
+
+// Calories resolves the calories in a single serving.
+func (sr *servingResolver) Calories() int32 {
+	return int32(sr.sv.Calories)
+}
+
+// productResolver resolves the fields of a product.
+type productResolver struct {
+	prd core.Product
+}
+
+// ID resolves the id of the product.
+func (pr *productResolver) ID() graphql.ID {
+	return graphql.ID(pr.prd.ID.String())
+}
+
+// UserID resolves the id of the user that submitted the product.
+func (pr *productResolver) UserID() graphql.ID {
+	return graphql.ID(pr.prd.UserID.String())
+}
+
+// Name resolves the name of the product.
+func (pr *productResolver) Name() string {
+	return pr.prd.Name
+}
+
+// Aliases resolves the alternative names of the product.
+func (pr *productResolver) Aliases() []string {
+	if pr.prd.Aliases == nil {
+		return make([]string, 0)
+	}
+
+	return pr.prd.Aliases
+}
+
+// Barcode resolves the barcode of the product, if it has one.
+func (pr *productResolver) Barcode() *string {
+	if pr.prd.Barcode == "" {
+		return nil
+	}
+
+	return &pr.prd.Barcode
+}
+
+// ImageURL resolves the image url of the product.
+func (pr *productResolver) ImageURL() string {
+	return pr.prd.ImageURL
+}
+
+// Description resolves the description of the product.
+func (pr *productResolver) Description() string {
+	return pr.prd.Description
+}
+
+// Serving resolves the serving information of the product.
+func (pr *productResolver) Serving() *servingResolver {
+	return &servingResolver{sv: pr.prd.Serving}
+}
+
+// Status resolves the moderation status of the product.
+func (pr *productResolver) Status() string {
+	return string(pr.prd.Status)
+}
+
+// Version resolves the revision number of the product.
+func (pr *productResolver) Version() int32 {
+	return int32(pr.prd.Version)
+}
+
+// CreatedAt resolves the creation time of the product.
+func (pr *productResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: pr.prd.CreatedAt}
+}
+
+// UpdatedAt resolves the time at which the product was last modified.
+func (pr *productResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: pr.prd.UpdatedAt}
+}
+
+// recipeResolver resolves the fields of a recipe.
+type recipeResolver struct {
+	ll  *graphqlLoaders
+	rec core.Recipe
+}
+
+// ID resolves the id of the recipe.
+func (rr *recipeResolver) ID() graphql.ID {
+	return graphql.ID(rr.rec.ID.String())
+}
+
+// UserID resolves the id of the user that created the recipe.
+func (rr *recipeResolver) UserID() graphql.ID {
+	return graphql.ID(rr.rec.UserID.String())
+}
+
+// Name resolves the name of the recipe.
+func (rr *recipeResolver) Name() string {
+	return rr.rec.Name
+}
+
+// ImageURL resolves the image url of the recipe.
+func (rr *recipeResolver) ImageURL() string {
+	return rr.rec.ImageURL
+}
+
+// Description resolves the description of the recipe.
+func (rr *recipeResolver) Description() string {
+	return rr.rec.Description
+}
+
+// Visibility resolves the visibility of the recipe.
+func (rr *recipeResolver) Visibility() string {
+	return string(rr.rec.Visibility)
+}
+
+// State resolves the publication state of the recipe.
+func (rr *recipeResolver) State() string {
+	return string(rr.rec.State)
+}
+
+// Products resolves the products of the recipe.
+func (rr *recipeResolver) Products() []*recipeProductResolver {
+	res := make([]*recipeProductResolver, 0, len(rr.rec.Products))
+	for _, rp := range rr.rec.Products {
+		res = append(res, &recipeProductResolver{ll: rr.ll, rp: rp})
+	}
+
+	return res
+}
+
+// Calories resolves the calories of all products of the recipe that are
+// visible to the viewer.
+func (rr *recipeResolver) Calories(ctx context.Context) (int32, error) {
+	cal := decimal.Zero
+
+	for _, prp := range rr.Products() {
+		prd, err := prp.product(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		if prd != nil {
+			cal = cal.Add(prp.rp.Calories(*prd))
+		}
+	}
+
+	return int32(cal.Round(0).IntPart()), nil
+}
+
+// Version resolves the revision number of the recipe.
+func (rr *recipeResolver) Version() int32 {
+	return int32(rr.rec.Version)
+}
+
+// CreatedAt resolves the creation time of the recipe.
+func (rr *recipeResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: rr.rec.CreatedAt}
+}
+
+// UpdatedAt resolves the time at which the recipe was last modified.
+func (rr *recipeResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: rr.rec.UpdatedAt}
+}
+
+// recipeProductResolver resolves the fields of a product of a recipe.
+type recipeProductResolver struct {
+	ll *graphqlLoaders
+	rp core.RecipeProduct
+}
+
+// product loads the product as seen by the viewer. Nil is returned if it
+// is not visible.
+func (rpr *recipeProductResolver) product(ctx context.Context) (*core.Product, error) {
+	prd, ok, err := rpr.ll.products.Load(ctx, rpr.rp.ProductID)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return &prd, nil
+}
+
+// ProductID resolves the id of the product.
+func (rpr *recipeProductResolver) ProductID() graphql.ID {
+	return graphql.ID(rpr.rp.ProductID.String())
+}
+
+// Quantity resolves the number of product servings.
+func (rpr *recipeProductResolver) Quantity() string {
+	return rpr.rp.Quantity.String()
+}
+
+// Product resolves the product.
+func (rpr *recipeProductResolver) Product(ctx context.Context) (*productResolver, error) {
+	prd, err := rpr.product(ctx)
+	if err != nil || prd == nil {
+		return nil, err
+	}
+
+	return &productResolver{prd: *prd}, nil
+}
+
+// Amount resolves the amount of the product in its serving measurement
+// unit.
+func (rpr *recipeProductResolver) Amount(ctx context.Context) (*string, error) {
+	prd, err := rpr.product(ctx)
+	if err != nil || prd == nil {
+		return nil, err
+	}
+
+	v := rpr.rp.Amount(*prd).String()
+
+	return &v, nil
+}
+
+// Calories resolves the calories of the product in the recipe.
+func (rpr *recipeProductResolver) Calories(ctx context.Context) (*int32, error) {
+	prd, err := rpr.product(ctx)
+	if err != nil || prd == nil {
+		return nil, err
+	}
+
+	v := int32(rpr.rp.Calories(*prd).Round(0).IntPart())
+
+	return &v, nil
+}
+
+// planResolver resolves the fields of a plan.
+type planResolver struct {
+	ll *graphqlLoaders
+	pl core.Plan
+}
+
+// ID resolves the id of the plan.
+func (pr *planResolver) ID() graphql.ID {
+	return graphql.ID(pr.pl.ID.String())
+}
+
+// UserID resolves the id of the user that created the plan.
+func (pr *planResolver) UserID() graphql.ID {
+	return graphql.ID(pr.pl.UserID.String())
+}
+
+// Name resolves the name of the plan.
+func (pr *planResolver) Name() string {
+	return pr.pl.Name
+}
+
+// Description resolves the description of the plan.
+func (pr *planResolver) Description() string {
+	return pr.pl.Description
+}
+
+// Visibility resolves the visibility of the plan.
+func (pr *planResolver) Visibility() string {
+	return string(pr.pl.Visibility)
+}
+
+// State resolves the publication state of the plan.
+func (pr *planResolver) State() string {
+	return string(pr.pl.State)
+}
+
+// Recipes resolves the recipes of the plan.
+func (pr *planResolver) Recipes() []*planRecipeResolver {
+	res := make([]*planRecipeResolver, 0, len(pr.pl.Recipes))
+	for _, prec := range pr.pl.Recipes {
+		res = append(res, &planRecipeResolver{ll: pr.ll, pr: prec})
+	}
+
+	return res
+}
+
+// Version resolves the revision number of the plan.
+func (pr *planResolver) Version() int32 {
+	return int32(pr.pl.Version)
+}
+
+// CreatedAt resolves the creation time of the plan.
+func (pr *planResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: pr.pl.CreatedAt}
+}
+
+// UpdatedAt resolves the time at which the plan was last modified.
+func (pr *planResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: pr.pl.UpdatedAt}
+}
+
+// planRecipeResolver resolves the fields of a recipe of a plan.
+type planRecipeResolver struct {
+	ll *graphqlLoaders
+	pr core.PlanRecipe
+}
+
+// RecipeID resolves the id of the recipe.
+func (prr *planRecipeResolver) RecipeID() graphql.ID {
+	return graphql.ID(prr.pr.RecipeID.String())
+}
+
+// Quantity resolves how many times the recipe is prepared.
+func (prr *planRecipeResolver) Quantity() int32 {
+	return int32(prr.pr.Quantity)
+}
+
+// Day resolves the day at which the recipe is scheduled, if any.
+func (prr *planRecipeResolver) Day() *string {
+	if prr.pr.Day == "" {
+		return nil
+	}
+
+	return &prr.pr.Day
+}
+
+// Recipe resolves the recipe as seen by the viewer. Recipes of all plans
+// of the request are loaded at once.
+func (prr *planRecipeResolver) Recipe(ctx context.Context) (*recipeResolver, error) {
+	rec, ok, err := prr.ll.recipes.Load(ctx, prr.pr.RecipeID)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return &recipeResolver{ll: prr.ll, rec: rec}, nil
+}
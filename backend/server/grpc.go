@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"foodie/core"
+	"foodie/server/apierr"
+	"foodie/server/pb"
+	"strings"
+
+	"github.com/rs/xid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// newGRPCServer creates the gRPC server of the API with its services. The
+// services use the same authorization and errors as the REST API.
+func (s *Server) newGRPCServer() *grpc.Server {
+	gs := grpc.NewServer(
+		grpc.UnaryInterceptor(s.grpcIdentifyUnary),
+		grpc.StreamInterceptor(s.grpcIdentifyStream),
+	)
+
+	pb.RegisterUserServiceServer(gs, &userService{s: s})
+	pb.RegisterProductServiceServer(gs, &productService{s: s})
+	pb.RegisterRecipeServiceServer(gs, &recipeService{s: s})
+	pb.RegisterPlanServiceServer(gs, &planService{s: s})
+
+	return gs
+}
+
+// grpcIdentify identifies the user of the incoming call by the
+// authorization metadata, which contains the same bearer token as the
+// Authorization header. Calls without it are anonymous.
+func (s *Server) grpcIdentify(ctx context.Context) (context.Context, *apierr.Error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	vv := md.Get("authorization")
+	if len(vv) == 0 {
+		return ctx, nil
+	}
+
+	parts := strings.SplitN(vv[0], " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, apierr.Unauthorized()
+	}
+
+	id, admin, aerr := s.authenticate(ctx, []byte(parts[1]))
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	return withViewer(ctx, id, admin), nil
+}
+
+// grpcIdentifyUnary is an interceptor that identifies the user of unary
+// calls.
+func (s *Server) grpcIdentifyUnary(
+	ctx context.Context,
+	req interface{},
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	ctx, aerr := s.grpcIdentify(ctx)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	return handler(ctx, req)
+}
+
+// grpcIdentifyStream is an interceptor that identifies the user of
+// streaming calls.
+func (s *Server) grpcIdentifyStream(
+	srv interface{},
+	ss grpc.ServerStream,
+	_ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx, aerr := s.grpcIdentify(ss.Context())
+	if aerr != nil {
+		return aerr
+	}
+
+	return handler(srv, &grpcStream{ServerStream: ss, ctx: ctx})
+}
+
+// grpcStream is a server stream with the context of the identified user.
+type grpcStream struct {
+	grpc.ServerStream
+
+	// ctx contains the viewer of the call.
+	ctx context.Context
+}
+
+// Context returns the context of the stream.
+func (gs *grpcStream) Context() context.Context {
+	return gs.ctx
+}
+
+// grpcViewer retrieves the viewer of the call. Anonymous calls have a nil
+// user id.
+func grpcViewer(ctx context.Context) core.Viewer {
+	id, _ := ctx.Value(_contextKeyUserID).(xid.ID)
+	admin, _ := ctx.Value(_contextKeyAdmin).(bool)
+
+	return core.Viewer{
+		UserID: id,
+		Admin:  admin,
+	}
+}
+
+// grpcAuthorized retrieves the viewer of the call, which must have been
+// authorized. If super is true, administrator permissions are required.
+func grpcAuthorized(ctx context.Context, super bool) (core.Viewer, *apierr.Error) {
+	vw := grpcViewer(ctx)
+
+	switch {
+	case vw.UserID.IsNil():
+		return core.Viewer{}, apierr.Unauthorized()
+	case super && !vw.Admin:
+		return core.Viewer{}, apierr.Forbidden()
+	default:
+		return vw, nil
+	}
+}
+
+// parseGRPCID parses the object id of the attribute.
+func parseGRPCID(attr, id string) (xid.ID, *apierr.Error) {
+	res, err := xid.FromString(id)
+	if err != nil {
+		return xid.NilID(), apierr.InvalidAttribute(attr, "must be a valid id")
+	}
+
+	return res, nil
+}
+
+// checkGRPCVersion checks whether the revision matches the version known
+// to the client. The check is skipped if the version is zero.
+func checkGRPCVersion(object string, rv core.Revision, version uint64) *apierr.Error {
+	if version != 0 && rv.Version != version {
+		return apierr.PreconditionFailed(object)
+	}
+
+	return nil
+}
+
+// streamGRPCPages sends the objects of all pages, starting at the cursor
+// of the request, until the limit of the request is reached.
+func streamGRPCPages[T any](
+	ctx context.Context,
+	s *Server,
+	req *pb.ListRequest,
+	fetch func(ctx context.Context, pg core.Page) ([]T, *core.Cursor, error),
+	send func(v T) error,
+) error {
+	pg := core.Page{
+		Limit: _maxPageLimit,
+	}
+
+	if req.Cursor != "" {
+		cur, aerr := core.ParseCursor(req.Cursor)
+		if aerr != nil {
+			return aerr
+		}
+
+		pg.Cursor = cur
+	}
+
+	var sent uint64
+
+	for {
+		if req.Limit > 0 && req.Limit-sent < pg.Limit {
+			pg.Limit = req.Limit - sent
+		}
+
+		vv, next, err := fetch(ctx, pg)
+		if err != nil {
+			return s.dbError(ctx, err, "fetching a page of a stream")
+		}
+
+		for _, v := range vv {
+			if err := send(v); err != nil {
+				return err
+			}
+		}
+
+		sent += uint64(len(vv))
+
+		if next == nil || (req.Limit > 0 && sent >= req.Limit) {
+			return nil
+		}
+
+		pg.Cursor = next
+	}
+}
+
+// _grpcVisibilities maps visibilities to their protobuf enum values.
+var _grpcVisibilities = map[core.Visibility]pb.Visibility{
+	core.VisibilityPrivate:  pb.Visibility_VISIBILITY_PRIVATE,
+	core.VisibilityUnlisted: pb.Visibility_VISIBILITY_UNLISTED,
+	core.VisibilityPublic:   pb.Visibility_VISIBILITY_PUBLIC,
+}
+
+// _grpcStates maps publication states to their protobuf enum values.
+var _grpcStates = map[core.State]pb.State{
+	core.StateDraft:     pb.State_STATE_DRAFT,
+	core.StatePublished: pb.State_STATE_PUBLISHED,
+}
+
+// visibilityFromGRPC converts the protobuf visibility. Unspecified
+// visibility defaults to public.
+func visibilityFromGRPC(v pb.Visibility) core.Visibility {
+	if v == pb.Visibility_VISIBILITY_UNSPECIFIED {
+		return core.VisibilityPublic
+	}
+
+	for cv, pv := range _grpcVisibilities {
+		if pv == v {
+			return cv
+		}
+	}
+
+	return core.Visibility(v.String())
+}
+
+// stateFromGRPC converts the protobuf publication state. Unspecified state
+// defaults to published.
+func stateFromGRPC(v pb.State) core.State {
+	if v == pb.State_STATE_UNSPECIFIED {
+		return core.StatePublished
+	}
+
+	for cv, pv := range _grpcStates {
+		if pv == v {
+			return cv
+		}
+	}
+
+	return core.State(v.String())
+}
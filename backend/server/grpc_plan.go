@@ -0,0 +1,202 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"foodie/core"
+	"foodie/db"
+	"foodie/server/apierr"
+	"foodie/server/pb"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// planService implements the gRPC plan service.
+type planService struct {
+	pb.UnimplementedPlanServiceServer
+
+	// s is the server whose database is used.
+	s *Server
+}
+
+// GetPlan retrieves a single plan.
+func (ps *planService) GetPlan(ctx context.Context, in *pb.IDRequest) (*pb.Plan, error) {
+	pl, aerr := ps.getPlan(ctx, grpcViewer(ctx), in.Id)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	return planToGRPC(pl), nil
+}
+
+// getPlan retrieves a single plan by its id as seen by the viewer.
+func (ps *planService) getPlan(ctx context.Context, vw core.Viewer, id string) (*core.Plan, *apierr.Error) {
+	pid, aerr := parseGRPCID("id", id)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	pl, err := db.GetPlanByID(ctx, ps.s.db, vw, pid)
+	switch err {
+	case nil:
+		// OK.
+	case db.ErrNotFound:
+		return nil, apierr.NotFound("plan")
+	default:
+		return nil, ps.s.dbError(ctx, err, "fetching plan by id")
+	}
+
+	return pl, nil
+}
+
+// ListPlans streams plans visible to the user, optionally only the ones of
+// a single user.
+func (ps *planService) ListPlans(in *pb.ListRequest, stream pb.PlanService_ListPlansServer) error {
+	ctx := stream.Context()
+	vw := grpcViewer(ctx)
+
+	fetch := func(ctx context.Context, pg core.Page) ([]core.Plan, *core.Cursor, error) {
+		return db.GetPlans(ctx, ps.s.db, vw, core.Query{}, pg)
+	}
+
+	if in.UserId != "" {
+		uid, aerr := parseGRPCID("user_id", in.UserId)
+		if aerr != nil {
+			return aerr
+		}
+
+		fetch = func(ctx context.Context, pg core.Page) ([]core.Plan, *core.Cursor, error) {
+			return db.GetPlansByUserID(ctx, ps.s.db, vw, uid, core.Query{}, pg)
+		}
+	}
+
+	return streamGRPCPages(ctx, ps.s, in, fetch, func(pl core.Plan) error {
+		return stream.Send(planToGRPC(&pl))
+	})
+}
+
+// CreatePlan creates a plan of the authorized user.
+func (ps *planService) CreatePlan(ctx context.Context, in *pb.PlanInput) (*pb.Plan, error) {
+	vw, aerr := grpcAuthorized(ctx, false)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	pc, aerr := planCoreFromGRPC(in)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	pl, aerr := ps.s.createPlan(ctx, vw.UserID, pc)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	return planToGRPC(pl), nil
+}
+
+// UpdatePlan replaces a plan of the authorized user.
+func (ps *planService) UpdatePlan(ctx context.Context, in *pb.UpdatePlanRequest) (*pb.Plan, error) {
+	vw, aerr := grpcAuthorized(ctx, false)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	pc, aerr := planCoreFromGRPC(in.Plan)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	pl, aerr := ps.getPlan(ctx, vw, in.Id)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	if pl.UserID.Compare(vw.UserID) != 0 {
+		return nil, apierr.Forbidden()
+	}
+
+	if aerr := checkGRPCVersion("plan", pl.Revision, in.Version); aerr != nil {
+		return nil, aerr
+	}
+
+	pl, aerr = ps.s.updatePlan(ctx, vw.UserID, pl, pc)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	return planToGRPC(pl), nil
+}
+
+// DeletePlan moves a plan to the trash. Only administrators can delete
+// plans of other users.
+func (ps *planService) DeletePlan(ctx context.Context, in *pb.IDRequest) (*emptypb.Empty, error) {
+	vw, aerr := grpcAuthorized(ctx, false)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	pid, aerr := parseGRPCID("id", in.Id)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	if aerr := ps.s.deletePlan(ctx, vw, pid); aerr != nil {
+		return nil, aerr
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// planToGRPC converts the plan to its protobuf message.
+func planToGRPC(pl *core.Plan) *pb.Plan {
+	res := &pb.Plan{
+		Id:          pl.ID.String(),
+		UserId:      pl.UserID.String(),
+		Name:        pl.Name,
+		Description: pl.Description,
+		Visibility:  _grpcVisibilities[pl.Visibility],
+		State:       _grpcStates[pl.State],
+		Recipes:     make([]*pb.PlanRecipe, 0, len(pl.Recipes)),
+		Version:     pl.Version,
+		CreatedAt:   timestamppb.New(pl.CreatedAt),
+		UpdatedAt:   timestamppb.New(pl.UpdatedAt),
+	}
+
+	for _, pr := range pl.Recipes {
+		res.Recipes = append(res.Recipes, &pb.PlanRecipe{
+			RecipeId: pr.RecipeID.String(),
+			Quantity: pr.Quantity,
+			Day:      pr.Day,
+		})
+	}
+
+	return res
+}
+
+// planCoreFromGRPC converts the protobuf plan input.
+func planCoreFromGRPC(in *pb.PlanInput) (core.PlanCore, *apierr.Error) {
+	pc := core.PlanCore{
+		Name:        in.GetName(),
+		Description: in.GetDescription(),
+		Visibility:  visibilityFromGRPC(in.GetVisibility()),
+		State:       stateFromGRPC(in.GetState()),
+		Recipes:     make([]core.PlanRecipe, 0, len(in.GetRecipes())),
+	}
+
+	for i, pr := range in.GetRecipes() {
+		rid, aerr := parseGRPCID(fmt.Sprintf("recipes[%d].recipe_id", i), pr.RecipeId)
+		if aerr != nil {
+			return core.PlanCore{}, aerr
+		}
+
+		pc.Recipes = append(pc.Recipes, core.PlanRecipe{
+			RecipeID: rid,
+			Quantity: pr.Quantity,
+			Day:      pr.Day,
+		})
+	}
+
+	return pc, nil
+}
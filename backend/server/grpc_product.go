@@ -0,0 +1,209 @@
+package server
+
+import (
+	"context"
+	"foodie/core"
+	"foodie/db"
+	"foodie/server/apierr"
+	"foodie/server/pb"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// productService implements the gRPC product service.
+type productService struct {
+	pb.UnimplementedProductServiceServer
+
+	// s is the server whose database and product index are used.
+	s *Server
+}
+
+// GetProduct retrieves a single product.
+func (ps *productService) GetProduct(ctx context.Context, in *pb.IDRequest) (*pb.Product, error) {
+	prd, aerr := ps.getProduct(ctx, grpcViewer(ctx), in.Id)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	return productToGRPC(prd), nil
+}
+
+// getProduct retrieves a single product by its id as seen by the viewer.
+func (ps *productService) getProduct(ctx context.Context, vw core.Viewer, id string) (*core.Product, *apierr.Error) {
+	pid, aerr := parseGRPCID("id", id)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	prd, err := db.GetProductByID(ctx, ps.s.db, vw, pid)
+	switch err {
+	case nil:
+		// OK.
+	case db.ErrNotFound:
+		return nil, apierr.NotFound("product")
+	default:
+		return nil, ps.s.dbError(ctx, err, "fetching product by id")
+	}
+
+	return prd, nil
+}
+
+// ListProducts streams products visible to the user.
+func (ps *productService) ListProducts(in *pb.ListRequest, stream pb.ProductService_ListProductsServer) error {
+	ctx := stream.Context()
+	vw := grpcViewer(ctx)
+
+	return streamGRPCPages(
+		ctx,
+		ps.s,
+		in,
+		func(ctx context.Context, pg core.Page) ([]core.Product, *core.Cursor, error) {
+			return db.GetProducts(ctx, ps.s.db, vw, core.Query{}, pg)
+		},
+		func(prd core.Product) error {
+			return stream.Send(productToGRPC(&prd))
+		},
+	)
+}
+
+// CreateProduct creates a product. Products of regular users are submitted
+// for a review.
+func (ps *productService) CreateProduct(ctx context.Context, in *pb.ProductInput) (*pb.Product, error) {
+	vw, aerr := grpcAuthorized(ctx, false)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	pc, aerr := productCoreFromGRPC(in)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	prd, aerr := ps.s.createProduct(ctx, vw, pc)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	return productToGRPC(prd), nil
+}
+
+// UpdateProduct replaces a product. Requires administrator permissions.
+func (ps *productService) UpdateProduct(ctx context.Context, in *pb.UpdateProductRequest) (*pb.Product, error) {
+	vw, aerr := grpcAuthorized(ctx, true)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	pc, aerr := productCoreFromGRPC(in.Product)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	prd, aerr := ps.getProduct(ctx, vw, in.Id)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	if aerr := checkGRPCVersion("product", prd.Revision, in.Version); aerr != nil {
+		return nil, aerr
+	}
+
+	prd, aerr = ps.s.updateProduct(ctx, prd, pc)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	return productToGRPC(prd), nil
+}
+
+// DeleteProduct moves a product that is not used by recipes to the trash.
+// Requires administrator permissions.
+func (ps *productService) DeleteProduct(ctx context.Context, in *pb.IDRequest) (*emptypb.Empty, error) {
+	vw, aerr := grpcAuthorized(ctx, true)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	pid, aerr := parseGRPCID("id", in.Id)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	if aerr := ps.s.deleteProduct(ctx, vw, pid); aerr != nil {
+		return nil, aerr
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// _grpcServingTypes maps serving types to their protobuf enum values.
+var _grpcServingTypes = map[core.ServingType]pb.ServingType{
+	core.ServingTypeGrams:       pb.ServingType_SERVING_TYPE_GRAMS,
+	core.ServingTypeMilliliters: pb.ServingType_SERVING_TYPE_MILLILITERS,
+	core.ServingTypeUnits:       pb.ServingType_SERVING_TYPE_UNITS,
+}
+
+// _grpcProductStatuses maps product statuses to their protobuf enum
+// values.
+var _grpcProductStatuses = map[core.ProductStatus]pb.ProductStatus{
+	core.ProductStatusPending:  pb.ProductStatus_PRODUCT_STATUS_PENDING,
+	core.ProductStatusApproved: pb.ProductStatus_PRODUCT_STATUS_APPROVED,
+	core.ProductStatusRejected: pb.ProductStatus_PRODUCT_STATUS_REJECTED,
+}
+
+// productToGRPC converts the product to its protobuf message.
+func productToGRPC(prd *core.Product) *pb.Product {
+	return &pb.Product{
+		Id:          prd.ID.String(),
+		UserId:      prd.UserID.String(),
+		Name:        prd.Name,
+		Aliases:     prd.Aliases,
+		Barcode:     prd.Barcode,
+		ImageUrl:    prd.ImageURL,
+		Description: prd.Description,
+		Serving: &pb.Serving{
+			Type:     _grpcServingTypes[prd.Serving.Type],
+			Size:     prd.Serving.Size.String(),
+			Calories: int64(prd.Serving.Calories),
+		},
+		Status:          _grpcProductStatuses[prd.Status],
+		RejectionReason: prd.RejectionReason,
+		Version:         prd.Version,
+		CreatedAt:       timestamppb.New(prd.CreatedAt),
+		UpdatedAt:       timestamppb.New(prd.UpdatedAt),
+	}
+}
+
+// productCoreFromGRPC converts the protobuf product input. Unspecified
+// serving type is left empty, so that it is reported by the validation.
+func productCoreFromGRPC(in *pb.ProductInput) (core.ProductCore, *apierr.Error) {
+	pc := core.ProductCore{
+		Name:        in.GetName(),
+		Aliases:     in.GetAliases(),
+		Barcode:     in.GetBarcode(),
+		ImageURL:    in.GetImageUrl(),
+		Description: in.GetDescription(),
+		Serving: core.Serving{
+			Calories: int(in.GetServing().GetCalories()),
+		},
+	}
+
+	for st, pst := range _grpcServingTypes {
+		if pst == in.GetServing().GetType() {
+			pc.Serving.Type = st
+		}
+	}
+
+	if v := in.GetServing().GetSize(); v != "" {
+		size, err := decimal.NewFromString(v)
+		if err != nil {
+			return core.ProductCore{}, apierr.InvalidAttribute("size", "must be a decimal number")
+		}
+
+		pc.Serving.Size = size
+	}
+
+	return pc, nil
+}
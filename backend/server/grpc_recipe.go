@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"foodie/core"
+	"foodie/db"
+	"foodie/server/apierr"
+	"foodie/server/pb"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// recipeService implements the gRPC recipe service.
+type recipeService struct {
+	pb.UnimplementedRecipeServiceServer
+
+	// s is the server whose database is used.
+	s *Server
+}
+
+// GetRecipe retrieves a single recipe.
+func (rs *recipeService) GetRecipe(ctx context.Context, in *pb.IDRequest) (*pb.Recipe, error) {
+	rec, aerr := rs.getRecipe(ctx, grpcViewer(ctx), in.Id)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	return recipeToGRPC(rec), nil
+}
+
+// getRecipe retrieves a single recipe by its id as seen by the viewer.
+func (rs *recipeService) getRecipe(ctx context.Context, vw core.Viewer, id string) (*core.Recipe, *apierr.Error) {
+	rid, aerr := parseGRPCID("id", id)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	rec, err := db.GetRecipeByID(ctx, rs.s.db, vw, rid)
+	switch err {
+	case nil:
+		// OK.
+	case db.ErrNotFound:
+		return nil, apierr.NotFound("recipe")
+	default:
+		return nil, rs.s.dbError(ctx, err, "fetching recipe by id")
+	}
+
+	return rec, nil
+}
+
+// ListRecipes streams recipes visible to the user, optionally only the
+// ones of a single user.
+func (rs *recipeService) ListRecipes(in *pb.ListRequest, stream pb.RecipeService_ListRecipesServer) error {
+	ctx := stream.Context()
+	vw := grpcViewer(ctx)
+
+	fetch := func(ctx context.Context, pg core.Page) ([]core.Recipe, *core.Cursor, error) {
+		return db.GetRecipes(ctx, rs.s.db, vw, core.Query{}, pg)
+	}
+
+	if in.UserId != "" {
+		uid, aerr := parseGRPCID("user_id", in.UserId)
+		if aerr != nil {
+			return aerr
+		}
+
+		fetch = func(ctx context.Context, pg core.Page) ([]core.Recipe, *core.Cursor, error) {
+			return db.GetRecipesByUserID(ctx, rs.s.db, vw, uid, core.Query{}, pg)
+		}
+	}
+
+	return streamGRPCPages(ctx, rs.s, in, fetch, func(rec core.Recipe) error {
+		return stream.Send(recipeToGRPC(&rec))
+	})
+}
+
+// CreateRecipe creates a recipe of the authorized user.
+func (rs *recipeService) CreateRecipe(ctx context.Context, in *pb.RecipeInput) (*pb.Recipe, error) {
+	vw, aerr := grpcAuthorized(ctx, false)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	rc, aerr := recipeCoreFromGRPC(in)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	rec, aerr := rs.s.createRecipe(ctx, vw.UserID, rc)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	return recipeToGRPC(rec), nil
+}
+
+// UpdateRecipe replaces a recipe of the authorized user.
+func (rs *recipeService) UpdateRecipe(ctx context.Context, in *pb.UpdateRecipeRequest) (*pb.Recipe, error) {
+	vw, aerr := grpcAuthorized(ctx, false)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	rc, aerr := recipeCoreFromGRPC(in.Recipe)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	rec, aerr := rs.getRecipe(ctx, vw, in.Id)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	if rec.UserID.Compare(vw.UserID) != 0 {
+		return nil, apierr.Forbidden()
+	}
+
+	if aerr := checkGRPCVersion("recipe", rec.Revision, in.Version); aerr != nil {
+		return nil, aerr
+	}
+
+	rec, aerr = rs.s.updateRecipe(ctx, vw.UserID, rec, rc)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	return recipeToGRPC(rec), nil
+}
+
+// DeleteRecipe moves a recipe that is not included in plans to the trash.
+// Only administrators can delete recipes of other users.
+func (rs *recipeService) DeleteRecipe(ctx context.Context, in *pb.IDRequest) (*emptypb.Empty, error) {
+	vw, aerr := grpcAuthorized(ctx, false)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	rid, aerr := parseGRPCID("id", in.Id)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	if aerr := rs.s.deleteRecipe(ctx, vw, rid); aerr != nil {
+		return nil, aerr
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// recipeToGRPC converts the recipe to its protobuf message.
+func recipeToGRPC(rec *core.Recipe) *pb.Recipe {
+	res := &pb.Recipe{
+		Id:          rec.ID.String(),
+		UserId:      rec.UserID.String(),
+		Name:        rec.Name,
+		ImageUrl:    rec.ImageURL,
+		Description: rec.Description,
+		Visibility:  _grpcVisibilities[rec.Visibility],
+		State:       _grpcStates[rec.State],
+		Products:    make([]*pb.RecipeProduct, 0, len(rec.Products)),
+		Version:     rec.Version,
+		CreatedAt:   timestamppb.New(rec.CreatedAt),
+		UpdatedAt:   timestamppb.New(rec.UpdatedAt),
+	}
+
+	for _, rp := range rec.Products {
+		res.Products = append(res.Products, &pb.RecipeProduct{
+			ProductId: rp.ProductID.String(),
+			Quantity:  rp.Quantity.String(),
+		})
+	}
+
+	return res
+}
+
+// recipeCoreFromGRPC converts the protobuf recipe input.
+func recipeCoreFromGRPC(in *pb.RecipeInput) (core.RecipeCore, *apierr.Error) {
+	rc := core.RecipeCore{
+		Name:        in.GetName(),
+		ImageURL:    in.GetImageUrl(),
+		Description: in.GetDescription(),
+		Visibility:  visibilityFromGRPC(in.GetVisibility()),
+		State:       stateFromGRPC(in.GetState()),
+		Products:    make([]core.RecipeProduct, 0, len(in.GetProducts())),
+	}
+
+	for i, rp := range in.GetProducts() {
+		pid, aerr := parseGRPCID(fmt.Sprintf("products[%d].product_id", i), rp.ProductId)
+		if aerr != nil {
+			return core.RecipeCore{}, aerr
+		}
+
+		qty, err := decimal.NewFromString(rp.Quantity)
+		if err != nil {
+			return core.RecipeCore{}, apierr.InvalidAttribute(
+				fmt.Sprintf("products[%d].quantity", i),
+				"must be a decimal number",
+			)
+		}
+
+		rc.Products = append(rc.Products, core.RecipeProduct{
+			ProductID: pid,
+			Quantity:  qty,
+		})
+	}
+
+	return rc, nil
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"foodie/core"
+	"foodie/server/apierr"
+	"foodie/server/pb"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func Test_Server_GRPC(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		Authorization string
+		Call          func(ctx context.Context, cc *grpc.ClientConn) error
+		Code          codes.Code
+	}{
+		"Anonymous self": {
+			Call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				_, err := pb.NewUserServiceClient(cc).GetSelf(ctx, &emptypb.Empty{})
+				return err
+			},
+			Code: codes.Unauthenticated,
+		},
+		"Malformed authorization": {
+			Authorization: "Basic 123",
+			Call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				_, err := pb.NewUserServiceClient(cc).GetSelf(ctx, &emptypb.Empty{})
+				return err
+			},
+			Code: codes.Unauthenticated,
+		},
+		"Invalid token": {
+			Authorization: "Bearer 123",
+			Call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				_, err := pb.NewUserServiceClient(cc).GetSelf(ctx, &emptypb.Empty{})
+				return err
+			},
+			Code: codes.Unauthenticated,
+		},
+		"Invalid token of a stream": {
+			Authorization: "Bearer 123",
+			Call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				stream, err := pb.NewProductServiceClient(cc).ListProducts(ctx, &pb.ListRequest{})
+				if err != nil {
+					return err
+				}
+
+				_, err = stream.Recv()
+
+				return err
+			},
+			Code: codes.Unauthenticated,
+		},
+		"Invalid product id": {
+			Call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				_, err := pb.NewProductServiceClient(cc).GetProduct(ctx, &pb.IDRequest{Id: "x"})
+				return err
+			},
+			Code: codes.InvalidArgument,
+		},
+		"Invalid cursor": {
+			Call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				stream, err := pb.NewRecipeServiceClient(cc).ListRecipes(ctx, &pb.ListRequest{Cursor: "x"})
+				if err != nil {
+					return err
+				}
+
+				_, err = stream.Recv()
+
+				return err
+			},
+			Code: codes.InvalidArgument,
+		},
+		"Anonymous plan creation": {
+			Call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				_, err := pb.NewPlanServiceClient(cc).CreatePlan(ctx, &pb.PlanInput{Name: "test"})
+				return err
+			},
+			Code: codes.Unauthenticated,
+		},
+	}
+
+	for tName, tCase := range tests {
+		tCase := tCase
+
+		t.Run(tName, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Server{
+				log: logrus.New(),
+				auth: &AuthorizerMock{
+					ParseFunc: func(_ []byte, _ time.Time) (xid.ID, bool, *apierr.Error) {
+						return xid.NilID(), false, apierr.Unauthorized()
+					},
+				},
+			}
+
+			lis := bufconn.Listen(1024 * 1024)
+			gs := s.newGRPCServer()
+
+			go gs.Serve(lis) //nolint:errcheck // the listener is closed by the test.
+			defer gs.Stop()
+
+			cc, err := grpc.Dial(
+				"bufnet",
+				grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+					return lis.DialContext(ctx)
+				}),
+				grpc.WithTransportCredentials(insecure.NewCredentials()),
+			)
+			require.NoError(t, err)
+
+			defer cc.Close()
+
+			ctx := context.Background()
+			if tCase.Authorization != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", tCase.Authorization)
+			}
+
+			assert.Equal(t, tCase.Code, status.Code(tCase.Call(ctx, cc)))
+		})
+	}
+}
+
+func Test_grpcAuthorized(t *testing.T) {
+	t.Parallel()
+
+	uid := xid.New()
+
+	tests := map[string]struct {
+		Context context.Context
+		Super   bool
+		Viewer  core.Viewer
+		Error   *apierr.Error
+	}{
+		"Anonymous": {
+			Context: context.Background(),
+			Error:   apierr.Unauthorized(),
+		},
+		"Regular user": {
+			Context: withViewer(context.Background(), uid, false),
+			Viewer:  core.Viewer{UserID: uid},
+		},
+		"Regular user without administrator permissions": {
+			Context: withViewer(context.Background(), uid, false),
+			Super:   true,
+			Error:   apierr.Forbidden(),
+		},
+		"Administrator": {
+			Context: withViewer(context.Background(), uid, true),
+			Super:   true,
+			Viewer:  core.Viewer{UserID: uid, Admin: true},
+		},
+	}
+
+	for tName, tCase := range tests {
+		tCase := tCase
+
+		t.Run(tName, func(t *testing.T) {
+			t.Parallel()
+
+			vw, aerr := grpcAuthorized(tCase.Context, tCase.Super)
+			assert.Equal(t, tCase.Error, aerr)
+			assert.Equal(t, tCase.Viewer, vw)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"foodie/core"
+	"foodie/db"
+	"foodie/server/apierr"
+	"foodie/server/pb"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// userService implements the gRPC user service.
+type userService struct {
+	pb.UnimplementedUserServiceServer
+
+	// s is the server whose database and authorizer are used.
+	s *Server
+}
+
+// Register creates a new user and issues its access token.
+func (us *userService) Register(ctx context.Context, in *pb.UserInput) (*pb.Session, error) {
+	ses, aerr := us.s.register(ctx, core.UserInput{Name: in.Name, Password: in.Password})
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	return sessionToGRPC(ses), nil
+}
+
+// Login authenticates the user by its credentials and issues its access
+// token.
+func (us *userService) Login(ctx context.Context, in *pb.UserInput) (*pb.Session, error) {
+	ses, aerr := us.s.login(ctx, core.UserInput{Name: in.Name, Password: in.Password})
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	return sessionToGRPC(ses), nil
+}
+
+// GetSelf retrieves the authorized user.
+func (us *userService) GetSelf(ctx context.Context, _ *emptypb.Empty) (*pb.User, error) {
+	vw, aerr := grpcAuthorized(ctx, false)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	return us.getUser(ctx, vw.UserID.String())
+}
+
+// GetUser retrieves a single user. Requires administrator permissions.
+func (us *userService) GetUser(ctx context.Context, in *pb.IDRequest) (*pb.User, error) {
+	if _, aerr := grpcAuthorized(ctx, true); aerr != nil {
+		return nil, aerr
+	}
+
+	return us.getUser(ctx, in.Id)
+}
+
+// getUser retrieves a single user by its id.
+func (us *userService) getUser(ctx context.Context, id string) (*pb.User, error) {
+	uid, aerr := parseGRPCID("id", id)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	usr, err := db.GetUserByID(ctx, us.s.db, uid)
+	switch err {
+	case nil:
+		// OK.
+	case db.ErrNotFound:
+		return nil, apierr.NotFound("user")
+	default:
+		return nil, us.s.dbError(ctx, err, "fetching user by id")
+	}
+
+	return userToGRPC(usr), nil
+}
+
+// ListUsers streams users. Requires administrator permissions.
+func (us *userService) ListUsers(in *pb.ListRequest, stream pb.UserService_ListUsersServer) error {
+	ctx := stream.Context()
+
+	if _, aerr := grpcAuthorized(ctx, true); aerr != nil {
+		return aerr
+	}
+
+	return streamGRPCPages(
+		ctx,
+		us.s,
+		in,
+		func(ctx context.Context, pg core.Page) ([]core.User, *core.Cursor, error) {
+			return db.GetUsers(ctx, us.s.db, core.Query{}, pg)
+		},
+		func(usr core.User) error {
+			return stream.Send(userToGRPC(&usr))
+		},
+	)
+}
+
+// DeleteUser deletes the authorized user. Administrators can delete other
+// users by their ids.
+func (us *userService) DeleteUser(ctx context.Context, in *pb.IDRequest) (*emptypb.Empty, error) {
+	vw, aerr := grpcAuthorized(ctx, false)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	uid := vw.UserID
+
+	if in.Id != "" {
+		uid, aerr = parseGRPCID("id", in.Id)
+		if aerr != nil {
+			return nil, aerr
+		}
+
+		if uid != vw.UserID && !vw.Admin {
+			return nil, apierr.Forbidden()
+		}
+	}
+
+	if aerr := us.s.deleteUser(ctx, vw.Admin, uid); aerr != nil {
+		return nil, aerr
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// userToGRPC converts the user to its protobuf message.
+func userToGRPC(usr *core.User) *pb.User {
+	return &pb.User{
+		Id:        usr.ID.String(),
+		Name:      usr.Name,
+		Admin:     usr.Admin,
+		CreatedAt: timestamppb.New(usr.CreatedAt),
+	}
+}
+
+// sessionToGRPC converts the session to its protobuf message.
+func sessionToGRPC(ses *session) *pb.Session {
+	return &pb.Session{
+		User:        userToGRPC(ses.User),
+		AccessToken: ses.AccessToken,
+	}
+}
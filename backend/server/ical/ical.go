@@ -0,0 +1,136 @@
+// Package ical writes iCalendar (RFC 5545) feeds, so that scheduled plans
+// could be subscribed to by calendar apps.
+package ical
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType specifies the media type of iCalendar feeds.
+const ContentType = "text/calendar; charset=utf-8"
+
+const (
+	// _maxLineLength specifies the maximum length of content lines in
+	// octets, excluding the line break.
+	_maxLineLength = 75
+
+	// _dayLayout specifies the layout of DATE values.
+	_dayLayout = "20060102"
+
+	// _timeLayout specifies the layout of UTC DATE-TIME values.
+	_timeLayout = "20060102T150405Z"
+)
+
+// Calendar is a single iCalendar object.
+type Calendar struct {
+	// ProdID identifies the product that created the calendar.
+	ProdID string
+
+	// Name specifies the name of the calendar, which is displayed by
+	// calendar apps.
+	Name string
+
+	// Events contains the events of the calendar.
+	Events []Event
+}
+
+// Event is an all-day event.
+type Event struct {
+	// UID specifies the globally unique identifier of the event, which
+	// stays the same when the event changes.
+	UID string
+
+	// Stamp specifies a time at which the event was last modified.
+	Stamp time.Time
+
+	// Day specifies the day of the event.
+	Day time.Time
+
+	// Summary specifies the title of the event.
+	Summary string
+
+	// Description provides the details of the event.
+	Description string
+}
+
+// WriteTo writes the calendar in the iCalendar format. Lines end with
+// CRLF and are folded once they are longer than 75 octets.
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+
+	line := func(name, value string) {
+		fold(&buf, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", escape(c.ProdID))
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+
+	if c.Name != "" {
+		line("X-WR-CALNAME", escape(c.Name))
+	}
+
+	for _, ev := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", escape(ev.UID))
+		line("DTSTAMP", ev.Stamp.UTC().Format(_timeLayout))
+		line("DTSTART;VALUE=DATE", ev.Day.Format(_dayLayout))
+		line("DTEND;VALUE=DATE", ev.Day.AddDate(0, 0, 1).Format(_dayLayout))
+		line("SUMMARY", escape(ev.Summary))
+
+		if ev.Description != "" {
+			line("DESCRIPTION", escape(ev.Description))
+		}
+
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+
+	return buf.WriteTo(w)
+}
+
+// _escaper escapes the characters of TEXT values.
+var _escaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", "",
+)
+
+// escape escapes the text, so that it could be used as a TEXT value.
+func escape(text string) string {
+	return _escaper.Replace(text)
+}
+
+// fold writes the content line, splitting it into multiple lines of at
+// most 75 octets. Continuation lines start with a space, and multi-octet
+// characters are never split.
+func fold(buf *bytes.Buffer, line string) {
+	limit := _maxLineLength
+
+	for len(line) > limit {
+		n := limit
+		for n > 0 && !utf8.RuneStart(line[n]) {
+			n--
+		}
+
+		buf.WriteString(line[:n])
+		buf.WriteString("\r\n ")
+
+		line = line[n:]
+		limit = _maxLineLength - 1
+	}
+
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
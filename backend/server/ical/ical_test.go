@@ -0,0 +1,88 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Calendar_WriteTo(t *testing.T) {
+	cal := Calendar{
+		ProdID: "-//foodie//plans//EN",
+		Name:   "Week, 1",
+		Events: []Event{
+			{
+				UID:         "a@foodie",
+				Stamp:       time.Date(2022, 10, 1, 12, 30, 0, 0, time.FixedZone("", 2*60*60)),
+				Day:         time.Date(2022, 10, 31, 0, 0, 0, 0, time.UTC),
+				Summary:     "Soup; hot",
+				Description: "Ingredients:\n- 1 l Water",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	n, err := cal.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	assert.Equal(t, strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//foodie//plans//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		`X-WR-CALNAME:Week\, 1`,
+		"BEGIN:VEVENT",
+		"UID:a@foodie",
+		"DTSTAMP:20221001T103000Z",
+		"DTSTART;VALUE=DATE:20221031",
+		"DTEND;VALUE=DATE:20221101",
+		`SUMMARY:Soup\; hot`,
+		`DESCRIPTION:Ingredients:\n- 1 l Water`,
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n"), buf.String())
+}
+
+func Test_fold(t *testing.T) {
+	tests := map[string]struct {
+		Line   string
+		Result string
+	}{
+		"Short line": {
+			Line:   "SUMMARY:Soup",
+			Result: "SUMMARY:Soup\r\n",
+		},
+		"Long line": {
+			Line: "DESCRIPTION:" + strings.Repeat("a", 150),
+			Result: "DESCRIPTION:" + strings.Repeat("a", 63) + "\r\n " +
+				strings.Repeat("a", 74) + "\r\n " +
+				strings.Repeat("a", 13) + "\r\n",
+		},
+		"Multi-octet characters": {
+			Line:   "SUMMARY:" + strings.Repeat("ą", 40),
+			Result: "SUMMARY:" + strings.Repeat("ą", 33) + "\r\n " + strings.Repeat("ą", 7) + "\r\n",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			fold(&buf, test.Line)
+			assert.Equal(t, test.Result, buf.String())
+		})
+	}
+}
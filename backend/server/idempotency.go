@@ -0,0 +1,225 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"foodie/core"
+	"foodie/db"
+	"foodie/server/apierr"
+	"io"
+	"net/http"
+	"time"
+)
+
+// _idempotencyLease specifies how long a request with an idempotency key
+// is considered to be in progress. Requests that were not completed in
+// time, e.g. because the server crashed while handling them, are handled
+// again when retried.
+const _idempotencyLease = time.Minute
+
+// idempotent is a middleware that makes requests with the Idempotency-Key
+// header safe to retry. The first response of the user to a request with
+// the key is stored and replayed byte-for-byte to the retries of the same
+// request until the key expires. Retries with a different method, path
+// or body are refused. Server errors are not stored, so that the request
+// could be retried. Keys are scoped by users. Guests have no user id, so
+// their keys are scoped by the request itself instead: only the retries of
+// the same request share a key, and unrelated guests never collide.
+func (s *Server) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if aerr := core.ValidateIdempotencyKey(key); aerr != nil {
+			aerr.Respond(w)
+			return
+		}
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			apierr.MalformedDataInput(apierr.DataTypeRequestBody).Respond(w)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(data))
+
+		now := time.Now()
+		ir := core.IdempotentRequest{
+			UserID:    s.extractViewer(r).UserID,
+			Key:       key,
+			Hash:      hashRequest(r, data),
+			CreatedAt: now,
+		}
+
+		if ir.UserID.IsNil() {
+			ir.Key = guestKey(key, ir.Hash)
+		}
+
+		expiry, abandoned := now.Add(-s.idempotencyTTL), now.Add(-_idempotencyLease)
+
+		prev, err := db.GetIdempotentRequest(r.Context(), s.db, ir.UserID, ir.Key, expiry, abandoned)
+		switch err {
+		case nil:
+			s.replayIdempotentRequest(w, ir, prev)
+			return
+		case r.Context().Err():
+			apierr.Context().Respond(w)
+			return
+		case db.ErrNotFound:
+			// OK.
+		default:
+			s.log.WithError(err).Error("fetching idempotent request")
+			apierr.Database().Respond(w)
+
+			return
+		}
+
+		err = db.InsertIdempotentRequest(r.Context(), s.db, ir, expiry, abandoned)
+		switch err {
+		case nil:
+			// OK.
+		case r.Context().Err():
+			apierr.Context().Respond(w)
+			return
+		case db.ErrDuplicateIdempotencyKey:
+			apierr.Conflict("request", "request with the same idempotency key is in progress").Respond(w)
+			return
+		default:
+			s.log.WithError(err).Error("inserting idempotent request")
+			apierr.Database().Respond(w)
+
+			return
+		}
+
+		rw := &recordingWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r)
+
+		// The outcome is stored within the server context, since the
+		// request might already be cancelled.
+		if rw.status >= http.StatusInternalServerError || r.Context().Err() != nil {
+			if err := db.DeleteIdempotentRequest(s.ctx, s.db, ir.UserID, ir.Key); err != nil {
+				s.log.WithError(err).Error("deleting idempotent request")
+			}
+
+			return
+		}
+
+		ir.StatusCode = rw.status
+		if ir.StatusCode == 0 {
+			ir.StatusCode = http.StatusOK
+		}
+
+		ir.ContentType = rw.Header().Get("Content-Type")
+		ir.Body = rw.body.Bytes()
+
+		if err := db.CompleteIdempotentRequest(s.ctx, s.db, ir); err != nil {
+			s.log.WithError(err).Error("completing idempotent request")
+		}
+	})
+}
+
+// replayIdempotentRequest responds with the stored response of the
+// previous request with the same key, if it matches the request and is
+// already handled.
+func (s *Server) replayIdempotentRequest(w http.ResponseWriter, ir core.IdempotentRequest, prev *core.IdempotentRequest) {
+	if prev.Hash != ir.Hash {
+		apierr.IdempotencyKeyReused().Respond(w)
+		return
+	}
+
+	if !prev.Completed() {
+		apierr.Conflict("request", "request with the same idempotency key is in progress").Respond(w)
+		return
+	}
+
+	if prev.ContentType != "" {
+		w.Header().Set("Content-Type", prev.ContentType)
+	}
+
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(prev.StatusCode)
+
+	if _, err := w.Write(prev.Body); err != nil {
+		s.log.WithError(err).Error("writing replayed response")
+	}
+}
+
+// purgeIdempotentRequests periodically deletes the requests whose
+// idempotency keys have expired. It blocks until the server is stopped.
+func (s *Server) purgeIdempotentRequests() {
+	ticker := time.NewTicker(_purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		err := db.PurgeIdempotentRequests(s.ctx, s.db, time.Now().Add(-s.idempotencyTTL))
+		if err != nil && s.ctx.Err() == nil {
+			s.log.WithError(err).Error("purging idempotent requests")
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// hashRequest returns the hex encoded SHA-256 hash of the request method,
+// path and body.
+func hashRequest(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// guestKey returns the hex encoded SHA-256 hash of the idempotency key of a
+// guest and the hash of its request, which the key is scoped by.
+func guestKey(key, hash string) string {
+	h := sha256.New()
+	h.Write([]byte(key + "\n" + hash))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter records the status code and the body of the response
+// while writing it.
+type recordingWriter struct {
+	http.ResponseWriter
+
+	// status specifies the status code of the response.
+	status int
+
+	// body contains the written response body.
+	body bytes.Buffer
+}
+
+// WriteHeader records the status code and writes it.
+func (rw *recordingWriter) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the data and writes it.
+func (rw *recordingWriter) Write(data []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+
+	rw.body.Write(data)
+
+	return rw.ResponseWriter.Write(data)
+}
+
+// Unwrap returns the underlying response writer.
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
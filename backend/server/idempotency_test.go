@@ -0,0 +1,87 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Server_idempotent(t *testing.T) {
+	tests := map[string]struct {
+		Key        string
+		StatusCode int
+		Handled    bool
+	}{
+		"Request without a key": {
+			StatusCode: http.StatusCreated,
+			Handled:    true,
+		},
+		"Too long key": {
+			Key:        strings.Repeat("a", 256),
+			StatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for tName, tCase := range tests {
+		tCase := tCase
+
+		t.Run(tName, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Server{log: logrus.New()}
+
+			var handled bool
+
+			h := s.idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handled = true
+				w.WriteHeader(http.StatusCreated)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/recipes", strings.NewReader(`{}`))
+			if tCase.Key != "" {
+				req.Header.Set("Idempotency-Key", tCase.Key)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, tCase.StatusCode, rec.Code)
+			assert.Equal(t, tCase.Handled, handled)
+		})
+	}
+}
+
+func Test_hashRequest(t *testing.T) {
+	hash := hashRequest(httptest.NewRequest(http.MethodPost, "/recipes", nil), []byte(`{"a":1}`))
+
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, hashRequest(httptest.NewRequest(http.MethodPost, "/recipes", nil), []byte(`{"a":1}`)))
+	assert.NotEqual(t, hash, hashRequest(httptest.NewRequest(http.MethodPost, "/recipes", nil), []byte(`{"a":2}`)))
+	assert.NotEqual(t, hash, hashRequest(httptest.NewRequest(http.MethodPost, "/plans", nil), []byte(`{"a":1}`)))
+}
+
+func Test_guestKey(t *testing.T) {
+	key := guestKey("key", "hash")
+
+	assert.Len(t, key, 64)
+	assert.Equal(t, key, guestKey("key", "hash"))
+	assert.NotEqual(t, key, guestKey("key", "other"))
+	assert.NotEqual(t, key, guestKey("other", "hash"))
+}
+
+func Test_recordingWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := &recordingWriter{ResponseWriter: rec}
+
+	rw.WriteHeader(http.StatusConflict)
+	rw.Write([]byte("data")) //nolint:errcheck // the recorder does not fail.
+
+	assert.Equal(t, http.StatusConflict, rw.status)
+	assert.Equal(t, "data", rw.body.String())
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "data", rec.Body.String())
+}
@@ -0,0 +1,76 @@
+// Package mergepatch applies JSON merge patches as described in RFC 7396.
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ContentType specifies the media type of JSON merge patches.
+const ContentType = "application/merge-patch+json"
+
+// ErrTrailingData is returned whenever a JSON value is followed by more
+// data.
+var ErrTrailingData = errors.New("unexpected data after the JSON value")
+
+// Apply applies the merge patch to the JSON document and returns the
+// patched document. Members of the patch replace the members of the
+// document recursively, while null members remove them. A patch that is
+// not an object replaces the whole document.
+func Apply(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(merge(target, p))
+}
+
+// merge merges the patch value into the target value.
+func merge(target, patch interface{}) interface{} {
+	pm, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	tm, ok := target.(map[string]interface{})
+	if !ok {
+		tm = make(map[string]interface{}, len(pm))
+	}
+
+	for key, val := range pm {
+		if val == nil {
+			delete(tm, key)
+			continue
+		}
+
+		tm[key] = merge(tm[key], val)
+	}
+
+	return tm
+}
+
+// decode decodes a single JSON value. Numbers are kept in their original
+// representation, so that decimals do not lose their precision.
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var val interface{}
+	if err := dec.Decode(&val); err != nil {
+		return nil, err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, ErrTrailingData
+	}
+
+	return val, nil
+}
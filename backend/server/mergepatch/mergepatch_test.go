@@ -0,0 +1,131 @@
+package mergepatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Apply(t *testing.T) {
+	tests := map[string]struct {
+		Doc    string
+		Patch  string
+		Result string
+		Error  bool
+	}{
+		// Examples from the appendix A of RFC 7396.
+		"Replace a member": {
+			Doc:    `{"a":"b"}`,
+			Patch:  `{"a":"c"}`,
+			Result: `{"a":"c"}`,
+		},
+		"Add a member": {
+			Doc:    `{"a":"b"}`,
+			Patch:  `{"b":"c"}`,
+			Result: `{"a":"b","b":"c"}`,
+		},
+		"Remove a member": {
+			Doc:    `{"a":"b"}`,
+			Patch:  `{"a":null}`,
+			Result: `{}`,
+		},
+		"Remove one of the members": {
+			Doc:    `{"a":"b","b":"c"}`,
+			Patch:  `{"a":null}`,
+			Result: `{"b":"c"}`,
+		},
+		"Replace an array": {
+			Doc:    `{"a":["b"]}`,
+			Patch:  `{"a":"c"}`,
+			Result: `{"a":"c"}`,
+		},
+		"Replace with an array": {
+			Doc:    `{"a":"c"}`,
+			Patch:  `{"a":["b"]}`,
+			Result: `{"a":["b"]}`,
+		},
+		"Merge nested objects": {
+			Doc:    `{"a":{"b":"c"}}`,
+			Patch:  `{"a":{"b":"d","c":null}}`,
+			Result: `{"a":{"b":"d"}}`,
+		},
+		"Arrays are not merged": {
+			Doc:    `{"a":[{"b":"c"}]}`,
+			Patch:  `{"a":[1]}`,
+			Result: `{"a":[1]}`,
+		},
+		"Replace an array document": {
+			Doc:    `["a","b"]`,
+			Patch:  `["c","d"]`,
+			Result: `["c","d"]`,
+		},
+		"Replace an object document": {
+			Doc:    `{"a":"b"}`,
+			Patch:  `["c"]`,
+			Result: `["c"]`,
+		},
+		"Replace with null": {
+			Doc:    `{"a":"foo"}`,
+			Patch:  `null`,
+			Result: `null`,
+		},
+		"Replace with a string": {
+			Doc:    `{"a":"foo"}`,
+			Patch:  `"bar"`,
+			Result: `"bar"`,
+		},
+		"Keep nulls of the document": {
+			Doc:    `{"e":null}`,
+			Patch:  `{"a":1}`,
+			Result: `{"a":1,"e":null}`,
+		},
+		"Patch a non-object document": {
+			Doc:    `[1,2]`,
+			Patch:  `{"a":"b","c":null}`,
+			Result: `{"a":"b"}`,
+		},
+		"Drop nested nulls of new members": {
+			Doc:    `{}`,
+			Patch:  `{"a":{"bb":{"ccc":null}}}`,
+			Result: `{"a":{"bb":{}}}`,
+		},
+		"Keep number precision": {
+			Doc:    `{"quantity":0.1234567890123456789}`,
+			Patch:  `{"name":"a"}`,
+			Result: `{"name":"a","quantity":0.1234567890123456789}`,
+		},
+		"Malformed document": {
+			Doc:   `{`,
+			Patch: `{}`,
+			Error: true,
+		},
+		"Malformed patch": {
+			Doc:   `{}`,
+			Patch: `{"a":}`,
+			Error: true,
+		},
+		"Trailing data": {
+			Doc:   `{}`,
+			Patch: `{} {}`,
+			Error: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := Apply([]byte(test.Doc), []byte(test.Patch))
+			if test.Error {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.JSONEq(t, test.Result, string(res))
+		})
+	}
+}
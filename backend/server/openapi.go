@@ -0,0 +1,1343 @@
+package server
+
+import (
+	"foodie/core"
+	"foodie/server/apierr"
+	"foodie/server/mergepatch"
+	"foodie/server/openapi"
+	"net/http"
+	"strconv"
+)
+
+var (
+	// _publicAccess specifies that an operation does not require
+	// authorization.
+	_publicAccess []openapi.SecurityRequirement
+
+	// _optionalAccess specifies that an operation can be used by guests,
+	// but its results depend on the authorized user.
+	_optionalAccess = []openapi.SecurityRequirement{
+		{},
+		{"bearerAuth": {}},
+	}
+
+	// _requiredAccess specifies that an operation requires
+	// authorization.
+	_requiredAccess = []openapi.SecurityRequirement{
+		{"bearerAuth": {}},
+	}
+)
+
+// GetOpenAPI returns the OpenAPI document of the API.
+func (s *Server) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, s.spec)
+}
+
+// newSpec creates the OpenAPI document that describes every route of the
+// API router.
+func newSpec() *openapi.Document {
+	return &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:   "Foodie API",
+			Version: _version,
+		},
+		Servers: []openapi.Server{
+			{URL: "/api"},
+		},
+		Paths:      specPaths(),
+		Components: specComponents(),
+	}
+}
+
+// specPaths describes the operations of the API router by their paths.
+func specPaths() map[string]openapi.PathItem {
+	return map[string]openapi.PathItem{
+		"/login": {
+			"post": {
+				OperationID: "login",
+				Summary:     "Authenticates the user by its credentials.",
+				Tags:        []string{"users"},
+				Security:    _publicAccess,
+				RequestBody: jsonBody(openapi.Ref("UserInput")),
+				Responses:   responses(http.StatusOK, openapi.Ref("Session"), "BadRequest", "Unauthorized", "ServiceUnavailable"),
+			},
+		},
+		"/register": {
+			"post": {
+				OperationID: "register",
+				Summary:     "Registers a new user.",
+				Tags:        []string{"users"},
+				Security:    _publicAccess,
+				Parameters:  []openapi.Parameter{idempotencyKeyParam()},
+				RequestBody: jsonBody(openapi.Ref("UserInput")),
+				Responses:   responses(http.StatusOK, openapi.Ref("Session"), "BadRequest", "Conflict", "IdempotencyKeyReused", "ServiceUnavailable"),
+			},
+		},
+		"/self": {
+			"get": {
+				OperationID: "getSelf",
+				Summary:     "Retrieves the authorized user.",
+				Tags:        []string{"users"},
+				Security:    _requiredAccess,
+				Responses:   responses(http.StatusOK, openapi.Ref("User"), "Unauthorized", "NotFound", "ServiceUnavailable"),
+			},
+		},
+		"/self/calendar-token": {
+			"post": {
+				OperationID: "createCalendarToken",
+				Summary:     "Generates a new secret calendar token of the authorized user, replacing the previous one.",
+				Tags:        []string{"users"},
+				Security:    _requiredAccess,
+				Responses: responses(http.StatusOK, object(map[string]*openapi.Schema{
+					"token": {Type: "string", MinLength: openapi.Int(1)},
+				}, "token"), "Unauthorized", "ServiceUnavailable"),
+			},
+			"delete": {
+				OperationID: "revokeCalendarToken",
+				Summary:     "Revokes the secret calendar token of the authorized user.",
+				Tags:        []string{"users"},
+				Security:    _requiredAccess,
+				Responses:   responses(http.StatusNoContent, nil, "Unauthorized", "ServiceUnavailable"),
+			},
+		},
+		"/products": {
+			"get": {
+				OperationID: "getProducts",
+				Summary:     "Retrieves a page of products.",
+				Tags:        []string{"products"},
+				Security:    _optionalAccess,
+				Parameters:  listParams(),
+				Responses:   responses(http.StatusOK, pageOf(openapi.Ref("Product")), "BadRequest", "ServiceUnavailable"),
+			},
+			"post": {
+				OperationID: "createProduct",
+				Summary:     "Creates a product. Products of regular users are submitted for a review.",
+				Tags:        []string{"products"},
+				Security:    _requiredAccess,
+				RequestBody: jsonBody(openapi.Ref("ProductCore")),
+				Responses:   responses(http.StatusOK, openapi.Ref("Product"), "BadRequest", "Unauthorized", "Conflict", "ServiceUnavailable"),
+			},
+		},
+		"/products/suggest": {
+			"get": {
+				OperationID: "suggestProducts",
+				Summary:     "Suggests products by their names and aliases.",
+				Tags:        []string{"products"},
+				Security:    _optionalAccess,
+				Parameters: []openapi.Parameter{
+					queryParam("q", "Text to complete.", true, &openapi.Schema{Type: "string"}),
+					queryParam("limit", "Maximum number of suggestions.", false, &openapi.Schema{
+						Type:    "integer",
+						Minimum: openapi.Float(1),
+						Maximum: openapi.Float(_maxSuggestLimit),
+					}),
+				},
+				Responses: responses(http.StatusOK, openapi.ArrayOf(openapi.Ref("Product")), "BadRequest"),
+			},
+		},
+		"/products/submissions": {
+			"get": {
+				OperationID: "getProductSubmissions",
+				Summary:     "Retrieves a page of products by their moderation status.",
+				Tags:        []string{"products"},
+				Security:    _requiredAccess,
+				Parameters: append(pageParams(), queryParam(
+					"status",
+					"Moderation status of the products. Defaults to pending.",
+					false,
+					openapi.Ref("ProductStatus"),
+				)),
+				Responses: responses(http.StatusOK, pageOf(openapi.Ref("Product")), "BadRequest", "Unauthorized", "Forbidden", "ServiceUnavailable"),
+			},
+		},
+		"/products/import": {
+			"post": {
+				OperationID: "importProducts",
+				Summary:     "Creates or updates products in bulk, matching them by their barcodes or names.",
+				Tags:        []string{"products"},
+				Security:    _requiredAccess,
+				Parameters: []openapi.Parameter{
+					queryParam("dry_run", "Whether the changes are discarded after being checked.", false, &openapi.Schema{Type: "boolean"}),
+				},
+				RequestBody: &openapi.RequestBody{
+					Required: true,
+					Content: map[string]openapi.MediaType{
+						"text/csv":             {Schema: &openapi.Schema{Type: "string", Description: "CSV document with a header row."}},
+						"application/x-ndjson": {Schema: openapi.Ref("ProductCore")},
+					},
+				},
+				Responses: responses(http.StatusOK, openapi.Ref("ProductImport"), "BadRequest", "Unauthorized", "Forbidden", "Conflict", "ServiceUnavailable"),
+			},
+		},
+		"/products/export": {
+			"get": {
+				OperationID: "exportProducts",
+				Summary:     "Streams all products as a CSV or JSON lines document.",
+				Tags:        []string{"products"},
+				Security:    _requiredAccess,
+				Parameters: []openapi.Parameter{
+					queryParam("format", "Format of the document. Defaults to jsonl.", false, &openapi.Schema{
+						Type: "string",
+						Enum: []interface{}{"csv", "jsonl"},
+					}),
+				},
+				Responses: map[string]openapi.Response{
+					"200": {
+						Description: http.StatusText(http.StatusOK),
+						Content: map[string]openapi.MediaType{
+							"text/csv":             {Schema: &openapi.Schema{Type: "string"}},
+							"application/x-ndjson": {Schema: openapi.Ref("Product")},
+						},
+					},
+					"400": {Ref: "#/components/responses/BadRequest"},
+					"401": {Ref: "#/components/responses/Unauthorized"},
+					"403": {Ref: "#/components/responses/Forbidden"},
+					"503": {Ref: "#/components/responses/ServiceUnavailable"},
+				},
+			},
+		},
+		"/products/{productID}": {
+			"get": {
+				OperationID: "getProduct",
+				Summary:     "Retrieves a single product.",
+				Tags:        []string{"products"},
+				Security:    _optionalAccess,
+				Parameters:  append([]openapi.Parameter{pathID("productID")}, conditionalParams()...),
+				Responses:   responses(http.StatusOK, openapi.Ref("Product"), "BadRequest", "NotModified", "NotFound", "PreconditionFailed", "ServiceUnavailable"),
+			},
+			"put": {
+				OperationID: "replaceProduct",
+				Summary:     "Replaces a product.",
+				Tags:        []string{"products"},
+				Security:    _requiredAccess,
+				Parameters:  append([]openapi.Parameter{pathID("productID")}, conditionalParams()...),
+				RequestBody: jsonBody(openapi.Ref("ProductCore")),
+				Responses:   responses(http.StatusOK, openapi.Ref("Product"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "Conflict", "PreconditionFailed", "ServiceUnavailable"),
+			},
+			"patch": {
+				OperationID: "updateProduct",
+				Summary:     "Changes the provided attributes of a product.",
+				Tags:        []string{"products"},
+				Security:    _requiredAccess,
+				Parameters:  append([]openapi.Parameter{pathID("productID")}, conditionalParams()...),
+				RequestBody: mergePatchBody("ProductCore"),
+				Responses:   responses(http.StatusOK, openapi.Ref("Product"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "Conflict", "PreconditionFailed", "ServiceUnavailable"),
+			},
+			"delete": {
+				OperationID: "deleteProduct",
+				Summary:     "Moves a product to the trash, optionally reassigning its references to a replacement.",
+				Tags:        []string{"products"},
+				Security:    _requiredAccess,
+				Parameters: []openapi.Parameter{
+					pathID("productID"),
+					queryParam("replace_with", "Product that replaces the references of the deleted one.", false, openapi.Ref("ID")),
+				},
+				Responses: responses(http.StatusNoContent, nil, "BadRequest", "Unauthorized", "Forbidden", "NotFound", "InUse", "ServiceUnavailable"),
+			},
+		},
+		"/products/{productID}/approve": {
+			"post": {
+				OperationID: "approveProduct",
+				Summary:     "Approves a submitted product, optionally updating it.",
+				Tags:        []string{"products"},
+				Security:    _requiredAccess,
+				Parameters:  []openapi.Parameter{pathID("productID")},
+				RequestBody: &openapi.RequestBody{
+					Content: map[string]openapi.MediaType{
+						"application/json": {Schema: openapi.Ref("ProductCore")},
+					},
+				},
+				Responses: responses(http.StatusOK, openapi.Ref("Product"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "Conflict", "ServiceUnavailable"),
+			},
+		},
+		"/products/{productID}/reject": {
+			"post": {
+				OperationID: "rejectProduct",
+				Summary:     "Rejects a submitted product.",
+				Tags:        []string{"products"},
+				Security:    _requiredAccess,
+				Parameters:  []openapi.Parameter{pathID("productID")},
+				RequestBody: jsonBody(openapi.Ref("Rejection")),
+				Responses:   responses(http.StatusNoContent, nil, "BadRequest", "Unauthorized", "Forbidden", "NotFound", "Conflict", "ServiceUnavailable"),
+			},
+		},
+		"/products/{productID}/merge": {
+			"post": {
+				OperationID: "mergeProducts",
+				Summary:     "Merges duplicate products into the product.",
+				Tags:        []string{"products"},
+				Security:    _requiredAccess,
+				Parameters:  []openapi.Parameter{pathID("productID")},
+				RequestBody: jsonBody(openapi.Ref("ProductMergeInput")),
+				Responses:   responses(http.StatusOK, openapi.Ref("ProductMerge"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "Conflict", "ServiceUnavailable"),
+			},
+		},
+		"/products/{productID}/restore": {
+			"post": {
+				OperationID: "restoreProduct",
+				Summary:     "Restores a product from the trash.",
+				Tags:        []string{"products"},
+				Security:    _requiredAccess,
+				Parameters:  []openapi.Parameter{pathID("productID")},
+				Responses:   responses(http.StatusOK, openapi.Ref("Product"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "ServiceUnavailable"),
+			},
+		},
+		"/recipes": {
+			"get": {
+				OperationID: "getRecipes",
+				Summary:     "Retrieves a page of listed recipes.",
+				Tags:        []string{"recipes"},
+				Security:    _optionalAccess,
+				Parameters:  listParams(),
+				Responses:   responses(http.StatusOK, pageOf(openapi.Ref("Recipe")), "BadRequest", "ServiceUnavailable"),
+			},
+			"post": {
+				OperationID: "createRecipe",
+				Summary:     "Creates a recipe.",
+				Tags:        []string{"recipes"},
+				Security:    _requiredAccess,
+				Parameters:  []openapi.Parameter{idempotencyKeyParam()},
+				RequestBody: jsonBody(openapi.Ref("RecipeCore")),
+				Responses:   responses(http.StatusOK, openapi.Ref("Recipe"), "BadRequest", "Unauthorized", "Conflict", "IdempotencyKeyReused", "ServiceUnavailable"),
+			},
+		},
+		"/recipes/import/jsonld": {
+			"post": {
+				OperationID: "importRecipeJSONLD",
+				Summary:     "Creates a private draft recipe from a schema.org Recipe JSON-LD document or an HTML page containing it.",
+				Tags:        []string{"recipes"},
+				Security:    _requiredAccess,
+				Parameters: []openapi.Parameter{
+					queryParam("dry_run", "Whether the recipe is only checked without being saved.", false, &openapi.Schema{Type: "boolean"}),
+				},
+				RequestBody: &openapi.RequestBody{
+					Required: true,
+					Content: map[string]openapi.MediaType{
+						"application/ld+json": {Schema: &openapi.Schema{Type: "object", Description: "JSON-LD document containing a schema.org Recipe."}},
+						"text/html":           {Schema: &openapi.Schema{Type: "string", Description: "HTML page with a JSON-LD script."}},
+						"multipart/form-data": {Schema: object(map[string]*openapi.Schema{
+							"file": {Type: "string", Format: "binary"},
+						}, "file")},
+					},
+				},
+				Responses: responses(http.StatusOK, openapi.Ref("RecipeImport"), "BadRequest", "Unauthorized", "NotFound", "ServiceUnavailable"),
+			},
+		},
+		"/recipes/import/cooklang": {
+			"post": {
+				OperationID: "importRecipeCooklang",
+				Summary:     "Creates a private draft recipe from a Cooklang document.",
+				Tags:        []string{"recipes"},
+				Security:    _requiredAccess,
+				Parameters: []openapi.Parameter{
+					queryParam("dry_run", "Whether the recipe is only checked without being saved.", false, &openapi.Schema{Type: "boolean"}),
+				},
+				RequestBody: &openapi.RequestBody{
+					Required: true,
+					Content: map[string]openapi.MediaType{
+						"text/plain": {Schema: &openapi.Schema{Type: "string", Description: "Cooklang document."}},
+						"multipart/form-data": {Schema: object(map[string]*openapi.Schema{
+							"file": {Type: "string", Format: "binary"},
+						}, "file")},
+					},
+				},
+				Responses: responses(http.StatusOK, openapi.Ref("RecipeImport"), "BadRequest", "Unauthorized", "NotFound", "ServiceUnavailable"),
+			},
+		},
+		"/recipes/{recipeID}.cook": {
+			"get": {
+				OperationID: "getRecipeCooklang",
+				Summary:     "Retrieves a single recipe as a Cooklang document.",
+				Tags:        []string{"recipes"},
+				Security:    _optionalAccess,
+				Parameters:  append([]openapi.Parameter{pathID("recipeID")}, conditionalParams()...),
+				Responses: map[string]openapi.Response{
+					"200": {
+						Description: http.StatusText(http.StatusOK),
+						Content: map[string]openapi.MediaType{
+							"text/plain": {Schema: &openapi.Schema{Type: "string", Description: "Cooklang document."}},
+						},
+					},
+					"304": {Ref: "#/components/responses/NotModified"},
+					"400": {Ref: "#/components/responses/BadRequest"},
+					"404": {Ref: "#/components/responses/NotFound"},
+					"412": {Ref: "#/components/responses/PreconditionFailed"},
+					"503": {Ref: "#/components/responses/ServiceUnavailable"},
+				},
+			},
+		},
+		"/recipes/cookbook.html": {
+			"get": {
+				OperationID: "getCookbookHTML",
+				Summary:     "Retrieves recipes along with their shopping list as a print-friendly HTML page.",
+				Tags:        []string{"recipes"},
+				Security:    _optionalAccess,
+				Parameters:  []openapi.Parameter{cookbookIDsParam()},
+				Responses:   documentResponses("text/html", "Print-friendly HTML page.", "BadRequest", "NotFound", "ServiceUnavailable"),
+			},
+		},
+		"/recipes/cookbook.md": {
+			"get": {
+				OperationID: "getCookbookMarkdown",
+				Summary:     "Retrieves recipes along with their shopping list as a Markdown document.",
+				Tags:        []string{"recipes"},
+				Security:    _optionalAccess,
+				Parameters:  []openapi.Parameter{cookbookIDsParam()},
+				Responses:   documentResponses("text/markdown", "Markdown document.", "BadRequest", "NotFound", "ServiceUnavailable"),
+			},
+		},
+		"/recipes/{recipeID}.html": {
+			"get": {
+				OperationID: "getRecipeHTML",
+				Summary:     "Retrieves a single recipe as a print-friendly HTML page.",
+				Tags:        []string{"recipes"},
+				Security:    _optionalAccess,
+				Parameters:  append([]openapi.Parameter{pathID("recipeID")}, conditionalParams()...),
+				Responses:   documentResponses("text/html", "Print-friendly HTML page.", "BadRequest", "NotModified", "NotFound", "PreconditionFailed", "ServiceUnavailable"),
+			},
+		},
+		"/recipes/{recipeID}.md": {
+			"get": {
+				OperationID: "getRecipeMarkdown",
+				Summary:     "Retrieves a single recipe as a Markdown document.",
+				Tags:        []string{"recipes"},
+				Security:    _optionalAccess,
+				Parameters:  append([]openapi.Parameter{pathID("recipeID")}, conditionalParams()...),
+				Responses:   documentResponses("text/markdown", "Markdown document.", "BadRequest", "NotModified", "NotFound", "PreconditionFailed", "ServiceUnavailable"),
+			},
+		},
+		"/recipes/{recipeID}.jsonld": {
+			"get": {
+				OperationID: "getRecipeJSONLD",
+				Summary:     "Retrieves a single recipe as a schema.org Recipe JSON-LD document.",
+				Tags:        []string{"recipes"},
+				Security:    _optionalAccess,
+				Parameters:  append([]openapi.Parameter{pathID("recipeID")}, conditionalParams()...),
+				Responses: map[string]openapi.Response{
+					"200": {
+						Description: http.StatusText(http.StatusOK),
+						Content: map[string]openapi.MediaType{
+							"application/ld+json": {Schema: &openapi.Schema{Type: "object", Description: "schema.org Recipe."}},
+						},
+					},
+					"304": {Ref: "#/components/responses/NotModified"},
+					"400": {Ref: "#/components/responses/BadRequest"},
+					"404": {Ref: "#/components/responses/NotFound"},
+					"412": {Ref: "#/components/responses/PreconditionFailed"},
+					"503": {Ref: "#/components/responses/ServiceUnavailable"},
+				},
+			},
+		},
+		"/recipes/{recipeID}": {
+			"get": {
+				OperationID: "getRecipe",
+				Summary:     "Retrieves a single recipe.",
+				Tags:        []string{"recipes"},
+				Security:    _optionalAccess,
+				Parameters:  append([]openapi.Parameter{pathID("recipeID")}, conditionalParams()...),
+				Responses:   responses(http.StatusOK, openapi.Ref("Recipe"), "BadRequest", "NotModified", "NotFound", "PreconditionFailed", "ServiceUnavailable"),
+			},
+			"put": {
+				OperationID: "replaceRecipe",
+				Summary:     "Replaces a recipe of the authorized user.",
+				Tags:        []string{"recipes"},
+				Security:    _requiredAccess,
+				Parameters:  append([]openapi.Parameter{pathID("recipeID")}, conditionalParams()...),
+				RequestBody: jsonBody(openapi.Ref("RecipeCore")),
+				Responses:   responses(http.StatusOK, openapi.Ref("Recipe"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "PreconditionFailed", "ServiceUnavailable"),
+			},
+			"patch": {
+				OperationID: "updateRecipe",
+				Summary:     "Changes the provided attributes of a recipe of the authorized user.",
+				Tags:        []string{"recipes"},
+				Security:    _requiredAccess,
+				Parameters:  append([]openapi.Parameter{pathID("recipeID")}, conditionalParams()...),
+				RequestBody: mergePatchBody("RecipeCore"),
+				Responses:   responses(http.StatusOK, openapi.Ref("Recipe"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "PreconditionFailed", "ServiceUnavailable"),
+			},
+			"delete": {
+				OperationID: "deleteRecipe",
+				Summary:     "Moves a recipe to the trash, optionally reassigning its references to a replacement.",
+				Tags:        []string{"recipes"},
+				Security:    _requiredAccess,
+				Parameters: []openapi.Parameter{
+					pathID("recipeID"),
+					queryParam("replace_with", "Recipe that replaces the references of the deleted one.", false, openapi.Ref("ID")),
+				},
+				Responses: responses(http.StatusNoContent, nil, "BadRequest", "Unauthorized", "NotFound", "InUse", "ServiceUnavailable"),
+			},
+		},
+		"/recipes/{recipeID}/restore": {
+			"post": {
+				OperationID: "restoreRecipe",
+				Summary:     "Restores a recipe from the trash.",
+				Tags:        []string{"recipes"},
+				Security:    _requiredAccess,
+				Parameters:  []openapi.Parameter{pathID("recipeID")},
+				Responses:   responses(http.StatusOK, openapi.Ref("Recipe"), "BadRequest", "Unauthorized", "NotFound", "ServiceUnavailable"),
+			},
+		},
+		"/recipes/{recipeID}/products/{productID}": {
+			"post": {
+				OperationID: "addRecipeProduct",
+				Summary:     "Adds a product to a recipe of the authorized user.",
+				Tags:        []string{"recipes"},
+				Security:    _requiredAccess,
+				Parameters:  append([]openapi.Parameter{pathID("recipeID"), pathID("productID")}, conditionalParams()...),
+				RequestBody: jsonBody(object(map[string]*openapi.Schema{
+					"quantity": openapi.Ref("Decimal"),
+				}, "quantity")),
+				Responses: responses(http.StatusOK, openapi.Ref("Recipe"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "Conflict", "PreconditionFailed", "ServiceUnavailable"),
+			},
+			"patch": {
+				OperationID: "updateRecipeProduct",
+				Summary:     "Changes the quantity of a product in a recipe of the authorized user.",
+				Tags:        []string{"recipes"},
+				Security:    _requiredAccess,
+				Parameters:  append([]openapi.Parameter{pathID("recipeID"), pathID("productID")}, conditionalParams()...),
+				RequestBody: jsonBody(object(map[string]*openapi.Schema{
+					"quantity": openapi.Ref("Decimal"),
+				}, "quantity")),
+				Responses: responses(http.StatusOK, openapi.Ref("Recipe"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "PreconditionFailed", "ServiceUnavailable"),
+			},
+			"delete": {
+				OperationID: "removeRecipeProduct",
+				Summary:     "Removes a product from a recipe of the authorized user.",
+				Tags:        []string{"recipes"},
+				Security:    _requiredAccess,
+				Parameters:  append([]openapi.Parameter{pathID("recipeID"), pathID("productID")}, conditionalParams()...),
+				Responses:   responses(http.StatusOK, openapi.Ref("Recipe"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "PreconditionFailed", "ServiceUnavailable"),
+			},
+		},
+		"/recipes/user/{userID}": {
+			"get": {
+				OperationID: "getUserRecipes",
+				Summary:     "Retrieves a page of recipes of a user.",
+				Tags:        []string{"recipes"},
+				Security:    _optionalAccess,
+				Parameters:  append([]openapi.Parameter{pathID("userID")}, listParams()...),
+				Responses:   responses(http.StatusOK, pageOf(openapi.Ref("Recipe")), "BadRequest", "ServiceUnavailable"),
+			},
+		},
+		"/plans": {
+			"get": {
+				OperationID: "getPlans",
+				Summary:     "Retrieves a page of listed plans.",
+				Tags:        []string{"plans"},
+				Security:    _optionalAccess,
+				Parameters:  listParams(),
+				Responses:   responses(http.StatusOK, pageOf(openapi.Ref("Plan")), "BadRequest", "ServiceUnavailable"),
+			},
+			"post": {
+				OperationID: "createPlan",
+				Summary:     "Creates a plan.",
+				Tags:        []string{"plans"},
+				Security:    _requiredAccess,
+				Parameters:  []openapi.Parameter{idempotencyKeyParam()},
+				RequestBody: jsonBody(openapi.Ref("PlanCore")),
+				Responses:   responses(http.StatusOK, openapi.Ref("Plan"), "BadRequest", "Unauthorized", "Conflict", "IdempotencyKeyReused", "ServiceUnavailable"),
+			},
+		},
+		"/plans/{planID}": {
+			"get": {
+				OperationID: "getPlan",
+				Summary:     "Retrieves a single plan.",
+				Tags:        []string{"plans"},
+				Security:    _optionalAccess,
+				Parameters:  append([]openapi.Parameter{pathID("planID")}, conditionalParams()...),
+				Responses:   responses(http.StatusOK, openapi.Ref("Plan"), "BadRequest", "NotModified", "NotFound", "PreconditionFailed", "ServiceUnavailable"),
+			},
+			"put": {
+				OperationID: "replacePlan",
+				Summary:     "Replaces a plan of the authorized user.",
+				Tags:        []string{"plans"},
+				Security:    _requiredAccess,
+				Parameters:  append([]openapi.Parameter{pathID("planID")}, conditionalParams()...),
+				RequestBody: jsonBody(openapi.Ref("PlanCore")),
+				Responses:   responses(http.StatusOK, openapi.Ref("Plan"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "PreconditionFailed", "ServiceUnavailable"),
+			},
+			"patch": {
+				OperationID: "updatePlan",
+				Summary:     "Changes the provided attributes of a plan of the authorized user.",
+				Tags:        []string{"plans"},
+				Security:    _requiredAccess,
+				Parameters:  append([]openapi.Parameter{pathID("planID")}, conditionalParams()...),
+				RequestBody: mergePatchBody("PlanCore"),
+				Responses:   responses(http.StatusOK, openapi.Ref("Plan"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "PreconditionFailed", "ServiceUnavailable"),
+			},
+			"delete": {
+				OperationID: "deletePlan",
+				Summary:     "Moves a plan to the trash.",
+				Tags:        []string{"plans"},
+				Security:    _requiredAccess,
+				Parameters:  []openapi.Parameter{pathID("planID")},
+				Responses:   responses(http.StatusNoContent, nil, "BadRequest", "Unauthorized", "NotFound", "ServiceUnavailable"),
+			},
+		},
+		"/plans/{planID}/restore": {
+			"post": {
+				OperationID: "restorePlan",
+				Summary:     "Restores a plan from the trash.",
+				Tags:        []string{"plans"},
+				Security:    _requiredAccess,
+				Parameters:  []openapi.Parameter{pathID("planID")},
+				Responses:   responses(http.StatusOK, openapi.Ref("Plan"), "BadRequest", "Unauthorized", "NotFound", "ServiceUnavailable"),
+			},
+		},
+		"/plans/{planID}.html": {
+			"get": {
+				OperationID: "getPlanHTML",
+				Summary:     "Retrieves a single plan along with its shopping list as a print-friendly HTML page.",
+				Tags:        []string{"plans"},
+				Security:    _optionalAccess,
+				Parameters:  append([]openapi.Parameter{pathID("planID")}, conditionalParams()...),
+				Responses:   documentResponses("text/html", "Print-friendly HTML page.", "BadRequest", "NotModified", "NotFound", "PreconditionFailed", "ServiceUnavailable"),
+			},
+		},
+		"/plans/{planID}.md": {
+			"get": {
+				OperationID: "getPlanMarkdown",
+				Summary:     "Retrieves a single plan along with its shopping list as a Markdown document.",
+				Tags:        []string{"plans"},
+				Security:    _optionalAccess,
+				Parameters:  append([]openapi.Parameter{pathID("planID")}, conditionalParams()...),
+				Responses:   documentResponses("text/markdown", "Markdown document.", "BadRequest", "NotModified", "NotFound", "PreconditionFailed", "ServiceUnavailable"),
+			},
+		},
+		"/plans/{planID}/calendar.ics": {
+			"get": {
+				OperationID: "getPlanCalendar",
+				Summary:     "Retrieves the scheduled recipes of a plan as an iCalendar feed.",
+				Tags:        []string{"plans"},
+				Security:    _optionalAccess,
+				Parameters: []openapi.Parameter{
+					pathID("planID"),
+					queryParam("token", "Secret calendar token of the viewer, used instead of the authorization header.", false, &openapi.Schema{Type: "string", MinLength: openapi.Int(1)}),
+				},
+				Responses: map[string]openapi.Response{
+					"200": {
+						Description: http.StatusText(http.StatusOK),
+						Content: map[string]openapi.MediaType{
+							"text/calendar": {Schema: &openapi.Schema{Type: "string", Description: "RFC 5545 calendar."}},
+						},
+					},
+					"400": {Ref: "#/components/responses/BadRequest"},
+					"401": {Ref: "#/components/responses/Unauthorized"},
+					"404": {Ref: "#/components/responses/NotFound"},
+					"503": {Ref: "#/components/responses/ServiceUnavailable"},
+				},
+			},
+		},
+		"/plans/{planID}/recipes/{recipeID}": {
+			"post": {
+				OperationID: "addPlanRecipe",
+				Summary:     "Adds a recipe to a plan of the authorized user.",
+				Tags:        []string{"plans"},
+				Security:    _requiredAccess,
+				Parameters:  append([]openapi.Parameter{pathID("planID"), pathID("recipeID")}, conditionalParams()...),
+				RequestBody: jsonBody(object(map[string]*openapi.Schema{
+					"quantity": {Type: "integer", Minimum: openapi.Float(1)},
+					"day":      openapi.Ref("Day"),
+				}, "quantity")),
+				Responses: responses(http.StatusOK, openapi.Ref("Plan"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "Conflict", "PreconditionFailed", "ServiceUnavailable"),
+			},
+			"patch": {
+				OperationID: "updatePlanRecipe",
+				Summary:     "Changes the quantity or the day of a recipe in a plan of the authorized user.",
+				Tags:        []string{"plans"},
+				Security:    _requiredAccess,
+				Parameters:  append([]openapi.Parameter{pathID("planID"), pathID("recipeID")}, conditionalParams()...),
+				RequestBody: jsonBody(object(map[string]*openapi.Schema{
+					"quantity": {Type: "integer", Minimum: openapi.Float(1)},
+					"day":      openapi.Ref("Day"),
+				})),
+				Responses: responses(http.StatusOK, openapi.Ref("Plan"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "PreconditionFailed", "ServiceUnavailable"),
+			},
+			"delete": {
+				OperationID: "removePlanRecipe",
+				Summary:     "Removes a recipe from a plan of the authorized user.",
+				Tags:        []string{"plans"},
+				Security:    _requiredAccess,
+				Parameters:  append([]openapi.Parameter{pathID("planID"), pathID("recipeID")}, conditionalParams()...),
+				Responses:   responses(http.StatusOK, openapi.Ref("Plan"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "PreconditionFailed", "ServiceUnavailable"),
+			},
+		},
+		"/plans/user/{userID}": {
+			"get": {
+				OperationID: "getUserPlans",
+				Summary:     "Retrieves a page of plans of a user.",
+				Tags:        []string{"plans"},
+				Security:    _optionalAccess,
+				Parameters:  append([]openapi.Parameter{pathID("userID")}, listParams()...),
+				Responses:   responses(http.StatusOK, pageOf(openapi.Ref("Plan")), "BadRequest", "ServiceUnavailable"),
+			},
+		},
+		"/users": {
+			"get": {
+				OperationID: "getUsers",
+				Summary:     "Retrieves a page of users.",
+				Tags:        []string{"users"},
+				Security:    _requiredAccess,
+				Parameters:  listParams(),
+				Responses:   responses(http.StatusOK, pageOf(openapi.Ref("User")), "BadRequest", "Unauthorized", "Forbidden", "ServiceUnavailable"),
+			},
+			"post": {
+				OperationID: "createAdminUser",
+				Summary:     "Creates a new admin user.",
+				Tags:        []string{"users"},
+				Security:    _requiredAccess,
+				RequestBody: jsonBody(openapi.Ref("UserInput")),
+				Responses:   responses(http.StatusOK, openapi.Ref("User"), "BadRequest", "Unauthorized", "Forbidden", "Conflict", "ServiceUnavailable"),
+			},
+			"patch": {
+				OperationID: "updateUserPassword",
+				Summary:     "Updates the password of the authorized user.",
+				Tags:        []string{"users"},
+				Security:    _requiredAccess,
+				RequestBody: jsonBody(openapi.Ref("PasswordUpdate")),
+				Responses:   responses(http.StatusNoContent, nil, "BadRequest", "Unauthorized", "ServiceUnavailable"),
+			},
+			"delete": {
+				OperationID: "deleteSelf",
+				Summary:     "Deletes the authorized user.",
+				Tags:        []string{"users"},
+				Security:    _requiredAccess,
+				Responses:   responses(http.StatusNoContent, nil, "BadRequest", "Unauthorized", "ServiceUnavailable"),
+			},
+		},
+		"/users/{userID}": {
+			"get": {
+				OperationID: "getUser",
+				Summary:     "Retrieves a single user.",
+				Tags:        []string{"users"},
+				Security:    _requiredAccess,
+				Parameters:  []openapi.Parameter{pathID("userID")},
+				Responses:   responses(http.StatusOK, openapi.Ref("User"), "BadRequest", "Unauthorized", "Forbidden", "NotFound", "ServiceUnavailable"),
+			},
+			"delete": {
+				OperationID: "deleteUser",
+				Summary:     "Deletes a user.",
+				Tags:        []string{"users"},
+				Security:    _requiredAccess,
+				Parameters:  []openapi.Parameter{pathID("userID")},
+				Responses:   responses(http.StatusNoContent, nil, "BadRequest", "Unauthorized", "Forbidden", "NotFound", "ServiceUnavailable"),
+			},
+		},
+		"/webhooks": {
+			"get": {
+				OperationID: "getWebhooks",
+				Summary:     "Retrieves a page of webhooks of the authorized user.",
+				Tags:        []string{"webhooks"},
+				Security:    _requiredAccess,
+				Parameters:  pageParams(),
+				Responses:   responses(http.StatusOK, pageOf(openapi.Ref("Webhook")), "BadRequest", "Unauthorized", "ServiceUnavailable"),
+			},
+			"post": {
+				OperationID: "createWebhook",
+				Summary:     "Creates a webhook of the authorized user. The signing secret is returned only once.",
+				Tags:        []string{"webhooks"},
+				Security:    _requiredAccess,
+				RequestBody: jsonBody(openapi.Ref("WebhookCore")),
+				Responses: responses(http.StatusOK, extend("Webhook", map[string]*openapi.Schema{
+					"secret": {Type: "string", Pattern: "^[0-9a-f]{64}$"},
+				}, "secret"), "BadRequest", "Unauthorized", "ServiceUnavailable"),
+			},
+		},
+		"/webhooks/{webhookID}": {
+			"get": {
+				OperationID: "getWebhook",
+				Summary:     "Retrieves a single webhook.",
+				Tags:        []string{"webhooks"},
+				Security:    _requiredAccess,
+				Parameters:  []openapi.Parameter{pathID("webhookID")},
+				Responses:   responses(http.StatusOK, openapi.Ref("Webhook"), "BadRequest", "Unauthorized", "NotFound", "ServiceUnavailable"),
+			},
+			"put": {
+				OperationID: "updateWebhook",
+				Summary:     "Changes the url, events or the active flag of a webhook. Omitted attributes keep their values.",
+				Tags:        []string{"webhooks"},
+				Security:    _requiredAccess,
+				Parameters:  []openapi.Parameter{pathID("webhookID")},
+				RequestBody: jsonBody(openapi.Ref("WebhookCore")),
+				Responses:   responses(http.StatusOK, openapi.Ref("Webhook"), "BadRequest", "Unauthorized", "NotFound", "ServiceUnavailable"),
+			},
+			"delete": {
+				OperationID: "deleteWebhook",
+				Summary:     "Deletes a webhook together with its delivery log.",
+				Tags:        []string{"webhooks"},
+				Security:    _requiredAccess,
+				Parameters:  []openapi.Parameter{pathID("webhookID")},
+				Responses:   responses(http.StatusNoContent, nil, "BadRequest", "Unauthorized", "NotFound", "ServiceUnavailable"),
+			},
+		},
+		"/webhooks/{webhookID}/deliveries": {
+			"get": {
+				OperationID: "getWebhookDeliveries",
+				Summary:     "Retrieves a page of the delivery log of a webhook.",
+				Tags:        []string{"webhooks"},
+				Security:    _requiredAccess,
+				Parameters:  append([]openapi.Parameter{pathID("webhookID")}, pageParams()...),
+				Responses:   responses(http.StatusOK, pageOf(openapi.Ref("WebhookDelivery")), "BadRequest", "Unauthorized", "NotFound", "ServiceUnavailable"),
+			},
+		},
+		"/trash": {
+			"get": {
+				OperationID: "getTrash",
+				Summary:     "Retrieves a page of objects that were moved to the trash.",
+				Tags:        []string{"trash"},
+				Security:    _requiredAccess,
+				Parameters:  pageParams(),
+				Responses:   responses(http.StatusOK, openapi.Ref("TrashPage"), "BadRequest", "Unauthorized", "ServiceUnavailable"),
+			},
+		},
+		"/events": {
+			"get": {
+				OperationID: "getEvents",
+				Summary:     "Streams the changes of the objects that the authorized user is allowed to see as Server-Sent Events.",
+				Tags:        []string{"events"},
+				Security:    _requiredAccess,
+				Parameters: []openapi.Parameter{
+					headerParam("Last-Event-ID", "Id of the last received event. Newer events that are still kept are sent first."),
+				},
+				Responses: documentResponses("text/event-stream", "Stream of change events.", "BadRequest", "Unauthorized", "ServiceUnavailable"),
+			},
+		},
+		"/search": {
+			"get": {
+				OperationID: "search",
+				Summary:     "Searches products, recipes and plans by their names and descriptions.",
+				Tags:        []string{"search"},
+				Security:    _optionalAccess,
+				Parameters: []openapi.Parameter{
+					queryParam("q", "Text to search for.", true, &openapi.Schema{Type: "string"}),
+				},
+				Responses: responses(http.StatusOK, openapi.Ref("SearchResults"), "BadRequest", "ServiceUnavailable"),
+			},
+		},
+		"/graphql": {
+			"post": {
+				OperationID: "graphql",
+				Summary:     "Executes a GraphQL query or mutation. Errors of the operation are reported in the errors list.",
+				Tags:        []string{"graphql"},
+				Security:    _optionalAccess,
+				RequestBody: jsonBody(object(map[string]*openapi.Schema{
+					"query":         {Type: "string", MinLength: openapi.Int(1)},
+					"operationName": {Type: "string"},
+					"variables":     {Type: "object"},
+				}, "query")),
+				Responses: responses(http.StatusOK, object(map[string]*openapi.Schema{
+					"data": {Type: "object"},
+					"errors": {Type: "array", Items: object(map[string]*openapi.Schema{
+						"message":    {Type: "string"},
+						"path":       {Type: "array", Items: &openapi.Schema{}},
+						"extensions": {Type: "object"},
+					}, "message")},
+				}), "BadRequest"),
+			},
+		},
+		"/version": {
+			"get": {
+				OperationID: "getVersion",
+				Summary:     "Retrieves the server version.",
+				Tags:        []string{"meta"},
+				Security:    _publicAccess,
+				Responses: responses(http.StatusOK, object(map[string]*openapi.Schema{
+					"version": {Type: "string"},
+				}, "version")),
+			},
+		},
+		"/openapi.json": {
+			"get": {
+				OperationID: "getOpenAPI",
+				Summary:     "Retrieves this document.",
+				Tags:        []string{"meta"},
+				Security:    _publicAccess,
+				Responses:   responses(http.StatusOK, &openapi.Schema{Type: "object"}),
+			},
+		},
+	}
+}
+
+// specComponents describes the core types, the error responses and the
+// authorization scheme of the API.
+func specComponents() openapi.Components {
+	str := func() *openapi.Schema { return &openapi.Schema{Type: "string"} }
+	nonEmpty := func() *openapi.Schema { return &openapi.Schema{Type: "string", MinLength: openapi.Int(1)} }
+	timestamp := func() *openapi.Schema { return &openapi.Schema{Type: "string", Format: "date-time"} }
+	version := func() *openapi.Schema { return &openapi.Schema{Type: "integer", Minimum: openapi.Float(1)} }
+
+	return openapi.Components{
+		Schemas: map[string]*openapi.Schema{
+			"ID": {
+				Type:        "string",
+				Pattern:     "^[0-9a-v]{20}$",
+				Description: "Unique object identifier.",
+			},
+			"Decimal": {
+				Type:        []string{"number", "string"},
+				Description: "Decimal number.",
+			},
+			"Day": {
+				Type:        "string",
+				Pattern:     "^([0-9]{4}-[0-9]{2}-[0-9]{2})?$",
+				Description: "Day in the YYYY-MM-DD format. It is empty if nothing is scheduled.",
+			},
+			"Cursor": {
+				Type:        []string{"string", "null"},
+				Description: "Opaque cursor of the next page. It is null on the last page.",
+			},
+			"Visibility": {
+				Type: "string",
+				Enum: []interface{}{"private", "unlisted", "public"},
+			},
+			"State": {
+				Type: "string",
+				Enum: []interface{}{"draft", "published"},
+			},
+			"ServingType": {
+				Type: "string",
+				Enum: []interface{}{"grams", "milliliters", "units"},
+			},
+			"ProductStatus": {
+				Type: "string",
+				Enum: []interface{}{"pending", "approved", "rejected"},
+			},
+			"Serving": object(map[string]*openapi.Schema{
+				"type":     openapi.Ref("ServingType"),
+				"size":     openapi.Ref("Decimal"),
+				"calories": {Type: "integer", Minimum: openapi.Float(0)},
+			}, "type", "size", "calories"),
+			"ProductCore": object(map[string]*openapi.Schema{
+				"name":        nonEmpty(),
+				"aliases":     openapi.ArrayOf(nonEmpty()),
+				"barcode":     {Type: "string", Pattern: "^[0-9]{8,14}$"},
+				"image_url":   str(),
+				"description": nonEmpty(),
+				"serving":     openapi.Ref("Serving"),
+			}, "name", "description", "serving"),
+			"Product": extend("ProductCore", map[string]*openapi.Schema{
+				"id":               openapi.Ref("ID"),
+				"user_id":          openapi.Ref("ID"),
+				"status":           openapi.Ref("ProductStatus"),
+				"rejection_reason": str(),
+				"created_at":       timestamp(),
+				"version":          version(),
+				"updated_at":       timestamp(),
+				"deleted_at":       timestamp(),
+			}, "id", "user_id", "status", "created_at", "version", "updated_at"),
+			"Rejection": object(map[string]*openapi.Schema{
+				"reason": nonEmpty(),
+			}, "reason"),
+			"ProductMergeInput": object(map[string]*openapi.Schema{
+				"source_ids": {Type: "array", Items: openapi.Ref("ID"), MinItems: openapi.Int(1)},
+			}, "source_ids"),
+			"ProductMerge": object(map[string]*openapi.Schema{
+				"target_id":  openapi.Ref("ID"),
+				"source_ids": openapi.ArrayOf(openapi.Ref("ID")),
+				"recipes": openapi.ArrayOf(object(map[string]*openapi.Schema{
+					"recipe_id": openapi.Ref("ID"),
+					"quantity":  openapi.Ref("Decimal"),
+					"summed":    {Type: "boolean"},
+				}, "recipe_id", "quantity", "summed")),
+			}, "target_id", "source_ids", "recipes"),
+			"ProductImport": object(map[string]*openapi.Schema{
+				"dry_run":   {Type: "boolean"},
+				"created":   {Type: "integer", Minimum: openapi.Float(0)},
+				"updated":   {Type: "integer", Minimum: openapi.Float(0)},
+				"unchanged": {Type: "integer", Minimum: openapi.Float(0)},
+				"failed":    {Type: "integer", Minimum: openapi.Float(0)},
+				"errors": openapi.ArrayOf(object(map[string]*openapi.Schema{
+					"row":     {Type: "integer", Minimum: openapi.Float(1)},
+					"message": str(),
+					"errors": openapi.ArrayOf(object(map[string]*openapi.Schema{
+						"attribute": str(),
+						"message":   str(),
+					}, "attribute", "message")),
+				}, "row")),
+			}, "dry_run", "created", "updated", "unchanged", "failed", "errors"),
+			"RecipeProduct": object(map[string]*openapi.Schema{
+				"product_id": openapi.Ref("ID"),
+				"quantity":   openapi.Ref("Decimal"),
+			}, "product_id", "quantity"),
+			"RecipeImport": object(map[string]*openapi.Schema{
+				"recipe": openapi.Ref("Recipe"),
+				"unmatched": openapi.ArrayOf(object(map[string]*openapi.Schema{
+					"ingredient": str(),
+					"reason":     str(),
+				}, "ingredient", "reason")),
+			}, "recipe", "unmatched"),
+			"RecipeCore": object(map[string]*openapi.Schema{
+				"name":        nonEmpty(),
+				"image_url":   str(),
+				"description": nonEmpty(),
+				"visibility":  openapi.Ref("Visibility"),
+				"state":       openapi.Ref("State"),
+				"products":    {Type: "array", Items: openapi.Ref("RecipeProduct"), MinItems: openapi.Int(2)},
+			}, "name", "description", "visibility", "state", "products"),
+			"Recipe": extend("RecipeCore", map[string]*openapi.Schema{
+				"id":         openapi.Ref("ID"),
+				"user_id":    openapi.Ref("ID"),
+				"created_at": timestamp(),
+				"version":    version(),
+				"updated_at": timestamp(),
+				"deleted_at": timestamp(),
+			}, "id", "user_id", "created_at", "version", "updated_at"),
+			"PlanRecipe": object(map[string]*openapi.Schema{
+				"recipe_id": openapi.Ref("ID"),
+				"quantity":  {Type: "integer", Minimum: openapi.Float(1)},
+				"day":       openapi.Ref("Day"),
+			}, "recipe_id", "quantity"),
+			"PlanCore": object(map[string]*openapi.Schema{
+				"name":        nonEmpty(),
+				"description": nonEmpty(),
+				"visibility":  openapi.Ref("Visibility"),
+				"state":       openapi.Ref("State"),
+				"recipes":     {Type: "array", Items: openapi.Ref("PlanRecipe"), MinItems: openapi.Int(1)},
+			}, "name", "description", "visibility", "state", "recipes"),
+			"Plan": extend("PlanCore", map[string]*openapi.Schema{
+				"id":         openapi.Ref("ID"),
+				"user_id":    openapi.Ref("ID"),
+				"created_at": timestamp(),
+				"version":    version(),
+				"updated_at": timestamp(),
+				"deleted_at": timestamp(),
+			}, "id", "user_id", "created_at", "version", "updated_at"),
+			"User": object(map[string]*openapi.Schema{
+				"id":         openapi.Ref("ID"),
+				"name":       str(),
+				"admin":      {Type: "boolean"},
+				"created_at": timestamp(),
+			}, "id", "name", "admin", "created_at"),
+			"UserInput": object(map[string]*openapi.Schema{
+				"name":     nonEmpty(),
+				"password": {Type: "string", MinLength: openapi.Int(4)},
+			}, "name", "password"),
+			"PasswordUpdate": object(map[string]*openapi.Schema{
+				"password":     {Type: "string", MinLength: openapi.Int(4)},
+				"old_password": str(),
+			}, "password", "old_password"),
+			"Session": object(map[string]*openapi.Schema{
+				"user":         openapi.Ref("User"),
+				"access_token": str(),
+			}, "user", "access_token"),
+			"Dependent": object(map[string]*openapi.Schema{
+				"id":      openapi.Ref("ID"),
+				"user_id": openapi.Ref("ID"),
+				"name":    str(),
+				"deleted": {Type: "boolean"},
+			}, "id", "user_id", "deleted"),
+			"Dependents": object(map[string]*openapi.Schema{
+				"recipes": openapi.ArrayOf(openapi.Ref("Dependent")),
+				"plans":   openapi.ArrayOf(openapi.Ref("Dependent")),
+			}, "recipes", "plans"),
+			"TrashPage": object(map[string]*openapi.Schema{
+				"data": object(map[string]*openapi.Schema{
+					"products": openapi.ArrayOf(openapi.Ref("Product")),
+					"recipes":  openapi.ArrayOf(openapi.Ref("Recipe")),
+					"plans":    openapi.ArrayOf(openapi.Ref("Plan")),
+				}, "products", "recipes", "plans"),
+				"next_cursor": openapi.Ref("Cursor"),
+			}, "data", "next_cursor"),
+			"EventType": {
+				Type: "string",
+				Enum: eventTypes(),
+			},
+			"WebhookCore": object(map[string]*openapi.Schema{
+				"url":    {Type: "string", Format: "uri"},
+				"events": {Type: "array", Items: openapi.Ref("EventType"), MinItems: openapi.Int(1)},
+				"active": {Type: "boolean"},
+			}, "url", "events"),
+			"Webhook": extend("WebhookCore", map[string]*openapi.Schema{
+				"id":         openapi.Ref("ID"),
+				"user_id":    openapi.Ref("ID"),
+				"created_at": timestamp(),
+			}, "id", "user_id", "created_at"),
+			"WebhookDelivery": object(map[string]*openapi.Schema{
+				"id":              openapi.Ref("ID"),
+				"webhook_id":      openapi.Ref("ID"),
+				"event":           openapi.Ref("EventType"),
+				"payload":         {Type: "object", Description: "Signed body that is posted to the webhook."},
+				"status":          {Type: "string", Enum: []interface{}{"pending", "succeeded", "failed"}},
+				"attempts":        {Type: "integer", Minimum: openapi.Float(0)},
+				"next_attempt_at": {Type: []string{"string", "null"}, Format: "date-time"},
+				"response_status": {Type: "integer"},
+				"error":           str(),
+				"created_at":      timestamp(),
+			}, "id", "webhook_id", "event", "payload", "status", "attempts", "created_at"),
+			"Problem": object(map[string]*openapi.Schema{
+				"type":   str(),
+				"title":  str(),
+				"status": {Type: "integer"},
+				"detail": str(),
+				"code": {
+					Type:        "string",
+					Description: "Stable machine-readable error code.",
+					Enum: []interface{}{
+						apierr.CodeUnauthorized,
+						apierr.CodeForbidden,
+						apierr.CodeConflict,
+						apierr.CodeInUse,
+						apierr.CodeIdempotencyKeyReused,
+						apierr.CodeRequestCanceled,
+						apierr.CodeNotFound,
+						apierr.CodeRouteNotFound,
+						apierr.CodeMethodNotAllowed,
+						apierr.CodeDatabase,
+						apierr.CodeInternal,
+						apierr.CodeInvalidAttribute,
+						apierr.CodeBadRequest,
+						apierr.CodeInvalidBody,
+						apierr.CodeMalformedJSON,
+					},
+				},
+				"attribute":  {Type: "string", Description: "Invalid attribute of the request."},
+				"object":     {Type: "string", Description: "Type of the object that the error is about."},
+				"request_id": str(),
+				"errors": {
+					Type:        "array",
+					Description: "All invalid attributes of the request.",
+					Items: object(map[string]*openapi.Schema{
+						"attribute": str(),
+						"message":   str(),
+					}, "attribute", "message"),
+				},
+			}, "type", "title", "status", "code"),
+			"SearchResults": object(map[string]*openapi.Schema{
+				"products": openapi.ArrayOf(openapi.Ref("Product")),
+				"recipes":  openapi.ArrayOf(openapi.Ref("Recipe")),
+				"plans":    openapi.ArrayOf(openapi.Ref("Plan")),
+			}, "products", "recipes", "plans"),
+		},
+		Responses: map[string]openapi.Response{
+			"BadRequest":           problemResponse("Invalid request data or parameters.", openapi.Ref("Problem")),
+			"Unauthorized":         problemResponse("Missing or invalid authorization token.", openapi.Ref("Problem")),
+			"Forbidden":            problemResponse("Admin privileges are required.", openapi.Ref("Problem")),
+			"NotFound":             problemResponse("Object does not exist or is not visible to the requester.", openapi.Ref("Problem")),
+			"Conflict":             problemResponse("Object is in a conflicting state.", openapi.Ref("Problem")),
+			"ServiceUnavailable":   problemResponse("Database is unavailable.", openapi.Ref("Problem")),
+			"PreconditionFailed":   problemResponse("Object was modified since the version known to the client.", openapi.Ref("Problem")),
+			"IdempotencyKeyReused": problemResponse("Idempotency key was already used for a different request.", openapi.Ref("Problem")),
+			"NotModified": {
+				Description: "Object was not modified since the version known to the client.",
+			},
+			"InUse": problemResponse("Object is referenced by other objects.", extend("Problem", map[string]*openapi.Schema{
+				"details": openapi.Ref("Dependents"),
+			}, "details")),
+		},
+		SecuritySchemes: map[string]openapi.SecurityScheme{
+			"bearerAuth": {
+				Type:         "http",
+				Scheme:       "bearer",
+				BearerFormat: "JWT",
+			},
+		},
+	}
+}
+
+// eventTypes returns the enum values of the webhook event types.
+func eventTypes() []interface{} {
+	vv := make([]interface{}, 0, len(core.EventTypes))
+	for _, et := range core.EventTypes {
+		vv = append(vv, et)
+	}
+
+	return vv
+}
+
+// object creates a schema of an object with the provided properties.
+func object(props map[string]*openapi.Schema, required ...string) *openapi.Schema {
+	return &openapi.Schema{
+		Type:       "object",
+		Properties: props,
+		Required:   required,
+	}
+}
+
+// extend creates a schema of an object that contains all properties of
+// the base schema along with the provided ones.
+func extend(base string, props map[string]*openapi.Schema, required ...string) *openapi.Schema {
+	return &openapi.Schema{
+		AllOf: []*openapi.Schema{
+			openapi.Ref(base),
+			object(props, required...),
+		},
+	}
+}
+
+// pageOf creates a schema of a single page of a paginated list.
+func pageOf(items *openapi.Schema) *openapi.Schema {
+	return object(map[string]*openapi.Schema{
+		"data":        openapi.ArrayOf(items),
+		"next_cursor": openapi.Ref("Cursor"),
+	}, "data", "next_cursor")
+}
+
+// jsonBody creates a required JSON request body.
+func jsonBody(sch *openapi.Schema) *openapi.RequestBody {
+	return &openapi.RequestBody{
+		Required: true,
+		Content: map[string]openapi.MediaType{
+			"application/json": {Schema: sch},
+		},
+	}
+}
+
+// mergePatchBody creates a required request body of a JSON merge patch
+// (RFC 7396) of the schema.
+func mergePatchBody(base string) *openapi.RequestBody {
+	return &openapi.RequestBody{
+		Required: true,
+		Content: map[string]openapi.MediaType{
+			mergepatch.ContentType: {Schema: &openapi.Schema{
+				Type:        "object",
+				Description: "Attributes of " + base + " to change. Null values reset the attributes.",
+			}},
+		},
+	}
+}
+
+// problemResponse creates an error response with a problem details
+// object. Requests with the text error format header receive a plain text
+// message instead.
+func problemResponse(desc string, sch *openapi.Schema) openapi.Response {
+	return openapi.Response{
+		Description: desc,
+		Content: map[string]openapi.MediaType{
+			apierr.ContentType: {Schema: sch},
+			"text/plain":       {Schema: &openapi.Schema{Type: "string"}},
+		},
+	}
+}
+
+// responses creates the responses of an operation. The successful
+// response contains JSON described by the schema, unless the schema is
+// nil. Error responses reference the components by their names.
+func responses(code int, sch *openapi.Schema, errs ...string) map[string]openapi.Response {
+	ok := openapi.Response{Description: http.StatusText(code)}
+	if sch != nil {
+		ok.Content = map[string]openapi.MediaType{
+			"application/json": {Schema: sch},
+		}
+	}
+
+	rr := map[string]openapi.Response{
+		strconv.Itoa(code): ok,
+	}
+
+	codes := map[string]int{
+		"BadRequest":           http.StatusBadRequest,
+		"Unauthorized":         http.StatusUnauthorized,
+		"Forbidden":            http.StatusForbidden,
+		"NotFound":             http.StatusNotFound,
+		"Conflict":             http.StatusConflict,
+		"InUse":                http.StatusConflict,
+		"NotModified":          http.StatusNotModified,
+		"PreconditionFailed":   http.StatusPreconditionFailed,
+		"IdempotencyKeyReused": http.StatusUnprocessableEntity,
+		"ServiceUnavailable":   http.StatusServiceUnavailable,
+	}
+
+	for _, name := range errs {
+		rr[strconv.Itoa(codes[name])] = openapi.Response{
+			Ref: "#/components/responses/" + name,
+		}
+	}
+
+	return rr
+}
+
+// documentResponses creates the responses of an operation that retrieves
+// a document of the media type instead of JSON. Error responses reference
+// the components by their names.
+func documentResponses(mediaType, desc string, errs ...string) map[string]openapi.Response {
+	rr := responses(http.StatusOK, nil, errs...)
+	rr[strconv.Itoa(http.StatusOK)] = openapi.Response{
+		Description: http.StatusText(http.StatusOK),
+		Content: map[string]openapi.MediaType{
+			mediaType: {Schema: &openapi.Schema{Type: "string", Description: desc}},
+		},
+	}
+
+	return rr
+}
+
+// pathID creates a required path parameter of an object id.
+func pathID(name string) openapi.Parameter {
+	return openapi.Parameter{
+		Name:     name,
+		In:       "path",
+		Required: true,
+		Schema:   openapi.Ref("ID"),
+	}
+}
+
+// queryParam creates a query parameter.
+func queryParam(name, desc string, required bool, sch *openapi.Schema) openapi.Parameter {
+	return openapi.Parameter{
+		Name:        name,
+		In:          "query",
+		Description: desc,
+		Required:    required,
+		Schema:      sch,
+	}
+}
+
+// headerParam creates a string header parameter.
+func headerParam(name, desc string) openapi.Parameter {
+	return openapi.Parameter{
+		Name:        name,
+		In:          "header",
+		Description: desc,
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+}
+
+// idempotencyKeyParam creates the header parameter of the idempotency key,
+// which makes the request safe to retry.
+func idempotencyKeyParam() openapi.Parameter {
+	return headerParam("Idempotency-Key", "Unique key of the request. Retries with the same key and body receive the first response.")
+}
+
+// cookbookIDsParam creates the required query parameter of the recipe ids
+// of a cookbook.
+func cookbookIDsParam() openapi.Parameter {
+	return queryParam("ids", "Comma separated ids of the recipes.", true, &openapi.Schema{
+		Type:    "string",
+		Pattern: "^[0-9a-v]{20}(,[0-9a-v]{20})*$",
+	})
+}
+
+// conditionalParams creates the parameters of conditional requests.
+func conditionalParams() []openapi.Parameter {
+	return []openapi.Parameter{
+		headerParam("If-Match", "Entity tags of which one must match the current version of the object."),
+		headerParam("If-None-Match", "Entity tags of which none may match the current version of the object."),
+		headerParam("If-Modified-Since", "Time after which the object must have been modified."),
+		headerParam("If-Unmodified-Since", "Time after which the object must not have been modified."),
+	}
+}
+
+// pageParams creates the pagination query parameters.
+func pageParams() []openapi.Parameter {
+	return []openapi.Parameter{
+		queryParam("limit", "Maximum number of objects in the page.", false, &openapi.Schema{
+			Type:    "integer",
+			Minimum: openapi.Float(1),
+			Maximum: openapi.Float(_maxPageLimit),
+		}),
+		queryParam("cursor", "Cursor of the page returned by the previous page.", false, &openapi.Schema{Type: "string"}),
+	}
+}
+
+// listParams creates the pagination and sorting query parameters of
+// lists. Lists can also be filtered by any of their fields, e.g.
+// calories_lte=200, which are not described individually.
+func listParams() []openapi.Parameter {
+	return append(pageParams(), queryParam(
+		"sort",
+		"Comma separated fields to sort by. Fields prefixed with - are sorted in descending order.",
+		false,
+		&openapi.Schema{Type: "string"},
+	))
+}
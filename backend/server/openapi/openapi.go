@@ -0,0 +1,225 @@
+// Package openapi describes HTTP APIs with OpenAPI 3 documents and
+// validates requests against them.
+package openapi
+
+import "strings"
+
+// Version specifies the version of the OpenAPI specification that the
+// documents conform to.
+const Version = "3.1.0"
+
+// Document is the root object of an OpenAPI document.
+type Document struct {
+	// OpenAPI specifies the version of the OpenAPI specification.
+	OpenAPI string `json:"openapi"`
+
+	// Info contains the metadata of the API.
+	Info Info `json:"info"`
+
+	// Servers contains the base URLs of the API.
+	Servers []Server `json:"servers,omitempty"`
+
+	// Paths contains the operations of the API by their path templates.
+	Paths map[string]PathItem `json:"paths"`
+
+	// Components contains reusable objects that are referenced by the
+	// operations.
+	Components Components `json:"components"`
+}
+
+// Operation retrieves the operation by its HTTP method and path template.
+// Nil is returned if the document does not describe it.
+func (d *Document) Operation(method, path string) *Operation {
+	return d.Paths[path][strings.ToLower(method)]
+}
+
+// Info contains the metadata of the API.
+type Info struct {
+	// Title specifies the name of the API.
+	Title string `json:"title"`
+
+	// Version specifies the version of the API.
+	Version string `json:"version"`
+}
+
+// Server specifies a base URL of the API.
+type Server struct {
+	// URL specifies the base URL.
+	URL string `json:"url"`
+}
+
+// PathItem contains the operations of a single path by their lowercase
+// HTTP methods.
+type PathItem map[string]*Operation
+
+// Operation describes a single API operation.
+type Operation struct {
+	// OperationID specifies a unique name of the operation.
+	OperationID string `json:"operationId"`
+
+	// Summary contains a short description of the operation.
+	Summary string `json:"summary"`
+
+	// Tags is used to group operations.
+	Tags []string `json:"tags,omitempty"`
+
+	// Security specifies the security schemes that the operation
+	// accepts. Operations without security requirements are public.
+	Security []SecurityRequirement `json:"security,omitempty"`
+
+	// Parameters contains the path and query parameters.
+	Parameters []Parameter `json:"parameters,omitempty"`
+
+	// RequestBody describes the request body, if the operation accepts
+	// one.
+	RequestBody *RequestBody `json:"requestBody,omitempty"`
+
+	// Responses contains the possible responses by their status codes.
+	Responses map[string]Response `json:"responses"`
+}
+
+// SecurityRequirement maps security scheme names to their required
+// scopes.
+type SecurityRequirement map[string][]string
+
+// Parameter describes a single path or query parameter.
+type Parameter struct {
+	// Name specifies the name of the parameter.
+	Name string `json:"name"`
+
+	// In specifies the location of the parameter, either path, query or
+	// header.
+	In string `json:"in"`
+
+	// Description provides a brief description of the parameter.
+	Description string `json:"description,omitempty"`
+
+	// Required specifies whether the parameter must be provided.
+	Required bool `json:"required,omitempty"`
+
+	// Schema describes the value of the parameter.
+	Schema *Schema `json:"schema"`
+}
+
+// RequestBody describes a request body.
+type RequestBody struct {
+	// Required specifies whether the request body must be provided.
+	Required bool `json:"required,omitempty"`
+
+	// Content contains the accepted media types.
+	Content map[string]MediaType `json:"content"`
+}
+
+// Response describes a single response or references a reusable one.
+type Response struct {
+	// Ref references a response of the document components.
+	Ref string `json:"$ref,omitempty"`
+
+	// Description provides a brief description of the response.
+	Description string `json:"description,omitempty"`
+
+	// Content contains the media types of the response.
+	Content map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType describes the content of a single media type.
+type MediaType struct {
+	// Schema describes the content.
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Components contains reusable objects of the document.
+type Components struct {
+	// Schemas contains the reusable schemas by their names.
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+
+	// Responses contains the reusable responses by their names.
+	Responses map[string]Response `json:"responses,omitempty"`
+
+	// SecuritySchemes contains the security schemes by their names.
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes an authentication method.
+type SecurityScheme struct {
+	// Type specifies the type of the scheme, e.g. http.
+	Type string `json:"type"`
+
+	// Scheme specifies the HTTP authorization scheme, e.g. bearer.
+	Scheme string `json:"scheme,omitempty"`
+
+	// BearerFormat specifies the format of the bearer token.
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Schema is a JSON Schema that describes a value.
+type Schema struct {
+	// Ref references a schema of the document components.
+	Ref string `json:"$ref,omitempty"`
+
+	// Type specifies the type of the value. It is either a single type
+	// name or a list of them.
+	Type interface{} `json:"type,omitempty"`
+
+	// Format specifies the format of the value, e.g. date-time.
+	Format string `json:"format,omitempty"`
+
+	// Description provides a brief description of the value.
+	Description string `json:"description,omitempty"`
+
+	// Enum contains all allowed values.
+	Enum []interface{} `json:"enum,omitempty"`
+
+	// Pattern specifies a regular expression that strings must match.
+	Pattern string `json:"pattern,omitempty"`
+
+	// MinLength specifies the minimum length of strings.
+	MinLength *int `json:"minLength,omitempty"`
+
+	// MaxLength specifies the maximum length of strings.
+	MaxLength *int `json:"maxLength,omitempty"`
+
+	// Minimum specifies the minimum value of numbers.
+	Minimum *float64 `json:"minimum,omitempty"`
+
+	// Maximum specifies the maximum value of numbers.
+	Maximum *float64 `json:"maximum,omitempty"`
+
+	// Items describes the elements of arrays.
+	Items *Schema `json:"items,omitempty"`
+
+	// MinItems specifies the minimum number of array elements.
+	MinItems *int `json:"minItems,omitempty"`
+
+	// Properties describes the properties of objects.
+	Properties map[string]*Schema `json:"properties,omitempty"`
+
+	// Required contains the properties that objects must contain.
+	Required []string `json:"required,omitempty"`
+
+	// AllOf contains schemas that the value must match.
+	AllOf []*Schema `json:"allOf,omitempty"`
+}
+
+// Ref creates a schema that references a schema of the document
+// components by its name.
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// ArrayOf creates a schema of an array with the provided elements.
+func ArrayOf(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+// Int returns a pointer to the integer. It is used to set schema
+// limits.
+func Int(v int) *int {
+	return &v
+}
+
+// Float returns a pointer to the number. It is used to set schema
+// limits.
+func Float(v float64) *float64 {
+	return &v
+}
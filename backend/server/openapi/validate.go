@@ -0,0 +1,280 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"foodie/server/apierr"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Validator validates requests against the operations of a document.
+type Validator struct {
+	// doc is the document that requests are validated against.
+	doc *Document
+
+	// bodies contains the compiled request body schemas of operations.
+	bodies map[*Operation]*gojsonschema.Schema
+
+	// params contains the compiled schemas of operation parameters.
+	params map[*Operation][]*gojsonschema.Schema
+}
+
+// NewValidator compiles the schemas of the document and creates a fresh
+// instance of the validator.
+func NewValidator(doc *Document) (*Validator, error) {
+	v := &Validator{
+		doc:    doc,
+		bodies: make(map[*Operation]*gojsonschema.Schema),
+		params: make(map[*Operation][]*gojsonschema.Schema),
+	}
+
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			for _, prm := range op.Parameters {
+				sch, err := v.compile(prm.Schema)
+				if err != nil {
+					return nil, fmt.Errorf("%s %s: parameter %s: %w", method, path, prm.Name, err)
+				}
+
+				v.params[op] = append(v.params[op], sch)
+			}
+
+			if op.RequestBody == nil {
+				continue
+			}
+
+			mt, ok := op.RequestBody.Content["application/json"]
+			if !ok || mt.Schema == nil {
+				continue
+			}
+
+			sch, err := v.compile(mt.Schema)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: request body: %w", method, path, err)
+			}
+
+			v.bodies[op] = sch
+		}
+	}
+
+	return v, nil
+}
+
+// compile compiles the schema along with the document components, so that
+// the references can be resolved.
+func (v *Validator) compile(sch *Schema) (*gojsonschema.Schema, error) {
+	return gojsonschema.NewSchema(gojsonschema.NewGoLoader(struct {
+		Components Components `json:"components"`
+		AllOf      []*Schema  `json:"allOf"`
+	}{
+		Components: v.doc.Components,
+		AllOf:      []*Schema{sch},
+	}))
+}
+
+// Middleware validates the path parameters, the query parameters and the
+// JSON body of incoming requests against the operations of the routes.
+// Requests of routes that the document does not describe are passed
+// through.
+func (v *Validator) Middleware(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+				path = rctx.RoutePath
+			}
+
+			mctx := chi.NewRouteContext()
+			if !routes.Match(mctx, r.Method, path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			op := v.doc.Operation(r.Method, NormalizePath(mctx.RoutePattern()))
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if aerr := v.validateParams(op, mctx, r); aerr != nil {
+				aerr.Respond(w)
+				return
+			}
+
+			if aerr := v.validateBody(op, r); aerr != nil {
+				aerr.Respond(w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// validateParams validates the path and query parameters of the request.
+func (v *Validator) validateParams(op *Operation, mctx *chi.Context, r *http.Request) *apierr.Error {
+	query := r.URL.Query()
+
+	for i, prm := range op.Parameters {
+		var (
+			raw string
+			ok  bool
+		)
+
+		switch prm.In {
+		case "path":
+			raw, ok = mctx.URLParam(prm.Name), true
+		case "query":
+			ok = query.Has(prm.Name)
+			raw = query.Get(prm.Name)
+		default:
+			continue
+		}
+
+		if !ok {
+			if prm.Required {
+				return apierr.InvalidAttribute(prm.Name, "is required")
+			}
+
+			continue
+		}
+
+		val, aerr := parseParam(prm, raw)
+		if aerr != nil {
+			return aerr
+		}
+
+		res, err := v.params[op][i].Validate(gojsonschema.NewGoLoader(val))
+		if err != nil {
+			return apierr.InvalidAttribute(prm.Name, "invalid value")
+		}
+
+		if !res.Valid() {
+			return apierr.InvalidAttribute(prm.Name, res.Errors()[0].Description())
+		}
+	}
+
+	return nil
+}
+
+// parseParam parses the raw parameter value by the type of its schema.
+func parseParam(prm Parameter, raw string) (interface{}, *apierr.Error) {
+	switch prm.Schema.Type {
+	case "integer":
+		i, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, apierr.InvalidAttribute(prm.Name, "must be an integer")
+		}
+
+		return i, nil
+	case "number":
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, apierr.InvalidAttribute(prm.Name, "must be a number")
+		}
+
+		return f, nil
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, apierr.InvalidAttribute(prm.Name, "must be a boolean")
+		}
+
+		return b, nil
+	default:
+		return raw, nil
+	}
+}
+
+// validateBody validates the JSON body of the request. The body is
+// restored afterwards, so that handlers can read it again.
+func (v *Validator) validateBody(op *Operation, r *http.Request) *apierr.Error {
+	sch, ok := v.bodies[op]
+	if !ok {
+		return nil
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return apierr.MalformedDataInput(apierr.DataTypeRequestBody)
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if op.RequestBody.Required {
+			return apierr.MalformedDataInput(apierr.DataTypeRequestBody)
+		}
+
+		return nil
+	}
+
+	if !json.Valid(data) {
+		return apierr.MalformedDataInput(apierr.DataTypeJSON)
+	}
+
+	res, err := sch.Validate(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return apierr.MalformedDataInput(apierr.DataTypeJSON)
+	}
+
+	if res.Valid() {
+		return nil
+	}
+
+	return bodyError(res.Errors())
+}
+
+// bodyError converts the schema validation errors into an API error that
+// contains all invalid attributes. Errors that only summarize other errors
+// are skipped.
+func bodyError(ee []gojsonschema.ResultError) *apierr.Error {
+	var vv apierr.Violations
+
+	for _, e := range ee {
+		switch e.Type() {
+		case "number_all_of", "number_any_of", "number_one_of":
+			continue
+		}
+
+		field, desc := e.Field(), e.Description()
+		if e.Type() == "required" {
+			field = strings.TrimPrefix(
+				fmt.Sprintf("%s.%v", field, e.Details()["property"]),
+				gojsonschema.STRING_CONTEXT_ROOT+".",
+			)
+			desc = "is required"
+		}
+
+		if field == gojsonschema.STRING_CONTEXT_ROOT {
+			return apierr.BadRequest(desc)
+		}
+
+		vv.Add(field, desc)
+	}
+
+	if aerr := vv.Err(); aerr != nil {
+		return aerr
+	}
+
+	return apierr.BadRequest("invalid body")
+}
+
+// NormalizePath converts a chi route pattern into an OpenAPI path
+// template. Trailing slashes of subrouter index routes are removed.
+func NormalizePath(pattern string) string {
+	pattern = strings.TrimSuffix(pattern, "/*")
+
+	if len(pattern) > 1 {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+
+	return pattern
+}
@@ -0,0 +1,196 @@
+package openapi
+
+import (
+	"encoding/json"
+	"foodie/server/apierr"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Validator_Middleware(t *testing.T) {
+	doc := &Document{
+		OpenAPI: Version,
+		Paths: map[string]PathItem{
+			"/items": {
+				"get": {
+					Parameters: []Parameter{
+						{Name: "limit", In: "query", Schema: &Schema{Type: "integer", Minimum: Float(1)}},
+					},
+				},
+				"post": {
+					RequestBody: &RequestBody{
+						Required: true,
+						Content: map[string]MediaType{
+							"application/json": {Schema: Ref("Item")},
+						},
+					},
+				},
+			},
+			"/items/{itemID}": {
+				"get": {
+					Parameters: []Parameter{
+						{Name: "itemID", In: "path", Required: true, Schema: &Schema{Type: "string", Pattern: "^[0-9]+$"}},
+					},
+				},
+			},
+		},
+		Components: Components{
+			Schemas: map[string]*Schema{
+				"Item": {
+					Type:     "object",
+					Required: []string{"name"},
+					Properties: map[string]*Schema{
+						"name": {Type: "string", MinLength: Int(1)},
+						"tags": ArrayOf(&Schema{Type: "string"}),
+					},
+				},
+			},
+		},
+	}
+
+	v, err := NewValidator(doc)
+	require.NoError(t, err)
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	r := chi.NewRouter()
+	r.Use(v.Middleware(r))
+	r.Route("/items", func(sr chi.Router) {
+		sr.Get("/", ok)
+		sr.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			assert.NotEmpty(t, data)
+			ok(w, r)
+		})
+		sr.Get("/{itemID}", ok)
+	})
+	r.Get("/other", ok)
+
+	root := chi.NewRouter()
+	root.Mount("/api", r)
+
+	tests := map[string]struct {
+		Method    string
+		Target    string
+		Body      string
+		Code      int
+		Error     apierr.Code
+		Attribute string
+	}{
+		"Valid query parameter": {
+			Method: http.MethodGet,
+			Target: "/api/items?limit=5",
+			Code:   http.StatusNoContent,
+		},
+		"Query parameter of an invalid type": {
+			Method:    http.MethodGet,
+			Target:    "/api/items?limit=x",
+			Code:      http.StatusBadRequest,
+			Error:     apierr.CodeInvalidAttribute,
+			Attribute: "limit",
+		},
+		"Query parameter out of range": {
+			Method:    http.MethodGet,
+			Target:    "/api/items?limit=0",
+			Code:      http.StatusBadRequest,
+			Error:     apierr.CodeInvalidAttribute,
+			Attribute: "limit",
+		},
+		"Invalid path parameter": {
+			Method:    http.MethodGet,
+			Target:    "/api/items/abc",
+			Code:      http.StatusBadRequest,
+			Error:     apierr.CodeInvalidAttribute,
+			Attribute: "itemID",
+		},
+		"Valid path parameter": {
+			Method: http.MethodGet,
+			Target: "/api/items/123",
+			Code:   http.StatusNoContent,
+		},
+		"Valid body": {
+			Method: http.MethodPost,
+			Target: "/api/items",
+			Body:   `{"name":"a","tags":["b"]}`,
+			Code:   http.StatusNoContent,
+		},
+		"Missing body": {
+			Method: http.MethodPost,
+			Target: "/api/items",
+			Code:   http.StatusBadRequest,
+			Error:  apierr.CodeInvalidBody,
+		},
+		"Malformed body": {
+			Method: http.MethodPost,
+			Target: "/api/items",
+			Body:   `{"name":`,
+			Code:   http.StatusBadRequest,
+			Error:  apierr.CodeMalformedJSON,
+		},
+		"Missing property": {
+			Method:    http.MethodPost,
+			Target:    "/api/items",
+			Body:      `{}`,
+			Code:      http.StatusBadRequest,
+			Error:     apierr.CodeInvalidAttribute,
+			Attribute: "name",
+		},
+		"Invalid nested property": {
+			Method:    http.MethodPost,
+			Target:    "/api/items",
+			Body:      `{"name":"a","tags":[1]}`,
+			Code:      http.StatusBadRequest,
+			Error:     apierr.CodeInvalidAttribute,
+			Attribute: "tags.0",
+		},
+		"Undescribed route": {
+			Method: http.MethodGet,
+			Target: "/api/other?limit=x",
+			Code:   http.StatusNoContent,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(test.Method, test.Target, strings.NewReader(test.Body))
+
+			root.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.Code, rec.Code)
+
+			if test.Error == "" {
+				return
+			}
+
+			var res struct {
+				Code      apierr.Code `json:"code"`
+				Attribute string      `json:"attribute"`
+			}
+
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			assert.Equal(t, test.Error, res.Code)
+			assert.Equal(t, test.Attribute, res.Attribute)
+		})
+	}
+}
+
+func Test_NormalizePath(t *testing.T) {
+	assert.Equal(t, "/", NormalizePath("/"))
+	assert.Equal(t, "/items", NormalizePath("/items/"))
+	assert.Equal(t, "/items", NormalizePath("/items/*"))
+	assert.Equal(t, "/items/{itemID}", NormalizePath("/items/{itemID}"))
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"foodie/server/openapi"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newSpec(t *testing.T) {
+	s, err := NewServer(nil, "0", "", []byte("secret"), time.Hour, time.Hour, nil, false)
+	require.NoError(t, err)
+
+	routes := make(map[string]bool)
+
+	err = chi.Walk(s.apiRouter(), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		key := method + " " + openapi.NormalizePath(route)
+		routes[key] = true
+
+		assert.NotNil(t, s.spec.Operation(method, openapi.NormalizePath(route)), "%s is not described by the OpenAPI document", key)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	var stale []string
+
+	ids := make(map[string]bool)
+
+	for path, item := range s.spec.Paths {
+		for method, op := range item {
+			key := strings.ToUpper(method) + " " + path
+			if !routes[key] {
+				stale = append(stale, key)
+			}
+
+			assert.False(t, ids[op.OperationID], "operation id %q is not unique", op.OperationID)
+			ids[op.OperationID] = true
+		}
+	}
+
+	sort.Strings(stale)
+	assert.Empty(t, stale, "the OpenAPI document describes routes that do not exist")
+
+	_, err = openapi.NewValidator(s.spec)
+	assert.NoError(t, err)
+}
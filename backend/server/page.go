@@ -0,0 +1,69 @@
+package server
+
+import (
+	"foodie/core"
+	"foodie/server/apierr"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// _defaultPageLimit specifies the page limit that is used when the
+	// client does not provide one.
+	_defaultPageLimit = 50
+
+	// _maxPageLimit specifies the maximum page limit that the clients are
+	// allowed to request.
+	_maxPageLimit = 100
+)
+
+// page contains a single page of a paginated list.
+type page struct {
+	// Data contains the objects of the page.
+	Data any `json:"data"`
+
+	// NextCursor specifies the cursor of the next page. It is null on the
+	// last page.
+	NextCursor *core.Cursor `json:"next_cursor"`
+}
+
+// extractPage extracts the pagination parameters from the URL query.
+func (s *Server) extractPage(r *http.Request) (core.Page, *apierr.Error) {
+	pg := core.Page{
+		Limit: _defaultPageLimit,
+	}
+
+	q := r.URL.Query()
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || limit == 0 || limit > _maxPageLimit {
+			return core.Page{}, apierr.InvalidAttribute(
+				"limit",
+				"must be a number between 1 and "+strconv.Itoa(_maxPageLimit),
+			)
+		}
+
+		pg.Limit = limit
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		cur, aerr := core.ParseCursor(v)
+		if aerr != nil {
+			return core.Page{}, aerr
+		}
+
+		pg.Cursor = cur
+	}
+
+	return pg, nil
+}
+
+// respondPage writes a single page of a paginated list to the response
+// writer.
+func (s *Server) respondPage(w http.ResponseWriter, data any, next *core.Cursor) {
+	s.respondJSON(w, page{
+		Data:       data,
+		NextCursor: next,
+	})
+}
@@ -0,0 +1,12 @@
+// Package pb contains the protobuf messages and the gRPC services of the
+// API. They mirror the product, recipe, plan and user operations of the
+// REST API. Requests are authorized by the "authorization" metadata that
+// contains the same bearer token as the REST API. Errors are reported with
+// the gRPC status codes and the google.rpc.ErrorInfo details, whose reason
+// contains the error code of the REST API.
+//
+// The code is generated from foodie.proto with buf, protoc-gen-go and
+// protoc-gen-go-grpc.
+//
+//go:generate buf generate
+package pb
@@ -8,6 +8,8 @@ import (
 	"foodie/server/apierr"
 	"io"
 	"net/http"
+
+	"github.com/rs/xid"
 )
 
 // CreatePlan creates a plan.
@@ -24,40 +26,64 @@ func (s *Server) CreatePlan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var pc core.PlanCore
+	pc := core.PlanCore{
+		Visibility: core.VisibilityPublic,
+		State:      core.StatePublished,
+	}
+
 	if err := json.Unmarshal(data, &pc); err != nil {
 		apierr.MalformedDataInput(apierr.DataTypeJSON).Respond(w)
 		return
 	}
 
-	if aerr := s.validatePlanCore(r.Context(), pc); aerr != nil {
+	pl, aerr := s.createPlan(r.Context(), uid, pc)
+	if aerr != nil {
 		aerr.Respond(w)
 		return
 	}
 
-	pl, err := db.InsertPlan(r.Context(), s.db, uid, pc)
+	s.respondJSON(w, pl)
+}
+
+// createPlan validates the plan core and creates a plan of the user.
+func (s *Server) createPlan(ctx context.Context, uid xid.ID, pc core.PlanCore) (*core.Plan, *apierr.Error) {
+	if aerr := s.validatePlanCore(ctx, uid, pc); aerr != nil {
+		return nil, aerr
+	}
+
+	pl, err := db.InsertPlan(ctx, s.db, uid, pc)
 	switch err {
 	case nil:
 		// OK.
-	case r.Context().Err():
-		apierr.Context().Respond(w)
-		return
+	case ctx.Err():
+		return nil, apierr.Context()
 	case db.ErrNotFound:
-		apierr.NotFound("recipe").Respond(w)
-		return
+		return nil, apierr.NotFound("recipe")
 	default:
 		s.log.WithError(err).Error("inserting a plan")
-		apierr.Database().Respond(w)
-
-		return
+		return nil, apierr.Database()
 	}
 
-	s.respondJSON(w, pl)
+	s.emitPlan(core.EventPlanCreated, *pl)
+
+	return pl, nil
 }
 
-// GetPlans retrieves all plans.
+// GetPlans retrieves a page of plans.
 func (s *Server) GetPlans(w http.ResponseWriter, r *http.Request) {
-	pp, err := db.GetPlans(r.Context(), s.db)
+	pg, aerr := s.extractPage(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	q, aerr := core.ParseQuery(r.URL.Query(), core.PlanFields)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	pp, next, err := db.GetPlans(r.Context(), s.db, s.extractViewer(r), q, pg)
 	switch err {
 	case nil:
 		// OK.
@@ -71,10 +97,10 @@ func (s *Server) GetPlans(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.respondJSON(w, pp)
+	s.respondPage(w, pp, next)
 }
 
-// GetUserPlans retrieves user plans.
+// GetUserPlans retrieves a page of user plans.
 func (s *Server) GetUserPlans(w http.ResponseWriter, r *http.Request) {
 	uid, aerr := s.extractPathID(r, "userID")
 	if aerr != nil {
@@ -82,7 +108,19 @@ func (s *Server) GetUserPlans(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pp, err := db.GetPlansByUserID(r.Context(), s.db, uid)
+	pg, aerr := s.extractPage(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	q, aerr := core.ParseQuery(r.URL.Query(), core.PlanFields)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	pp, next, err := db.GetPlansByUserID(r.Context(), s.db, s.extractViewer(r), uid, q, pg)
 	switch err {
 	case nil:
 		// OK.
@@ -96,7 +134,7 @@ func (s *Server) GetUserPlans(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.respondJSON(w, pp)
+	s.respondPage(w, pp, next)
 }
 
 // GetPlan retrieves a single plan by its id.
@@ -107,7 +145,7 @@ func (s *Server) GetPlan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pl, err := db.GetPlanByID(r.Context(), s.db, pid)
+	pl, err := db.GetPlanByID(r.Context(), s.db, s.extractViewer(r), pid)
 	switch err {
 	case nil:
 		// OK.
@@ -124,11 +162,16 @@ func (s *Server) GetPlan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.checkPreconditions(w, r, "plan", pl.Revision) {
+		return
+	}
+
 	s.respondJSON(w, pl)
 }
 
-// UpdatePlan updates existing plan by its id. The plan can be
-// updated only by the user which created it.
+// UpdatePlan updates existing plan by its id. PUT requests replace the
+// plan, while PATCH requests change only the provided attributes. The plan
+// can be updated only by the user which created it.
 func (s *Server) UpdatePlan(w http.ResponseWriter, r *http.Request) {
 	pid, aerr := s.extractPathID(r, "planID")
 	if aerr != nil {
@@ -148,7 +191,7 @@ func (s *Server) UpdatePlan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pl, err := db.GetPlanByID(r.Context(), s.db, pid)
+	pl, err := db.GetPlanByID(r.Context(), s.db, s.extractViewer(r), pid)
 	switch err {
 	case nil:
 		// OK.
@@ -170,18 +213,83 @@ func (s *Server) UpdatePlan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var pc core.PlanCore
-	if err := json.Unmarshal(data, &pc); err != nil {
-		apierr.MalformedDataInput(apierr.DataTypeJSON).Respond(w)
+	if !s.checkPreconditions(w, r, "plan", pl.Revision) {
+		return
+	}
+
+	pc, aerr := decodeCore(r, data, pl.PlanCore)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	pl, aerr = s.updatePlan(r.Context(), uid, pl, pc)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	s.setRevision(w, pl.Revision)
+	s.respondJSON(w, pl)
+}
+
+// updatePlan validates the plan core and replaces the core of the plan of
+// the user with it, unless the plan was modified since it was fetched.
+func (s *Server) updatePlan(ctx context.Context, uid xid.ID, pl *core.Plan, pc core.PlanCore) (*core.Plan, *apierr.Error) {
+	if aerr := s.validatePlanCore(ctx, uid, pc); aerr != nil {
+		return nil, aerr
+	}
+
+	pl, err := db.UpdatePlanByID(ctx, s.db, pl.ID, pl.Version, pl.PlanCore, pc)
+	switch err {
+	case nil:
+		// OK.
+	case ctx.Err():
+		return nil, apierr.Context()
+	case db.ErrVersionMismatch:
+		return nil, apierr.PreconditionFailed("plan")
+	case db.ErrNotFound:
+		return nil, apierr.NotFound("recipe")
+	default:
+		s.log.WithError(err).Error("updating plan")
+		return nil, apierr.Database()
+	}
+
+	s.emitPlan(core.EventPlanUpdated, *pl)
+
+	return pl, nil
+}
+
+// UpdatePlanRecipe adds a recipe to the plan (POST), changes its quantity
+// or day (PATCH) or removes it from the plan (DELETE). The plan can be
+// updated only by the user which created it and must keep at least one
+// recipe.
+func (s *Server) UpdatePlanRecipe(w http.ResponseWriter, r *http.Request) {
+	pid, aerr := s.extractPathID(r, "planID")
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	rid, aerr := s.extractPathID(r, "recipeID")
+	if aerr != nil {
+		aerr.Respond(w)
 		return
 	}
 
-	if aerr := s.validatePlanCore(r.Context(), pc); aerr != nil {
+	uid, aerr := s.extractContextUserID(r)
+	if aerr != nil {
 		aerr.Respond(w)
 		return
 	}
 
-	pl, err = db.UpdatePlanByID(r.Context(), s.db, pid, pc)
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		apierr.MalformedDataInput(apierr.DataTypeRequestBody).Respond(w)
+		return
+	}
+
+	pl, err := db.GetPlanByID(r.Context(), s.db, s.extractViewer(r), pid)
 	switch err {
 	case nil:
 		// OK.
@@ -189,15 +297,105 @@ func (s *Server) UpdatePlan(w http.ResponseWriter, r *http.Request) {
 		apierr.Context().Respond(w)
 		return
 	case db.ErrNotFound:
+		apierr.NotFound("plan").Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("fetching plan by id")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	if pl.UserID.Compare(uid) != 0 {
+		apierr.Forbidden().Respond(w)
+		return
+	}
+
+	if !s.checkPreconditions(w, r, "plan", pl.Revision) {
+		return
+	}
+
+	idx := -1
+
+	for i, cur := range pl.Recipes {
+		if cur.RecipeID == rid {
+			idx = i
+			break
+		}
+	}
+
+	if r.Method == http.MethodPost && idx >= 0 {
+		apierr.Conflict("recipe", "recipe is already in the plan").Respond(w)
+		return
+	}
+
+	if r.Method != http.MethodPost && idx < 0 {
 		apierr.NotFound("recipe").Respond(w)
 		return
+	}
+
+	var pr core.PlanRecipe
+
+	if r.Method != http.MethodDelete {
+		var cur core.PlanRecipe
+		if idx >= 0 {
+			cur = pl.Recipes[idx]
+		}
+
+		if pr, aerr = decodeCore(r, data, cur); aerr != nil {
+			aerr.Respond(w)
+			return
+		}
+	}
+
+	pr.PlanID = pid
+	pr.RecipeID = rid
+
+	pc := pl.PlanCore
+	pc.Recipes = make([]core.PlanRecipe, 0, len(pl.Recipes)+1)
+
+	for i, cur := range pl.Recipes {
+		switch {
+		case i != idx:
+			pc.Recipes = append(pc.Recipes, cur)
+		case r.Method == http.MethodPatch:
+			pc.Recipes = append(pc.Recipes, pr)
+		}
+	}
+
+	if r.Method == http.MethodPost {
+		pc.Recipes = append(pc.Recipes, pr)
+	}
+
+	if aerr := s.validatePlanCore(r.Context(), uid, pc); aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		pl, err = db.DeletePlanRecipe(r.Context(), s.db, pl.Version, pid, rid)
+	} else {
+		pl, err = db.UpsertPlanRecipe(r.Context(), s.db, pl.Version, pr)
+	}
+
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	case db.ErrVersionMismatch:
+		apierr.PreconditionFailed("plan").Respond(w)
+		return
 	default:
-		s.log.WithError(err).Error("creating a new plan")
+		s.log.WithError(err).Error("updating plan recipe")
 		apierr.Database().Respond(w)
 
 		return
 	}
 
+	s.emitPlan(core.EventPlanUpdated, *pl)
+	s.setRevision(w, pl.Revision)
 	s.respondJSON(w, pl)
 }
 
@@ -216,69 +414,135 @@ func (s *Server) DeletePlan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !adm {
-		uid, aerr := s.extractContextUserID(r)
-		if aerr != nil {
-			aerr.Respond(w)
-			return
-		}
+	uid, aerr := s.extractContextUserID(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
 
-		pl, err := db.GetPlanByID(r.Context(), s.db, pid)
-		switch err {
-		case nil:
-			// OK.
-		case r.Context().Err():
-			apierr.Context().Respond(w)
-			return
-		case db.ErrNotFound:
-			apierr.NotFound("plan").Respond(w)
-			return
-		default:
-			s.log.WithError(err).Error("fetching plan by id")
-			apierr.Database().Respond(w)
+	if aerr := s.deletePlan(r.Context(), core.Viewer{UserID: uid, Admin: adm}, pid); aerr != nil {
+		aerr.Respond(w)
+		return
+	}
 
-			return
-		}
+	w.WriteHeader(http.StatusNoContent)
+}
 
-		if pl.UserID.Compare(uid) != 0 {
-			apierr.Forbidden().Respond(w)
-			return
-		}
+// deletePlan moves a plan to the trash. The plan can be deleted only by an
+// admin or the user that created it.
+func (s *Server) deletePlan(ctx context.Context, vw core.Viewer, pid xid.ID) *apierr.Error {
+	pl, aerr := s.checkPlanOwner(ctx, vw, pid)
+	if aerr != nil {
+		return aerr
+	}
+
+	err := db.DeletePlanByID(ctx, s.db, pid)
+	switch err {
+	case nil:
+		// OK.
+	case ctx.Err():
+		return apierr.Context()
+	default:
+		s.log.WithError(err).Error("deleting plan by id")
+		return apierr.Database()
 	}
 
-	err := db.DeletePlanByID(r.Context(), s.db, pid)
+	s.emitPlan(core.EventPlanDeleted, *pl)
+
+	return nil
+}
+
+// checkPlanOwner checks whether the viewer is allowed to modify the plan,
+// i.e. is an admin or the user that created it. The plan is returned if
+// the viewer is allowed to modify it.
+func (s *Server) checkPlanOwner(ctx context.Context, vw core.Viewer, pid xid.ID) (*core.Plan, *apierr.Error) {
+	pl, err := db.GetPlanByID(ctx, s.db, vw, pid)
+	switch err {
+	case nil:
+		// OK.
+	case ctx.Err():
+		return nil, apierr.Context()
+	case db.ErrNotFound:
+		return nil, apierr.NotFound("plan")
+	default:
+		s.log.WithError(err).Error("fetching plan by id")
+		return nil, apierr.Database()
+	}
+
+	if !vw.Admin && pl.UserID.Compare(vw.UserID) != 0 {
+		return nil, apierr.Forbidden()
+	}
+
+	return pl, nil
+}
+
+// RestorePlan restores a plan from the trash by its id. The plan can
+// be restored only by an admin or the user that created it.
+func (s *Server) RestorePlan(w http.ResponseWriter, r *http.Request) {
+	pid, aerr := s.extractPathID(r, "planID")
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	pl, err := db.GetDeletedPlanByID(r.Context(), s.db, pid)
 	switch err {
 	case nil:
 		// OK.
 	case r.Context().Err():
 		apierr.Context().Respond(w)
 		return
+	case db.ErrNotFound:
+		apierr.NotFound("plan").Respond(w)
+		return
 	default:
-		s.log.WithError(err).Error("deleting plan by id")
+		s.log.WithError(err).Error("fetching deleted plan by id")
 		apierr.Database().Respond(w)
 
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-}
+	vw := s.extractViewer(r)
+	if !vw.Admin && pl.UserID.Compare(vw.UserID) != 0 {
+		apierr.Forbidden().Respond(w)
+		return
+	}
 
-// validatePlanCore validates plan core attributes.
-func (s *Server) validatePlanCore(ctx context.Context, pc core.PlanCore) *apierr.Error {
-	rr, err := db.GetRecipes(ctx, s.db)
+	err = db.RestorePlanByID(r.Context(), s.db, pid)
 	switch err {
 	case nil:
 		// OK.
-	case ctx.Err():
-		return apierr.Context()
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
 	default:
-		s.log.WithError(err).Error("fetching recipes")
-		return apierr.Database()
+		s.log.WithError(err).Error("restoring plan by id")
+		apierr.Database().Respond(w)
+
+		return
 	}
 
+	pl.DeletedAt = nil
+
+	s.emitPlan(core.EventPlanUpdated, *pl)
+	s.respondJSON(w, pl)
+}
+
+// validatePlanCore validates plan core attributes. The plan can reference
+// only the recipes that are visible to the user that owns it.
+func (s *Server) validatePlanCore(ctx context.Context, uid xid.ID, pc core.PlanCore) *apierr.Error {
 	for _, pr := range pc.Recipes {
-		if _, ok := pr.FindMatching(rr); !ok {
+		_, err := db.GetRecipeByID(ctx, s.db, core.Viewer{UserID: uid}, pr.RecipeID)
+		switch err {
+		case nil:
+			// OK.
+		case ctx.Err():
+			return apierr.Context()
+		case db.ErrNotFound:
 			return apierr.NotFound("recipe")
+		default:
+			s.log.WithError(err).Error("fetching recipe by id")
+			return apierr.Database()
 		}
 	}
 
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"foodie/core"
+	"foodie/server/apierr"
+	"net/http"
+)
+
+// setRevision sets the ETag and Last-Modified headers of the object
+// revision.
+func (s *Server) setRevision(w http.ResponseWriter, rv core.Revision) {
+	w.Header().Set("ETag", rv.ETag())
+	w.Header().Set("Last-Modified", rv.UpdatedAt.UTC().Format(http.TimeFormat))
+}
+
+// checkPreconditions evaluates the conditional headers of the request
+// against the revision of the object in the order defined by RFC 9110 and
+// sets the revision headers. If a condition fails, 304 Not Modified is
+// written to GET and HEAD requests, 412 Precondition Failed is written to
+// the others, and false is returned.
+func (s *Server) checkPreconditions(
+	w http.ResponseWriter,
+	r *http.Request,
+	object string,
+	rv core.Revision,
+) bool {
+	s.setRevision(w, rv)
+
+	if im := r.Header.Get("If-Match"); im != "" {
+		if !rv.MatchesETag(im, false) {
+			apierr.PreconditionFailed(object).Respond(w)
+			return false
+		}
+	} else if ius := r.Header.Get("If-Unmodified-Since"); ius != "" {
+		if t, err := http.ParseTime(ius); err == nil && rv.ModifiedSince(t) {
+			apierr.PreconditionFailed(object).Respond(w)
+			return false
+		}
+	}
+
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if !rv.MatchesETag(inm, true) {
+			return true
+		}
+
+		if !safe {
+			apierr.PreconditionFailed(object).Respond(w)
+			return false
+		}
+
+		w.WriteHeader(http.StatusNotModified)
+
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && safe {
+		if t, err := http.ParseTime(ims); err == nil && !rv.ModifiedSince(t) {
+			w.WriteHeader(http.StatusNotModified)
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"foodie/core"
+	"foodie/server/apierr"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Server_checkPreconditions(t *testing.T) {
+	tstamp := time.Date(2022, 9, 14, 12, 0, 0, 0, time.UTC)
+	rv := core.Revision{
+		Version:   3,
+		UpdatedAt: tstamp,
+	}
+
+	tests := map[string]struct {
+		Method string
+		Header map[string]string
+		Result bool
+		Status int
+	}{
+		"Unconditional request": {
+			Method: http.MethodPatch,
+			Result: true,
+		},
+		"Matching If-Match": {
+			Method: http.MethodPatch,
+			Header: map[string]string{"If-Match": `"3"`},
+			Result: true,
+		},
+		"Stale If-Match": {
+			Method: http.MethodPatch,
+			Header: map[string]string{"If-Match": `"2"`},
+			Status: http.StatusPreconditionFailed,
+		},
+		"Weak If-Match": {
+			Method: http.MethodPatch,
+			Header: map[string]string{"If-Match": `W/"3"`},
+			Status: http.StatusPreconditionFailed,
+		},
+		"If-Match takes precedence over If-Unmodified-Since": {
+			Method: http.MethodPatch,
+			Header: map[string]string{
+				"If-Match":            `"3"`,
+				"If-Unmodified-Since": tstamp.Add(-time.Hour).Format(http.TimeFormat),
+			},
+			Result: true,
+		},
+		"Unmodified since": {
+			Method: http.MethodPatch,
+			Header: map[string]string{"If-Unmodified-Since": tstamp.Format(http.TimeFormat)},
+			Result: true,
+		},
+		"Modified since": {
+			Method: http.MethodPatch,
+			Header: map[string]string{"If-Unmodified-Since": tstamp.Add(-time.Hour).Format(http.TimeFormat)},
+			Status: http.StatusPreconditionFailed,
+		},
+		"Matching If-None-Match of a GET request": {
+			Method: http.MethodGet,
+			Header: map[string]string{"If-None-Match": `"1", W/"3"`},
+			Status: http.StatusNotModified,
+		},
+		"Different If-None-Match of a GET request": {
+			Method: http.MethodGet,
+			Header: map[string]string{"If-None-Match": `"2"`},
+			Result: true,
+		},
+		"Matching If-None-Match of a PATCH request": {
+			Method: http.MethodPatch,
+			Header: map[string]string{"If-None-Match": "*"},
+			Status: http.StatusPreconditionFailed,
+		},
+		"If-None-Match takes precedence over If-Modified-Since": {
+			Method: http.MethodGet,
+			Header: map[string]string{
+				"If-None-Match":     `"2"`,
+				"If-Modified-Since": tstamp.Format(http.TimeFormat),
+			},
+			Result: true,
+		},
+		"Not modified since": {
+			Method: http.MethodGet,
+			Header: map[string]string{"If-Modified-Since": tstamp.Format(http.TimeFormat)},
+			Status: http.StatusNotModified,
+		},
+		"Modified since of a GET request": {
+			Method: http.MethodGet,
+			Header: map[string]string{"If-Modified-Since": tstamp.Add(-time.Hour).Format(http.TimeFormat)},
+			Result: true,
+		},
+		"If-Modified-Since of a PATCH request": {
+			Method: http.MethodPatch,
+			Header: map[string]string{"If-Modified-Since": tstamp.Format(http.TimeFormat)},
+			Result: true,
+		},
+		"Invalid date": {
+			Method: http.MethodGet,
+			Header: map[string]string{"If-Modified-Since": "yesterday"},
+			Result: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(test.Method, "/", nil)
+			for k, v := range test.Header {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+
+			s := &Server{}
+			assert.Equal(t, test.Result, s.checkPreconditions(rec, req, "recipe", rv))
+			assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+			assert.Equal(t, "Wed, 14 Sep 2022 12:00:00 GMT", rec.Header().Get("Last-Modified"))
+
+			if test.Result {
+				return
+			}
+
+			assert.Equal(t, test.Status, rec.Code)
+
+			if test.Status != http.StatusPreconditionFailed {
+				assert.Empty(t, rec.Body.Bytes())
+				return
+			}
+
+			var res map[string]interface{}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			assert.Equal(t, string(apierr.CodePreconditionFailed), res["code"])
+		})
+	}
+}
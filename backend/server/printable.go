@@ -0,0 +1,196 @@
+package server
+
+import (
+	"foodie/db"
+	"foodie/server/apierr"
+	"foodie/server/printable"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/xid"
+)
+
+// _maxCookbookRecipes specifies the maximum number of recipes in a single
+// cookbook.
+const _maxCookbookRecipes = 50
+
+// GetRecipePrintable retrieves a single recipe by its id as a printable
+// document in the format.
+func (s *Server) GetRecipePrintable(f printable.Format) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec, pp, ok := s.fetchRecipeWithProducts(w, r)
+		if !ok {
+			return
+		}
+
+		pr := printable.NewRecipe(rec, pp, 1)
+
+		s.respondPrintable(w, f, &printable.Document{
+			Title:   rec.Name,
+			Recipes: []printable.Recipe{pr},
+		})
+	}
+}
+
+// GetCookbookPrintable retrieves the recipes by the ids in the ids query
+// parameter as a single printable document in the format, along with the
+// shopping list of all of their ingredients.
+func (s *Server) GetCookbookPrintable(f printable.Format) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ids, aerr := extractQueryIDs(r, "ids", _maxCookbookRecipes)
+		if aerr != nil {
+			aerr.Respond(w)
+			return
+		}
+
+		vw := s.extractViewer(r)
+		rr := make([]printable.Recipe, 0, len(ids))
+
+		for _, rid := range ids {
+			rec, pp, aerr := s.fetchViewerRecipe(r, vw, rid)
+			if aerr != nil {
+				aerr.Respond(w)
+				return
+			}
+
+			if rec == nil {
+				apierr.NotFound("recipe").Respond(w)
+				return
+			}
+
+			rr = append(rr, printable.NewRecipe(*rec, pp, 1))
+		}
+
+		s.respondPrintable(w, f, &printable.Document{
+			Title:    "Cookbook",
+			Recipes:  rr,
+			Shopping: printable.ShoppingList(rr),
+		})
+	}
+}
+
+// GetPlanPrintable retrieves a single plan by its id as a printable
+// document in the format. Recipes are multiplied by their quantities and
+// ordered by their days, and are followed by the shopping list of all of
+// their ingredients. Recipes that are not visible to the viewer are left
+// out.
+func (s *Server) GetPlanPrintable(f printable.Format) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pid, aerr := s.extractPathID(r, "planID")
+		if aerr != nil {
+			aerr.Respond(w)
+			return
+		}
+
+		vw := s.extractViewer(r)
+
+		pl, err := db.GetPlanByID(r.Context(), s.db, vw, pid)
+		switch err {
+		case nil:
+			// OK.
+		case r.Context().Err():
+			apierr.Context().Respond(w)
+			return
+		case db.ErrNotFound:
+			apierr.NotFound("plan").Respond(w)
+			return
+		default:
+			s.log.WithError(err).Error("fetching plan by id")
+			apierr.Database().Respond(w)
+
+			return
+		}
+
+		if !s.checkPreconditions(w, r, "plan", pl.Revision) {
+			return
+		}
+
+		rr := make([]printable.Recipe, 0, len(pl.Recipes))
+
+		for _, pr := range pl.Recipes {
+			rec, pp, aerr := s.fetchViewerRecipe(r, vw, pr.RecipeID)
+			if aerr != nil {
+				aerr.Respond(w)
+				return
+			}
+
+			if rec == nil {
+				continue
+			}
+
+			prec := printable.NewRecipe(*rec, pp, pr.Quantity)
+			prec.Day = pr.Day
+
+			rr = append(rr, prec)
+		}
+
+		sort.SliceStable(rr, func(i, j int) bool {
+			if rr[i].Day == "" || rr[j].Day == "" {
+				return rr[j].Day == "" && rr[i].Day != ""
+			}
+
+			return rr[i].Day < rr[j].Day
+		})
+
+		s.respondPrintable(w, f, &printable.Document{
+			Title:       pl.Name,
+			Description: pl.Description,
+			Recipes:     rr,
+			Shopping:    printable.ShoppingList(rr),
+		})
+	}
+}
+
+// respondPrintable renders the document in the format and writes it to
+// the response.
+func (s *Server) respondPrintable(w http.ResponseWriter, f printable.Format, doc *printable.Document) {
+	var buf strings.Builder
+
+	if err := doc.Render(&buf, f); err != nil {
+		s.log.WithError(err).Error("rendering printable document")
+		apierr.Internal().Respond(w)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", f.ContentType())
+
+	if _, err := w.Write([]byte(buf.String())); err != nil {
+		s.log.WithError(err).Error("writing to client response data")
+	}
+}
+
+// extractQueryIDs extracts a required comma separated list of unique
+// object ids from the URL query. Duplicate ids are removed.
+func extractQueryIDs(r *http.Request, key string, max int) ([]xid.ID, *apierr.Error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return nil, apierr.InvalidAttribute(key, "cannot be empty")
+	}
+
+	parts := strings.Split(v, ",")
+	ids := make([]xid.ID, 0, len(parts))
+	seen := make(map[xid.ID]struct{}, len(parts))
+
+	for _, part := range parts {
+		id, err := xid.FromString(strings.TrimSpace(part))
+		if err != nil {
+			return nil, apierr.InvalidAttribute(key, "must contain valid ids")
+		}
+
+		if _, ok := seen[id]; ok {
+			continue
+		}
+
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+
+	if len(ids) > max {
+		return nil, apierr.InvalidAttribute(key, "cannot contain more than "+strconv.Itoa(max)+" ids")
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,256 @@
+// Package printable renders recipes and plans as print-friendly HTML
+// pages and Markdown documents, so that they could be printed or shared
+// without the web app.
+package printable
+
+import (
+	"embed"
+	"errors"
+	"foodie/core"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
+)
+
+// Format specifies the format of rendered documents.
+type Format string
+
+// Format values.
+const (
+	// FormatHTML specifies a print-friendly HTML page.
+	FormatHTML Format = "html"
+
+	// FormatMarkdown specifies a Markdown document.
+	FormatMarkdown Format = "md"
+)
+
+// ContentType returns the media type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// ErrUnknownFormat is returned whenever a document is rendered in a format
+// that is not supported.
+var ErrUnknownFormat = errors.New("unknown document format")
+
+//go:embed templates
+var _templates embed.FS
+
+var (
+	// _htmlTemplate renders documents as HTML pages.
+	_htmlTemplate = htmltemplate.Must(htmltemplate.New("document.html.tmpl").
+			Funcs(htmltemplate.FuncMap{"amount": formatAmount}).
+			ParseFS(_templates, "templates/document.html.tmpl"))
+
+	// _markdownTemplate renders documents as Markdown documents.
+	_markdownTemplate = texttemplate.Must(texttemplate.New("document.md.tmpl").
+				Funcs(texttemplate.FuncMap{"amount": formatAmount, "md": escapeMarkdown, "url": escapeURL}).
+				ParseFS(_templates, "templates/document.md.tmpl"))
+)
+
+// Document is a printable collection of recipes, e.g. a single recipe, a
+// cookbook or a plan.
+type Document struct {
+	// Title specifies the title of the document.
+	Title string
+
+	// Description provides a brief description of the document.
+	Description string
+
+	// Recipes contains the recipes of the document.
+	Recipes []Recipe
+
+	// Shopping contains the total amounts of ingredients needed for all
+	// recipes. It is not rendered if it is empty.
+	Shopping []Ingredient
+}
+
+// Calories returns the calories of all recipes of the document.
+func (d *Document) Calories() decimal.Decimal {
+	cal := decimal.Zero
+	for _, rec := range d.Recipes {
+		cal = cal.Add(rec.Calories)
+	}
+
+	return cal.Round(0)
+}
+
+// Render writes the document in the format.
+func (d *Document) Render(w io.Writer, f Format) error {
+	switch f {
+	case FormatHTML:
+		return _htmlTemplate.Execute(w, d)
+	case FormatMarkdown:
+		return _markdownTemplate.Execute(w, d)
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+// Recipe is a printable recipe along with its ingredients.
+type Recipe struct {
+	// Name specifies the name of the recipe.
+	Name string
+
+	// Description provides a brief description of the recipe.
+	Description string
+
+	// ImageURL specifies the image url of the recipe.
+	ImageURL string
+
+	// Day specifies the day in the YYYY-MM-DD format at which the recipe
+	// is planned, if any.
+	Day string
+
+	// Quantity specifies how many times the recipe is prepared.
+	Quantity uint64
+
+	// Ingredients contains the ingredients of the recipe, multiplied by
+	// its quantity.
+	Ingredients []Ingredient
+
+	// Calories specifies the calories of all ingredients.
+	Calories decimal.Decimal
+}
+
+// Ingredient is a product along with its amount.
+type Ingredient struct {
+	// ProductID specifies the id of the product.
+	ProductID xid.ID
+
+	// Name specifies the name of the product.
+	Name string
+
+	// Amount specifies the amount of the product.
+	Amount decimal.Decimal
+
+	// Unit specifies the unit of the amount. It is empty if the amount is
+	// a number of units.
+	Unit string
+
+	// Calories specifies the calories of the amount.
+	Calories decimal.Decimal
+}
+
+// NewRecipe creates a printable recipe of the recipe, with all amounts
+// and calories multiplied by the quantity. Products that are not provided
+// are left out.
+func NewRecipe(rec core.Recipe, pp []core.Product, qty uint64) Recipe {
+	mul := decimal.NewFromInt(int64(qty))
+
+	pr := Recipe{
+		Name:        rec.Name,
+		Description: rec.Description,
+		ImageURL:    rec.ImageURL,
+		Quantity:    qty,
+		Ingredients: make([]Ingredient, 0, len(rec.Products)),
+		Calories:    decimal.Zero,
+	}
+
+	for _, rp := range rec.Products {
+		prd, ok := rp.FindMatching(pp)
+		if !ok {
+			continue
+		}
+
+		ing := Ingredient{
+			ProductID: prd.ID,
+			Name:      prd.Name,
+			Amount:    rp.Amount(prd).Mul(mul),
+			Unit:      prd.Serving.Type.Unit(),
+			Calories:  rp.Calories(prd).Mul(mul).Round(0),
+		}
+
+		pr.Ingredients = append(pr.Ingredients, ing)
+		pr.Calories = pr.Calories.Add(ing.Calories)
+	}
+
+	return pr
+}
+
+// ShoppingList sums the amounts of the same products used by the recipes.
+// Ingredients are sorted by their names.
+func ShoppingList(rr []Recipe) []Ingredient {
+	index := make(map[xid.ID]int)
+	ii := make([]Ingredient, 0)
+
+	for _, rec := range rr {
+		for _, ing := range rec.Ingredients {
+			if i, ok := index[ing.ProductID]; ok {
+				ii[i].Amount = ii[i].Amount.Add(ing.Amount)
+				ii[i].Calories = ii[i].Calories.Add(ing.Calories)
+
+				continue
+			}
+
+			index[ing.ProductID] = len(ii)
+			ii = append(ii, ing)
+		}
+	}
+
+	sort.SliceStable(ii, func(i, j int) bool {
+		return strings.ToLower(ii[i].Name) < strings.ToLower(ii[j].Name)
+	})
+
+	return ii
+}
+
+// formatAmount formats the amount along with its unit, e.g. "250 g".
+func formatAmount(amount decimal.Decimal, unit string) string {
+	if unit == "" {
+		return amount.Round(2).String()
+	}
+
+	return amount.Round(2).String() + " " + unit
+}
+
+// _markdownEscaper escapes the characters that have a special meaning in
+// Markdown.
+var _markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+	">", `\>`,
+	"#", `\#`,
+	"|", `\|`,
+	"\r\n", " ",
+	"\n", " ",
+)
+
+// escapeMarkdown escapes the text, so that it is rendered as is on a
+// single line.
+func escapeMarkdown(text string) string {
+	return _markdownEscaper.Replace(text)
+}
+
+// _urlEscaper escapes the characters that end Markdown link
+// destinations.
+var _urlEscaper = strings.NewReplacer(
+	" ", "%20",
+	"(", "%28",
+	")", "%29",
+	"<", "%3C",
+	">", "%3E",
+)
+
+// escapeURL escapes the url, so that it could be used as a Markdown link
+// destination.
+func escapeURL(url string) string {
+	return _urlEscaper.Replace(url)
+}
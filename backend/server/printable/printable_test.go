@@ -0,0 +1,165 @@
+package printable
+
+import (
+	"bytes"
+	"foodie/core"
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDocument() Document {
+	chicken := core.Product{
+		ID: xid.New(),
+		ProductCore: core.ProductCore{
+			Name: "Chicken Breast",
+			Serving: core.Serving{
+				Type:     core.ServingTypeGrams,
+				Size:     decimal.NewFromInt(100),
+				Calories: 165,
+			},
+		},
+	}
+
+	banana := core.Product{
+		ID: xid.New(),
+		ProductCore: core.ProductCore{
+			Name: "Banana",
+			Serving: core.Serving{
+				Type:     core.ServingTypeUnits,
+				Size:     decimal.NewFromInt(1),
+				Calories: 105,
+			},
+		},
+	}
+
+	pp := []core.Product{chicken, banana}
+
+	rr := []Recipe{
+		NewRecipe(core.Recipe{
+			RecipeCore: core.RecipeCore{
+				Name:        "Chicken with bananas",
+				Description: "Unusual, but *tasty*",
+				ImageURL:    "https://example.com/chicken (1).png",
+				Products: []core.RecipeProduct{
+					{ProductID: chicken.ID, Quantity: decimal.New(25, -1)},
+					{ProductID: banana.ID, Quantity: decimal.NewFromInt(1)},
+				},
+			},
+		}, pp, 2),
+		NewRecipe(core.Recipe{
+			RecipeCore: core.RecipeCore{
+				Name:        "Bananas",
+				Description: "Just bananas",
+				Products: []core.RecipeProduct{
+					{ProductID: banana.ID, Quantity: decimal.NewFromInt(3)},
+					{ProductID: xid.New(), Quantity: decimal.NewFromInt(1)},
+				},
+			},
+		}, pp, 1),
+	}
+
+	rr[0].Day = "2022-10-31"
+
+	return Document{
+		Title:    "Week | 1",
+		Recipes:  rr,
+		Shopping: ShoppingList(rr),
+	}
+}
+
+func Test_NewRecipe(t *testing.T) {
+	doc := testDocument()
+
+	assert.Len(t, doc.Recipes[0].Ingredients, 2)
+	assert.Equal(t, "825", doc.Recipes[0].Ingredients[0].Calories.String())
+	assert.Equal(t, "500", doc.Recipes[0].Ingredients[0].Amount.String())
+	assert.Equal(t, "1035", doc.Recipes[0].Calories.String())
+
+	assert.Len(t, doc.Recipes[1].Ingredients, 1)
+	assert.Equal(t, "315", doc.Recipes[1].Calories.String())
+
+	assert.Equal(t, "1350", doc.Calories().String())
+}
+
+func Test_ShoppingList(t *testing.T) {
+	doc := testDocument()
+
+	require.Len(t, doc.Shopping, 2)
+	assert.Equal(t, "Banana", doc.Shopping[0].Name)
+	assert.Equal(t, "5", doc.Shopping[0].Amount.String())
+	assert.Equal(t, "Chicken Breast", doc.Shopping[1].Name)
+	assert.Equal(t, "500", doc.Shopping[1].Amount.String())
+}
+
+func Test_Document_Render(t *testing.T) {
+	doc := testDocument()
+
+	t.Run("Markdown", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+
+		require.NoError(t, doc.Render(&buf, FormatMarkdown))
+		assert.Equal(t, `# Week \| 1
+
+Calories: 1350
+
+## Chicken with bananas ×2
+
+_2022-10-31_
+
+![](https://example.com/chicken%20%281%29.png)
+
+Unusual, but \*tasty\*
+
+| Ingredient | Amount | Calories |
+| --- | ---: | ---: |
+| Chicken Breast | 500 g | 825 |
+| Banana | 2 | 210 |
+| **Total** | | **1035** |
+
+## Bananas
+
+Just bananas
+
+| Ingredient | Amount | Calories |
+| --- | ---: | ---: |
+| Banana | 3 | 315 |
+| **Total** | | **315** |
+
+## Shopping list
+
+| Ingredient | Amount |
+| --- | ---: |
+| Banana | 5 |
+| Chicken Breast | 500 g |
+`, buf.String())
+	})
+
+	t.Run("HTML", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+
+		require.NoError(t, doc.Render(&buf, FormatHTML))
+
+		out := buf.String()
+		assert.Contains(t, out, "<title>Week | 1</title>")
+		assert.Contains(t, out, "<h2>Chicken with bananas ×2</h2>")
+		assert.Contains(t, out, `<img src="https://example.com/chicken%20%281%29.png" alt="">`)
+		assert.Contains(t, out, `<tr><td>Chicken Breast</td><td class="number">500 g</td><td class="number">825</td></tr>`)
+		assert.Contains(t, out, `<tfoot><tr><th>Total</th><th></th><th class="number">1035</th></tr></tfoot>`)
+		assert.Contains(t, out, "<h2>Shopping list</h2>")
+		assert.Contains(t, out, `<tr><td>Banana</td><td class="number">5</td></tr>`)
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		t.Parallel()
+
+		assert.ErrorIs(t, doc.Render(&bytes.Buffer{}, Format("pdf")), ErrUnknownFormat)
+	})
+}
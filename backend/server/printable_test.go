@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"foodie/core"
+	"foodie/db"
+	"foodie/server/printable"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/rs/xid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Server_Printable(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	rec, other := insertSharedRecipe(t, dbh)
+
+	pl, err := db.InsertPlan(context.Background(), dbh, rec.UserID, core.PlanCore{
+		Name:        "Week",
+		Description: "Meals of the week.",
+		Visibility:  core.VisibilityPublic,
+		State:       core.StatePublished,
+		Recipes:     []core.PlanRecipe{{RecipeID: rec.ID, Quantity: 1}},
+	})
+	require.NoError(t, err)
+
+	s := &Server{log: logrus.New(), db: dbh}
+
+	tests := map[string]struct {
+		Handler http.HandlerFunc
+		Key     string
+		ID      xid.ID
+		Query   string
+		UserID  xid.ID
+		Secret  bool
+	}{
+		"Recipe of another user": {
+			Handler: s.GetRecipePrintable(printable.FormatMarkdown),
+			Key:     "recipeID",
+			ID:      rec.ID,
+			UserID:  other,
+		},
+		"Own recipe": {
+			Handler: s.GetRecipePrintable(printable.FormatMarkdown),
+			Key:     "recipeID",
+			ID:      rec.ID,
+			UserID:  rec.UserID,
+			Secret:  true,
+		},
+		"Cookbook of another user's recipe": {
+			Handler: s.GetCookbookPrintable(printable.FormatMarkdown),
+			Query:   "?ids=" + rec.ID.String(),
+			UserID:  other,
+		},
+		"Plan of another user": {
+			Handler: s.GetPlanPrintable(printable.FormatMarkdown),
+			Key:     "planID",
+			ID:      pl.ID,
+			UserID:  other,
+		},
+		"Own plan": {
+			Handler: s.GetPlanPrintable(printable.FormatMarkdown),
+			Key:     "planID",
+			ID:      pl.ID,
+			UserID:  rec.UserID,
+			Secret:  true,
+		},
+	}
+
+	for tName, tCase := range tests {
+		t.Run(tName, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			if tCase.Key != "" {
+				rctx.URLParams.Add(tCase.Key, tCase.ID.String())
+			}
+
+			ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+			ctx = withViewer(ctx, tCase.UserID, false)
+
+			req := httptest.NewRequest(http.MethodGet, "http://test.com/"+tCase.Query, nil)
+			req = req.WithContext(ctx)
+			resp := httptest.NewRecorder()
+
+			tCase.Handler(resp, req)
+
+			require.Equal(t, http.StatusOK, resp.Code)
+			assert.Contains(t, resp.Body.String(), "Oats")
+			assert.Equal(t, tCase.Secret, strings.Contains(resp.Body.String(), "Secret"))
+		})
+	}
+}
@@ -1,16 +1,34 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"foodie/core"
 	"foodie/db"
 	"foodie/server/apierr"
 	"io"
 	"net/http"
+
+	"github.com/rs/xid"
 )
 
-// CreateProduct creates a product.
+// CreateProduct creates a product. Products created by admins are approved
+// immediately, while products created by regular users are submitted for
+// a review and can be used only by their submitters until approved.
 func (s *Server) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	uid, aerr := s.extractContextUserID(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	adm, aerr := s.extractContextAdmin(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
 	data, err := io.ReadAll(r.Body)
 	if err != nil {
 		apierr.MalformedDataInput(apierr.DataTypeRequestBody).Respond(w)
@@ -23,12 +41,61 @@ func (s *Server) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	prd, aerr := s.createProduct(r.Context(), core.Viewer{UserID: uid, Admin: adm}, pc)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	s.respondJSON(w, prd)
+}
+
+// createProduct validates the product core and creates a product of the
+// viewer. Products created by admins are approved immediately.
+func (s *Server) createProduct(ctx context.Context, vw core.Viewer, pc core.ProductCore) (*core.Product, *apierr.Error) {
 	if aerr := pc.Validate(); aerr != nil {
+		return nil, aerr
+	}
+
+	st := core.ProductStatusPending
+	if vw.Admin {
+		st = core.ProductStatusApproved
+	}
+
+	prd, err := db.InsertProduct(ctx, s.db, vw.UserID, st, pc)
+	switch err {
+	case nil:
+		// OK.
+	case ctx.Err():
+		return nil, apierr.Context()
+	case db.ErrDuplicateBarcode:
+		return nil, apierr.Conflict("product", "barcode is used by another product")
+	default:
+		s.log.WithError(err).Error("creating a new product")
+		return nil, apierr.Database()
+	}
+
+	s.indexProduct(*prd)
+	s.emitProduct(core.EventProductCreated, *prd)
+
+	return prd, nil
+}
+
+// GetProducts retrieves a page of products.
+func (s *Server) GetProducts(w http.ResponseWriter, r *http.Request) {
+	pg, aerr := s.extractPage(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	q, aerr := core.ParseQuery(r.URL.Query(), core.ProductFields)
+	if aerr != nil {
 		aerr.Respond(w)
 		return
 	}
 
-	prd, err := db.InsertProduct(r.Context(), s.db, pc)
+	pp, next, err := db.GetProducts(r.Context(), s.db, s.extractViewer(r), q, pg)
 	switch err {
 	case nil:
 		// OK.
@@ -36,43 +103,63 @@ func (s *Server) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		apierr.Context().Respond(w)
 		return
 	default:
-		s.log.WithError(err).Error("creating a new product")
+		s.log.WithError(err).Error("fetching products")
 		apierr.Database().Respond(w)
 
 		return
 	}
 
-	s.respondJSON(w, prd)
+	s.respondPage(w, pp, next)
 }
 
-// GetProducts retrieves all products.
-func (s *Server) GetProducts(w http.ResponseWriter, r *http.Request) {
-	pp, err := db.GetProducts(r.Context(), s.db)
+// GetProduct retrieves a single product by its id.
+func (s *Server) GetProduct(w http.ResponseWriter, r *http.Request) {
+	pid, aerr := s.extractPathID(r, "productID")
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	prd, err := db.GetProductByID(r.Context(), s.db, s.extractViewer(r), pid)
 	switch err {
 	case nil:
 		// OK.
 	case r.Context().Err():
 		apierr.Context().Respond(w)
 		return
+	case db.ErrNotFound:
+		apierr.NotFound("product").Respond(w)
+		return
 	default:
-		s.log.WithError(err).Error("fetching products")
+		s.log.WithError(err).Error("fetching product by id")
 		apierr.Database().Respond(w)
 
 		return
 	}
 
-	s.respondJSON(w, pp)
+	if !s.checkPreconditions(w, r, "product", prd.Revision) {
+		return
+	}
+
+	s.respondJSON(w, prd)
 }
 
-// GetProduct retrieves a single product by its id.
-func (s *Server) GetProduct(w http.ResponseWriter, r *http.Request) {
+// UpdateProduct updates existing product by its id. PUT requests replace
+// the product, while PATCH requests change only the provided attributes.
+func (s *Server) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	pid, aerr := s.extractPathID(r, "productID")
 	if aerr != nil {
 		aerr.Respond(w)
 		return
 	}
 
-	prd, err := db.GetProductByID(r.Context(), s.db, pid)
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		apierr.MalformedDataInput(apierr.DataTypeRequestBody).Respond(w)
+		return
+	}
+
+	prd, err := db.GetProductByID(r.Context(), s.db, s.extractViewer(r), pid)
 	switch err {
 	case nil:
 		// OK.
@@ -89,11 +176,94 @@ func (s *Server) GetProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.checkPreconditions(w, r, "product", prd.Revision) {
+		return
+	}
+
+	pc, aerr := decodeCore(r, data, prd.ProductCore)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	prd, aerr = s.updateProduct(r.Context(), prd, pc)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	s.setRevision(w, prd.Revision)
 	s.respondJSON(w, prd)
 }
 
-// UpdateProduct updates existing product by its id.
-func (s *Server) UpdateProduct(w http.ResponseWriter, r *http.Request) {
+// updateProduct validates the product core and replaces the core of the
+// product with it, unless the product was modified since it was fetched.
+func (s *Server) updateProduct(ctx context.Context, prd *core.Product, pc core.ProductCore) (*core.Product, *apierr.Error) {
+	if aerr := pc.Validate(); aerr != nil {
+		return nil, aerr
+	}
+
+	prd, err := db.UpdateProductByID(ctx, s.db, prd.ID, prd.Version, prd.ProductCore, pc)
+	switch err {
+	case nil:
+		// OK.
+	case ctx.Err():
+		return nil, apierr.Context()
+	case db.ErrVersionMismatch:
+		return nil, apierr.PreconditionFailed("product")
+	case db.ErrDuplicateBarcode:
+		return nil, apierr.Conflict("product", "barcode is used by another product")
+	default:
+		s.log.WithError(err).Error("updating product")
+		return nil, apierr.Database()
+	}
+
+	s.indexProduct(*prd)
+	s.emitProduct(core.EventProductUpdated, *prd)
+
+	return prd, nil
+}
+
+// GetProductSubmissions retrieves a page of products by their moderation
+// status. By default, pending products are retrieved.
+func (s *Server) GetProductSubmissions(w http.ResponseWriter, r *http.Request) {
+	st := core.ProductStatusPending
+	if v := r.URL.Query().Get("status"); v != "" {
+		st = core.ProductStatus(v)
+	}
+
+	if aerr := st.Validate(); aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	pg, aerr := s.extractPage(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	pp, next, err := db.GetProductsByStatus(r.Context(), s.db, st, pg)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("fetching products by status")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	s.respondPage(w, pp, next)
+}
+
+// ApproveProduct approves a submitted product by its id. If the request
+// body contains the product core, the product is updated before being
+// approved.
+func (s *Server) ApproveProduct(w http.ResponseWriter, r *http.Request) {
 	pid, aerr := s.extractPathID(r, "productID")
 	if aerr != nil {
 		aerr.Respond(w)
@@ -106,74 +276,398 @@ func (s *Server) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var pc core.ProductCore
-	if err := json.Unmarshal(data, &pc); err != nil {
-		apierr.MalformedDataInput(apierr.DataTypeJSON).Respond(w)
+	var pc *core.ProductCore
+
+	if len(data) > 0 {
+		pc = &core.ProductCore{}
+		if err := json.Unmarshal(data, pc); err != nil {
+			apierr.MalformedDataInput(apierr.DataTypeJSON).Respond(w)
+			return
+		}
+
+		if aerr := pc.Validate(); aerr != nil {
+			aerr.Respond(w)
+			return
+		}
+	}
+
+	if _, aerr := s.checkProductSubmission(r, pid); aerr != nil {
+		aerr.Respond(w)
 		return
 	}
 
-	prd, err := db.UpdateProductByID(r.Context(), s.db, pid, pc)
+	prd, err := db.ApproveProductByID(r.Context(), s.db, pid, pc)
 	switch err {
 	case nil:
 		// OK.
 	case r.Context().Err():
 		apierr.Context().Respond(w)
 		return
+	case db.ErrDuplicateBarcode:
+		apierr.Conflict("product", "barcode is used by another product").Respond(w)
+		return
+	case db.ErrNotPending:
+		apierr.Conflict("product", "product is not pending").Respond(w)
+		return
 	default:
-		s.log.WithError(err).Error("updating product")
+		s.log.WithError(err).Error("approving product")
 		apierr.Database().Respond(w)
 
 		return
 	}
 
+	s.indexProduct(*prd)
+	s.emitProduct(core.EventProductUpdated, *prd)
 	s.respondJSON(w, prd)
 }
 
-// DeleteProduct deletes existing product by its id.
-func (s *Server) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+// RejectProduct rejects a submitted product by its id. The reason of the
+// rejection must be provided.
+func (s *Server) RejectProduct(w http.ResponseWriter, r *http.Request) {
 	pid, aerr := s.extractPathID(r, "productID")
 	if aerr != nil {
 		aerr.Respond(w)
 		return
 	}
 
-	_, err := db.GetProductByID(r.Context(), s.db, pid)
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		apierr.MalformedDataInput(apierr.DataTypeRequestBody).Respond(w)
+		return
+	}
+
+	var inp struct {
+		Reason string `json:"reason"`
+	}
+
+	if err := json.Unmarshal(data, &inp); err != nil {
+		apierr.MalformedDataInput(apierr.DataTypeJSON).Respond(w)
+		return
+	}
+
+	if inp.Reason == "" {
+		apierr.InvalidAttribute("reason", "cannot be empty").Respond(w)
+		return
+	}
+
+	prd, aerr := s.checkProductSubmission(r, pid)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	err = db.RejectProductByID(r.Context(), s.db, pid, inp.Reason)
 	switch err {
 	case nil:
 		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	case db.ErrNotPending:
+		apierr.Conflict("product", "product is not pending").Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("rejecting product")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	prd.Status = core.ProductStatusRejected
+	prd.RejectionReason = inp.Reason
+
+	s.unindexProducts(pid)
+	s.emitProduct(core.EventProductDeleted, *prd)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkProductSubmission checks whether the product exists and is waiting
+// for a review. The product is returned if it is.
+func (s *Server) checkProductSubmission(r *http.Request, pid xid.ID) (*core.Product, *apierr.Error) {
+	prd, err := db.GetProductByID(r.Context(), s.db, s.extractViewer(r), pid)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		return nil, apierr.Context()
 	case db.ErrNotFound:
-		apierr.NotFound("product").Respond(w)
+		return nil, apierr.NotFound("product")
+	default:
+		s.log.WithError(err).Error("fetching product by id")
+		return nil, apierr.Database()
+	}
+
+	if prd.Status != core.ProductStatusPending {
+		return nil, apierr.Conflict("product", "product is not pending")
+	}
+
+	return prd, nil
+}
+
+// MergeProducts merges duplicate products into the product specified in
+// the path. References of the source products are moved to the target
+// product and the source products are deleted.
+func (s *Server) MergeProducts(w http.ResponseWriter, r *http.Request) {
+	tid, aerr := s.extractPathID(r, "productID")
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		apierr.MalformedDataInput(apierr.DataTypeRequestBody).Respond(w)
+		return
+	}
+
+	var inp struct {
+		SourceIDs []xid.ID `json:"source_ids"`
+	}
+
+	if err := json.Unmarshal(data, &inp); err != nil {
+		apierr.MalformedDataInput(apierr.DataTypeJSON).Respond(w)
+		return
+	}
+
+	sids := make([]xid.ID, 0, len(inp.SourceIDs))
+	seen := make(map[xid.ID]struct{}, len(inp.SourceIDs))
+
+	for _, sid := range inp.SourceIDs {
+		if sid == tid {
+			apierr.InvalidAttribute("source_ids", "cannot contain the target product").Respond(w)
+			return
+		}
+
+		if _, ok := seen[sid]; ok {
+			continue
+		}
+
+		seen[sid] = struct{}{}
+		sids = append(sids, sid)
+	}
+
+	if len(sids) == 0 {
+		apierr.InvalidAttribute("source_ids", "must contain at least one element").Respond(w)
 		return
+	}
+
+	sources, err := db.GetProductsByIDs(r.Context(), s.db, s.extractViewer(r), sids)
+	switch err {
+	case nil:
+		// OK.
 	case r.Context().Err():
 		apierr.Context().Respond(w)
 		return
 	default:
-		s.log.WithError(err).Error("getting products by product id")
+		s.log.WithError(err).Error("fetching merged products")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	report, err := db.MergeProducts(r.Context(), s.db, tid, sids)
+
+	var ferr *db.TooFewProductsError
+
+	switch {
+	case err == nil:
+		// OK.
+	case err == r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	case err == db.ErrNotFound:
+		apierr.NotFound("product").Respond(w)
+		return
+	case err == db.ErrServingMismatch:
+		apierr.Conflict("product", "serving type mismatch").Respond(w)
+		return
+	case errors.As(err, &ferr):
+		s.respondTooFewProducts(w, r, ferr)
+		return
+	default:
+		s.log.WithError(err).Error("merging products")
 		apierr.Database().Respond(w)
 
 		return
 	}
 
-	recipes, err := db.GetRecipeProductsByProductID(r.Context(), s.db, pid)
+	s.unindexProducts(sids...)
+
+	for _, prd := range sources {
+		s.emitProduct(core.EventProductDeleted, prd)
+	}
+
+	s.respondJSON(w, report)
+}
+
+// respondTooFewProducts refuses a merge of products that would leave the
+// recipes of the error with fewer than two products.
+func (s *Server) respondTooFewProducts(w http.ResponseWriter, r *http.Request, ferr *db.TooFewProductsError) {
+	deps := core.Dependents{
+		Recipes: ferr.Recipes,
+		Plans:   make([]core.Dependent, 0),
+	}
+
+	deps.Redact(s.extractViewer(r))
+	apierr.InUse("product", deps).Respond(w)
+}
+
+// DeleteProduct deletes existing product by its id. If the product is
+// used by recipes, the deletion is refused with the list of dependent
+// objects, unless a replacement product is provided with the replace_with
+// query parameter, in which case the references are reassigned to it.
+func (s *Server) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	pid, aerr := s.extractPathID(r, "productID")
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	rep, aerr := s.extractQueryID(r, "replace_with")
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	if rep == pid {
+		apierr.InvalidAttribute("replace_with", "must differ from the deleted product").Respond(w)
+		return
+	}
+
+	if rep.IsNil() {
+		if aerr := s.deleteProduct(r.Context(), s.extractViewer(r), pid); aerr != nil {
+			aerr.Respond(w)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+		return
+	}
+
+	prd, err := db.GetProductByID(r.Context(), s.db, s.extractViewer(r), pid)
 	switch err {
 	case nil:
 		// OK.
+	case db.ErrNotFound:
+		apierr.NotFound("product").Respond(w)
+		return
 	case r.Context().Err():
 		apierr.Context().Respond(w)
 		return
 	default:
-		s.log.WithError(err).Error("getting recipe products by product id")
+		s.log.WithError(err).Error("getting products by product id")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	_, err = db.MergeProducts(r.Context(), s.db, rep, []xid.ID{pid})
+
+	var ferr *db.TooFewProductsError
+
+	switch {
+	case err == nil:
+		// OK.
+	case err == r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	case err == db.ErrNotFound:
+		apierr.NotFound("product").Respond(w)
+		return
+	case err == db.ErrServingMismatch:
+		apierr.Conflict("product", "serving type mismatch").Respond(w)
+		return
+	case errors.As(err, &ferr):
+		s.respondTooFewProducts(w, r, ferr)
+		return
+	default:
+		s.log.WithError(err).Error("replacing product")
 		apierr.Database().Respond(w)
 
 		return
 	}
 
-	if len(recipes) > 0 {
-		apierr.Conflict("product in use").Respond(w)
+	s.unindexProducts(pid)
+	s.emitProduct(core.EventProductDeleted, *prd)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteProduct moves a product to the trash, unless it is used by
+// recipes.
+func (s *Server) deleteProduct(ctx context.Context, vw core.Viewer, pid xid.ID) *apierr.Error {
+	prd, err := db.GetProductByID(ctx, s.db, vw, pid)
+	switch err {
+	case nil:
+		// OK.
+	case db.ErrNotFound:
+		return apierr.NotFound("product")
+	case ctx.Err():
+		return apierr.Context()
+	default:
+		s.log.WithError(err).Error("getting products by product id")
+		return apierr.Database()
+	}
+
+	deps, err := db.GetProductDependents(ctx, s.db, pid)
+	switch err {
+	case nil:
+		// OK.
+	case ctx.Err():
+		return apierr.Context()
+	default:
+		s.log.WithError(err).Error("getting product dependents")
+		return apierr.Database()
+	}
+
+	if !deps.Empty() {
+		deps.Redact(vw)
+		return apierr.InUse("product", deps)
+	}
+
+	err = db.DeleteProductByID(ctx, s.db, pid)
+	switch err {
+	case nil:
+		// OK.
+	case ctx.Err():
+		return apierr.Context()
+	default:
+		s.log.WithError(err).Error("deleting product by id")
+		return apierr.Database()
+	}
+
+	s.unindexProducts(pid)
+	s.emitProduct(core.EventProductDeleted, *prd)
+
+	return nil
+}
+
+// RestoreProduct restores a product from the trash by its id.
+func (s *Server) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	pid, aerr := s.extractPathID(r, "productID")
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	prd, err := db.GetDeletedProductByID(r.Context(), s.db, pid)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	case db.ErrNotFound:
+		apierr.NotFound("product").Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("fetching deleted product by id")
+		apierr.Database().Respond(w)
+
 		return
 	}
 
-	err = db.DeleteProductByID(r.Context(), s.db, pid)
+	err = db.RestoreProductByID(r.Context(), s.db, pid)
 	switch err {
 	case nil:
 		// OK.
@@ -181,11 +675,15 @@ func (s *Server) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		apierr.Context().Respond(w)
 		return
 	default:
-		s.log.WithError(err).Error("deleting product by id")
+		s.log.WithError(err).Error("restoring product by id")
 		apierr.Database().Respond(w)
 
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	prd.DeletedAt = nil
+
+	s.indexProduct(*prd)
+	s.emitProduct(core.EventProductUpdated, *prd)
+	s.respondJSON(w, prd)
 }
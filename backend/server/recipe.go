@@ -8,6 +8,8 @@ import (
 	"foodie/server/apierr"
 	"io"
 	"net/http"
+
+	"github.com/rs/xid"
 )
 
 // CreateRecipe creates a recipe.
@@ -24,40 +26,64 @@ func (s *Server) CreateRecipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var rc core.RecipeCore
+	rc := core.RecipeCore{
+		Visibility: core.VisibilityPublic,
+		State:      core.StatePublished,
+	}
+
 	if err := json.Unmarshal(data, &rc); err != nil {
 		apierr.MalformedDataInput(apierr.DataTypeJSON).Respond(w)
 		return
 	}
 
-	if aerr := s.validateRecipeCore(r.Context(), rc); aerr != nil {
+	rec, aerr := s.createRecipe(r.Context(), uid, rc)
+	if aerr != nil {
 		aerr.Respond(w)
 		return
 	}
 
-	rec, err := db.InsertRecipe(r.Context(), s.db, uid, rc)
+	s.respondJSON(w, rec)
+}
+
+// createRecipe validates the recipe core and creates a recipe of the user.
+func (s *Server) createRecipe(ctx context.Context, uid xid.ID, rc core.RecipeCore) (*core.Recipe, *apierr.Error) {
+	if aerr := s.validateRecipeCore(ctx, uid, rc); aerr != nil {
+		return nil, aerr
+	}
+
+	rec, err := db.InsertRecipe(ctx, s.db, uid, rc)
 	switch err {
 	case nil:
 		// OK.
-	case r.Context().Err():
-		apierr.Context().Respond(w)
-		return
+	case ctx.Err():
+		return nil, apierr.Context()
 	case db.ErrNotFound:
-		apierr.NotFound("product").Respond(w)
-		return
+		return nil, apierr.NotFound("product")
 	default:
 		s.log.WithError(err).Error("inserting a recipe")
-		apierr.Database().Respond(w)
-
-		return
+		return nil, apierr.Database()
 	}
 
-	s.respondJSON(w, rec)
+	s.emitRecipe(core.EventRecipeCreated, *rec)
+
+	return rec, nil
 }
 
-// GetRecipes retrieves all recipes.
+// GetRecipes retrieves a page of recipes.
 func (s *Server) GetRecipes(w http.ResponseWriter, r *http.Request) {
-	rr, err := db.GetRecipes(r.Context(), s.db)
+	pg, aerr := s.extractPage(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	q, aerr := core.ParseQuery(r.URL.Query(), core.RecipeFields)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	rr, next, err := db.GetRecipes(r.Context(), s.db, s.extractViewer(r), q, pg)
 	switch err {
 	case nil:
 		// OK.
@@ -71,10 +97,10 @@ func (s *Server) GetRecipes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.respondJSON(w, rr)
+	s.respondPage(w, rr, next)
 }
 
-// GetUserRecipes retrieves user recipes.
+// GetUserRecipes retrieves a page of user recipes.
 func (s *Server) GetUserRecipes(w http.ResponseWriter, r *http.Request) {
 	uid, aerr := s.extractPathID(r, "userID")
 	if aerr != nil {
@@ -82,7 +108,19 @@ func (s *Server) GetUserRecipes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rr, err := db.GetRecipesByUserID(r.Context(), s.db, uid)
+	pg, aerr := s.extractPage(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	q, aerr := core.ParseQuery(r.URL.Query(), core.RecipeFields)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	rr, next, err := db.GetRecipesByUserID(r.Context(), s.db, s.extractViewer(r), uid, q, pg)
 	switch err {
 	case nil:
 		// OK.
@@ -96,7 +134,7 @@ func (s *Server) GetUserRecipes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.respondJSON(w, rr)
+	s.respondPage(w, rr, next)
 }
 
 // GetRecipe retrieves a single recipe by its id.
@@ -107,7 +145,7 @@ func (s *Server) GetRecipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rec, err := db.GetRecipeByID(r.Context(), s.db, rid)
+	rec, err := db.GetRecipeByID(r.Context(), s.db, s.extractViewer(r), rid)
 	switch err {
 	case nil:
 		// OK.
@@ -124,11 +162,16 @@ func (s *Server) GetRecipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.checkPreconditions(w, r, "recipe", rec.Revision) {
+		return
+	}
+
 	s.respondJSON(w, rec)
 }
 
-// UpdateRecipe updates existing recipe by its id. The recipe can be
-// updated only by the user which created it.
+// UpdateRecipe updates existing recipe by its id. PUT requests replace the
+// recipe, while PATCH requests change only the provided attributes. The
+// recipe can be updated only by the user which created it.
 func (s *Server) UpdateRecipe(w http.ResponseWriter, r *http.Request) {
 	rid, aerr := s.extractPathID(r, "recipeID")
 	if aerr != nil {
@@ -148,7 +191,7 @@ func (s *Server) UpdateRecipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rec, err := db.GetRecipeByID(r.Context(), s.db, rid)
+	rec, err := db.GetRecipeByID(r.Context(), s.db, s.extractViewer(r), rid)
 	switch err {
 	case nil:
 		// OK.
@@ -170,18 +213,89 @@ func (s *Server) UpdateRecipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var rc core.RecipeCore
-	if err := json.Unmarshal(data, &rc); err != nil {
-		apierr.MalformedDataInput(apierr.DataTypeJSON).Respond(w)
+	if !s.checkPreconditions(w, r, "recipe", rec.Revision) {
 		return
 	}
 
-	if aerr := s.validateRecipeCore(r.Context(), rc); aerr != nil {
+	rc, aerr := decodeCore(r, data, rec.RecipeCore)
+	if aerr != nil {
 		aerr.Respond(w)
 		return
 	}
 
-	rec, err = db.UpdateRecipeByID(r.Context(), s.db, rid, rc)
+	rec, aerr = s.updateRecipe(r.Context(), uid, rec, rc)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	s.setRevision(w, rec.Revision)
+	s.respondJSON(w, rec)
+}
+
+// updateRecipe validates the recipe core and replaces the core of the
+// recipe of the user with it, unless the recipe was modified since it was
+// fetched.
+func (s *Server) updateRecipe(
+	ctx context.Context,
+	uid xid.ID,
+	rec *core.Recipe,
+	rc core.RecipeCore,
+) (*core.Recipe, *apierr.Error) {
+	if aerr := s.validateRecipeCore(ctx, uid, rc); aerr != nil {
+		return nil, aerr
+	}
+
+	rec, err := db.UpdateRecipeByID(ctx, s.db, rec.ID, rec.Version, rec.RecipeCore, rc)
+	switch err {
+	case nil:
+		// OK.
+	case ctx.Err():
+		return nil, apierr.Context()
+	case db.ErrVersionMismatch:
+		return nil, apierr.PreconditionFailed("recipe")
+	case db.ErrNotFound:
+		return nil, apierr.NotFound("product")
+	default:
+		s.log.WithError(err).Error("updating recipe")
+		return nil, apierr.Database()
+	}
+
+	s.emitRecipe(core.EventRecipeUpdated, *rec)
+
+	return rec, nil
+}
+
+// UpdateRecipeProduct adds a product to the recipe (POST), changes its
+// quantity (PATCH) or removes it from the recipe (DELETE). The recipe can
+// be updated only by the user which created it and must keep at least two
+// products.
+func (s *Server) UpdateRecipeProduct(w http.ResponseWriter, r *http.Request) {
+	rid, aerr := s.extractPathID(r, "recipeID")
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	pid, aerr := s.extractPathID(r, "productID")
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	uid, aerr := s.extractContextUserID(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		apierr.MalformedDataInput(apierr.DataTypeRequestBody).Respond(w)
+		return
+	}
+
+	rec, err := db.GetRecipeByID(r.Context(), s.db, s.extractViewer(r), rid)
 	switch err {
 	case nil:
 		// OK.
@@ -189,20 +303,113 @@ func (s *Server) UpdateRecipe(w http.ResponseWriter, r *http.Request) {
 		apierr.Context().Respond(w)
 		return
 	case db.ErrNotFound:
+		apierr.NotFound("recipe").Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("fetching recipe by id")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	if rec.UserID.Compare(uid) != 0 {
+		apierr.Forbidden().Respond(w)
+		return
+	}
+
+	if !s.checkPreconditions(w, r, "recipe", rec.Revision) {
+		return
+	}
+
+	idx := -1
+
+	for i, cur := range rec.Products {
+		if cur.ProductID == pid {
+			idx = i
+			break
+		}
+	}
+
+	if r.Method == http.MethodPost && idx >= 0 {
+		apierr.Conflict("product", "product is already in the recipe").Respond(w)
+		return
+	}
+
+	if r.Method != http.MethodPost && idx < 0 {
 		apierr.NotFound("product").Respond(w)
 		return
+	}
+
+	var rp core.RecipeProduct
+
+	if r.Method != http.MethodDelete {
+		var cur core.RecipeProduct
+		if idx >= 0 {
+			cur = rec.Products[idx]
+		}
+
+		if rp, aerr = decodeCore(r, data, cur); aerr != nil {
+			aerr.Respond(w)
+			return
+		}
+	}
+
+	rp.RecipeID = rid
+	rp.ProductID = pid
+
+	rc := rec.RecipeCore
+	rc.Products = make([]core.RecipeProduct, 0, len(rec.Products)+1)
+
+	for i, cur := range rec.Products {
+		switch {
+		case i != idx:
+			rc.Products = append(rc.Products, cur)
+		case r.Method == http.MethodPatch:
+			rc.Products = append(rc.Products, rp)
+		}
+	}
+
+	if r.Method == http.MethodPost {
+		rc.Products = append(rc.Products, rp)
+	}
+
+	if aerr := s.validateRecipeCore(r.Context(), uid, rc); aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		rec, err = db.DeleteRecipeProduct(r.Context(), s.db, rec.Version, rid, pid)
+	} else {
+		rec, err = db.UpsertRecipeProduct(r.Context(), s.db, rec.Version, rp)
+	}
+
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	case db.ErrVersionMismatch:
+		apierr.PreconditionFailed("recipe").Respond(w)
+		return
 	default:
-		s.log.WithError(err).Error("creating a new recipe")
+		s.log.WithError(err).Error("updating recipe product")
 		apierr.Database().Respond(w)
 
 		return
 	}
 
+	s.emitRecipe(core.EventRecipeUpdated, *rec)
+	s.setRevision(w, rec.Revision)
 	s.respondJSON(w, rec)
 }
 
 // DeleteRecipe deletes existing recipe by its id. The recipe can be deleted
-// only by an admin or the user that created it.
+// only by an admin or the user that created it. If the recipe is included
+// in plans, the deletion is refused with the list of dependent plans,
+// unless a replacement recipe is provided with the replace_with query
+// parameter, in which case the references are reassigned to it.
 func (s *Server) DeleteRecipe(w http.ResponseWriter, r *http.Request) {
 	rid, aerr := s.extractPathID(r, "recipeID")
 	if aerr != nil {
@@ -210,43 +417,157 @@ func (s *Server) DeleteRecipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	adm, aerr := s.extractContextAdmin(r)
+	rep, aerr := s.extractQueryID(r, "replace_with")
 	if aerr != nil {
 		aerr.Respond(w)
 		return
 	}
 
-	if !adm {
-		uid, aerr := s.extractContextUserID(r)
-		if aerr != nil {
+	if rep == rid {
+		apierr.InvalidAttribute("replace_with", "must differ from the deleted recipe").Respond(w)
+		return
+	}
+
+	vw := s.extractViewer(r)
+
+	if rep.IsNil() {
+		if aerr := s.deleteRecipe(r.Context(), vw, rid); aerr != nil {
 			aerr.Respond(w)
 			return
 		}
 
-		rec, err := db.GetRecipeByID(r.Context(), s.db, rid)
-		switch err {
-		case nil:
-			// OK.
-		case r.Context().Err():
-			apierr.Context().Respond(w)
-			return
-		case db.ErrNotFound:
-			apierr.NotFound("recipe").Respond(w)
-			return
-		default:
-			s.log.WithError(err).Error("fetching recipe by id")
-			apierr.Database().Respond(w)
+		w.WriteHeader(http.StatusNoContent)
 
-			return
-		}
+		return
+	}
+
+	rec, aerr := s.checkRecipeOwner(r.Context(), vw, rid)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	deps, err := db.GetRecipeDependents(r.Context(), s.db, rid)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("getting recipe dependents")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	s.replaceRecipe(w, r, rec, rep, deps)
+}
+
+// checkRecipeOwner checks whether the viewer is allowed to modify the
+// recipe, i.e. is an admin or the user that created it. The recipe is
+// returned if the viewer is allowed to modify it.
+func (s *Server) checkRecipeOwner(ctx context.Context, vw core.Viewer, rid xid.ID) (*core.Recipe, *apierr.Error) {
+	rec, err := db.GetRecipeByID(ctx, s.db, vw, rid)
+	switch err {
+	case nil:
+		// OK.
+	case ctx.Err():
+		return nil, apierr.Context()
+	case db.ErrNotFound:
+		return nil, apierr.NotFound("recipe")
+	default:
+		s.log.WithError(err).Error("fetching recipe by id")
+		return nil, apierr.Database()
+	}
+
+	if !vw.Admin && rec.UserID.Compare(vw.UserID) != 0 {
+		return nil, apierr.Forbidden()
+	}
+
+	return rec, nil
+}
+
+// deleteRecipe moves a recipe to the trash. The recipe can be deleted only
+// by an admin or the user that created it, and only if it is not included
+// in plans.
+func (s *Server) deleteRecipe(ctx context.Context, vw core.Viewer, rid xid.ID) *apierr.Error {
+	rec, aerr := s.checkRecipeOwner(ctx, vw, rid)
+	if aerr != nil {
+		return aerr
+	}
+
+	deps, err := db.GetRecipeDependents(ctx, s.db, rid)
+	switch err {
+	case nil:
+		// OK.
+	case ctx.Err():
+		return apierr.Context()
+	default:
+		s.log.WithError(err).Error("getting recipe dependents")
+		return apierr.Database()
+	}
+
+	if !deps.Empty() {
+		deps.Redact(vw)
+		return apierr.InUse("recipe", deps)
+	}
+
+	err = db.DeleteRecipeByID(ctx, s.db, rid)
+	switch err {
+	case nil:
+		// OK.
+	case ctx.Err():
+		return apierr.Context()
+	default:
+		s.log.WithError(err).Error("deleting recipe by id")
+		return apierr.Database()
+	}
+
+	s.emitRecipe(core.EventRecipeDeleted, *rec)
+
+	return nil
+}
+
+// replaceRecipe moves a recipe to the trash after reassigning the
+// dependent plans to the replacement recipe. The replacement must be
+// visible to the requester and to the owners of all dependent plans.
+func (s *Server) replaceRecipe(
+	w http.ResponseWriter,
+	r *http.Request,
+	rec *core.Recipe,
+	repID xid.ID,
+	deps *core.Dependents,
+) {
+	rep, err := db.GetRecipeByID(r.Context(), s.db, s.extractViewer(r), repID)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	case db.ErrNotFound:
+		apierr.InvalidAttribute("replace_with", "must be an existing recipe").Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("fetching recipe by id")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	for _, pln := range deps.Plans {
+		if !(core.Viewer{UserID: pln.UserID}).CanSee(rep.UserID, rep.Visibility, rep.State) {
+			apierr.InvalidAttribute(
+				"replace_with",
+				"must be visible to the owners of dependent plans",
+			).Respond(w)
 
-		if rec.UserID.Compare(uid) != 0 {
-			apierr.Forbidden().Respond(w)
 			return
 		}
 	}
 
-	plans, err := db.GetPlanRecipesByRecipeID(r.Context(), s.db, rid)
+	err = db.ReplaceRecipeByID(r.Context(), s.db, rec.ID, repID)
 	switch err {
 	case nil:
 		// OK.
@@ -254,37 +575,77 @@ func (s *Server) DeleteRecipe(w http.ResponseWriter, r *http.Request) {
 		apierr.Context().Respond(w)
 		return
 	default:
-		s.log.WithError(err).Error("getting plan recipes by recipe id")
+		s.log.WithError(err).Error("replacing recipe")
 		apierr.Database().Respond(w)
 
 		return
 	}
 
-	if len(plans) > 0 {
-		apierr.Conflict("recipe in use").Respond(w)
+	s.emitRecipe(core.EventRecipeDeleted, *rec)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreRecipe restores a recipe from the trash by its id. The recipe can
+// be restored only by an admin or the user that created it.
+func (s *Server) RestoreRecipe(w http.ResponseWriter, r *http.Request) {
+	rid, aerr := s.extractPathID(r, "recipeID")
+	if aerr != nil {
+		aerr.Respond(w)
 		return
 	}
 
-	err = db.DeleteRecipeByID(r.Context(), s.db, rid)
+	rec, err := db.GetDeletedRecipeByID(r.Context(), s.db, rid)
 	switch err {
 	case nil:
 		// OK.
 	case r.Context().Err():
 		apierr.Context().Respond(w)
 		return
+	case db.ErrNotFound:
+		apierr.NotFound("recipe").Respond(w)
+		return
 	default:
-		s.log.WithError(err).Error("deleting recipe by id")
+		s.log.WithError(err).Error("fetching deleted recipe by id")
 		apierr.Database().Respond(w)
 
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	vw := s.extractViewer(r)
+	if !vw.Admin && rec.UserID.Compare(vw.UserID) != 0 {
+		apierr.Forbidden().Respond(w)
+		return
+	}
+
+	err = db.RestoreRecipeByID(r.Context(), s.db, rid)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("restoring recipe by id")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	rec.DeletedAt = nil
+
+	s.emitRecipe(core.EventRecipeUpdated, *rec)
+	s.respondJSON(w, rec)
 }
 
-// validateRecipeCore validates recipe core attributes.
-func (s *Server) validateRecipeCore(ctx context.Context, rc core.RecipeCore) *apierr.Error {
-	pp, err := db.GetProducts(ctx, s.db)
+// validateRecipeCore validates recipe core attributes. The recipe can use
+// only approved products and pending products submitted by its owner.
+func (s *Server) validateRecipeCore(ctx context.Context, uid xid.ID, rc core.RecipeCore) *apierr.Error {
+	pids := make([]xid.ID, 0, len(rc.Products))
+	for _, rp := range rc.Products {
+		pids = append(pids, rp.ProductID)
+	}
+
+	pp, err := db.GetProductsByIDs(ctx, s.db, core.Viewer{UserID: uid}, pids)
 	switch err {
 	case nil:
 		// OK.
@@ -296,7 +657,8 @@ func (s *Server) validateRecipeCore(ctx context.Context, rc core.RecipeCore) *ap
 	}
 
 	for _, rp := range rc.Products {
-		if _, ok := rp.FindMatching(pp); !ok {
+		prd, ok := rp.FindMatching(pp)
+		if !ok || !prd.UsableBy(uid) {
 			return apierr.NotFound("product")
 		}
 	}
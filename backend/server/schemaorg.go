@@ -0,0 +1,364 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"foodie/core"
+	"foodie/db"
+	"foodie/server/apierr"
+	"foodie/server/schemaorg"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
+)
+
+// _maxUploadSize specifies the maximum size of uploaded recipe documents.
+const _maxUploadSize = 5 << 20
+
+// GetRecipeJSONLD retrieves a single recipe by its id as a schema.org
+// Recipe JSON-LD document.
+func (s *Server) GetRecipeJSONLD(w http.ResponseWriter, r *http.Request) {
+	rec, pp, ok := s.fetchRecipeWithProducts(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := json.Marshal(schemaorg.NewRecipe(rec, pp))
+	if err != nil {
+		s.log.WithError(err).Error("marshaling recipe json-ld")
+		apierr.Internal().Respond(w)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", schemaorg.ContentType)
+
+	if _, err := w.Write(data); err != nil {
+		s.log.WithError(err).Error("writing to client response data")
+	}
+}
+
+// ImportRecipeJSONLD creates a recipe from a schema.org Recipe JSON-LD
+// document or an HTML page containing it, which is provided either as
+// the request body or as the file field of a multipart form. Ingredients
+// are matched to products by their names, and the ones that could not be
+// matched are reported. The recipe is created as a private draft, so that
+// it could be reviewed. If the dry_run query parameter is true, the recipe
+// is not saved.
+func (s *Server) ImportRecipeJSONLD(w http.ResponseWriter, r *http.Request) {
+	uid, aerr := s.extractContextUserID(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	dry, aerr := extractDryRun(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	doc, aerr := extractDocument(w, r, map[string]string{
+		".html":   "text/html",
+		".htm":    "text/html",
+		".json":   schemaorg.ContentType,
+		".jsonld": schemaorg.ContentType,
+	})
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	var (
+		sr  *schemaorg.Recipe
+		err error
+	)
+
+	switch doc.MediaType {
+	case "text/html", "application/xhtml+xml":
+		sr, err = schemaorg.ParseHTML(bytes.NewReader(doc.Data))
+	case schemaorg.ContentType, "application/json":
+		sr, err = schemaorg.Parse(doc.Data)
+	default:
+		apierr.BadRequest("content type must be application/ld+json or text/html").Respond(w)
+		return
+	}
+
+	switch {
+	case err == nil:
+		// OK.
+	case errors.Is(err, schemaorg.ErrNoRecipe):
+		apierr.BadRequest(err.Error()).Respond(w)
+		return
+	default:
+		apierr.MalformedDataInput(apierr.DataTypeJSON).Respond(w)
+		return
+	}
+
+	res, aerr := s.importRecipe(r.Context(), uid, sr.Core(), sr.Ingredients(), dry)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	s.respondJSON(w, res)
+}
+
+// fetchRecipeWithProducts retrieves the recipe by the id in the path
+// along with its products, both as seen by the viewer of the request.
+// Errors are responded to, in which case false is returned.
+func (s *Server) fetchRecipeWithProducts(w http.ResponseWriter, r *http.Request) (core.Recipe, []core.Product, bool) {
+	rid, aerr := s.extractPathID(r, "recipeID")
+	if aerr != nil {
+		aerr.Respond(w)
+		return core.Recipe{}, nil, false
+	}
+
+	vw := s.extractViewer(r)
+
+	rec, err := db.GetRecipeByID(r.Context(), s.db, vw, rid)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return core.Recipe{}, nil, false
+	case db.ErrNotFound:
+		apierr.NotFound("recipe").Respond(w)
+		return core.Recipe{}, nil, false
+	default:
+		s.log.WithError(err).Error("fetching recipe by id")
+		apierr.Database().Respond(w)
+
+		return core.Recipe{}, nil, false
+	}
+
+	if !s.checkPreconditions(w, r, "recipe", rec.Revision) {
+		return core.Recipe{}, nil, false
+	}
+
+	pids := make([]xid.ID, 0, len(rec.Products))
+	for _, rp := range rec.Products {
+		pids = append(pids, rp.ProductID)
+	}
+
+	pp, err := db.GetProductsByIDs(r.Context(), s.db, vw, pids)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return core.Recipe{}, nil, false
+	default:
+		s.log.WithError(err).Error("fetching products")
+		apierr.Database().Respond(w)
+
+		return core.Recipe{}, nil, false
+	}
+
+	return *rec, pp, true
+}
+
+// importRecipe matches the ingredients to products, adds them to the
+// recipe core and saves the recipe, unless the import is a dry run.
+// Ingredients that match the same product are merged.
+func (s *Server) importRecipe(
+	ctx context.Context,
+	uid xid.ID,
+	rc core.RecipeCore,
+	ii []core.Ingredient,
+	dry bool,
+) (*core.RecipeImport, *apierr.Error) {
+	res := &core.RecipeImport{
+		Unmatched: make([]core.UnmatchedIngredient, 0),
+	}
+
+	rc.Products = make([]core.RecipeProduct, 0, len(ii))
+	index := make(map[xid.ID]int, len(ii))
+
+	for _, ing := range ii {
+		rp, reason := s.matchIngredient(uid, ing)
+		if reason != "" {
+			res.Unmatched = append(res.Unmatched, core.UnmatchedIngredient{
+				Ingredient: ing,
+				Reason:     reason,
+			})
+
+			continue
+		}
+
+		if i, ok := index[rp.ProductID]; ok {
+			rc.Products[i].Quantity = rc.Products[i].Quantity.Add(rp.Quantity)
+			continue
+		}
+
+		index[rp.ProductID] = len(rc.Products)
+		rc.Products = append(rc.Products, rp)
+	}
+
+	if aerr := s.validateRecipeCore(ctx, uid, rc); aerr != nil {
+		return nil, aerr
+	}
+
+	if dry {
+		res.Recipe = core.Recipe{RecipeCore: rc, UserID: uid}
+		return res, nil
+	}
+
+	rec, err := db.InsertRecipe(ctx, s.db, uid, rc)
+	switch err {
+	case nil:
+		// OK.
+	case ctx.Err():
+		return nil, apierr.Context()
+	case db.ErrNotFound:
+		return nil, apierr.NotFound("product")
+	default:
+		s.log.WithError(err).Error("inserting an imported recipe")
+		return nil, apierr.Database()
+	}
+
+	s.emitRecipe(core.EventRecipeCreated, *rec)
+	res.Recipe = *rec
+
+	return res, nil
+}
+
+// matchIngredient matches the ingredient to the best suggested product
+// that can be used by the user and converts its amount to the quantity of
+// the product servings. The ingredient name is replaced by its alias, if
+// one is configured. A single serving is used if the amount is not
+// provided. If the ingredient cannot be matched, the reason is returned.
+func (s *Server) matchIngredient(uid xid.ID, ing core.Ingredient) (core.RecipeProduct, string) {
+	name := ing.Name
+	if alias, ok := s.aliases[normalizeIngredient(name)]; ok {
+		name = alias
+	}
+
+	pp := s.products.Suggest(name, 1, func(prd core.Product) bool {
+		return prd.UsableBy(uid)
+	})
+
+	if len(pp) == 0 {
+		return core.RecipeProduct{}, "no matching product"
+	}
+
+	if !ing.Amount.IsPositive() {
+		return core.RecipeProduct{ProductID: pp[0].ID, Quantity: decimal.NewFromInt(1)}, ""
+	}
+
+	rp, ok := core.NewRecipeProduct(pp[0], ing.Amount, ing.Unit)
+	if !ok || !rp.Quantity.IsPositive() {
+		return core.RecipeProduct{}, "unit cannot be converted"
+	}
+
+	return rp, ""
+}
+
+// normalizeIngredient normalizes the ingredient name, so that it could be
+// looked up in the aliases.
+func normalizeIngredient(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// extractDryRun extracts the dry_run query parameter, which is false by
+// default.
+func extractDryRun(r *http.Request) (bool, *apierr.Error) {
+	v := r.URL.Query().Get("dry_run")
+	if v == "" {
+		return false, nil
+	}
+
+	dry, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, apierr.InvalidAttribute("dry_run", "must be a boolean")
+	}
+
+	return dry, nil
+}
+
+// document is a document provided in the request.
+type document struct {
+	// Data contains the document.
+	Data []byte
+
+	// MediaType specifies the media type of the document.
+	MediaType string
+
+	// Name specifies the name of the uploaded file without its
+	// extension. It is empty if the document is the request body.
+	Name string
+}
+
+// extractDocument reads the document provided either as the request body
+// or as the file field of a multipart form. The media type of uploaded
+// files is determined by their extension, if it is one of the provided
+// ones, or by their content type. Request bodies larger than the maximum
+// upload size are refused.
+func extractDocument(w http.ResponseWriter, r *http.Request, exts map[string]string) (*document, *apierr.Error) {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, apierr.BadRequest("content type is invalid")
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, _maxUploadSize)
+
+	if mt != "multipart/form-data" {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, uploadError(err)
+		}
+
+		return &document{Data: data, MediaType: mt}, nil
+	}
+
+	if err := r.ParseMultipartForm(_maxUploadSize); err != nil {
+		return nil, uploadError(err)
+	}
+
+	f, fh, err := r.FormFile("file")
+	if err != nil {
+		return nil, apierr.InvalidAttribute("file", "must be provided")
+	}
+
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, apierr.MalformedDataInput(apierr.DataTypeRequestBody)
+	}
+
+	ext := path.Ext(fh.Filename)
+	doc := &document{
+		Data: data,
+		Name: strings.TrimSuffix(path.Base(fh.Filename), ext),
+	}
+
+	if mt, ok := exts[strings.ToLower(ext)]; ok {
+		doc.MediaType = mt
+		return doc, nil
+	}
+
+	doc.MediaType, _, _ = mime.ParseMediaType(fh.Header.Get("Content-Type"))
+
+	return doc, nil
+}
+
+// uploadError creates an error of a request body that could not be read.
+func uploadError(err error) *apierr.Error {
+	var merr *http.MaxBytesError
+	if errors.As(err, &merr) {
+		return apierr.BadRequest(fmt.Sprintf("document must not exceed %d bytes", merr.Limit))
+	}
+
+	return apierr.MalformedDataInput(apierr.DataTypeRequestBody)
+}
@@ -0,0 +1,485 @@
+// Package schemaorg converts recipes to and from schema.org Recipe JSON-LD
+// documents, which are used by other recipe tools and search engines.
+package schemaorg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"foodie/core"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/net/html"
+)
+
+// ContentType specifies the media type of JSON-LD documents.
+const ContentType = "application/ld+json"
+
+// ErrNoRecipe is returned whenever a document does not contain a
+// schema.org Recipe.
+var ErrNoRecipe = errors.New("document does not contain a schema.org Recipe")
+
+// Recipe is a schema.org Recipe.
+type Recipe struct {
+	// Context specifies the JSON-LD vocabulary.
+	Context string `json:"@context,omitempty"`
+
+	// Type specifies the schema.org type.
+	Type string `json:"@type"`
+
+	// Identifier specifies the id of the recipe.
+	Identifier string `json:"identifier,omitempty"`
+
+	// Name specifies the name of the recipe.
+	Name string `json:"name"`
+
+	// Description provides a brief description of the recipe.
+	Description string `json:"description,omitempty"`
+
+	// Image specifies the image url of the recipe.
+	Image string `json:"image,omitempty"`
+
+	// DatePublished specifies the date at which the recipe was created.
+	DatePublished string `json:"datePublished,omitempty"`
+
+	// DateModified specifies the time at which the recipe was last
+	// modified.
+	DateModified string `json:"dateModified,omitempty"`
+
+	// RecipeIngredient contains the ingredients along with their amounts.
+	RecipeIngredient []string `json:"recipeIngredient"`
+
+	// Nutrition specifies the nutrition information of the recipe.
+	Nutrition *Nutrition `json:"nutrition,omitempty"`
+}
+
+// Nutrition is a schema.org NutritionInformation.
+type Nutrition struct {
+	// Type specifies the schema.org type.
+	Type string `json:"@type"`
+
+	// Calories specifies the calories of the whole recipe, e.g.
+	// "250 calories".
+	Calories string `json:"calories,omitempty"`
+}
+
+// NewRecipe creates a schema.org Recipe of the recipe. Ingredients are
+// rendered from the recipe products and their amounts, while the calories
+// of all products are summed. Products that are not provided are left
+// out.
+func NewRecipe(rec core.Recipe, pp []core.Product) Recipe {
+	sr := Recipe{
+		Context:          "https://schema.org",
+		Type:             "Recipe",
+		Identifier:       rec.ID.String(),
+		Name:             rec.Name,
+		Description:      rec.Description,
+		Image:            rec.ImageURL,
+		DatePublished:    rec.CreatedAt.UTC().Format("2006-01-02"),
+		RecipeIngredient: make([]string, 0, len(rec.Products)),
+	}
+
+	if !rec.UpdatedAt.IsZero() {
+		sr.DateModified = rec.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+
+	cal := decimal.Zero
+
+	for _, rp := range rec.Products {
+		prd, ok := rp.FindMatching(pp)
+		if !ok {
+			continue
+		}
+
+		sr.RecipeIngredient = append(
+			sr.RecipeIngredient,
+			FormatIngredient(rp.Amount(prd), prd.Serving.Type.Unit(), prd.Name),
+		)
+		cal = cal.Add(rp.Calories(prd))
+	}
+
+	sr.Nutrition = &Nutrition{
+		Type:     "NutritionInformation",
+		Calories: cal.Round(0).String() + " calories",
+	}
+
+	return sr
+}
+
+// FormatIngredient formats the ingredient as a single line, e.g.
+// "250 g Chicken Breast".
+func FormatIngredient(amount decimal.Decimal, unit, name string) string {
+	parts := []string{amount.String()}
+	if unit != "" {
+		parts = append(parts, unit)
+	}
+
+	return strings.Join(append(parts, name), " ")
+}
+
+// Core returns the recipe core of the schema.org Recipe. The recipe has
+// no products, since they have to be matched by its ingredients. The name
+// is used as the description if there is none, and the recipe is a
+// private draft.
+func (sr *Recipe) Core() core.RecipeCore {
+	rc := core.RecipeCore{
+		Name:        strings.TrimSpace(sr.Name),
+		ImageURL:    strings.TrimSpace(sr.Image),
+		Description: strings.TrimSpace(sr.Description),
+		Visibility:  core.VisibilityPrivate,
+		State:       core.StateDraft,
+	}
+
+	if rc.Description == "" {
+		rc.Description = rc.Name
+	}
+
+	return rc
+}
+
+// Ingredients parses the ingredients of the schema.org Recipe.
+func (sr *Recipe) Ingredients() []core.Ingredient {
+	ii := make([]core.Ingredient, 0, len(sr.RecipeIngredient))
+
+	for _, text := range sr.RecipeIngredient {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		ii = append(ii, ParseIngredient(text))
+	}
+
+	return ii
+}
+
+// Parse finds the first schema.org Recipe in the JSON-LD document. The
+// recipe can be the document itself, an element of an array or of the
+// @graph, or nested in other objects.
+func Parse(data []byte) (*Recipe, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	node := findRecipe(doc)
+	if node == nil {
+		return nil, ErrNoRecipe
+	}
+
+	sr := &Recipe{
+		Context:          "https://schema.org",
+		Type:             "Recipe",
+		Identifier:       text(node["identifier"]),
+		Name:             text(node["name"]),
+		Description:      text(node["description"]),
+		Image:            url(node["image"]),
+		DatePublished:    text(node["datePublished"]),
+		DateModified:     text(node["dateModified"]),
+		RecipeIngredient: texts(node["recipeIngredient"]),
+	}
+
+	if len(sr.RecipeIngredient) == 0 {
+		// Older documents use the deprecated ingredients property.
+		sr.RecipeIngredient = texts(node["ingredients"])
+	}
+
+	if nut, ok := node["nutrition"].(map[string]interface{}); ok {
+		sr.Nutrition = &Nutrition{
+			Type:     "NutritionInformation",
+			Calories: text(nut["calories"]),
+		}
+	}
+
+	return sr, nil
+}
+
+// ParseHTML finds the first schema.org Recipe in the JSON-LD scripts of
+// the HTML document.
+func ParseHTML(r io.Reader) (*Recipe, error) {
+	z := html.NewTokenizer(r)
+
+	var inScript bool
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return nil, ErrNoRecipe
+			}
+
+			return nil, z.Err()
+		case html.StartTagToken:
+			name, hasAttr := z.TagName()
+			inScript = false
+
+			if string(name) != "script" {
+				continue
+			}
+
+			for hasAttr {
+				var key, val []byte
+
+				key, val, hasAttr = z.TagAttr()
+				if string(key) == "type" && strings.EqualFold(strings.TrimSpace(string(val)), ContentType) {
+					inScript = true
+				}
+			}
+		case html.TextToken:
+			if !inScript {
+				continue
+			}
+
+			inScript = false
+
+			if sr, err := Parse(z.Text()); err == nil {
+				return sr, nil
+			}
+		case html.EndTagToken, html.SelfClosingTagToken, html.CommentToken, html.DoctypeToken:
+			inScript = false
+		}
+	}
+}
+
+// findRecipe walks the JSON-LD value depth first and returns the first
+// object of the Recipe type.
+func findRecipe(v interface{}) map[string]interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		if isRecipe(vv["@type"]) {
+			return vv
+		}
+
+		if node := findRecipe(vv["@graph"]); node != nil {
+			return node
+		}
+
+		for key, val := range vv {
+			if key == "@graph" {
+				continue
+			}
+
+			if node := findRecipe(val); node != nil {
+				return node
+			}
+		}
+	case []interface{}:
+		for _, val := range vv {
+			if node := findRecipe(val); node != nil {
+				return node
+			}
+		}
+	}
+
+	return nil
+}
+
+// isRecipe checks whether the JSON-LD type, which is either a single
+// type or an array of them, contains the Recipe type.
+func isRecipe(v interface{}) bool {
+	for _, t := range texts(v) {
+		t = strings.TrimPrefix(t, "schema:")
+		t = strings.TrimPrefix(strings.TrimPrefix(t, "http://schema.org/"), "https://schema.org/")
+
+		if t == "Recipe" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// text returns the JSON-LD value as text. Numbers are formatted, while
+// objects and arrays are ignored, except for JSON-LD value objects.
+func text(v interface{}) string {
+	switch vv := v.(type) {
+	case string:
+		return strings.TrimSpace(vv)
+	case json.Number:
+		return vv.String()
+	case map[string]interface{}:
+		return text(vv["@value"])
+	default:
+		return ""
+	}
+}
+
+// texts returns the JSON-LD value, which is either a single value or an
+// array of them, as a list of texts.
+func texts(v interface{}) []string {
+	vv, ok := v.([]interface{})
+	if !ok {
+		vv = []interface{}{v}
+	}
+
+	res := make([]string, 0, len(vv))
+
+	for _, val := range vv {
+		if t := text(val); t != "" {
+			res = append(res, t)
+		}
+	}
+
+	return res
+}
+
+// url returns the first url of the JSON-LD value, which can be a text, an
+// ImageObject, or an array of them.
+func url(v interface{}) string {
+	switch vv := v.(type) {
+	case []interface{}:
+		for _, val := range vv {
+			if u := url(val); u != "" {
+				return u
+			}
+		}
+
+		return ""
+	case map[string]interface{}:
+		if u := text(vv["url"]); u != "" {
+			return u
+		}
+
+		return text(vv["@id"])
+	default:
+		return text(v)
+	}
+}
+
+var (
+	// _parenNote matches a parenthesized note of an ingredient.
+	_parenNote = regexp.MustCompile(`\([^)]*\)`)
+
+	// _commaNote matches the comma before a note of an ingredient. Commas of
+	// decimal numbers are not matched.
+	_commaNote = regexp.MustCompile(`,(\D|$)`)
+
+	// _attachedUnit matches an amount that is written together with its
+	// unit, e.g. 250g.
+	_attachedUnit = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)([^\d\s.,/]+)$`)
+
+	// _fractions maps unicode vulgar fractions to their values.
+	_fractions = map[rune]decimal.Decimal{
+		'¼': decimal.New(25, -2),
+		'½': decimal.New(5, -1),
+		'¾': decimal.New(75, -2),
+		'⅓': decimal.New(3333, -4),
+		'⅔': decimal.New(6667, -4),
+		'⅕': decimal.New(2, -1),
+		'⅛': decimal.New(125, -3),
+	}
+)
+
+// ParseIngredient parses an ingredient line, e.g. "1 1/2 cups rolled oats,
+// toasted", into its amount, unit and name. Parenthesized notes and notes
+// after a comma are left out of the name.
+func ParseIngredient(line string) core.Ingredient {
+	ing := core.Ingredient{Text: strings.TrimSpace(line)}
+
+	rest := _parenNote.ReplaceAllString(ing.Text, " ")
+	if loc := _commaNote.FindStringIndex(rest); loc != nil {
+		rest = rest[:loc[0]]
+	}
+
+	ff := strings.Fields(rest)
+
+	if len(ff) > 0 {
+		if m := _attachedUnit.FindStringSubmatch(ff[0]); m != nil && core.IsUnit(m[2]) {
+			ff = append([]string{m[1], m[2]}, ff[1:]...)
+		}
+	}
+
+	amount, n := parseAmount(ff)
+	ing.Amount = amount
+	ff = ff[n:]
+
+	if n > 0 && len(ff) > 1 && core.IsUnit(ff[0]) {
+		ing.Unit = ff[0]
+		ff = ff[1:]
+	}
+
+	if len(ff) > 1 && strings.EqualFold(ff[0], "of") {
+		ff = ff[1:]
+	}
+
+	ing.Name = strings.Join(ff, " ")
+
+	return ing
+}
+
+// parseAmount parses the amount at the beginning of the fields. Whole and
+// decimal numbers, fractions, mixed numbers and the first number of ranges
+// are supported. The number of fields that make up the amount is returned
+// along with it.
+func parseAmount(ff []string) (decimal.Decimal, int) {
+	if len(ff) == 0 {
+		return decimal.Zero, 0
+	}
+
+	first := ff[0]
+	if i := strings.IndexAny(first, "-–"); i > 0 {
+		first = first[:i]
+	}
+
+	amount, ok := parseNumber(first)
+	if !ok {
+		return decimal.Zero, 0
+	}
+
+	if len(ff) > 1 && amount.Equal(amount.Truncate(0)) {
+		if frac, ok := parseNumber(ff[1]); ok && frac.LessThan(decimal.New(1, 0)) && frac.IsPositive() {
+			return amount.Add(frac), 2
+		}
+	}
+
+	return amount, 1
+}
+
+// parseNumber parses a whole or decimal number, a fraction, or a number
+// followed by a unicode vulgar fraction.
+func parseNumber(v string) (decimal.Decimal, bool) {
+	if v == "" {
+		return decimal.Zero, false
+	}
+
+	rr := []rune(v)
+	if frac, ok := _fractions[rr[len(rr)-1]]; ok {
+		if len(rr) == 1 {
+			return frac, true
+		}
+
+		whole, ok := parseNumber(string(rr[:len(rr)-1]))
+		if !ok {
+			return decimal.Zero, false
+		}
+
+		return whole.Add(frac), true
+	}
+
+	if num, den, ok := strings.Cut(v, "/"); ok {
+		n, err := decimal.NewFromString(num)
+		if err != nil {
+			return decimal.Zero, false
+		}
+
+		d, err := decimal.NewFromString(den)
+		if err != nil || d.IsZero() {
+			return decimal.Zero, false
+		}
+
+		return n.DivRound(d, 4), true
+	}
+
+	d, err := decimal.NewFromString(strings.Replace(v, ",", ".", 1))
+	if err != nil {
+		return decimal.Zero, false
+	}
+
+	return d, true
+}
@@ -0,0 +1,225 @@
+package schemaorg
+
+import (
+	"foodie/core"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewRecipe(t *testing.T) {
+	chicken := core.Product{
+		ID: xid.New(),
+		ProductCore: core.ProductCore{
+			Name: "Chicken Breast",
+			Serving: core.Serving{
+				Type:     core.ServingTypeGrams,
+				Size:     decimal.NewFromInt(100),
+				Calories: 165,
+			},
+		},
+	}
+
+	banana := core.Product{
+		ID: xid.New(),
+		ProductCore: core.ProductCore{
+			Name: "Banana",
+			Serving: core.Serving{
+				Type:     core.ServingTypeUnits,
+				Size:     decimal.NewFromInt(1),
+				Calories: 105,
+			},
+		},
+	}
+
+	rec := core.Recipe{
+		ID: xid.New(),
+		RecipeCore: core.RecipeCore{
+			Name:        "Chicken with bananas",
+			Description: "Unusual",
+			ImageURL:    "https://example.com/image.png",
+			Products: []core.RecipeProduct{
+				{ProductID: chicken.ID, Quantity: decimal.New(25, -1)},
+				{ProductID: banana.ID, Quantity: decimal.NewFromInt(2)},
+				{ProductID: xid.New(), Quantity: decimal.NewFromInt(1)},
+			},
+		},
+		CreatedAt: time.Date(2022, 9, 15, 23, 52, 10, 0, time.UTC),
+	}
+
+	assert.Equal(t, Recipe{
+		Context:       "https://schema.org",
+		Type:          "Recipe",
+		Identifier:    rec.ID.String(),
+		Name:          "Chicken with bananas",
+		Description:   "Unusual",
+		Image:         "https://example.com/image.png",
+		DatePublished: "2022-09-15",
+		RecipeIngredient: []string{
+			"250 g Chicken Breast",
+			"2 Banana",
+		},
+		Nutrition: &Nutrition{
+			Type:     "NutritionInformation",
+			Calories: "623 calories",
+		},
+	}, NewRecipe(rec, []core.Product{chicken, banana}))
+}
+
+func Test_Parse(t *testing.T) {
+	tests := map[string]struct {
+		Data   string
+		Recipe *Recipe
+		Error  error
+	}{
+		"Recipe in a graph": {
+			Data: `{
+				"@context": "https://schema.org",
+				"@graph": [
+					{"@type": "WebPage", "name": "Page"},
+					{
+						"@type": ["Recipe", "HowTo"],
+						"name": "Pancakes",
+						"image": [{"@type": "ImageObject", "url": "https://example.com/a.png"}],
+						"recipeIngredient": ["2 eggs", "250 ml milk", ""],
+						"nutrition": {"@type": "NutritionInformation", "calories": 540}
+					}
+				]
+			}`,
+			Recipe: &Recipe{
+				Context:          "https://schema.org",
+				Type:             "Recipe",
+				Name:             "Pancakes",
+				Image:            "https://example.com/a.png",
+				RecipeIngredient: []string{"2 eggs", "250 ml milk"},
+				Nutrition: &Nutrition{
+					Type:     "NutritionInformation",
+					Calories: "540",
+				},
+			},
+		},
+		"Recipe with deprecated ingredients": {
+			Data: `[{"@type": "schema:Recipe", "name": "Tea", "image": "tea.png", "ingredients": "1 tea bag"}]`,
+			Recipe: &Recipe{
+				Context:          "https://schema.org",
+				Type:             "Recipe",
+				Name:             "Tea",
+				Image:            "tea.png",
+				RecipeIngredient: []string{"1 tea bag"},
+			},
+		},
+		"No recipe": {
+			Data:  `{"@type": "Article", "name": "News"}`,
+			Error: ErrNoRecipe,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			sr, err := Parse([]byte(test.Data))
+			assert.Equal(t, test.Error, err)
+			assert.Equal(t, test.Recipe, sr)
+		})
+	}
+}
+
+func Test_ParseHTML(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html>
+<head>
+	<script type="application/json">{"@type": "Recipe", "name": "Ignored"}</script>
+	<script type="application/ld+json">{"@type": "Organization", "name": "Blog"}</script>
+	<script type="application/ld+json">
+		{"@context": "https://schema.org", "@type": "Recipe", "name": "Soup", "recipeIngredient": ["1 l water"]}
+	</script>
+</head>
+<body></body>
+</html>`
+
+	sr, err := ParseHTML(strings.NewReader(page))
+	require.NoError(t, err)
+	assert.Equal(t, "Soup", sr.Name)
+	assert.Equal(t, []string{"1 l water"}, sr.RecipeIngredient)
+
+	_, err = ParseHTML(strings.NewReader("<html><body>No recipe</body></html>"))
+	assert.Equal(t, ErrNoRecipe, err)
+}
+
+func Test_Recipe_Core(t *testing.T) {
+	sr := Recipe{
+		Name:  " Soup ",
+		Image: "soup.png",
+	}
+
+	assert.Equal(t, core.RecipeCore{
+		Name:        "Soup",
+		ImageURL:    "soup.png",
+		Description: "Soup",
+		Visibility:  core.VisibilityPrivate,
+		State:       core.StateDraft,
+	}, sr.Core())
+}
+
+func Test_ParseIngredient(t *testing.T) {
+	tests := map[string]struct {
+		Amount decimal.Decimal
+		Unit   string
+		Name   string
+	}{
+		"2 eggs": {
+			Amount: decimal.NewFromInt(2),
+			Name:   "eggs",
+		},
+		"1 1/2 cups rolled oats, toasted": {
+			Amount: decimal.New(15, -1),
+			Unit:   "cups",
+			Name:   "rolled oats",
+		},
+		"½ tsp. salt": {
+			Amount: decimal.New(5, -1),
+			Unit:   "tsp.",
+			Name:   "salt",
+		},
+		"250g chicken breast (skinless)": {
+			Amount: decimal.NewFromInt(250),
+			Unit:   "g",
+			Name:   "chicken breast",
+		},
+		"0,5 l of milk": {
+			Amount: decimal.New(5, -1),
+			Unit:   "l",
+			Name:   "milk",
+		},
+		"2-3 bananas": {
+			Amount: decimal.NewFromInt(2),
+			Name:   "bananas",
+		},
+		"Salt to taste": {
+			Amount: decimal.Zero,
+			Name:   "Salt to taste",
+		},
+	}
+
+	for text, test := range tests {
+		text, test := text, test
+
+		t.Run(text, func(t *testing.T) {
+			t.Parallel()
+
+			ing := ParseIngredient(text)
+			assert.Equal(t, text, ing.Text)
+			assert.True(t, test.Amount.Equal(ing.Amount), "expected %s, got %s", test.Amount, ing.Amount)
+			assert.Equal(t, test.Unit, ing.Unit)
+			assert.Equal(t, test.Name, ing.Name)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/rs/xid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Server_GetRecipeJSONLD(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	rec, other := insertSharedRecipe(t, dbh)
+
+	tests := map[string]struct {
+		UserID xid.ID
+		Secret bool
+	}{
+		"Guest": {},
+		"Another user": {
+			UserID: other,
+		},
+		"Recipe owner": {
+			UserID: rec.UserID,
+			Secret: true,
+		},
+	}
+
+	for tName, tCase := range tests {
+		t.Run(tName, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("recipeID", rec.ID.String())
+
+			ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+			if !tCase.UserID.IsNil() {
+				ctx = withViewer(ctx, tCase.UserID, false)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "http://test.com/api/recipes/"+rec.ID.String()+".jsonld", nil)
+			req = req.WithContext(ctx)
+			resp := httptest.NewRecorder()
+
+			s := &Server{log: logrus.New(), db: dbh}
+			s.GetRecipeJSONLD(resp, req)
+
+			require.Equal(t, http.StatusOK, resp.Code)
+			assert.Contains(t, resp.Body.String(), "Oats")
+			assert.Equal(t, tCase.Secret, strings.Contains(resp.Body.String(), "Secret"))
+		})
+	}
+}
+
+func Test_extractDocument(t *testing.T) {
+	multipartBody := func(data string) (string, string) {
+		var buf bytes.Buffer
+
+		mw := multipart.NewWriter(&buf)
+
+		fw, err := mw.CreateFormFile("file", "pancakes.cook")
+		require.NoError(t, err)
+
+		_, err = fw.Write([]byte(data))
+		require.NoError(t, err)
+		require.NoError(t, mw.Close())
+
+		return mw.FormDataContentType(), buf.String()
+	}
+
+	tests := map[string]struct {
+		ContentType string
+		Body        string
+		Document    *document
+		StatusCode  int
+	}{
+		"Request body": {
+			ContentType: "text/plain",
+			Body:        "Mix @flour{100%g}.",
+			Document:    &document{Data: []byte("Mix @flour{100%g}."), MediaType: "text/plain"},
+		},
+		"Too large request body": {
+			ContentType: "text/plain",
+			Body:        strings.Repeat("a", _maxUploadSize+1),
+			StatusCode:  http.StatusBadRequest,
+		},
+		"Uploaded file": {
+			Body:     "Mix @flour{100%g}.",
+			Document: &document{Data: []byte("Mix @flour{100%g}."), MediaType: "text/plain", Name: "pancakes"},
+		},
+		"Too large uploaded file": {
+			Body:       strings.Repeat("a", _maxUploadSize+1),
+			StatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for tName, tCase := range tests {
+		tCase := tCase
+
+		t.Run(tName, func(t *testing.T) {
+			t.Parallel()
+
+			ct, body := tCase.ContentType, tCase.Body
+			if ct == "" {
+				ct, body = multipartBody(tCase.Body)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			req.Header.Set("Content-Type", ct)
+
+			doc, aerr := extractDocument(httptest.NewRecorder(), req, map[string]string{
+				".cook": "text/plain",
+			})
+			if tCase.StatusCode != 0 {
+				require.NotNil(t, aerr)
+
+				rec := httptest.NewRecorder()
+				aerr.Respond(rec)
+				assert.Equal(t, tCase.StatusCode, rec.Code)
+				assert.Contains(t, rec.Body.String(), "must not exceed")
+
+				return
+			}
+
+			require.Nil(t, aerr)
+			assert.Equal(t, tCase.Document, doc)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"foodie/core"
+	"foodie/db"
+	"foodie/server/apierr"
+	"net/http"
+	"strings"
+)
+
+const (
+	// _searchLimit specifies the maximum number of search results of a
+	// single type.
+	_searchLimit = 20
+)
+
+// Search searches products, recipes and plans by their names and
+// descriptions. The results are grouped by type and ordered by their
+// relevance. Only objects that are listed to the requester are returned.
+func (s *Server) Search(w http.ResponseWriter, r *http.Request) {
+	text := strings.TrimSpace(r.URL.Query().Get("q"))
+	if text == "" {
+		apierr.InvalidAttribute("q", "cannot be empty").Respond(w)
+		return
+	}
+
+	vw := s.extractViewer(r)
+
+	pp, err := db.SearchProducts(r.Context(), s.db, vw, text, _searchLimit)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("searching products")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	rr, err := db.SearchRecipes(r.Context(), s.db, vw, text, _searchLimit)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("searching recipes")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	pls, err := db.SearchPlans(r.Context(), s.db, vw, text, _searchLimit)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("searching plans")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	s.respondJSON(w, struct {
+		Products []core.Product `json:"products"`
+		Recipes  []core.Recipe  `json:"recipes"`
+		Plans    []core.Plan    `json:"plans"`
+	}{
+		Products: pp,
+		Recipes:  rr,
+		Plans:    pls,
+	})
+}
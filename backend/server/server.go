@@ -10,16 +10,28 @@ import (
 	"foodie/core"
 	"foodie/db"
 	"foodie/server/apierr"
+	"foodie/server/events"
+	"foodie/server/openapi"
+	"foodie/server/printable"
+	"foodie/server/suggest"
+	"foodie/server/webhook"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
+	"github.com/graph-gophers/graphql-go"
 	"github.com/rs/xid"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 )
 
+// _version specifies the version of the server.
+const _version = "1.0.0"
+
 // contextKey is an unique type used to store context data.
 type contextKey int
 
@@ -29,6 +41,10 @@ const (
 
 	// _contextKeyAdmin is used to store admin flag data.
 	_contextKeyAdmin
+
+	// _contextKeyGraphQL is used to store the loaders of a GraphQL
+	// request.
+	_contextKeyGraphQL
 )
 
 // Authorizer should authorize requests.
@@ -53,16 +69,113 @@ type Server struct {
 	// clients connections pool.
 	serv *http.Server
 
+	// grpc serves the gRPC services of the API.
+	grpc *grpc.Server
+
+	// grpcAddr is the address that the gRPC server listens on. The gRPC
+	// server is not started if it is empty.
+	grpcAddr string
+
 	// auth is used to authorize clients.
 	auth Authorizer
+
+	// products is an in-memory index used to suggest products by their
+	// names and aliases. It is kept in sync with the database by the
+	// product handlers.
+	products *suggest.Index[core.Product]
+
+	// spec is the OpenAPI document that describes the API.
+	spec *openapi.Document
+
+	// schema is the GraphQL schema of the API with its resolvers.
+	schema *graphql.Schema
+
+	// validator validates incoming requests against the OpenAPI document.
+	// Requests are not validated if it is nil.
+	validator *openapi.Validator
+
+	// retention specifies how long deleted objects are kept in the trash
+	// before being permanently deleted.
+	retention time.Duration
+
+	// idempotencyTTL specifies how long the responses of requests with
+	// idempotency keys are replayed.
+	idempotencyTTL time.Duration
+
+	// aliases maps lowercase ingredient names of imported recipes to the
+	// names of the products they should be matched to.
+	aliases map[string]string
+
+	// events broadcasts change notifications to the event streams.
+	events *events.Broker
+
+	// webhookClient is used to send webhook deliveries.
+	webhookClient *http.Client
+
+	// webhookWake wakes up the webhook dispatcher once new deliveries
+	// are stored.
+	webhookWake chan struct{}
+
+	// ctx is cancelled once the server is stopped. It is used to stop
+	// background workers.
+	ctx context.Context
+
+	// cancel cancels the server context.
+	cancel context.CancelFunc
+
+	// wg is used to wait for background workers to finish.
+	wg sync.WaitGroup
 }
 
-// NewServer creates a fresh instance of the server.
-func NewServer(dbh *sql.DB, port string, secret []byte) *Server {
+// NewServer creates a fresh instance of the server. Ingredients of
+// imported recipes are matched to products by the names that the aliases
+// map them to, if any. Responses of requests with idempotency keys are
+// replayed for the idempotency TTL. If validate is true, incoming requests
+// are validated against the OpenAPI document before being handled, and an
+// error is returned if the document cannot be compiled. The gRPC services
+// are served on the gRPC port, unless it is empty.
+func NewServer(
+	dbh *sql.DB,
+	port string,
+	grpcPort string,
+	secret []byte,
+	retention time.Duration,
+	idempotencyTTL time.Duration,
+	aliases map[string]string,
+	validate bool,
+) (*Server, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	s := &Server{
-		log:  logrus.New(),
-		db:   dbh,
-		auth: core.NewJWTAuth(secret),
+		log:            logrus.New(),
+		db:             dbh,
+		auth:           core.NewJWTAuth(secret),
+		products:       suggest.NewIndex[core.Product](),
+		spec:           newSpec(),
+		retention:      retention,
+		idempotencyTTL: idempotencyTTL,
+		aliases:        make(map[string]string, len(aliases)),
+		ctx:            ctx,
+		cancel:         cancel,
+		events:         events.NewBroker(_eventHistorySize),
+		webhookClient:  webhook.NewClient(_webhookTimeout),
+		webhookWake:    make(chan struct{}, 1),
+	}
+
+	s.schema = newGraphQLSchema(s)
+
+	for alias, name := range aliases {
+		s.aliases[normalizeIngredient(alias)] = name
+	}
+
+	if validate {
+		v, err := openapi.NewValidator(s.spec)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("compiling the OpenAPI document: %w", err)
+		}
+
+		s.validator = v
 	}
 
 	s.serv = &http.Server{
@@ -70,66 +183,198 @@ func NewServer(dbh *sql.DB, port string, secret []byte) *Server {
 		Handler: s.router(),
 	}
 
-	return s
+	s.grpc = s.newGRPCServer()
+
+	if grpcPort != "" {
+		s.grpcAddr = fmt.Sprintf(":%s", grpcPort)
+	}
+
+	return s, nil
 }
 
-// Start starts the server. It blocks until the server.Stop is called.
+// Start loads the in-memory indexes and starts the server and its
+// background workers. It blocks until the server.Stop is called.
 func (s *Server) Start() error {
+	if err := s.loadProducts(); err != nil {
+		return fmt.Errorf("loading products: %w", err)
+	}
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+		s.purgeTrash()
+	}()
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+		s.purgeIdempotentRequests()
+	}()
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+		s.dispatchWebhooks()
+	}()
+
+	if s.grpcAddr != "" {
+		lis, err := net.Listen("tcp", s.grpcAddr)
+		if err != nil {
+			return fmt.Errorf("listening for gRPC calls: %w", err)
+		}
+
+		s.wg.Add(1)
+
+		go func() {
+			defer s.wg.Done()
+
+			if err := s.grpc.Serve(lis); err != nil {
+				s.log.WithError(err).Error("unexpected gRPC server closure")
+			}
+		}()
+	}
+
 	return s.serv.ListenAndServe()
 }
 
-// Stop shuts down the server.
+// Stop shuts down the server and waits for its background workers to
+// finish. Open event streams are closed once the server context is
+// cancelled.
 func (s *Server) Stop() error {
-	return s.serv.Shutdown(context.Background())
+	s.cancel()
+	err := s.serv.Shutdown(context.Background())
+	s.grpc.GracefulStop()
+	s.wg.Wait()
+
+	return err
 }
 
 // router builds the server router.
 func (s *Server) router() chi.Router {
+	nr := chi.NewRouter()
+	nr.Use(middleware.RequestID, apierr.Middleware(middleware.GetReqID))
+	nr.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		apierr.RouteNotFound().Respond(w)
+	})
+	nr.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		apierr.MethodNotAllowed().Respond(w)
+	})
+	nr.Mount("/api", s.apiRouter())
+	nr.Mount("/debug", middleware.Profiler())
+
+	return nr
+}
+
+// apiRouter builds the router of the API. Every route must be described
+// by the OpenAPI document.
+func (s *Server) apiRouter() chi.Router {
 	r := chi.NewRouter()
 
+	if s.validator != nil {
+		r.Use(s.validator.Middleware(r))
+	}
+
 	r.Post("/login", s.Login)
-	r.Post("/register", s.Register)
+	r.With(s.idempotent).Post("/register", s.Register)
 
 	r.Route("/self", func(sr chi.Router) {
 		sr.Use(s.authorize(false))
 		sr.Get("/", s.Self)
+		sr.Post("/calendar-token", s.CreateCalendarToken)
+		sr.Delete("/calendar-token", s.RevokeCalendarToken)
+	})
+
+	r.Route("/webhooks", func(sr chi.Router) {
+		sr.Use(s.authorize(false))
+		sr.Get("/", s.GetWebhooks)
+		sr.Post("/", s.CreateWebhook)
+		sr.Get("/{webhookID}", s.GetWebhook)
+		sr.Put("/{webhookID}", s.UpdateWebhook)
+		sr.Delete("/{webhookID}", s.DeleteWebhook)
+		sr.Get("/{webhookID}/deliveries", s.GetWebhookDeliveries)
 	})
 
 	r.Route("/products", func(sr chi.Router) {
-		sr.Get("/", s.GetProducts)
-		sr.Get("/{productID}", s.GetProduct)
+		sr.Group(func(ssr chi.Router) {
+			ssr.Use(s.identify)
+			ssr.Get("/", s.GetProducts)
+			ssr.Get("/suggest", s.SuggestProducts)
+			ssr.Get("/{productID}", s.GetProduct)
+		})
 
 		sr.Group(func(ssr chi.Router) {
-			ssr.Use(s.authorize(true))
+			ssr.Use(s.authorize(false))
 			ssr.Post("/", s.CreateProduct)
+		})
+
+		sr.Group(func(ssr chi.Router) {
+			ssr.Use(s.authorize(true))
+			ssr.Get("/submissions", s.GetProductSubmissions)
+			ssr.Get("/export", s.ExportProducts)
+			ssr.Post("/import", s.ImportProducts)
+			ssr.Put("/{productID}", s.UpdateProduct)
 			ssr.Patch("/{productID}", s.UpdateProduct)
 			ssr.Delete("/{productID}", s.DeleteProduct)
+			ssr.Post("/{productID}/approve", s.ApproveProduct)
+			ssr.Post("/{productID}/reject", s.RejectProduct)
+			ssr.Post("/{productID}/merge", s.MergeProducts)
+			ssr.Post("/{productID}/restore", s.RestoreProduct)
 		})
 	})
 
 	r.Route("/recipes", func(sr chi.Router) {
-		sr.Get("/", s.GetRecipes)
-		sr.Get("/{recipeID}", s.GetRecipe)
-		sr.Get("/user/{userID}", s.GetUserRecipes)
+		sr.Group(func(ssr chi.Router) {
+			ssr.Use(s.identify)
+			ssr.Get("/", s.GetRecipes)
+			ssr.Get("/cookbook.html", s.GetCookbookPrintable(printable.FormatHTML))
+			ssr.Get("/cookbook.md", s.GetCookbookPrintable(printable.FormatMarkdown))
+			ssr.Get("/{recipeID}", s.GetRecipe)
+			ssr.Get("/{recipeID}.jsonld", s.GetRecipeJSONLD)
+			ssr.Get("/{recipeID}.cook", s.GetRecipeCooklang)
+			ssr.Get("/{recipeID}.html", s.GetRecipePrintable(printable.FormatHTML))
+			ssr.Get("/{recipeID}.md", s.GetRecipePrintable(printable.FormatMarkdown))
+			ssr.Get("/user/{userID}", s.GetUserRecipes)
+		})
 
 		sr.Group(func(ssr chi.Router) {
 			ssr.Use(s.authorize(false))
-			ssr.Post("/", s.CreateRecipe)
+			ssr.With(s.idempotent).Post("/", s.CreateRecipe)
+			ssr.Post("/import/jsonld", s.ImportRecipeJSONLD)
+			ssr.Post("/import/cooklang", s.ImportRecipeCooklang)
+			ssr.Put("/{recipeID}", s.UpdateRecipe)
 			ssr.Patch("/{recipeID}", s.UpdateRecipe)
 			ssr.Delete("/{recipeID}", s.DeleteRecipe)
+			ssr.Post("/{recipeID}/restore", s.RestoreRecipe)
+			ssr.Post("/{recipeID}/products/{productID}", s.UpdateRecipeProduct)
+			ssr.Patch("/{recipeID}/products/{productID}", s.UpdateRecipeProduct)
+			ssr.Delete("/{recipeID}/products/{productID}", s.UpdateRecipeProduct)
 		})
 	})
 
 	r.Route("/plans", func(sr chi.Router) {
-		sr.Get("/", s.GetPlans)
-		sr.Get("/{planID}", s.GetPlan)
-		sr.Get("/user/{userID}", s.GetUserPlans)
+		sr.Group(func(ssr chi.Router) {
+			ssr.Use(s.identify)
+			ssr.Get("/", s.GetPlans)
+			ssr.Get("/{planID}", s.GetPlan)
+			ssr.Get("/{planID}.html", s.GetPlanPrintable(printable.FormatHTML))
+			ssr.Get("/{planID}.md", s.GetPlanPrintable(printable.FormatMarkdown))
+			ssr.Get("/{planID}/calendar.ics", s.GetPlanCalendar)
+			ssr.Get("/user/{userID}", s.GetUserPlans)
+		})
 
 		sr.Group(func(ssr chi.Router) {
 			ssr.Use(s.authorize(false))
-			ssr.Post("/", s.CreatePlan)
+			ssr.With(s.idempotent).Post("/", s.CreatePlan)
+			ssr.Put("/{planID}", s.UpdatePlan)
 			ssr.Patch("/{planID}", s.UpdatePlan)
 			ssr.Delete("/{planID}", s.DeletePlan)
+			ssr.Post("/{planID}/restore", s.RestorePlan)
+			ssr.Post("/{planID}/recipes/{recipeID}", s.UpdatePlanRecipe)
+			ssr.Patch("/{planID}/recipes/{recipeID}", s.UpdatePlanRecipe)
+			ssr.Delete("/{planID}/recipes/{recipeID}", s.UpdatePlanRecipe)
 		})
 	})
 
@@ -149,13 +394,19 @@ func (s *Server) router() chi.Router {
 		})
 	})
 
-	r.Get("/version", s.GetVersion)
+	r.Route("/trash", func(sr chi.Router) {
+		sr.Use(s.authorize(false))
+		sr.Get("/", s.GetTrash)
+	})
 
-	nr := chi.NewRouter()
-	nr.Mount("/api", r)
-	nr.Mount("/debug", middleware.Profiler())
+	r.With(s.authorize(false)).Get("/events", s.GetEvents)
+	r.With(s.identify).Get("/search", s.Search)
+	r.With(s.identify).Post("/graphql", s.GraphQL)
 
-	return nr
+	r.Get("/version", s.GetVersion)
+	r.Get("/openapi.json", s.GetOpenAPI)
+
+	return r
 }
 
 // GetVersion returns server version information.
@@ -163,7 +414,7 @@ func (s *Server) GetVersion(w http.ResponseWriter, r *http.Request) {
 	s.respondJSON(w, struct {
 		Version string `json:"version"`
 	}{
-		Version: "1.0.0",
+		Version: _version,
 	})
 }
 
@@ -178,55 +429,101 @@ func (s *Server) authorize(super bool) func(http.Handler) http.Handler {
 				return
 			}
 
-			id, admin, aerr := s.auth.Parse(token, time.Now())
+			id, admin, aerr := s.authenticate(r.Context(), token)
 			if aerr != nil {
-				if aerr == apierr.Internal() {
-					s.log.WithField("token", token).Error("parsing token")
-				}
-
 				aerr.Respond(w)
 				return
 			}
 
-			_, err := db.GetUserByID(r.Context(), s.db, id)
-			switch err {
-			case nil:
-				// OK.
-			case db.ErrNotFound:
-				apierr.Unauthorized().Respond(w)
-				return
-			case r.Context().Err():
-				apierr.Context().Respond(w)
-				return
-			default:
-				s.log.WithError(err).Error("fetching user by name")
-				apierr.Database().Respond(w)
-				return
-			}
-
 			if super && !admin {
 				apierr.Forbidden().Respond(w)
 				return
 			}
 
-			next.ServeHTTP(
-				w,
-				r.WithContext(
-					context.WithValue(
-						context.WithValue(
-							r.Context(),
-							_contextKeyUserID,
-							id,
-						),
-						_contextKeyAdmin,
-						admin,
-					),
-				),
-			)
+			next.ServeHTTP(w, r.WithContext(withViewer(r.Context(), id, admin)))
 		})
 	}
 }
 
+// identify is a middleware that identifies the user of the incoming
+// request by its authorization token. Unlike authorize, requests without
+// the authorization header are passed through as anonymous guests.
+func (s *Server) identify(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, aerr := s.extractAuthorizationToken(r)
+		if aerr != nil {
+			aerr.Respond(w)
+			return
+		}
+
+		id, admin, aerr := s.authenticate(r.Context(), token)
+		if aerr != nil {
+			aerr.Respond(w)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(withViewer(r.Context(), id, admin)))
+	})
+}
+
+// authenticate parses the authorization token and checks whether the user
+// it belongs to still exists.
+func (s *Server) authenticate(ctx context.Context, token []byte) (xid.ID, bool, *apierr.Error) {
+	id, admin, aerr := s.auth.Parse(token, time.Now())
+	if aerr != nil {
+		if aerr == apierr.Internal() {
+			s.log.WithField("token", token).Error("parsing token")
+		}
+
+		return xid.NilID(), false, aerr
+	}
+
+	_, err := db.GetUserByID(ctx, s.db, id)
+	switch err {
+	case nil:
+		// OK.
+	case db.ErrNotFound:
+		return xid.NilID(), false, apierr.Unauthorized()
+	case ctx.Err():
+		return xid.NilID(), false, apierr.Context()
+	default:
+		s.log.WithError(err).Error("fetching user by name")
+		return xid.NilID(), false, apierr.Database()
+	}
+
+	return id, admin, nil
+}
+
+// dbError converts an unexpected database error to an API error. Errors
+// other than the cancellation of the context are logged with the message.
+func (s *Server) dbError(ctx context.Context, err error, msg string) *apierr.Error {
+	if err == ctx.Err() {
+		return apierr.Context()
+	}
+
+	s.log.WithError(err).Error(msg)
+
+	return apierr.Database()
+}
+
+// withViewer stores the user id and the admin flag in the context.
+func withViewer(ctx context.Context, id xid.ID, admin bool) context.Context {
+	return context.WithValue(
+		context.WithValue(
+			ctx,
+			_contextKeyUserID,
+			id,
+		),
+		_contextKeyAdmin,
+		admin,
+	)
+}
+
 // respondJSON marshals the given object and writes its data to the response
 // writer.
 func (s *Server) respondJSON(w http.ResponseWriter, obj any) {
@@ -264,6 +561,22 @@ func (s *Server) extractPathID(r *http.Request, key string) (xid.ID, *apierr.Err
 	return id, nil
 }
 
+// extractQueryID extracts an optional object id from the URL query. The
+// nil id is returned when the parameter is not set.
+func (s *Server) extractQueryID(r *http.Request, key string) (xid.ID, *apierr.Error) {
+	sid := r.URL.Query().Get(key)
+	if sid == "" {
+		return xid.NilID(), nil
+	}
+
+	id, err := xid.FromString(sid)
+	if err != nil {
+		return xid.NilID(), apierr.InvalidAttribute(key, "must be a valid id")
+	}
+
+	return id, nil
+}
+
 // extractAuthorizationToken extract authorization token from the
 // authorization header.
 func (s *Server) extractAuthorizationToken(r *http.Request) ([]byte, *apierr.Error) {
@@ -302,3 +615,15 @@ func (s *Server) extractContextAdmin(r *http.Request) (bool, *apierr.Error) {
 
 	return admin, nil
 }
+
+// extractViewer extracts the viewer from the request context. If the
+// request was not identified, an anonymous viewer is returned.
+func (s *Server) extractViewer(r *http.Request) core.Viewer {
+	id, _ := r.Context().Value(_contextKeyUserID).(xid.ID)
+	admin, _ := r.Context().Value(_contextKeyAdmin).(bool)
+
+	return core.Viewer{
+		UserID: id,
+		Admin:  admin,
+	}
+}
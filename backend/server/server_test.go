@@ -1,8 +1,10 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"foodie/core"
 	"foodie/db"
 	"net"
 	"os"
@@ -10,6 +12,9 @@ import (
 
 	"github.com/ory/dockertest/v3"
 	dc "github.com/ory/dockertest/v3/docker"
+	"github.com/rs/xid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
 )
 
 var _dbFn func(t *testing.T) *sql.DB
@@ -37,6 +42,57 @@ func cleanUpTables(t *testing.T, dbh *sql.DB) {
 	})
 }
 
+// insertSharedRecipe inserts a public recipe of a new user, which contains
+// an approved product named "Oats" and a pending product named "Secret",
+// and returns it along with the id of another new user.
+func insertSharedRecipe(t *testing.T, dbh *sql.DB) (*core.Recipe, xid.ID) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	owner, err := db.InsertUser(ctx, dbh, xid.New().String(), []byte{1}, false)
+	require.NoError(t, err)
+
+	other, err := db.InsertUser(ctx, dbh, xid.New().String(), []byte{1}, false)
+	require.NoError(t, err)
+
+	rec := &core.Recipe{RecipeCore: core.RecipeCore{
+		Name:        "Porridge",
+		Description: "Warm porridge.",
+		Visibility:  core.VisibilityPublic,
+		State:       core.StatePublished,
+	}}
+
+	for _, prd := range []struct {
+		Name   string
+		Status core.ProductStatus
+	}{
+		{Name: "Oats", Status: core.ProductStatusApproved},
+		{Name: "Secret", Status: core.ProductStatusPending},
+	} {
+		p, err := db.InsertProduct(ctx, dbh, owner.ID, prd.Status, core.ProductCore{
+			Name:        prd.Name,
+			Description: prd.Name,
+			Serving: core.Serving{
+				Type:     core.ServingTypeGrams,
+				Size:     decimal.NewFromInt(100),
+				Calories: 100,
+			},
+		})
+		require.NoError(t, err)
+
+		rec.Products = append(rec.Products, core.RecipeProduct{
+			ProductID: p.ID,
+			Quantity:  decimal.NewFromInt(1),
+		})
+	}
+
+	rec, err = db.InsertRecipe(ctx, dbh, owner.ID, rec.RecipeCore)
+	require.NoError(t, err)
+
+	return rec, other.ID
+}
+
 func setupDB() (cleanup func()) {
 	var err error
 
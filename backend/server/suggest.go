@@ -0,0 +1,97 @@
+package server
+
+import (
+	"foodie/core"
+	"foodie/db"
+	"foodie/server/apierr"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rs/xid"
+)
+
+const (
+	// _defaultSuggestLimit specifies the number of suggestions that is
+	// used when the client does not provide one.
+	_defaultSuggestLimit = 10
+
+	// _maxSuggestLimit specifies the maximum number of suggestions that
+	// the clients are allowed to request.
+	_maxSuggestLimit = 50
+)
+
+// SuggestProducts suggests products whose names or aliases match the
+// query by their prefixes or with small typos. Only products that can be
+// used by the requester are suggested.
+func (s *Server) SuggestProducts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	text := strings.TrimSpace(q.Get("q"))
+	if text == "" {
+		apierr.InvalidAttribute("q", "cannot be empty").Respond(w)
+		return
+	}
+
+	limit := _defaultSuggestLimit
+
+	if v := q.Get("limit"); v != "" {
+		var err error
+
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > _maxSuggestLimit {
+			apierr.InvalidAttribute(
+				"limit",
+				"must be a number between 1 and "+strconv.Itoa(_maxSuggestLimit),
+			).Respond(w)
+
+			return
+		}
+	}
+
+	vw := s.extractViewer(r)
+
+	pp := s.products.Suggest(text, limit, func(prd core.Product) bool {
+		return vw.Admin || prd.UsableBy(vw.UserID)
+	})
+
+	if pp == nil {
+		pp = make([]core.Product, 0)
+	}
+
+	s.respondJSON(w, pp)
+}
+
+// loadProducts fills the products suggestion index with all products
+// from the database.
+func (s *Server) loadProducts() error {
+	pp, err := db.GetAllProducts(s.ctx, s.db)
+	if err != nil {
+		return err
+	}
+
+	for _, prd := range pp {
+		s.indexProduct(prd)
+	}
+
+	return nil
+}
+
+// indexProduct adds the product to the suggestion index or updates it.
+// Rejected products are removed from the index, since they cannot be
+// used by anyone.
+func (s *Server) indexProduct(prd core.Product) {
+	if prd.Status == core.ProductStatusRejected {
+		s.products.Delete(prd.ID)
+		return
+	}
+
+	s.products.Put(prd.ID, prd, append([]string{prd.Name}, prd.Aliases...)...)
+}
+
+// unindexProducts removes the products from the suggestion index.
+func (s *Server) unindexProducts(ids ...xid.ID) {
+	for _, id := range ids {
+		s.products.Delete(id)
+	}
+}
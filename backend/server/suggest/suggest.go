@@ -0,0 +1,537 @@
+// Package suggest provides an in-memory index which suggests objects by
+// the prefixes and misspellings of their names.
+package suggest
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/rs/xid"
+)
+
+const (
+	// _scoreExact is the score of a term that matches a query token
+	// exactly.
+	_scoreExact = 4
+
+	// _scorePrefix is the score of a term that starts with a query token.
+	_scorePrefix = 2
+
+	// _scoreFuzzy is the score of a term that is within the allowed edit
+	// distance of a query token.
+	_scoreFuzzy = 1
+)
+
+// Index is an in-memory index which suggests values by the prefixes and
+// misspellings of their names. It is safe for concurrent use.
+type Index[T any] struct {
+	// mu guards all fields below.
+	mu sync.RWMutex
+
+	// entries contains the indexed values by their ids.
+	entries map[xid.ID]*entry[T]
+
+	// terms contains all terms of the indexed names.
+	terms map[string]*term[T]
+
+	// buckets contains all terms grouped by their first two characters.
+	// Terms in every bucket are kept in the lexical order, so that they
+	// can be found by their prefixes.
+	buckets map[string][]*term[T]
+
+	// grams maps trigrams to the terms that contain them. It is used to
+	// find candidates for the edit distance comparison.
+	grams map[string]map[*term[T]]struct{}
+}
+
+// entry contains a single indexed value.
+type entry[T any] struct {
+	// id specifies the id of the value.
+	id xid.ID
+
+	// value contains the indexed value.
+	value T
+
+	// name contains the normalized primary name of the value.
+	name string
+
+	// terms contains the normalized terms of all names of the value.
+	terms []string
+}
+
+// term contains a single normalized word of the indexed names.
+type term[T any] struct {
+	// text contains the normalized word.
+	text string
+
+	// entries contains the values whose names contain the word.
+	entries []*entry[T]
+}
+
+// result contains a suggested value along with its score.
+type result[T any] struct {
+	// entry contains the suggested value.
+	entry *entry[T]
+
+	// score specifies how well the value matches the query.
+	score int
+}
+
+// NewIndex creates a fresh instance of the index.
+func NewIndex[T any]() *Index[T] {
+	return &Index[T]{
+		entries: make(map[xid.ID]*entry[T]),
+		terms:   make(map[string]*term[T]),
+		buckets: make(map[string][]*term[T]),
+		grams:   make(map[string]map[*term[T]]struct{}),
+	}
+}
+
+// Put indexes the value by the provided names. The first name is treated
+// as the primary one. A value that was previously indexed with the same
+// id is replaced.
+func (ix *Index[T]) Put(id xid.ID, v T, names ...string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+
+	e := &entry[T]{
+		id:    id,
+		value: v,
+	}
+
+	if len(names) > 0 {
+		e.name = strings.ToLower(names[0])
+	}
+
+	seen := make(map[string]bool)
+
+	for _, name := range names {
+		for _, text := range tokenize(name) {
+			if seen[text] {
+				continue
+			}
+
+			seen[text] = true
+			e.terms = append(e.terms, text)
+			ix.addTerm(text, e)
+		}
+	}
+
+	ix.entries[id] = e
+}
+
+// Delete removes the value from the index by its id.
+func (ix *Index[T]) Delete(id xid.ID) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+}
+
+// Len returns the number of indexed values.
+func (ix *Index[T]) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return len(ix.entries)
+}
+
+// Suggest retrieves at most limit values whose names match every token
+// of the query either exactly, by a prefix or within a small edit
+// distance. Values are ordered by their relevance. Values for which the
+// filter function returns false are skipped.
+func (ix *Index[T]) Suggest(q string, limit int, filter func(T) bool) []T {
+	tokens := tokenize(q)
+	if len(tokens) == 0 || limit <= 0 {
+		return nil
+	}
+
+	// The longest token is the most selective one, so its matches are
+	// used as candidates which are then checked against the rest of the
+	// tokens.
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return len(tokens[i]) > len(tokens[j])
+	})
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	rr := make([]result[T], 0, limit+1)
+
+	ix.match(tokens[0], func(t *term[T], sc int) {
+		for _, e := range t.entries {
+			total := sc
+
+			for _, tok := range tokens[1:] {
+				best := 0
+
+				for _, text := range e.terms {
+					if tsc := scoreTerm(tok, text); tsc > best {
+						best = tsc
+					}
+				}
+
+				if best == 0 {
+					total = 0
+					break
+				}
+
+				total += best
+			}
+
+			if total > 0 {
+				rr = collect(rr, result[T]{entry: e, score: total}, limit, filter)
+			}
+		}
+	})
+
+	if len(rr) == 0 {
+		return nil
+	}
+
+	vv := make([]T, 0, len(rr))
+	for _, r := range rr {
+		vv = append(vv, r.entry.value)
+	}
+
+	return vv
+}
+
+// match calls the provided function with every term that matches the
+// token and the score of the match.
+func (ix *Index[T]) match(tok string, fn func(*term[T], int)) {
+	qr := []rune(tok)
+
+	if len(qr) < 2 {
+		for key, tt := range ix.buckets {
+			if strings.HasPrefix(key, tok) {
+				matchPrefix(tt, tok, fn)
+			}
+		}
+	} else {
+		matchPrefix(ix.buckets[bucketKey(tok)], tok, fn)
+	}
+
+	d := maxDistance(qr)
+	if d == 0 {
+		return
+	}
+
+	qg := trigrams(tok)
+	counts := make(map[*term[T]]int)
+
+	for _, g := range qg {
+		for t := range ix.grams[g] {
+			counts[t]++
+		}
+	}
+
+	// A single edit changes at most four trigrams of a term, so terms
+	// which share fewer trigrams with the token cannot be within the
+	// allowed distance.
+	for t, c := range counts {
+		if c < len(qg)-4*d || strings.HasPrefix(t.text, tok) {
+			continue
+		}
+
+		if distance(qr, []rune(t.text), d) <= d {
+			fn(t, _scoreFuzzy)
+		}
+	}
+}
+
+// addTerm adds the word of the value's names to the index.
+func (ix *Index[T]) addTerm(text string, e *entry[T]) {
+	t, ok := ix.terms[text]
+	if !ok {
+		t = &term[T]{text: text}
+		ix.terms[text] = t
+
+		key := bucketKey(text)
+		tt := ix.buckets[key]
+		i := searchTerms(tt, text)
+		tt = append(tt, nil)
+		copy(tt[i+1:], tt[i:])
+		tt[i] = t
+		ix.buckets[key] = tt
+
+		for _, g := range trigrams(text) {
+			gt, ok := ix.grams[g]
+			if !ok {
+				gt = make(map[*term[T]]struct{})
+				ix.grams[g] = gt
+			}
+
+			gt[t] = struct{}{}
+		}
+	}
+
+	t.entries = append(t.entries, e)
+}
+
+// remove removes the value and its terms from the index. It must be
+// called with the lock held.
+func (ix *Index[T]) remove(id xid.ID) {
+	e, ok := ix.entries[id]
+	if !ok {
+		return
+	}
+
+	delete(ix.entries, id)
+
+	for _, text := range e.terms {
+		t := ix.terms[text]
+
+		for i := range t.entries {
+			if t.entries[i] == e {
+				t.entries = append(t.entries[:i], t.entries[i+1:]...)
+				break
+			}
+		}
+
+		if len(t.entries) > 0 {
+			continue
+		}
+
+		delete(ix.terms, text)
+
+		key := bucketKey(text)
+		tt := ix.buckets[key]
+		i := searchTerms(tt, text)
+		tt = append(tt[:i], tt[i+1:]...)
+
+		if len(tt) > 0 {
+			ix.buckets[key] = tt
+		} else {
+			delete(ix.buckets, key)
+		}
+
+		for _, g := range trigrams(text) {
+			delete(ix.grams[g], t)
+
+			if len(ix.grams[g]) == 0 {
+				delete(ix.grams, g)
+			}
+		}
+	}
+}
+
+// matchPrefix calls the provided function with every term of the sorted
+// bucket that starts with the token.
+func matchPrefix[T any](tt []*term[T], tok string, fn func(*term[T], int)) {
+	for i := searchTerms(tt, tok); i < len(tt) && strings.HasPrefix(tt[i].text, tok); i++ {
+		if tt[i].text == tok {
+			fn(tt[i], _scoreExact)
+		} else {
+			fn(tt[i], _scorePrefix)
+		}
+	}
+}
+
+// searchTerms returns the position of the first term of the sorted bucket
+// that is not less than the text.
+func searchTerms[T any](tt []*term[T], text string) int {
+	return sort.Search(len(tt), func(i int) bool {
+		return tt[i].text >= text
+	})
+}
+
+// collect adds the result to the best results sorted by relevance, if it
+// belongs among them. Only the best results are kept, so that short
+// queries which match a large part of the index do not require sorting
+// all of the matches. A value that is already collected keeps its best
+// score.
+func collect[T any](rr []result[T], r result[T], limit int, filter func(T) bool) []result[T] {
+	if len(rr) == limit && !r.less(rr[len(rr)-1]) {
+		return rr
+	}
+
+	for i := range rr {
+		if rr[i].entry != r.entry {
+			continue
+		}
+
+		if rr[i].score >= r.score {
+			return rr
+		}
+
+		rr = append(rr[:i], rr[i+1:]...)
+
+		break
+	}
+
+	if filter != nil && !filter(r.entry.value) {
+		return rr
+	}
+
+	i := sort.Search(len(rr), func(i int) bool {
+		return r.less(rr[i])
+	})
+
+	rr = append(rr, result[T]{})
+	copy(rr[i+1:], rr[i:])
+	rr[i] = r
+
+	if len(rr) > limit {
+		rr = rr[:limit]
+	}
+
+	return rr
+}
+
+// less checks whether the result is more relevant than the other one.
+// Results with equal scores are ordered by their shorter primary names.
+func (r result[T]) less(other result[T]) bool {
+	switch {
+	case r.score != other.score:
+		return r.score > other.score
+	case len(r.entry.name) != len(other.entry.name):
+		return len(r.entry.name) < len(other.entry.name)
+	case r.entry.name != other.entry.name:
+		return r.entry.name < other.entry.name
+	default:
+		return r.entry.id.Compare(other.entry.id) < 0
+	}
+}
+
+// scoreTerm returns the score of the term matching the token, or zero if
+// it does not match.
+func scoreTerm(tok, text string) int {
+	switch {
+	case tok == text:
+		return _scoreExact
+	case strings.HasPrefix(text, tok):
+		return _scorePrefix
+	}
+
+	qr := []rune(tok)
+
+	d := maxDistance(qr)
+	if d > 0 && distance(qr, []rune(text), d) <= d {
+		return _scoreFuzzy
+	}
+
+	return 0
+}
+
+// tokenize splits the text into lowercase terms made of letters and
+// digits.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// bucketKey returns the key of the bucket that the term belongs to.
+func bucketKey(text string) string {
+	rr := []rune(text)
+	if len(rr) > 2 {
+		rr = rr[:2]
+	}
+
+	return string(rr)
+}
+
+// maxDistance returns the edit distance that is tolerated for the token.
+// Short tokens and tokens with digits, e.g. sizes or numbers, must match
+// exactly.
+func maxDistance(tok []rune) int {
+	for _, r := range tok {
+		if unicode.IsDigit(r) {
+			return 0
+		}
+	}
+
+	switch n := len(tok); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// trigrams returns the distinct trigrams of the term padded with a
+// boundary character on both sides.
+func trigrams(text string) []string {
+	rr := []rune("\x00" + text + "\x00")
+	seen := make(map[string]bool, len(rr))
+	gg := make([]string, 0, len(rr))
+
+	for i := 0; i+3 <= len(rr); i++ {
+		g := string(rr[i : i+3])
+		if seen[g] {
+			continue
+		}
+
+		seen[g] = true
+		gg = append(gg, g)
+	}
+
+	return gg
+}
+
+// distance calculates the edit distance between two terms, where an
+// edit is an insertion, a deletion, a substitution or a transposition of
+// two adjacent characters. The calculation is stopped early once the
+// distance exceeds the maximum, in which case a value greater than the
+// maximum is returned.
+func distance(a, b []rune, max int) int {
+	if d := len(a) - len(b); d > max || -d > max {
+		return max + 1
+	}
+
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		low := curr[0]
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = minInt(curr[j], prev2[j-2]+1)
+			}
+
+			if curr[j] < low {
+				low = curr[j]
+			}
+		}
+
+		if low > max {
+			return max + 1
+		}
+
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return prev[len(b)]
+}
+
+// minInt returns the smallest of the provided integers.
+func minInt(v int, vv ...int) int {
+	for _, w := range vv {
+		if w < v {
+			v = w
+		}
+	}
+
+	return v
+}
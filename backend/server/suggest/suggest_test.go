@@ -0,0 +1,134 @@
+package suggest
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Index_Suggest(t *testing.T) {
+	ix := NewIndex[string]()
+
+	ids := make(map[string]xid.ID)
+
+	for _, name := range []string{"Chicken Breast", "Chickpeas", "Brown Rice", "Rice cake", "Banana", "Cashew Milk", "Milk 2.5%"} {
+		ids[name] = xid.New()
+		ix.Put(ids[name], name, name)
+	}
+
+	ix.Put(xid.New(), "Zucchini", "Zucchini", "Courgette")
+
+	tests := map[string]struct {
+		Query  string
+		Filter func(string) bool
+		Result []string
+	}{
+		"Empty query": {
+			Query: " ",
+		},
+		"Prefix match": {
+			Query:  "chi",
+			Result: []string{"Chickpeas", "Chicken Breast"},
+		},
+		"Exact match is ranked first": {
+			Query:  "rice",
+			Result: []string{"Rice cake", "Brown Rice"},
+		},
+		"Multiple tokens must all match": {
+			Query:  "chicken bre",
+			Result: []string{"Chicken Breast"},
+		},
+		"Misspelled token": {
+			Query:  "banan",
+			Result: []string{"Banana"},
+		},
+		"Transposed letters": {
+			Query:  "cahsew",
+			Result: []string{"Cashew Milk"},
+		},
+		"Alias match": {
+			Query:  "courg",
+			Result: []string{"Zucchini"},
+		},
+		"Short tokens must not be fuzzy": {
+			Query: "xic",
+		},
+		"Numbers must not be fuzzy": {
+			Query: "milk 3",
+		},
+		"Filtered values": {
+			Query: "chi",
+			Filter: func(v string) bool {
+				return v != "Chickpeas"
+			},
+			Result: []string{"Chicken Breast"},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.Result, ix.Suggest(test.Query, 10, test.Filter))
+		})
+	}
+}
+
+func Test_Index_Put(t *testing.T) {
+	ix := NewIndex[string]()
+	id := xid.New()
+
+	ix.Put(id, "Banana", "Banana")
+	assert.Equal(t, []string{"Banana"}, ix.Suggest("ban", 10, nil))
+
+	ix.Put(id, "Apple", "Apple")
+	assert.Empty(t, ix.Suggest("ban", 10, nil))
+	assert.Equal(t, []string{"Apple"}, ix.Suggest("app", 10, nil))
+	assert.Equal(t, 1, ix.Len())
+
+	ix.Delete(id)
+	assert.Empty(t, ix.Suggest("app", 10, nil))
+	assert.Empty(t, ix.buckets)
+	assert.Empty(t, ix.grams)
+	assert.Equal(t, 0, ix.Len())
+}
+
+func Test_distance(t *testing.T) {
+	assert.Equal(t, 0, distance([]rune("oats"), []rune("oats"), 2))
+	assert.Equal(t, 1, distance([]rune("oats"), []rune("oat"), 2))
+	assert.Equal(t, 1, distance([]rune("cahsew"), []rune("cashew"), 2))
+	assert.Equal(t, 3, distance([]rune("kitten"), []rune("sitting"), 3))
+	assert.Equal(t, 2, distance([]rune("abc"), []rune("xyz"), 1))
+}
+
+func Benchmark_Index_Suggest(b *testing.B) {
+	ix := NewIndex[int]()
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 100000; i++ {
+		ix.Put(xid.New(), i, fmt.Sprintf("%s %s %d", randomWord(rnd), randomWord(rnd), i))
+	}
+
+	queries := []string{"a", "chi", "banan", "qwertyui", "ab cd"}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		ix.Suggest(queries[i%len(queries)], 10, nil)
+	}
+}
+
+// randomWord generates a random lowercase word.
+func randomWord(rnd *rand.Rand) string {
+	rr := make([]rune, 4+rnd.Intn(6))
+	for i := range rr {
+		rr[i] = rune('a' + rnd.Intn(26))
+	}
+
+	return string(rr)
+}
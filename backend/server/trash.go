@@ -0,0 +1,145 @@
+package server
+
+import (
+	"foodie/core"
+	"foodie/db"
+	"foodie/server/apierr"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	// _purgeInterval specifies how often the trash is purged.
+	_purgeInterval = time.Hour
+)
+
+// GetTrash retrieves a page of products, recipes and plans that were moved
+// to the trash. Admins retrieve all deleted objects, while other users
+// retrieve only their own. All three kinds of objects share a single list
+// order, so that a single cursor is able to point into it.
+func (s *Server) GetTrash(w http.ResponseWriter, r *http.Request) {
+	pg, aerr := s.extractPage(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	vw := s.extractViewer(r)
+
+	pp, pnext, err := db.GetDeletedProducts(r.Context(), s.db, vw, pg)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("fetching deleted products")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	rr, rnext, err := db.GetDeletedRecipes(r.Context(), s.db, vw, pg)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("fetching deleted recipes")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	pls, plnext, err := db.GetDeletedPlans(r.Context(), s.db, vw, pg)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("fetching deleted plans")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	cc := make([]core.Cursor, 0, len(pp)+len(rr)+len(pls))
+
+	for _, prd := range pp {
+		cc = append(cc, prd.Cursor())
+	}
+
+	for _, rec := range rr {
+		cc = append(cc, rec.Cursor())
+	}
+
+	for _, pl := range pls {
+		cc = append(cc, pl.Cursor())
+	}
+
+	sort.Slice(cc, func(i, j int) bool {
+		return cc[i].Before(cc[j])
+	})
+
+	var next *core.Cursor
+
+	if uint64(len(cc)) > pg.Limit || pnext != nil || rnext != nil || plnext != nil {
+		last := cc[len(cc)-1]
+		if uint64(len(cc)) > pg.Limit {
+			last = cc[pg.Limit-1]
+		}
+
+		next = &last
+
+		pp = trimTrash(pp, last)
+		rr = trimTrash(rr, last)
+		pls = trimTrash(pls, last)
+	}
+
+	s.respondPage(w, struct {
+		Products []core.Product `json:"products"`
+		Recipes  []core.Recipe  `json:"recipes"`
+		Plans    []core.Plan    `json:"plans"`
+	}{
+		Products: pp,
+		Recipes:  rr,
+		Plans:    pls,
+	}, next)
+}
+
+// trimTrash drops the deleted objects that are positioned after the
+// provided cursor.
+func trimTrash[T interface{ Cursor() core.Cursor }](oo []T, last core.Cursor) []T {
+	for i, obj := range oo {
+		if last.Before(obj.Cursor()) {
+			return oo[:i]
+		}
+	}
+
+	return oo
+}
+
+// purgeTrash periodically deletes objects that were kept in the trash
+// longer than the retention period. It blocks until the server is stopped.
+func (s *Server) purgeTrash() {
+	ticker := time.NewTicker(_purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := db.PurgeDeleted(s.ctx, s.db, time.Now().Add(-s.retention)); err != nil && s.ctx.Err() == nil {
+			s.log.WithError(err).Error("purging trash")
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
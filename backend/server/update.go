@@ -0,0 +1,34 @@
+package server
+
+import (
+	"encoding/json"
+	"foodie/server/apierr"
+	"foodie/server/mergepatch"
+	"net/http"
+)
+
+// decodeCore decodes the request body of an update of the object core. PUT
+// requests replace the current core completely, while PATCH requests are
+// applied to it as JSON merge patches, so that omitted attributes keep
+// their current values.
+func decodeCore[T any](r *http.Request, data []byte, cur T) (T, *apierr.Error) {
+	var res T
+
+	if r.Method == http.MethodPatch {
+		doc, err := json.Marshal(cur)
+		if err != nil {
+			return res, apierr.Internal()
+		}
+
+		data, err = mergepatch.Apply(doc, data)
+		if err != nil {
+			return res, apierr.MalformedDataInput(apierr.DataTypeJSON)
+		}
+	}
+
+	if err := json.Unmarshal(data, &res); err != nil {
+		return res, apierr.MalformedDataInput(apierr.DataTypeJSON)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"foodie/core"
+	"foodie/db"
+	"foodie/server/events"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_decodeCore(t *testing.T) {
+	cur := core.PlanCore{
+		Name:        "1",
+		Description: "2",
+		Visibility:  core.VisibilityPublic,
+		State:       core.StatePublished,
+		Recipes: []core.PlanRecipe{
+			{Quantity: 3},
+		},
+	}
+
+	tests := map[string]struct {
+		Method string
+		Body   string
+		Result core.PlanCore
+		Error  bool
+	}{
+		"Patch keeps omitted attributes": {
+			Method: http.MethodPatch,
+			Body:   `{"name":"3"}`,
+			Result: core.PlanCore{
+				Name:        "3",
+				Description: "2",
+				Visibility:  core.VisibilityPublic,
+				State:       core.StatePublished,
+				Recipes:     cur.Recipes,
+			},
+		},
+		"Patch resets null attributes": {
+			Method: http.MethodPatch,
+			Body:   `{"description":null,"recipes":[]}`,
+			Result: core.PlanCore{
+				Name:       "1",
+				Visibility: core.VisibilityPublic,
+				State:      core.StatePublished,
+				Recipes:    []core.PlanRecipe{},
+			},
+		},
+		"Put replaces all attributes": {
+			Method: http.MethodPut,
+			Body:   `{"name":"3"}`,
+			Result: core.PlanCore{
+				Name: "3",
+			},
+		},
+		"Malformed patch": {
+			Method: http.MethodPatch,
+			Body:   `{"name":`,
+			Error:  true,
+		},
+		"Patch of another type": {
+			Method: http.MethodPatch,
+			Body:   `["name"]`,
+			Error:  true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(test.Method, "/", nil)
+
+			res, aerr := decodeCore(req, []byte(test.Body), cur)
+			if test.Error {
+				assert.NotNil(t, aerr)
+				return
+			}
+
+			require.Nil(t, aerr)
+			assert.Equal(t, test.Result, res)
+		})
+	}
+}
+
+func Test_Server_PatchSubresources(t *testing.T) {
+	dbh := _dbFn(t)
+	cleanUpTables(t, dbh)
+
+	rec, _ := insertSharedRecipe(t, dbh)
+
+	pl, err := db.InsertPlan(context.Background(), dbh, rec.UserID, core.PlanCore{
+		Name:        "Week",
+		Description: "Meals of the week.",
+		Visibility:  core.VisibilityPrivate,
+		State:       core.StateDraft,
+		Recipes: []core.PlanRecipe{{
+			RecipeID: rec.ID,
+			Quantity: 1,
+			Day:      "2026-10-19",
+		}},
+	})
+	require.NoError(t, err)
+
+	s := &Server{
+		log:    logrus.New(),
+		db:     dbh,
+		events: events.NewBroker(1),
+		ctx:    context.Background(),
+	}
+
+	patch := func(h http.HandlerFunc, params map[string]string, body string) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		for k, v := range params {
+			rctx.URLParams.Add(k, v)
+		}
+
+		ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+		ctx = withViewer(ctx, rec.UserID, false)
+
+		req := httptest.NewRequest(http.MethodPatch, "http://test.com/", strings.NewReader(body))
+		req = req.WithContext(ctx)
+		resp := httptest.NewRecorder()
+
+		h(resp, req)
+
+		return resp
+	}
+
+	t.Run("Recipe product keeps omitted quantity", func(t *testing.T) {
+		resp := patch(s.UpdateRecipeProduct, map[string]string{
+			"recipeID":  rec.ID.String(),
+			"productID": rec.Products[0].ProductID.String(),
+		}, `{}`)
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var res core.Recipe
+
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &res))
+		require.Len(t, res.Products, 2)
+
+		for _, rp := range res.Products {
+			assert.True(t, rp.Quantity.Equal(decimal.NewFromInt(1)))
+		}
+	})
+
+	t.Run("Plan recipe keeps omitted day", func(t *testing.T) {
+		resp := patch(s.UpdatePlanRecipe, map[string]string{
+			"planID":   pl.ID.String(),
+			"recipeID": rec.ID.String(),
+		}, `{"quantity":2}`)
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var res core.Plan
+
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &res))
+		require.Len(t, res.Recipes, 1)
+		assert.Equal(t, uint64(2), res.Recipes[0].Quantity)
+		assert.Equal(t, "2026-10-19", res.Recipes[0].Day)
+	})
+}
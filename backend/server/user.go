@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"foodie/core"
 	"foodie/db"
@@ -37,7 +38,7 @@ func (s *Server) CreateAdminUser(w http.ResponseWriter, r *http.Request) {
 	case db.ErrNotFound:
 		// OK.
 	case nil:
-		apierr.Conflict("user").Respond(w)
+		apierr.Conflict("user", "user already exists").Respond(w)
 		return
 	case r.Context().Err():
 		apierr.Context().Respond(w)
@@ -77,6 +78,7 @@ func (s *Server) CreateAdminUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.emitUser(core.EventUserCreated, *usr)
 	s.respondJSON(w, usr)
 }
 
@@ -108,9 +110,21 @@ func (s *Server) Self(w http.ResponseWriter, r *http.Request) {
 	s.respondJSON(w, usr)
 }
 
-// GetUsers retrieves all users.
+// GetUsers retrieves a page of users.
 func (s *Server) GetUsers(w http.ResponseWriter, r *http.Request) {
-	uu, err := db.GetUsers(r.Context(), s.db)
+	pg, aerr := s.extractPage(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	q, aerr := core.ParseQuery(r.URL.Query(), core.UserFields)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	uu, next, err := db.GetUsers(r.Context(), s.db, q, pg)
 	switch err {
 	case nil:
 		// OK.
@@ -124,7 +138,7 @@ func (s *Server) GetUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.respondJSON(w, uu)
+	s.respondPage(w, uu, next)
 }
 
 // GetUser retrieves user by the user id.
@@ -234,6 +248,7 @@ func (s *Server) UpdateUserPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.emitUser(core.EventUserUpdated, *user)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -267,41 +282,8 @@ func (s *Server) DeleteUser(super bool) func(http.ResponseWriter, *http.Request)
 			return
 		}
 
-		if adm {
-			usr, err := db.GetUserByID(r.Context(), s.db, uid)
-			switch err {
-			case nil:
-				// OK.
-			case r.Context().Err():
-				apierr.Context().Respond(w)
-				return
-			case db.ErrNotFound:
-				apierr.NotFound("user").Respond(w)
-				return
-			default:
-				s.log.WithError(err).Error("fetching user by id")
-				apierr.Database().Respond(w)
-
-				return
-			}
-
-			if usr.Name == core.RootAdminName {
-				apierr.BadRequest("cannot delete root admin").Respond(w)
-				return
-			}
-		}
-
-		err := db.DeleteUserByID(r.Context(), s.db, uid)
-		switch err {
-		case nil:
-			// OK.
-		case r.Context().Err():
-			apierr.Context().Respond(w)
-			return
-		default:
-			s.log.WithError(err).Error("creating a new user")
-			apierr.Database().Respond(w)
-
+		if aerr := s.deleteUser(r.Context(), adm, uid); aerr != nil {
+			aerr.Respond(w)
 			return
 		}
 
@@ -309,6 +291,51 @@ func (s *Server) DeleteUser(super bool) func(http.ResponseWriter, *http.Request)
 	}
 }
 
+// deleteUser deletes the user. If the request is made by an admin, the
+// root admin is refused to be deleted.
+func (s *Server) deleteUser(ctx context.Context, adm bool, uid xid.ID) *apierr.Error {
+	usr, err := db.GetUserByID(ctx, s.db, uid)
+	switch err {
+	case nil:
+		// OK.
+	case ctx.Err():
+		return apierr.Context()
+	case db.ErrNotFound:
+		return apierr.NotFound("user")
+	default:
+		s.log.WithError(err).Error("fetching user by id")
+		return apierr.Database()
+	}
+
+	if adm && usr.Name == core.RootAdminName {
+		return apierr.BadRequest("cannot delete root admin")
+	}
+
+	err = db.DeleteUserByID(ctx, s.db, uid)
+	switch err {
+	case nil:
+		// OK.
+	case ctx.Err():
+		return apierr.Context()
+	default:
+		s.log.WithError(err).Error("deleting user by id")
+		return apierr.Database()
+	}
+
+	s.emitUser(core.EventUserDeleted, *usr)
+
+	return nil
+}
+
+// session contains the authenticated user and its access token.
+type session struct {
+	// User specifies the authenticated user.
+	User *core.User `json:"user"`
+
+	// AccessToken contains the authorization token of the user.
+	AccessToken string `json:"access_token"`
+}
+
 // Login authenticates the user by its credentials and returns a JWT token.
 func (s *Server) Login(w http.ResponseWriter, r *http.Request) {
 	data, err := io.ReadAll(r.Body)
@@ -323,18 +350,27 @@ func (s *Server) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	usr, err := db.GetUserByName(r.Context(), s.db, ui.Name)
+	ses, aerr := s.login(r.Context(), ui)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	s.respondJSON(w, ses)
+}
+
+// login authenticates the user by its credentials and starts a new
+// session.
+func (s *Server) login(ctx context.Context, ui core.UserInput) (*session, *apierr.Error) {
+	usr, err := db.GetUserByName(ctx, s.db, ui.Name)
 	switch err {
 	case nil:
 		// OK.
-	case r.Context().Err(), db.ErrNotFound:
-		apierr.Unauthorized().Respond(w)
-		return
+	case ctx.Err(), db.ErrNotFound:
+		return nil, apierr.Unauthorized()
 	default:
 		s.log.WithError(err).Error("fetching user by name")
-		apierr.Database().Respond(w)
-
-		return
+		return nil, apierr.Database()
 	}
 
 	err = bcrypt.CompareHashAndPassword(usr.PasswordHash, []byte(ui.Password))
@@ -342,30 +378,13 @@ func (s *Server) Login(w http.ResponseWriter, r *http.Request) {
 	case nil:
 		// OK.
 	case bcrypt.ErrMismatchedHashAndPassword:
-		apierr.BadRequest("invalid password").Respond(w)
-		return
+		return nil, apierr.BadRequest("invalid password")
 	default:
 		s.log.WithError(err).Error("comparing bcrypt hash to a password")
-		apierr.Internal().Respond(w)
-
-		return
+		return nil, apierr.Internal()
 	}
 
-	token, err := s.auth.Issue(usr.ID, usr.Admin, time.Now())
-	if err != nil {
-		s.log.WithError(err).Error("creating a token")
-		apierr.Internal().Respond(w)
-
-		return
-	}
-
-	s.respondJSON(w, struct {
-		User        *core.User `json:"user"`
-		AccessToken string     `json:"access_token"`
-	}{
-		User:        usr,
-		AccessToken: string(token),
-	})
+	return s.newSession(usr)
 }
 
 // Register creates a new user by its credentials and retrieves a JWT token.
@@ -382,69 +401,67 @@ func (s *Server) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if aerr := ui.Validate(); aerr != nil {
+	ses, aerr := s.register(r.Context(), ui)
+	if aerr != nil {
 		aerr.Respond(w)
 		return
 	}
 
-	_, err = db.GetUserByName(r.Context(), s.db, ui.Name)
+	s.respondJSON(w, ses)
+}
+
+// register creates a new regular user by its credentials and starts a new
+// session.
+func (s *Server) register(ctx context.Context, ui core.UserInput) (*session, *apierr.Error) {
+	if aerr := ui.Validate(); aerr != nil {
+		return nil, aerr
+	}
+
+	_, err := db.GetUserByName(ctx, s.db, ui.Name)
 	switch err {
 	case db.ErrNotFound:
 		// OK.
 	case nil:
-		apierr.Conflict("user").Respond(w)
-		return
-	case r.Context().Err():
-		apierr.Context().Respond(w)
-		return
+		return nil, apierr.Conflict("user", "user already exists")
+	case ctx.Err():
+		return nil, apierr.Context()
 	default:
 		s.log.WithError(err).Error("fetching user by name")
-		apierr.Database().Respond(w)
-
-		return
+		return nil, apierr.Database()
 	}
 
 	ph, err := bcrypt.GenerateFromPassword([]byte(ui.Password), bcrypt.DefaultCost)
 	if err != nil {
 		s.log.WithError(err).Error("generating bcrypt password hash")
-		apierr.Internal().Respond(w)
-
-		return
+		return nil, apierr.Internal()
 	}
 
-	usr, err := db.InsertUser(
-		r.Context(),
-		s.db,
-		ui.Name,
-		ph,
-		false,
-	)
+	usr, err := db.InsertUser(ctx, s.db, ui.Name, ph, false)
 	switch err {
 	case nil:
 		// OK.
-	case r.Context().Err():
-		apierr.Context().Respond(w)
-		return
+	case ctx.Err():
+		return nil, apierr.Context()
 	default:
 		s.log.WithError(err).Error("creating a new user")
-		apierr.Database().Respond(w)
-
-		return
+		return nil, apierr.Database()
 	}
 
+	s.emitUser(core.EventUserCreated, *usr)
+
+	return s.newSession(usr)
+}
+
+// newSession issues a new access token of the user.
+func (s *Server) newSession(usr *core.User) (*session, *apierr.Error) {
 	token, err := s.auth.Issue(usr.ID, usr.Admin, time.Now())
 	if err != nil {
 		s.log.WithError(err).Error("creating a token")
-		apierr.Internal().Respond(w)
-
-		return
+		return nil, apierr.Internal()
 	}
 
-	s.respondJSON(w, struct {
-		User        *core.User `json:"user"`
-		AccessToken string     `json:"access_token"`
-	}{
+	return &session{
 		User:        usr,
 		AccessToken: string(token),
-	})
+	}, nil
 }
@@ -2,7 +2,9 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"foodie/core"
+	"foodie/server/events"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -95,9 +97,11 @@ func Test_Server_Register(t *testing.T) {
 			resp := httptest.NewRecorder()
 
 			server := &Server{
-				log:  logrus.New(),
-				db:   dbh,
-				auth: test.Auth,
+				log:    logrus.New(),
+				db:     dbh,
+				auth:   test.Auth,
+				events: events.NewBroker(1),
+				ctx:    context.Background(),
 			}
 
 			server.Register(resp, req)
@@ -0,0 +1,466 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"foodie/core"
+	"foodie/db"
+	"foodie/server/apierr"
+	"foodie/server/webhook"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+const (
+	// _webhookInterval specifies how often the dispatcher checks for due
+	// deliveries when it is not woken up by new events.
+	_webhookInterval = 10 * time.Second
+
+	// _webhookLease specifies how long a claimed delivery is reserved for
+	// the dispatcher. Deliveries that were not finished, e.g. because the
+	// server was stopped, are attempted again once it expires. The
+	// deliveries of a batch are attempted concurrently, so the lease only
+	// has to outlast a single attempt.
+	_webhookLease = 2 * time.Minute
+
+	// _webhookTimeout specifies how long a single delivery attempt may
+	// take.
+	_webhookTimeout = 15 * time.Second
+
+	// _webhookBatchSize specifies how many deliveries are claimed at once.
+	_webhookBatchSize = 20
+
+	// _webhookMaxAttempts specifies how many times a delivery is attempted
+	// before it is marked as failed.
+	_webhookMaxAttempts = 10
+
+	// _maxWebhookErrorLength specifies the maximum length of stored
+	// delivery errors. It matches the size of the error column.
+	_maxWebhookErrorLength = 1023
+)
+
+// createdWebhook is the webhook that was just created together with its
+// secret, which is not returned afterwards.
+type createdWebhook struct {
+	*core.Webhook
+
+	// Secret contains the key that deliveries are signed with.
+	Secret string `json:"secret"`
+}
+
+// CreateWebhook creates a webhook of the user. The signing secret is
+// returned only once, when the webhook is created.
+func (s *Server) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	uid, aerr := s.extractContextUserID(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		apierr.MalformedDataInput(apierr.DataTypeRequestBody).Respond(w)
+		return
+	}
+
+	wc := core.WebhookCore{
+		Active: true,
+	}
+
+	if err := json.Unmarshal(data, &wc); err != nil {
+		apierr.MalformedDataInput(apierr.DataTypeJSON).Respond(w)
+		return
+	}
+
+	if aerr := wc.Validate(); aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	secret, err := core.NewWebhookSecret()
+	if err != nil {
+		s.log.WithError(err).Error("generating webhook secret")
+		apierr.Internal().Respond(w)
+
+		return
+	}
+
+	wh, err := db.InsertWebhook(r.Context(), s.db, uid, secret, wc)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("inserting a webhook")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	s.respondJSON(w, createdWebhook{
+		Webhook: wh,
+		Secret:  wh.Secret,
+	})
+}
+
+// GetWebhooks retrieves a page of webhooks of the user.
+func (s *Server) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	uid, aerr := s.extractContextUserID(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	pg, aerr := s.extractPage(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	ww, next, err := db.GetWebhooksByUserID(r.Context(), s.db, uid, pg)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("fetching webhooks")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	s.respondPage(w, ww, next)
+}
+
+// GetWebhook retrieves a single webhook by its id. The webhook can be
+// retrieved only by an admin or the user that created it.
+func (s *Server) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	wh, aerr := s.fetchOwnWebhook(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	s.respondJSON(w, wh)
+}
+
+// UpdateWebhook replaces the url, events and the active flag of the
+// webhook. The webhook can be updated only by an admin or the user that
+// created it.
+func (s *Server) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	wh, aerr := s.fetchOwnWebhook(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		apierr.MalformedDataInput(apierr.DataTypeRequestBody).Respond(w)
+		return
+	}
+
+	wc := wh.WebhookCore
+	if err := json.Unmarshal(data, &wc); err != nil {
+		apierr.MalformedDataInput(apierr.DataTypeJSON).Respond(w)
+		return
+	}
+
+	if aerr := wc.Validate(); aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	err = db.UpdateWebhookByID(r.Context(), s.db, wh.ID, wc)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("updating webhook")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	wh.WebhookCore = wc
+	s.respondJSON(w, wh)
+}
+
+// DeleteWebhook deletes the webhook together with its delivery log. The
+// webhook can be deleted only by an admin or the user that created it.
+func (s *Server) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	wh, aerr := s.fetchOwnWebhook(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	err := db.DeleteWebhookByID(r.Context(), s.db, wh.ID)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("deleting webhook by id")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries retrieves a page of the delivery log of the
+// webhook, from the oldest delivery to the newest.
+func (s *Server) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	wh, aerr := s.fetchOwnWebhook(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	pg, aerr := s.extractPage(r)
+	if aerr != nil {
+		aerr.Respond(w)
+		return
+	}
+
+	dd, next, err := db.GetWebhookDeliveries(r.Context(), s.db, wh.ID, pg)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		apierr.Context().Respond(w)
+		return
+	default:
+		s.log.WithError(err).Error("fetching webhook deliveries")
+		apierr.Database().Respond(w)
+
+		return
+	}
+
+	s.respondPage(w, dd, next)
+}
+
+// fetchOwnWebhook retrieves the webhook specified in the path. Webhooks of
+// other users are reported as not found, unless the viewer is an admin.
+func (s *Server) fetchOwnWebhook(r *http.Request) (*core.Webhook, *apierr.Error) {
+	wid, aerr := s.extractPathID(r, "webhookID")
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	wh, err := db.GetWebhookByID(r.Context(), s.db, wid)
+	switch err {
+	case nil:
+		// OK.
+	case r.Context().Err():
+		return nil, apierr.Context()
+	case db.ErrNotFound:
+		return nil, apierr.NotFound("webhook")
+	default:
+		s.log.WithError(err).Error("fetching webhook by id")
+		return nil, apierr.Database()
+	}
+
+	vw := s.extractViewer(r)
+	if !vw.Admin && wh.UserID != vw.UserID {
+		return nil, apierr.NotFound("webhook")
+	}
+
+	return wh, nil
+}
+
+// storeWebhookDeliveries stores a pending delivery of the event payload
+// for every active webhook that is subscribed to it and whose owner is
+// allowed to see the object, and wakes up the dispatcher. The deliveries
+// are stored within the server context, so that they are not lost when
+// the request that caused the event is finished. Failures are only
+// logged, since the change itself has already been made.
+func (s *Server) storeWebhookDeliveries(et core.EventType, visible func(core.Viewer) bool, payload []byte) {
+	ww, err := db.GetWebhooksByEvent(s.ctx, s.db, et)
+	if err != nil {
+		s.log.WithError(err).Error("fetching webhooks by event")
+		return
+	}
+
+	now := time.Now()
+	dd := make([]core.WebhookDelivery, 0, len(ww))
+
+	for _, wh := range ww {
+		if !visible(wh.Viewer()) {
+			continue
+		}
+
+		dd = append(dd, core.WebhookDelivery{
+			ID:            xid.New(),
+			WebhookID:     wh.ID,
+			Event:         et,
+			Payload:       payload,
+			Status:        core.DeliveryStatusPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+		})
+	}
+
+	if len(dd) == 0 {
+		return
+	}
+
+	if err := db.InsertWebhookDeliveries(s.ctx, s.db, dd...); err != nil {
+		s.log.WithError(err).Error("inserting webhook deliveries")
+		return
+	}
+
+	select {
+	case s.webhookWake <- struct{}{}:
+	default:
+		// The dispatcher is already going to check for due deliveries.
+	}
+}
+
+// dispatchWebhooks attempts the due webhook deliveries whenever new events
+// are emitted and periodically, so that retries and deliveries left over
+// from previous runs of the server are picked up. It blocks until the
+// server context is cancelled.
+func (s *Server) dispatchWebhooks() {
+	ticker := time.NewTicker(_webhookInterval)
+	defer ticker.Stop()
+
+	for {
+		for s.dispatchWebhookBatch() {
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.webhookWake:
+		}
+	}
+}
+
+// dispatchWebhookBatch claims and attempts a single batch of due
+// deliveries. The deliveries are attempted concurrently, so that a slow
+// webhook does not hold up the others and every attempt finishes well
+// within the lease. It reports whether a full batch was claimed, i.e.
+// whether more deliveries might be due.
+func (s *Server) dispatchWebhookBatch() bool {
+	dd, err := db.ClaimWebhookDeliveries(s.ctx, s.db, time.Now(), _webhookLease, _webhookBatchSize)
+	if err != nil {
+		if s.ctx.Err() == nil {
+			s.log.WithError(err).Error("claiming webhook deliveries")
+		}
+
+		return false
+	}
+
+	var wg sync.WaitGroup
+
+	defer wg.Wait()
+
+	webhooks := make(map[xid.ID]*core.Webhook)
+
+	for _, wd := range dd {
+		wh, ok := webhooks[wd.WebhookID]
+		if !ok {
+			wh, err = db.GetWebhookByID(s.ctx, s.db, wd.WebhookID)
+			switch err {
+			case nil:
+				// OK.
+			case db.ErrNotFound:
+				// The webhook was deleted together with its deliveries.
+				continue
+			default:
+				if s.ctx.Err() == nil {
+					s.log.WithError(err).Error("fetching webhook by id")
+				}
+
+				return false
+			}
+
+			webhooks[wd.WebhookID] = wh
+		}
+
+		wg.Add(1)
+
+		go func(wh core.Webhook, wd core.WebhookDelivery) {
+			defer wg.Done()
+			s.attemptWebhookDelivery(wh, wd)
+		}(*wh, wd)
+	}
+
+	return len(dd) == _webhookBatchSize
+}
+
+// attemptWebhookDelivery sends the delivery to the webhook and stores the
+// outcome. Failed deliveries are retried with an exponential backoff
+// until the maximum number of attempts is reached. Deliveries of inactive
+// webhooks fail without being sent.
+func (s *Server) attemptWebhookDelivery(wh core.Webhook, wd core.WebhookDelivery) {
+	wd.Attempts++
+	wd.NextAttemptAt = nil
+	wd.ResponseStatus = 0
+	wd.Error = ""
+
+	var err error
+
+	if wh.Active {
+		ctx, cancel := context.WithTimeout(s.ctx, _webhookTimeout)
+		defer cancel()
+
+		wd.ResponseStatus, err = webhook.Send(ctx, s.webhookClient, webhook.Delivery{
+			ID:     wd.ID.String(),
+			Event:  string(wd.Event),
+			URL:    wh.URL,
+			Secret: []byte(wh.Secret),
+			Body:   wd.Payload,
+		}, time.Now())
+	} else {
+		err = errors.New("webhook is inactive")
+	}
+
+	switch {
+	case s.ctx.Err() != nil:
+		// The server is being stopped, the delivery is attempted again
+		// once its lease expires.
+		return
+	case err == nil:
+		wd.Status = core.DeliveryStatusSucceeded
+	case !wh.Active || wd.Attempts >= _webhookMaxAttempts:
+		wd.Status = core.DeliveryStatusFailed
+		wd.Error = truncate(err.Error(), _maxWebhookErrorLength)
+	default:
+		next := time.Now().Add(webhook.Backoff(wd.Attempts))
+		wd.NextAttemptAt = &next
+		wd.Error = truncate(err.Error(), _maxWebhookErrorLength)
+	}
+
+	if err := db.UpdateWebhookDelivery(s.ctx, s.db, wd); err != nil && s.ctx.Err() == nil {
+		s.log.WithError(err).Error("updating webhook delivery")
+	}
+}
+
+// truncate shortens the text to the maximum number of characters.
+func truncate(v string, max int) string {
+	rr := []rune(v)
+	if len(rr) <= max {
+		return v
+	}
+
+	return string(rr[:max])
+}
@@ -0,0 +1,289 @@
+// Package webhook signs and sends webhook deliveries. Deliveries are signed
+// with HMAC-SHA256, so that receivers could check that they were sent by
+// the server and were not replayed.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// HeaderSignature is the header that contains the signature of the
+	// delivery.
+	HeaderSignature = "X-Foodie-Signature"
+
+	// HeaderEvent is the header that contains the event type of the
+	// delivery.
+	HeaderEvent = "X-Foodie-Event"
+
+	// HeaderDelivery is the header that contains the id of the delivery,
+	// which stays the same when the delivery is retried.
+	HeaderDelivery = "X-Foodie-Delivery"
+)
+
+const (
+	// _minBackoff specifies the delay before the second attempt of a
+	// delivery.
+	_minBackoff = 30 * time.Second
+
+	// _maxBackoff specifies the maximum delay between attempts of a
+	// delivery.
+	_maxBackoff = 6 * time.Hour
+
+	// _maxResponseSize specifies how much of the response body is read,
+	// so that the connection could be reused.
+	_maxResponseSize = 64 << 10
+)
+
+var (
+	// ErrInvalidSignature is returned when the signature header is
+	// malformed or does not match the body.
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrExpiredSignature is returned when the signature was created
+	// outside of the allowed tolerance.
+	ErrExpiredSignature = errors.New("expired signature")
+
+	// ErrForbiddenAddress is returned when a delivery would be sent to an
+	// address that is not public, e.g. a loopback, private, shared,
+	// link-local or unspecified one.
+	ErrForbiddenAddress = errors.New("forbidden address")
+)
+
+var (
+	// _forbiddenPrefixes contains the special-purpose IPv4 ranges that are
+	// not reported by the netip.Addr methods: "this network", the shared
+	// address space of carrier-grade NATs, IETF protocol assignments,
+	// benchmarking and reserved ranges.
+	_forbiddenPrefixes = []netip.Prefix{
+		netip.MustParsePrefix("0.0.0.0/8"),
+		netip.MustParsePrefix("100.64.0.0/10"),
+		netip.MustParsePrefix("192.0.0.0/24"),
+		netip.MustParsePrefix("198.18.0.0/15"),
+		netip.MustParsePrefix("240.0.0.0/4"),
+	}
+
+	// _embeddingPrefixes contains the IPv6 ranges whose addresses embed
+	// an IPv4 address in their last 32 bits: IPv4-compatible and NAT64
+	// addresses.
+	_embeddingPrefixes = []netip.Prefix{
+		netip.MustParsePrefix("::/96"),
+		netip.MustParsePrefix("64:ff9b::/96"),
+	}
+)
+
+// Sign returns the value of the signature header of the body sent at the
+// provided time. The value has the "t=<unix time>,v1=<hex HMAC>" format,
+// where the HMAC is calculated over "<unix time>.<body>".
+func Sign(secret []byte, ts time.Time, body []byte) string {
+	unix := strconv.FormatInt(ts.Unix(), 10)
+
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac(secret, unix, body))
+}
+
+// Verify checks whether the signature header matches the body and was
+// created no longer than the tolerance ago. The tolerance check is
+// skipped if it is zero.
+func Verify(secret []byte, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var unix, sig string
+
+	for _, part := range strings.Split(header, ",") {
+		key, val, _ := strings.Cut(part, "=")
+
+		switch key {
+		case "t":
+			unix = val
+		case "v1":
+			sig = val
+		}
+	}
+
+	sec, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	data, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(data, mac(secret, unix, body)) {
+		return ErrInvalidSignature
+	}
+
+	if tolerance > 0 && now.Sub(time.Unix(sec, 0)).Abs() > tolerance {
+		return ErrExpiredSignature
+	}
+
+	return nil
+}
+
+// mac calculates the HMAC of the signed payload.
+func mac(secret []byte, unix string, body []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(unix))
+	h.Write([]byte{'.'})
+	h.Write(body)
+
+	return h.Sum(nil)
+}
+
+// Backoff returns the delay before the next attempt of a delivery that
+// has failed the provided number of times. The delay doubles after every
+// attempt, up to a limit.
+func Backoff(attempts uint) time.Duration {
+	d := _minBackoff
+
+	for i := uint(1); i < attempts; i++ {
+		d *= 2
+		if d >= _maxBackoff {
+			return _maxBackoff
+		}
+	}
+
+	return d
+}
+
+// Delivery is a single signed request to a webhook.
+type Delivery struct {
+	// ID specifies the id of the delivery.
+	ID string
+
+	// Event specifies the event type of the delivery.
+	Event string
+
+	// URL specifies the address of the webhook.
+	URL string
+
+	// Secret specifies the key that the delivery is signed with.
+	Secret []byte
+
+	// Body contains the JSON body of the delivery.
+	Body []byte
+}
+
+// NewClient creates a client that sends deliveries with the provided
+// timeout. The client refuses to connect to addresses that are not
+// public, so that webhooks could not be used to reach internal services.
+// The resolved address of every connection is checked, which also covers
+// host names that resolve to internal addresses. Redirects are not
+// followed.
+func NewClient(timeout time.Duration) *http.Client {
+	return newClient(timeout, checkAddress)
+}
+
+// newClient creates a client that checks the addresses of its connections
+// with the provided control function, if it is not nil.
+func newClient(timeout time.Duration, control func(string, string, syscall.RawConn) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: control,
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// Proxies are not used, since the address of the webhook
+			// could not be checked otherwise.
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkAddress refuses connections to addresses that are not public. It
+// is called with the resolved address right before connecting.
+func checkAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	if !isPublic(ip) {
+		return fmt.Errorf("%w %s", ErrForbiddenAddress, ip)
+	}
+
+	return nil
+}
+
+// isPublic checks whether the address is publicly routable. IPv4 addresses
+// embedded in IPv6 ones are checked as IPv4 addresses, so that blocked
+// addresses could not be reached through their mapped forms.
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	for _, p := range _embeddingPrefixes {
+		if p.Contains(ip) {
+			b := ip.As16()
+			ip = netip.AddrFrom4([4]byte{b[12], b[13], b[14], b[15]})
+
+			break
+		}
+	}
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, p := range _forbiddenPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Send posts the signed delivery to the webhook. The response status code
+// is returned if a response was received. Responses with non-2xx status
+// codes, including redirects, are reported as errors.
+func Send(ctx context.Context, client *http.Client, d Delivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, d.ID)
+	req.Header.Set(HeaderSignature, Sign(d.Secret, now, d.Body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, _maxResponseSize)) //nolint:errcheck // the body is irrelevant.
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Sign_Verify(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"event":"recipe.created"}`)
+	ts := time.Unix(1666000000, 0)
+
+	sig := Sign(secret, ts, body)
+	assert.Regexp(t, `^t=1666000000,v1=[0-9a-f]{64}$`, sig)
+
+	tests := map[string]struct {
+		Secret    []byte
+		Header    string
+		Body      []byte
+		Now       time.Time
+		Tolerance time.Duration
+		Error     error
+	}{
+		"Malformed header": {
+			Secret: secret,
+			Header: "v1=123",
+			Body:   body,
+			Error:  ErrInvalidSignature,
+		},
+		"Different secret": {
+			Secret: []byte("other"),
+			Header: sig,
+			Body:   body,
+			Error:  ErrInvalidSignature,
+		},
+		"Different body": {
+			Secret: secret,
+			Header: sig,
+			Body:   []byte(`{}`),
+			Error:  ErrInvalidSignature,
+		},
+		"Expired signature": {
+			Secret:    secret,
+			Header:    sig,
+			Body:      body,
+			Now:       ts.Add(10 * time.Minute),
+			Tolerance: 5 * time.Minute,
+			Error:     ErrExpiredSignature,
+		},
+		"Successful verification": {
+			Secret:    secret,
+			Header:    sig,
+			Body:      body,
+			Now:       ts.Add(time.Minute),
+			Tolerance: 5 * time.Minute,
+		},
+	}
+
+	for tName, tCase := range tests {
+		tCase := tCase
+
+		t.Run(tName, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tCase.Error, Verify(tCase.Secret, tCase.Header, tCase.Body, tCase.Now, tCase.Tolerance))
+		})
+	}
+}
+
+func Test_Backoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(0))
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 4*time.Minute, Backoff(4))
+	assert.Equal(t, 6*time.Hour, Backoff(20))
+}
+
+func Test_Send(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Now()
+
+	t.Run("successful delivery", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.Equal(t, "recipe.created", r.Header.Get(HeaderEvent))
+			assert.Equal(t, "1", r.Header.Get(HeaderDelivery))
+			assert.NoError(t, Verify(secret, r.Header.Get(HeaderSignature), body, now, time.Minute))
+
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer srv.Close()
+
+		code, err := Send(context.Background(), srv.Client(), Delivery{
+			ID:     "1",
+			Event:  "recipe.created",
+			URL:    srv.URL,
+			Secret: secret,
+			Body:   []byte(`{}`),
+		}, now)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, code)
+	})
+
+	t.Run("unexpected status", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer srv.Close()
+
+		code, err := Send(context.Background(), srv.Client(), Delivery{URL: srv.URL}, now)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusBadGateway, code)
+	})
+
+	t.Run("unreachable receiver", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+
+		code, err := Send(context.Background(), srv.Client(), Delivery{URL: srv.URL}, now)
+		assert.Error(t, err)
+		assert.Zero(t, code)
+	})
+
+	t.Run("redirects are not followed", func(t *testing.T) {
+		t.Parallel()
+
+		var redirected bool
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/target" {
+				redirected = true
+				return
+			}
+
+			http.Redirect(w, r, "/target", http.StatusTemporaryRedirect)
+		}))
+		defer srv.Close()
+
+		code, err := Send(context.Background(), newClient(time.Second, nil), Delivery{URL: srv.URL}, now)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusTemporaryRedirect, code)
+		assert.False(t, redirected)
+	})
+}
+
+func Test_NewClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("internal address was reached")
+	}))
+	defer srv.Close()
+
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+
+	tests := map[string]struct {
+		URL string
+	}{
+		"Loopback address": {
+			URL: srv.URL,
+		},
+		"Loopback host name": {
+			URL: fmt.Sprintf("http://localhost:%d", port),
+		},
+		"IPv6 loopback address": {
+			URL: fmt.Sprintf("http://[::1]:%d", port),
+		},
+		"Private address": {
+			URL: "http://10.0.0.1",
+		},
+		"Link-local address": {
+			URL: "http://169.254.169.254/latest/meta-data",
+		},
+		"Unspecified address": {
+			URL: fmt.Sprintf("http://0.0.0.0:%d", port),
+		},
+	}
+
+	for tName, tCase := range tests {
+		tCase := tCase
+
+		t.Run(tName, func(t *testing.T) {
+			t.Parallel()
+
+			code, err := Send(context.Background(), NewClient(time.Second), Delivery{URL: tCase.URL}, time.Now())
+			assert.ErrorIs(t, err, ErrForbiddenAddress)
+			assert.Zero(t, code)
+		})
+	}
+}
+
+func Test_checkAddress(t *testing.T) {
+	assert.NoError(t, checkAddress("tcp", "93.184.216.34:443", nil))
+	assert.NoError(t, checkAddress("tcp6", "[2606:2800:220:1:248:1893:25c8:1946]:443", nil))
+	assert.ErrorIs(t, checkAddress("tcp", "192.168.1.1:80", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, checkAddress("tcp6", "[::ffff:127.0.0.1]:80", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, checkAddress("tcp6", "[fe80::1]:80", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, checkAddress("tcp6", "[fd00::1]:80", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, checkAddress("tcp", "100.64.0.1:80", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, checkAddress("tcp", "100.127.255.254:80", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, checkAddress("tcp", "0.0.0.0:80", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, checkAddress("tcp", "0.1.2.3:80", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, checkAddress("tcp6", "[::]:80", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, checkAddress("tcp6", "[::ffff:0.0.0.0]:80", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, checkAddress("tcp6", "[::ffff:100.64.0.1]:80", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, checkAddress("tcp6", "[::ffff:169.254.169.254]:80", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, checkAddress("tcp6", "[::127.0.0.1]:80", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, checkAddress("tcp6", "[64:ff9b::10.0.0.1]:80", nil), ErrForbiddenAddress)
+	assert.NoError(t, checkAddress("tcp", "100.128.0.1:80", nil))
+	assert.NoError(t, checkAddress("tcp6", "[64:ff9b::93.184.216.34]:443", nil))
+}
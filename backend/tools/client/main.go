@@ -132,13 +132,15 @@ func (c *Client) Products(ctx context.Context) ([]core.Product, error) {
 
 	switch resp.StatusCode {
 	case http.StatusOK:
-		var pp []core.Product
+		var pg struct {
+			Data []core.Product `json:"data"`
+		}
 
-		if err := json.NewDecoder(resp.Body).Decode(&pp); err != nil {
+		if err := json.NewDecoder(resp.Body).Decode(&pg); err != nil {
 			return nil, err
 		}
 
-		return pp, nil
+		return pg.Data, nil
 	default:
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
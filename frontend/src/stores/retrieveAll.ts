@@ -0,0 +1,28 @@
+import axios, { type AxiosRequestConfig } from 'axios'
+
+// retrieveAll retrieves every page of a paginated list by following the
+// next_cursor of each page. It resolves to a response-like object, so that
+// it can be used in place of a single axios request.
+export async function retrieveAll(url: string, config: AxiosRequestConfig = {}) {
+	let data: Array<any> = [];
+	let cursor: string | null = null;
+
+	do {
+		const response: any = await axios.get(url, {
+			...config,
+			params: {
+				...config.params,
+				limit: 100,
+				...(cursor ? { cursor: cursor } : {}),
+			},
+		});
+
+		data = data.concat(response.data.data);
+		cursor = response.data.next_cursor;
+	} while (cursor);
+
+	return {
+		status: 200,
+		data: data,
+	}
+}
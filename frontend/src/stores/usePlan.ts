@@ -3,6 +3,7 @@ import { defineStore } from "pinia"
 import { useAuth } from "@/stores/useAuth"
 import { useRecipe } from "@/stores/useRecipe"
 import axios from 'axios'
+import { retrieveAll } from "@/stores/retrieveAll"
 
 export const usePlan = defineStore("plan", () => {
 	interface Plan {
@@ -26,8 +27,7 @@ export const usePlan = defineStore("plan", () => {
 
 			let recipes = response.data;
 
-			return axios
-				.get("/api/plans")
+			return retrieveAll("/api/plans")
 				.then((response) => {
 					if (response.status == 200) {
 						let new_plans = response.data;
@@ -2,6 +2,7 @@ import { ref } from "vue"
 import { defineStore } from "pinia"
 import { useAuth } from "@/stores/useAuth"
 import axios from 'axios'
+import { retrieveAll } from "@/stores/retrieveAll"
 
 export const useProduct = defineStore("product", () => {
 	interface Product {
@@ -34,8 +35,7 @@ export const useProduct = defineStore("product", () => {
 	}
 
 	function retrieveProducts() {
-		return axios
-			.get("/api/products")
+		return retrieveAll("/api/products")
 			.then((response) => {
 				if (response.status == 200) {
 					products.value = response.data;
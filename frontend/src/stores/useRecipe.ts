@@ -3,6 +3,7 @@ import { defineStore } from "pinia"
 import { useAuth } from "@/stores/useAuth"
 import { useProduct } from "@/stores/useProduct"
 import axios from 'axios'
+import { retrieveAll } from "@/stores/retrieveAll"
 
 export const useRecipe = defineStore("recipe", () => {
 	interface Recipe {
@@ -26,8 +27,7 @@ export const useRecipe = defineStore("recipe", () => {
 
 			let products = response.data;
 
-			return axios
-				.get("/api/recipes")
+			return retrieveAll("/api/recipes")
 				.then((response) => {
 					if (response.status == 200) {
 						let new_recipes = response.data;
@@ -2,17 +2,17 @@ import { ref } from "vue"
 import { defineStore } from "pinia"
 import { useAuth } from "@/stores/useAuth"
 import axios from 'axios'
+import { retrieveAll } from "@/stores/retrieveAll"
 
 export const useUser = defineStore("user", () => {
 	const users = ref<Array<Object>>([]);
 
 	function retrieveUsers() {
-		return axios
-			.get("/api/users", {
-				headers: {
-					Authorization: 'Bearer ' + useAuth().key,
-				}
-			})
+		return retrieveAll("/api/users", {
+			headers: {
+				Authorization: 'Bearer ' + useAuth().key,
+			}
+		})
 			.then((response) => {
 				if (response.status == 200) {
 					users.value = response.data;